@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -11,6 +12,10 @@ import (
 	"time"
 
 	querybuilder "github.com/omarhamdy49/go-query-builder"
+	"github.com/omarhamdy49/go-query-builder/pkg/anomaly"
+	"github.com/omarhamdy49/go-query-builder/pkg/ratelimit"
+	"github.com/omarhamdy49/go-query-builder/pkg/security"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
 // Real-Time Threat Detection & Advanced Protection
@@ -64,59 +69,37 @@ func realtimeThreatDetection() {
 
 	fmt.Println("\n🔍 Analyzing Query Patterns for Anomalies:")
 
-	// Simulate various query patterns
-	queryPatterns := []struct {
+	monitor := anomaly.New(anomaly.Options{
+		MaxQueries:          100,
+		MaxDistinctBindings: 3,
+		RowSpikeFactor:      10,
+		MinRows:             1000,
+	}).OnAlert(func(ctx context.Context, alert anomaly.Alert) {
+		fmt.Printf("     🚨 %s\n", alert)
+		log.Printf("SECURITY RESPONSE: %s - initiating lockdown", alert)
+	})
+	removeMonitor := querybuilder.AddHook(monitor)
+	defer removeMonitor()
+
+	patterns := []struct {
 		description string
-		queries     []string
-		riskLevel   string
+		caller      string
+		ids         []int
 	}{
-		{
-			"Normal User Behavior",
-			[]string{
-				"SELECT * FROM users WHERE id = ?",
-				"SELECT * FROM posts WHERE author_id = ?",
-				"UPDATE users SET last_login = ? WHERE id = ?",
-			},
-			"LOW",
-		},
-		{
-			"Suspicious Enumeration Pattern",
-			[]string{
-				"SELECT * FROM users WHERE id = 1",
-				"SELECT * FROM users WHERE id = 2",
-				"SELECT * FROM users WHERE id = 3",
-				"SELECT * FROM users WHERE id = 4",
-			},
-			"MEDIUM",
-		},
-		{
-			"Potential Attack Pattern",
-			[]string{
-				"SELECT * FROM users WHERE name = 'admin'",
-				"SELECT * FROM users WHERE name = 'administrator'",
-				"SELECT * FROM users WHERE name = 'root'",
-				"SELECT * FROM users WHERE role = 'admin'",
-			},
-			"HIGH",
-		},
+		{"Normal User Behavior", "user-42", []int{42, 42, 42}},
+		{"Suspicious Enumeration Pattern", "user-666", []int{1, 2, 3, 4, 5}},
 	}
 
-	detector := NewAnomalyDetector()
+	for _, pattern := range patterns {
+		fmt.Printf("\n   📊 Pattern: %s\n", pattern.description)
 
-	for _, pattern := range queryPatterns {
-		fmt.Printf("\n   📊 Pattern: %s (Risk: %s)\n", pattern.description, pattern.riskLevel)
-
-		anomalyScore := detector.AnalyzeQueryPattern(pattern.queries)
-		fmt.Printf("     Anomaly Score: %.2f/10.0\n", anomalyScore)
-
-		if anomalyScore > 7.0 {
-			fmt.Printf("     🚨 HIGH RISK: Automatic security response triggered!\n")
-			detector.TriggerSecurityResponse(pattern.description, anomalyScore)
-		} else if anomalyScore > 5.0 {
-			fmt.Printf("     ⚠️  MEDIUM RISK: Enhanced monitoring enabled\n")
-		} else {
-			fmt.Printf("     ✅ LOW RISK: Normal behavior pattern\n")
+		callerCtx := ratelimit.WithCaller(ctx, pattern.caller)
+		for _, id := range pattern.ids {
+			if _, err := querybuilder.QB().Table("users").Where("id", id).First(callerCtx); err != nil && !errors.Is(err, types.ErrNotFound) {
+				fmt.Printf("     ❌ Query failed: %v\n", err)
+			}
 		}
+		fmt.Printf("     %d statements this window\n", monitor.Queries(pattern.caller))
 	}
 
 	// ==================================================================
@@ -127,7 +110,16 @@ func realtimeThreatDetection() {
 
 	fmt.Println("\n🛡️  Testing Intelligent Query Protection:")
 
-	queryFirewall := NewQueryFirewall()
+	queryFirewall := security.NewFirewall(
+		security.DenyUnboundedLike(),
+		security.DenySelectAll("users"),
+		security.MaxJoins(3),
+		security.MaxInSize(1000).As(security.FirewallWarn),
+	).OnViolation(func(v security.FirewallViolation) {
+		log.Printf("FIREWALL %s: rule %s on %s - %s", strings.ToUpper(v.Action.String()), v.Rule, v.Table, v.Message)
+	})
+	querybuilder.SetFirewall(queryFirewall)
+	defer querybuilder.SetFirewall(nil)
 
 	// Test various queries against firewall rules
 	testQueries := []struct {
@@ -169,25 +161,23 @@ func realtimeThreatDetection() {
 	for _, test := range testQueries {
 		fmt.Printf("\n   🧪 Testing: %s\n", test.description)
 
-		if queryFirewall.ShouldBlock(test.description) {
-			fmt.Printf("     🚫 BLOCKED by intelligent firewall\n")
-			queryFirewall.LogBlockedQuery(test.description, "FIREWALL_RULE_VIOLATION")
-		} else {
-			fmt.Printf("     ✅ ALLOWED through firewall\n")
-
-			// Execute the actual query
-			start := time.Now()
-			count, err := test.query()
-			duration := time.Since(start)
-
-			if err != nil {
-				fmt.Printf("     ❌ Query execution failed: %v\n", err)
-			} else {
-				fmt.Printf("     📊 Query executed: %d results in %v\n", count, duration)
-			}
+		start := time.Now()
+		count, err := test.query()
+		duration := time.Since(start)
+
+		switch {
+		case errors.Is(err, types.ErrFirewallDenied):
+			fmt.Printf("     🚫 BLOCKED by query firewall: %v\n", err)
+		case err != nil:
+			fmt.Printf("     ❌ Query execution failed: %v\n", err)
+		default:
+			fmt.Printf("     ✅ ALLOWED through firewall: %d results in %v\n", count, duration)
 		}
 	}
 
+	stats := queryFirewall.Stats()
+	fmt.Printf("\n   📈 Firewall: %d checked, %d denied, %d warned\n", stats.Checked, stats.Denied, stats.Warned)
+
 	// ==================================================================
 	// 4. HONEYPOT & TRAP QUERIES
 	// ==================================================================
@@ -196,39 +186,32 @@ func realtimeThreatDetection() {
 
 	fmt.Println("\n🍯 Deploying Security Honeypots:")
 
-	honeypotManager := NewHoneypotManager()
+	honeypots := security.NewHoneypots().
+		Block("admin_users", "user_secrets", "backup_data").
+		Flag("password").
+		OnAlert(func(ctx context.Context, alert security.HoneypotAlert) {
+			fmt.Printf("     🚨 HONEYPOT TRIGGERED: %s by %q (blocked: %v)\n", alert.Identifier, alert.Caller, alert.Blocked)
+			log.Printf("HONEYPOT ALERT: %s triggered by %s - %s", alert.Identifier, alert.Caller, alert.SQL)
+		})
+	removeHoneypots := querybuilder.AddHook(honeypots)
+	defer removeHoneypots()
 
-	// Set up honeypot traps
-	honeypots := []struct {
-		name        string
-		trapType    string
-		description string
-	}{
-		{"admin_users table", "FAKE_TABLE", "Non-existent admin table to catch privilege escalation"},
-		{"password column", "SENSITIVE_COLUMN", "Trap for attempts to access password data"},
-		{"user_secrets table", "HIGH_VALUE_TARGET", "Fake table with sensitive-sounding name"},
-		{"backup_data table", "DATA_EXFILTRATION", "Trap for bulk data extraction attempts"},
-	}
-
-	for _, honeypot := range honeypots {
-		fmt.Printf("   🍯 Honeypot: %s (%s)\n", honeypot.name, honeypot.trapType)
-		fmt.Printf("      Purpose: %s\n", honeypot.description)
-		honeypotManager.DeployHoneypot(honeypot.name, honeypot.trapType)
-	}
+	fmt.Println("   🍯 admin_users, user_secrets, backup_data: fake tables, queries are blocked")
+	fmt.Println("   🍯 password: sensitive column, queries run but are reported")
 
 	// Test honeypot triggers
 	fmt.Println("\n   🕵️  Simulating Honeypot Triggers:")
-	suspiciousQueries := []string{
-		"admin_users",  // Should trigger fake table honeypot
-		"password",     // Should trigger sensitive column honeypot
-		"user_secrets", // Should trigger high-value target honeypot
-		"backup_data",  // Should trigger data exfiltration honeypot
+	intruderCtx := ratelimit.WithCaller(ctx, "user-666")
+	suspiciousQueries := []types.QueryBuilder{
+		querybuilder.QB().Table("admin_users"),
+		querybuilder.QB().Table("users").Select("email", "password"),
+		querybuilder.QB().Table("user_secrets"),
+		querybuilder.QB().Table("backup_data").Limit(10000),
 	}
 
 	for _, query := range suspiciousQueries {
-		if honeypotManager.CheckHoneypotTrigger(query) {
-			fmt.Printf("     🚨 HONEYPOT TRIGGERED: Suspicious query detected - '%s'\n", query)
-			honeypotManager.AlertSecurityTeam(query, "HONEYPOT_TRIGGER")
+		if _, err := query.Get(intruderCtx); errors.Is(err, types.ErrHoneypot) {
+			fmt.Printf("     🚫 BLOCKED: %v\n", err)
 		}
 	}
 
@@ -372,109 +355,6 @@ func (rl *RateLimiter) LogSuspiciousActivity(userID, activity string) {
 	log.Printf("SECURITY ALERT: User %s - %s", userID, activity)
 }
 
-type AnomalyDetector struct {
-	patterns map[string]int
-}
-
-func NewAnomalyDetector() *AnomalyDetector {
-	return &AnomalyDetector{
-		patterns: make(map[string]int),
-	}
-}
-
-func (ad *AnomalyDetector) AnalyzeQueryPattern(queries []string) float64 {
-	score := 0.0
-
-	// Check for enumeration patterns
-	if len(queries) > 3 {
-		score += 3.0
-	}
-
-	// Check for admin-related queries
-	for _, query := range queries {
-		if strings.Contains(strings.ToLower(query), "admin") {
-			score += 2.0
-		}
-		if strings.Contains(strings.ToLower(query), "root") {
-			score += 2.5
-		}
-		if strings.Contains(strings.ToLower(query), "password") {
-			score += 3.0
-		}
-	}
-
-	// Cap at 10.0
-	if score > 10.0 {
-		score = 10.0
-	}
-
-	return score
-}
-
-func (ad *AnomalyDetector) TriggerSecurityResponse(pattern string, score float64) {
-	log.Printf("SECURITY RESPONSE: Pattern '%s' with score %.2f - Initiating lockdown", pattern, score)
-}
-
-type QueryFirewall struct {
-	rules map[string]bool
-}
-
-func NewQueryFirewall() *QueryFirewall {
-	qf := &QueryFirewall{
-		rules: make(map[string]bool),
-	}
-
-	// Initialize firewall rules
-	qf.rules["broad scan"] = true
-	qf.rules["data mining"] = true
-	qf.rules["bulk extract"] = true
-
-	return qf
-}
-
-func (qf *QueryFirewall) ShouldBlock(query string) bool {
-	queryLower := strings.ToLower(query)
-
-	for rule := range qf.rules {
-		if strings.Contains(queryLower, rule) {
-			return true
-		}
-	}
-
-	return false
-}
-
-func (qf *QueryFirewall) LogBlockedQuery(query, reason string) {
-	log.Printf("FIREWALL BLOCK: Query '%s' - Reason: %s", query, reason)
-}
-
-type HoneypotManager struct {
-	honeypots map[string]string
-}
-
-func NewHoneypotManager() *HoneypotManager {
-	return &HoneypotManager{
-		honeypots: make(map[string]string),
-	}
-}
-
-func (hm *HoneypotManager) DeployHoneypot(name, trapType string) {
-	hm.honeypots[name] = trapType
-}
-
-func (hm *HoneypotManager) CheckHoneypotTrigger(query string) bool {
-	for name := range hm.honeypots {
-		if strings.Contains(strings.ToLower(query), strings.ToLower(name)) {
-			return true
-		}
-	}
-	return false
-}
-
-func (hm *HoneypotManager) AlertSecurityTeam(trigger, alertType string) {
-	log.Printf("HONEYPOT ALERT: %s triggered - Type: %s", trigger, alertType)
-}
-
 type ThreatIntelligence struct {
 	knownThreats map[string]float64
 }
@@ -7,12 +7,14 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"time"
 
 	querybuilder "github.com/omarhamdy49/go-query-builder"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
 // Ultimate Security Features - Enterprise-Grade Protection
@@ -109,6 +111,8 @@ func main3() {
 	fmt.Println("====================================")
 
 	fmt.Println("\n⚡ Testing Query Complexity Limits:")
+	fmt.Println("   Connections started with DB_COMPLEXITY_BUDGET=10 reject statements scoring above 10")
+	fmt.Println("   (joins, subqueries, LIMIT-less scans and LIKE wildcards add to the score)")
 
 	// Test various query complexity scenarios
 	complexityTests := []struct {
@@ -153,6 +157,10 @@ func main3() {
 		count, err := test.queryFunc()
 		duration := time.Since(start)
 
+		if errors.Is(err, types.ErrComplexityBudget) {
+			fmt.Printf("     🚫 BLOCKED before reaching the database: %v\n", err)
+			continue
+		}
 		if err != nil {
 			fmt.Printf("     ❌ Query failed: %v\n", err)
 			continue
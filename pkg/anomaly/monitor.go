@@ -0,0 +1,253 @@
+// Package anomaly watches the statements each caller runs and reports unusual patterns: callers
+// running far more statements than usual, the same query shape run with many different bindings
+// (enumerating ids or emails one at a time), and queries returning many more rows than they
+// normally do.
+//
+// A Monitor is registered as a query hook (see querybuilder.AddHook) and never blocks statements;
+// it calls the function set with OnAlert, which may for example revoke the caller's session. The
+// caller is read from the context with ratelimit.WithCaller.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/ratelimit"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Kind is the kind of an Alert.
+type Kind string
+
+const (
+	// RateExceeded means a caller ran more than Options.MaxQueries statements within a window.
+	RateExceeded Kind = "rate_exceeded"
+	// Enumeration means a caller ran one query shape with more than Options.MaxDistinctBindings
+	// different bindings within a window.
+	Enumeration Kind = "enumeration"
+	// RowSpike means a query returned more than Options.RowSpikeFactor times the rows its shape
+	// usually returns.
+	RowSpike Kind = "row_spike"
+)
+
+// Options sets the thresholds of a Monitor. Zero values disable the corresponding check.
+type Options struct {
+	Window              time.Duration // length of the counting windows, default one minute
+	MaxQueries          int           // statements per caller and window
+	MaxDistinctBindings int           // distinct bindings of one query shape per caller and window
+	RowSpikeFactor      float64       // rows compared with the average of the query shape
+	MinRows             int64         // results smaller than this never count as a spike
+	MinSamples          int64         // executions of a query shape before spikes are reported
+}
+
+// Alert describes a threshold exceeded by a caller.
+type Alert struct {
+	Kind        Kind
+	Caller      string
+	Table       string
+	Fingerprint string  // the statement with literals and IN lists normalized
+	Count       int64   // statements or distinct bindings in the window, or rows for a RowSpike
+	Baseline    float64 // the usual number of rows of the query shape, for a RowSpike
+}
+
+// String describes the alert.
+func (a Alert) String() string {
+	switch a.Kind {
+	case RowSpike:
+		return fmt.Sprintf("%s: caller %q read %d rows from %s, usually %.0f", a.Kind, a.Caller, a.Count, a.Table, a.Baseline)
+	default:
+		return fmt.Sprintf("%s: caller %q reached %d on %s", a.Kind, a.Caller, a.Count, a.Table)
+	}
+}
+
+type callerWindow struct {
+	start    time.Time
+	queries  int
+	bindings map[string]map[string]struct{} // fingerprint -> distinct bindings
+	alerted  map[string]bool                // kind and fingerprint already reported in this window
+}
+
+type baseline struct {
+	average float64
+	samples int64
+}
+
+// Monitor aggregates per-caller query fingerprints and rates and reports anomalies.
+type Monitor struct {
+	options Options
+	onAlert func(ctx context.Context, alert Alert)
+
+	mu        sync.Mutex
+	callers   map[string]*callerWindow
+	baselines map[string]*baseline
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// New creates a Monitor with the given thresholds.
+func New(options Options) *Monitor {
+	if options.Window <= 0 {
+		options.Window = time.Minute
+	}
+	if options.MinSamples <= 0 {
+		options.MinSamples = 10
+	}
+	return &Monitor{
+		options:   options,
+		callers:   make(map[string]*callerWindow),
+		baselines: make(map[string]*baseline),
+		now:       time.Now,
+	}
+}
+
+// OnAlert sets the function called with every alert. It runs on the goroutine that ran the
+// statement, so slow reactions should be handed off.
+func (m *Monitor) OnAlert(fn func(ctx context.Context, alert Alert)) *Monitor {
+	m.onAlert = fn
+	return m
+}
+
+// BeforeQuery implements types.QueryHook. It lets every statement run.
+func (m *Monitor) BeforeQuery(context.Context, types.QueryEvent) error {
+	return nil
+}
+
+// AfterQuery implements types.QueryObserver, recording the statement and reporting the
+// thresholds it exceeds.
+func (m *Monitor) AfterQuery(ctx context.Context, entry types.QueryLog) {
+	if entry.Err != nil {
+		return
+	}
+	caller := ratelimit.CallerFromContext(ctx)
+	fingerprint := Fingerprint(entry.SQL)
+	alerts := m.record(caller, fingerprint, entry)
+
+	if m.onAlert == nil {
+		return
+	}
+	for _, alert := range alerts {
+		m.onAlert(ctx, alert)
+	}
+}
+
+func (m *Monitor) record(caller, fingerprint string, entry types.QueryLog) []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.prune(now)
+
+	window := m.callers[caller]
+	if window == nil || now.Sub(window.start) >= m.options.Window {
+		window = &callerWindow{start: now, bindings: make(map[string]map[string]struct{}), alerted: make(map[string]bool)}
+		m.callers[caller] = window
+	}
+	window.queries++
+
+	var alerts []Alert
+	alert := func(kind Kind, count int64, baseline float64) {
+		key := string(kind) + "\x00" + fingerprint
+		if kind == RateExceeded {
+			key = string(kind)
+		}
+		if window.alerted[key] {
+			return
+		}
+		window.alerted[key] = true
+		alerts = append(alerts, Alert{Kind: kind, Caller: caller, Table: entry.Table, Fingerprint: fingerprint, Count: count, Baseline: baseline})
+	}
+
+	if limit := m.options.MaxQueries; limit > 0 && window.queries > limit {
+		alert(RateExceeded, int64(window.queries), 0)
+	}
+
+	if limit := m.options.MaxDistinctBindings; limit > 0 && len(entry.Bindings) > 0 {
+		seen := window.bindings[fingerprint]
+		if seen == nil {
+			seen = make(map[string]struct{})
+			window.bindings[fingerprint] = seen
+		}
+		if len(seen) <= limit {
+			seen[fmt.Sprint(entry.Bindings)] = struct{}{}
+		}
+		if len(seen) > limit {
+			alert(Enumeration, int64(len(seen)), 0)
+		}
+	}
+
+	if factor := m.options.RowSpikeFactor; factor > 0 && entry.Rows >= 0 && isRead(entry.SQL) {
+		b := m.baselines[fingerprint]
+		if b == nil {
+			b = &baseline{}
+			m.baselines[fingerprint] = b
+		}
+		if b.samples >= m.options.MinSamples && entry.Rows >= m.options.MinRows && float64(entry.Rows) > factor*b.average {
+			alert(RowSpike, entry.Rows, b.average)
+		}
+		b.samples++
+		b.average += (float64(entry.Rows) - b.average) / float64(min(b.samples, 100))
+	}
+
+	return alerts
+}
+
+// prune drops the windows of callers that stopped sending statements. It runs at most once per
+// window.
+func (m *Monitor) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < m.options.Window {
+		return
+	}
+	m.lastPrune = now
+
+	for caller, window := range m.callers {
+		if now.Sub(window.start) >= m.options.Window {
+			delete(m.callers, caller)
+		}
+	}
+}
+
+// Queries returns the number of statements caller ran in its current window.
+func (m *Monitor) Queries(caller string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if window := m.callers[caller]; window != nil && m.now().Sub(window.start) < m.options.Window {
+		return window.queries
+	}
+	return 0
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteral  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	dollarParam    = regexp.MustCompile(`\$\d+`)
+	placeholderSet = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint normalizes sql to its shape: literals and PostgreSQL parameters become ?, IN lists
+// of any length become (?+) and whitespace is collapsed, so the same query with different values
+// has the same fingerprint.
+func Fingerprint(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = dollarParam.ReplaceAllString(sql, "?")
+	sql = numberLiteral.ReplaceAllString(sql, "?")
+	sql = placeholderSet.ReplaceAllString(sql, "(?+)")
+	return strings.TrimSpace(whitespace.ReplaceAllString(sql, " "))
+}
+
+func isRead(sql string) bool {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "(SELECT":
+		return true
+	}
+	return false
+}
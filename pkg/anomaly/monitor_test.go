@@ -0,0 +1,76 @@
+package anomaly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/querybuildertest"
+	"github.com/omarhamdy49/go-query-builder/pkg/ratelimit"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE id = 42":              "SELECT * FROM users WHERE id = ?",
+		"SELECT *  FROM users\n WHERE name = 'O''Brien'": "SELECT * FROM users WHERE name = ?",
+		"SELECT * FROM users WHERE id IN ($1, $2, $3)":   "SELECT * FROM users WHERE id IN (?+)",
+		"SELECT * FROM t2 WHERE id IN (?, ?) LIMIT 10":   "SELECT * FROM t2 WHERE id IN (?+) LIMIT ?",
+	}
+	for sql, expected := range tests {
+		if got := Fingerprint(sql); got != expected {
+			t.Errorf("Fingerprint(%q) = %q, expected %q", sql, got, expected)
+		}
+	}
+}
+
+func TestMonitorAlerts(t *testing.T) {
+	var alerts []Alert
+	monitor := New(Options{MaxQueries: 5, MaxDistinctBindings: 3, RowSpikeFactor: 5, MinRows: 100, MinSamples: 3}).
+		OnAlert(func(_ context.Context, alert Alert) { alerts = append(alerts, alert) })
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+
+	ctx := ratelimit.WithCaller(context.Background(), "scraper")
+	for id := 1; id <= 6; id++ {
+		monitor.AfterQuery(ctx, types.QueryLog{Table: "users", SQL: "SELECT * FROM users WHERE id = ?", Bindings: []interface{}{id}, Rows: 1})
+	}
+	if len(alerts) != 2 || alerts[0].Kind != Enumeration || alerts[1].Kind != RateExceeded {
+		t.Fatalf("Expected one enumeration and one rate alert, got %v", alerts)
+	}
+	if alerts[0].Caller != "scraper" || alerts[0].Count != 4 {
+		t.Errorf("Unexpected enumeration alert %+v", alerts[0])
+	}
+
+	alerts = nil
+	now = now.Add(time.Minute)
+	report := types.QueryLog{Table: "orders", SQL: "SELECT * FROM orders WHERE day = ?", Bindings: []interface{}{"monday"}}
+	for _, rows := range []int64{50, 60, 40, 250, 5000} {
+		report.Rows = rows
+		monitor.AfterQuery(ctx, report)
+	}
+	if len(alerts) != 1 || alerts[0].Kind != RowSpike || alerts[0].Count != 5000 {
+		t.Fatalf("Expected a single row spike alert, got %v", alerts)
+	}
+	if monitor.Queries("scraper") != 5 {
+		t.Errorf("Expected the window to restart, got %d statements", monitor.Queries("scraper"))
+	}
+}
+
+func TestMonitorObservesStatements(t *testing.T) {
+	monitor := New(Options{})
+	remove := execution.AddHook(monitor)
+	defer remove()
+
+	db := querybuildertest.New(types.MySQL)
+	ctx := ratelimit.WithCaller(context.Background(), "api")
+	for i := 0; i < 3; i++ {
+		if _, err := db.Table("users").Where("id", i).Get(ctx); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	if n := monitor.Queries("api"); n != 3 {
+		t.Errorf("Expected 3 observed statements, got %d", n)
+	}
+}
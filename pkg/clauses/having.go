@@ -11,6 +11,7 @@ type HavingClause struct {
 	Value    interface{}
 	Boolean  types.BooleanOperator
 	Raw      string
+	Bindings []interface{}
 }
 
 // NewHavingClause creates a new HAVING clause with the specified column, operator, and value.
@@ -23,11 +24,12 @@ func NewHavingClause(column string, operator types.Operator, value interface{})
 	}
 }
 
-// NewHavingRawClause creates a new HAVING clause using raw SQL.
-func NewHavingRawClause(raw string) *HavingClause {
+// NewHavingRawClause creates a new HAVING clause using raw SQL whose placeholders are bound to the given values.
+func NewHavingRawClause(raw string, bindings ...interface{}) *HavingClause {
 	return &HavingClause{
-		Raw:     raw,
-		Boolean: types.And,
+		Raw:      raw,
+		Bindings: bindings,
+		Boolean:  types.And,
 	}
 }
 
@@ -65,4 +67,9 @@ func (h *HavingClause) GetBoolean() types.BooleanOperator {
 // GetRaw returns the raw SQL for this HAVING clause.
 func (h *HavingClause) GetRaw() string {
 	return h.Raw
+}
+
+// GetBindings returns the bindings of the raw SQL for this HAVING clause.
+func (h *HavingClause) GetBindings() []interface{} {
+	return h.Bindings
 }
\ No newline at end of file
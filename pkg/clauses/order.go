@@ -8,6 +8,7 @@ import (
 type OrderClause struct {
 	Column    string
 	Direction types.OrderDirection
+	Nulls     types.NullsPosition
 	Raw       string
 }
 
@@ -19,6 +20,15 @@ func NewOrderClause(column string, direction types.OrderDirection) *OrderClause
 	}
 }
 
+// NewOrderNullsClause creates a new ORDER BY clause with an explicit NULL ordering.
+func NewOrderNullsClause(column string, direction types.OrderDirection, nulls types.NullsPosition) *OrderClause {
+	return &OrderClause{
+		Column:    column,
+		Direction: direction,
+		Nulls:     nulls,
+	}
+}
+
 // NewOrderRawClause creates a new ORDER BY clause using raw SQL.
 func NewOrderRawClause(raw string) *OrderClause {
 	return &OrderClause{
@@ -41,6 +51,11 @@ func (o *OrderClause) GetDirection() types.OrderDirection {
 	return o.Direction
 }
 
+// GetNulls returns the NULL ordering for this ORDER BY clause, or "" for the database default.
+func (o *OrderClause) GetNulls() types.NullsPosition {
+	return o.Nulls
+}
+
 // GetRaw returns the raw SQL for this ORDER BY clause.
 func (o *OrderClause) GetRaw() string {
 	return o.Raw
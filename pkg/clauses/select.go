@@ -2,9 +2,10 @@ package clauses
 
 // SelectClause represents a SELECT clause in an SQL query.
 type SelectClause struct {
-	Column string
-	Alias  string
-	Raw    string
+	Column   string
+	Alias    string
+	Raw      string
+	Bindings []interface{}
 }
 
 // NewSelectClause creates a new SELECT clause for the specified column.
@@ -29,6 +30,14 @@ func NewSelectRawClause(raw string) *SelectClause {
 	}
 }
 
+// NewSelectRawClauseWithBindings creates a new raw SELECT clause whose placeholders are bound to the given values.
+func NewSelectRawClauseWithBindings(raw string, bindings ...interface{}) *SelectClause {
+	return &SelectClause{
+		Raw:      raw,
+		Bindings: bindings,
+	}
+}
+
 // IsRaw returns true if this is a raw SELECT clause.
 func (s *SelectClause) IsRaw() bool {
 	return s.Raw != ""
@@ -52,4 +61,9 @@ func (s *SelectClause) GetAlias() string {
 // GetRaw returns the raw SQL for this SELECT clause.
 func (s *SelectClause) GetRaw() string {
 	return s.Raw
+}
+
+// GetBindings returns the bindings for this SELECT clause.
+func (s *SelectClause) GetBindings() []interface{} {
+	return s.Bindings
 }
\ No newline at end of file
@@ -4,10 +4,19 @@ import (
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
-// UnionClause represents a UNION clause in an SQL query.
+// Set operators combining a query with another.
+const (
+	SetUnion     = "UNION"
+	SetIntersect = "INTERSECT"
+	SetExcept    = "EXCEPT"
+)
+
+// UnionClause represents a UNION clause in an SQL query, or another set operation (INTERSECT or
+// EXCEPT) when Operator is set.
 type UnionClause struct {
-	Query types.QueryBuilder
-	All   bool
+	Query    types.QueryBuilder
+	All      bool
+	Operator string
 }
 
 // NewUnionClause creates a new UNION clause for the specified query.
@@ -26,12 +35,44 @@ func NewUnionAllClause(query types.QueryBuilder) *UnionClause {
 	}
 }
 
+// NewIntersectClause creates a new INTERSECT clause for the specified query.
+func NewIntersectClause(query types.QueryBuilder) *UnionClause {
+	return &UnionClause{
+		Query:    query,
+		Operator: SetIntersect,
+	}
+}
+
+// NewExceptClause creates a new EXCEPT clause for the specified query.
+func NewExceptClause(query types.QueryBuilder) *UnionClause {
+	return &UnionClause{
+		Query:    query,
+		Operator: SetExcept,
+	}
+}
+
 // IsUnionAll returns true if this is a UNION ALL clause.
 func (u *UnionClause) IsUnionAll() bool {
 	return u.All
 }
 
+// GetOperator returns the set operator of this clause: SetUnion, SetIntersect or SetExcept.
+func (u *UnionClause) GetOperator() string {
+	if u.Operator == "" {
+		return SetUnion
+	}
+	return u.Operator
+}
+
+// Keyword returns the SQL keyword of this clause, e.g. "UNION ALL".
+func (u *UnionClause) Keyword() string {
+	if u.All {
+		return u.GetOperator() + " ALL"
+	}
+	return u.GetOperator()
+}
+
 // GetQuery returns the query for this UNION clause.
 func (u *UnionClause) GetQuery() types.QueryBuilder {
 	return u.Query
-}
\ No newline at end of file
+}
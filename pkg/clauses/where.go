@@ -1,6 +1,10 @@
 package clauses
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
@@ -14,6 +18,9 @@ type WhereClause struct {
 	Query    types.QueryBuilder
 	Boolean  types.BooleanOperator
 	Raw      string
+	Bindings []interface{}
+	FullText *types.FullTextOptions
+	Columns  []string // tuple_in: the compared columns, with Values holding the tuples flattened
 }
 
 // NewWhereClause creates a new WHERE clause with the specified column, operator, and value.
@@ -27,12 +34,13 @@ func NewWhereClause(column string, operator types.Operator, value interface{}) *
 	}
 }
 
-// NewWhereRawClause creates a new WHERE clause using raw SQL.
-func NewWhereRawClause(raw string) *WhereClause {
+// NewWhereRawClause creates a new WHERE clause using raw SQL whose placeholders are bound to the given values.
+func NewWhereRawClause(raw string, bindings ...interface{}) *WhereClause {
 	return &WhereClause{
-		Type:    "raw",
-		Raw:     raw,
-		Boolean: types.And,
+		Type:     "raw",
+		Raw:      raw,
+		Bindings: bindings,
+		Boolean:  types.And,
 	}
 }
 
@@ -52,6 +60,29 @@ func NewWhereBetweenClause(column string, values []interface{}, not bool) *Where
 	}
 }
 
+// NewWhereBetweenColumnsClause creates a new WHERE clause checking that a value lies between two columns.
+func NewWhereBetweenColumnsClause(value interface{}, lowColumn, highColumn string) *WhereClause {
+	return &WhereClause{
+		Type:     "between_columns",
+		Operator: types.OpBetween,
+		Value:    value,
+		Values:   []interface{}{lowColumn, highColumn},
+		Boolean:  types.And,
+	}
+}
+
+// NewWhereRelativeDateClause creates a new WHERE clause comparing a column to the database's current
+// time shifted back by the given duration.
+func NewWhereRelativeDateClause(column string, operator types.Operator, ago time.Duration) *WhereClause {
+	return &WhereClause{
+		Type:     "relative_date",
+		Column:   column,
+		Operator: operator,
+		Value:    ago.Microseconds(),
+		Boolean:  types.And,
+	}
+}
+
 // NewWhereInClause creates a new IN or NOT IN WHERE clause.
 func NewWhereInClause(column string, values []interface{}, not bool) *WhereClause {
 	operator := types.OpIn
@@ -68,6 +99,51 @@ func NewWhereInClause(column string, values []interface{}, not bool) *WhereClaus
 	}
 }
 
+// NewWhereIntegerInClause creates an IN WHERE clause with the values inlined in the SQL rather than
+// bound, so the list is not limited by the driver's maximum number of parameters. An empty list
+// matches no rows.
+func NewWhereIntegerInClause(column string, values []int64) *WhereClause {
+	raw := "1 = 0"
+	if len(values) > 0 {
+		sql := make([]byte, 0, len(column)+5+len(values)*8)
+		sql = append(sql, column...)
+		sql = append(sql, " IN ("...)
+		for i, value := range values {
+			if i > 0 {
+				sql = append(sql, ", "...)
+			}
+			sql = strconv.AppendInt(sql, value, 10)
+		}
+		raw = string(append(sql, ')'))
+	}
+
+	return &WhereClause{
+		Type:     "integer_in",
+		Column:   column,
+		Operator: types.OpIn,
+		Raw:      raw,
+		Boolean:  types.And,
+	}
+}
+
+// NewWhereTupleInClause creates a WHERE clause that matches rows whose columns, compared as a
+// tuple, equal one of tuples. An empty list matches no rows.
+func NewWhereTupleInClause(columns []string, tuples [][]interface{}) *WhereClause {
+	values := make([]interface{}, 0, len(tuples)*len(columns))
+	for _, tuple := range tuples {
+		values = append(values, tuple...)
+	}
+
+	return &WhereClause{
+		Type:     "tuple_in",
+		Column:   "(" + strings.Join(columns, ", ") + ")",
+		Operator: types.OpIn,
+		Values:   values,
+		Columns:  columns,
+		Boolean:  types.And,
+	}
+}
+
 // NewWhereNullClause creates a new IS NULL or IS NOT NULL WHERE clause.
 func NewWhereNullClause(column string, not bool) *WhereClause {
 	operator := types.OpIsNull
@@ -121,13 +197,73 @@ func NewWhereJSONLengthClause(column string, operator types.Operator, value inte
 }
 
 // NewWhereFullTextClause creates a new WHERE clause for full-text search across multiple columns.
-func NewWhereFullTextClause(columns []string, value string) *WhereClause {
+func NewWhereFullTextClause(columns []string, value string, options types.FullTextOptions) *WhereClause {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+
 	return &WhereClause{
 		Type:     "fulltext",
-		Values:   make([]interface{}, len(columns)),
+		Values:   values,
 		Operator: types.OpFullText,
 		Value:    value,
 		Boolean:  types.And,
+		FullText: &options,
+	}
+}
+
+// NewWhereErrorClause creates a WHERE clause holding an error found while building the query, such
+// as an invalid option. Compiling a query that has one fails with err.
+func NewWhereErrorClause(err error) *WhereClause {
+	return &WhereClause{
+		Type:    "error",
+		Value:   err,
+		Boolean: types.And,
+	}
+}
+
+// NewWhereLikeClause creates a new LIKE WHERE clause. Passing types.OpILike makes the match case-insensitive.
+func NewWhereLikeClause(column string, operator types.Operator, pattern string) *WhereClause {
+	return &WhereClause{
+		Type:     "like",
+		Column:   column,
+		Operator: operator,
+		Value:    pattern,
+		Boolean:  types.And,
+	}
+}
+
+// NewWhereArrayClause creates a new WHERE clause comparing an array column against an array value.
+func NewWhereArrayClause(column string, operator types.Operator, value interface{}) *WhereClause {
+	return &WhereClause{
+		Type:     "array",
+		Column:   column,
+		Operator: operator,
+		Value:    value,
+		Boolean:  types.And,
+	}
+}
+
+// NewWhereArrayAnyClause creates a new WHERE clause comparing a value against any element of an array column.
+func NewWhereArrayAnyClause(column string, operator types.Operator, value interface{}) *WhereClause {
+	return &WhereClause{
+		Type:     "array_any",
+		Column:   column,
+		Operator: operator,
+		Value:    value,
+		Boolean:  types.And,
+	}
+}
+
+// NewWhereDistanceClause creates a new WHERE clause bounding the distance from a point column. raw
+// is the driver's condition, or "" when the driver has no spatial functions.
+func NewWhereDistanceClause(column string, raw string) *WhereClause {
+	return &WhereClause{
+		Type:    "distance",
+		Column:  column,
+		Raw:     raw,
+		Boolean: types.And,
 	}
 }
 
@@ -150,4 +286,9 @@ func (w *WhereClause) HasSubQuery() bool {
 // IsRaw returns true if the WHERE clause contains raw SQL.
 func (w *WhereClause) IsRaw() bool {
 	return w.Type == "raw"
+}
+
+// GetBindings returns the bindings of the raw SQL of the WHERE clause.
+func (w *WhereClause) GetBindings() []interface{} {
+	return w.Bindings
 }
\ No newline at end of file
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// CredentialSource fetches database credentials from an external store.
+type CredentialSource interface {
+	Fetch(ctx context.Context) (types.Credentials, error)
+}
+
+// EnvCredentials reads credentials from environment variables (DB_USER / DB_PASSWORD by default).
+type EnvCredentials struct {
+	UsernameVar string
+	PasswordVar string
+}
+
+// Fetch implements CredentialSource.
+func (s EnvCredentials) Fetch(_ context.Context) (types.Credentials, error) {
+	usernameVar, passwordVar := s.UsernameVar, s.PasswordVar
+	if usernameVar == "" {
+		usernameVar = "DB_USER"
+	}
+	if passwordVar == "" {
+		passwordVar = "DB_PASSWORD"
+	}
+
+	password, ok := os.LookupEnv(passwordVar)
+	if !ok {
+		return types.Credentials{}, fmt.Errorf("environment variable %s is not set", passwordVar)
+	}
+	return types.Credentials{Username: os.Getenv(usernameVar), Password: password}, nil
+}
+
+// FileCredentials reads credentials from a file, such as a Docker or Kubernetes secret. The file
+// holds either a JSON object with username and password, or just the password. The file is read on
+// every fetch, so a rotated secret is picked up for new connections.
+type FileCredentials struct {
+	Path string
+}
+
+// Fetch implements CredentialSource.
+func (s FileCredentials) Fetch(_ context.Context) (types.Credentials, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return types.Credentials{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "{") {
+		return parseSecretJSON(content)
+	}
+	return types.Credentials{Password: content}, nil
+}
+
+// VaultCredentials reads credentials from HashiCorp Vault over its HTTP API. Path is the full API
+// path after /v1/, e.g. "database/creds/app" (dynamic secrets) or "secret/data/db" (KV v2).
+type VaultCredentials struct {
+	Address string
+	Token   string
+	Path    string
+	Client  *http.Client
+}
+
+// Fetch implements CredentialSource.
+func (s VaultCredentials) Fetch(ctx context.Context) (types.Credentials, error) {
+	url := strings.TrimRight(s.Address, "/") + "/v1/" + strings.TrimLeft(s.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return types.Credentials{}, fmt.Errorf("failed to build Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return types.Credentials{}, fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return types.Credentials{}, fmt.Errorf("failed to read secret from Vault: %s", resp.Status)
+	}
+
+	var secret struct {
+		LeaseDuration int `json:"lease_duration"`
+		Data          struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Data     *struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+			} `json:"data"` // KV v2 nests the secret one level deeper
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return types.Credentials{}, fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	credentials := types.Credentials{Username: secret.Data.Username, Password: secret.Data.Password}
+	if secret.Data.Data != nil {
+		credentials = types.Credentials{Username: secret.Data.Data.Username, Password: secret.Data.Data.Password}
+	}
+	if secret.LeaseDuration > 0 {
+		credentials.ExpiresAt = time.Now().Add(time.Duration(secret.LeaseDuration) * time.Second)
+	}
+	return credentials, nil
+}
+
+// SecretsManagerCredentials reads credentials stored as a JSON secret string, in the format AWS
+// Secrets Manager uses for RDS secrets. GetSecretString performs the actual lookup, typically a
+// call to the AWS SDK's GetSecretValue, so this package does not depend on the SDK.
+type SecretsManagerCredentials struct {
+	SecretID        string
+	GetSecretString func(ctx context.Context, secretID string) (string, error)
+}
+
+// Fetch implements CredentialSource.
+func (s SecretsManagerCredentials) Fetch(ctx context.Context) (types.Credentials, error) {
+	if s.GetSecretString == nil {
+		return types.Credentials{}, fmt.Errorf("no GetSecretString function configured for secret %s", s.SecretID)
+	}
+
+	secret, err := s.GetSecretString(ctx, s.SecretID)
+	if err != nil {
+		return types.Credentials{}, fmt.Errorf("failed to read secret %s: %w", s.SecretID, err)
+	}
+	return parseSecretJSON(secret)
+}
+
+func parseSecretJSON(content string) (types.Credentials, error) {
+	var secret struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(content), &secret); err != nil {
+		return types.Credentials{}, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return types.Credentials{Username: secret.Username, Password: secret.Password}, nil
+}
+
+// RotatingCredentials caches credentials from a source and fetches them again once they expire or
+// are older than TTL, so secrets can rotate without restarting the application.
+type RotatingCredentials struct {
+	source    CredentialSource
+	ttl       time.Duration
+	mu        sync.Mutex
+	cached    types.Credentials
+	fetchedAt time.Time
+}
+
+// NewRotatingCredentials wraps source with a cache that refreshes after ttl (default 5 minutes).
+func NewRotatingCredentials(source CredentialSource, ttl time.Duration) *RotatingCredentials {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &RotatingCredentials{source: source, ttl: ttl}
+}
+
+// Fetch implements CredentialSource, returning cached credentials while they are still fresh.
+func (r *RotatingCredentials) Fetch(ctx context.Context) (types.Credentials, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.fetchedAt.IsZero() && now.Sub(r.fetchedAt) < r.ttl &&
+		(r.cached.ExpiresAt.IsZero() || now.Before(r.cached.ExpiresAt.Add(-30*time.Second))) {
+		return r.cached, nil
+	}
+
+	credentials, err := r.source.Fetch(ctx)
+	if err != nil {
+		return types.Credentials{}, err
+	}
+	r.cached = credentials
+	r.fetchedAt = now
+	return credentials, nil
+}
+
+// Invalidate forces the next Fetch to go to the source, e.g. after an authentication failure.
+func (r *RotatingCredentials) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetchedAt = time.Time{}
+}
+
+// UseCredentials makes new connections from config take their credentials from source.
+func UseCredentials(config *types.Config, source CredentialSource) {
+	config.CredentialsProvider = source.Fetch
+}
+
+// credentialSourceFromEnv returns the credential source selected by DB_PASSWORD_FILE or
+// DB_VAULT_PATH, or nil when the plain DB_USER / DB_PASSWORD variables should be used.
+func credentialSourceFromEnv() (CredentialSource, error) {
+	var source CredentialSource
+	switch {
+	case getEnv("DB_VAULT_PATH", "") != "":
+		address := getEnv("VAULT_ADDR", "")
+		if address == "" {
+			return nil, fmt.Errorf("VAULT_ADDR is required when DB_VAULT_PATH is set")
+		}
+		source = VaultCredentials{Address: address, Token: getEnv("VAULT_TOKEN", ""), Path: getEnv("DB_VAULT_PATH", "")}
+	case getEnv("DB_PASSWORD_FILE", "") != "":
+		source = FileCredentials{Path: getEnv("DB_PASSWORD_FILE", "")}
+	default:
+		return nil, nil
+	}
+
+	ttlStr := getEnv("DB_CREDENTIALS_TTL", "5m")
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_CREDENTIALS_TTL value: %s", ttlStr)
+	}
+	return NewRotatingCredentials(source, ttl), nil
+}
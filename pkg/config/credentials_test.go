@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestFileCredentials(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "password")
+	_ = os.WriteFile(plain, []byte("s3cret\n"), 0o600)
+	credentials, err := FileCredentials{Path: plain}.Fetch(context.Background())
+	if err != nil || credentials.Password != "s3cret" {
+		t.Errorf("Expected password s3cret, got: %+v, %v", credentials, err)
+	}
+
+	structured := filepath.Join(dir, "credentials.json")
+	_ = os.WriteFile(structured, []byte(`{"username":"app","password":"pw"}`), 0o600)
+	credentials, err = FileCredentials{Path: structured}.Fetch(context.Background())
+	if err != nil || credentials.Username != "app" || credentials.Password != "pw" {
+		t.Errorf("Expected app/pw, got: %+v, %v", credentials, err)
+	}
+}
+
+func TestVaultCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/database/creds/app" || r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"lease_duration":3600,"data":{"username":"v-app-123","password":"generated"}}`))
+	}))
+	defer server.Close()
+
+	credentials, err := VaultCredentials{Address: server.URL, Token: "token", Path: "database/creds/app"}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if credentials.Username != "v-app-123" || credentials.Password != "generated" || credentials.ExpiresAt.IsZero() {
+		t.Errorf("Expected dynamic credentials with an expiry, got: %+v", credentials)
+	}
+
+	if _, err := (VaultCredentials{Address: server.URL, Token: "wrong", Path: "database/creds/app"}).Fetch(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected token")
+	}
+}
+
+type countingSource struct{ calls int }
+
+func (s *countingSource) Fetch(_ context.Context) (types.Credentials, error) {
+	s.calls++
+	return types.Credentials{Username: "app", Password: "pw"}, nil
+}
+
+func TestRotatingCredentials(t *testing.T) {
+	source := &countingSource{}
+	rotating := NewRotatingCredentials(source, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, err := rotating.Fetch(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if source.calls != 1 {
+		t.Errorf("Expected cached credentials to be reused, got %d fetches", source.calls)
+	}
+
+	rotating.Invalidate()
+	_, _ = rotating.Fetch(context.Background())
+	if source.calls != 2 {
+		t.Errorf("Expected a fetch after Invalidate, got %d fetches", source.calls)
+	}
+}
@@ -25,6 +25,10 @@ func loadFromEnvInternal(loadEnvFile bool) (types.Config, error) {
 		loadDotEnv()
 	}
 	
+	if rawURL := getEnv("DB_URL", ""); rawURL != "" {
+		return ParseURL(rawURL)
+	}
+
 	config := types.Config{}
 
 	// Required fields
@@ -38,6 +42,8 @@ func loadFromEnvInternal(loadEnvFile bool) (types.Config, error) {
 		config.Driver = types.MySQL
 	case "postgres", "postgresql":
 		config.Driver = types.PostgreSQL
+	case "clickhouse":
+		config.Driver = types.ClickHouse
 	default:
 		return config, fmt.Errorf("unsupported database driver: %s", driverStr)
 	}
@@ -48,17 +54,28 @@ func loadFromEnvInternal(loadEnvFile bool) (types.Config, error) {
 		return config, fmt.Errorf("DB_NAME environment variable is required")
 	}
 
+	source, err := credentialSourceFromEnv()
+	if err != nil {
+		return config, err
+	}
+
 	config.Username = getEnv("DB_USER", "")
-	if config.Username == "" {
+	if config.Username == "" && source == nil {
 		return config, fmt.Errorf("DB_USER environment variable is required")
 	}
 
 	config.Password = getEnv("DB_PASSWORD", "")
+	if source != nil {
+		UseCredentials(&config, source)
+	}
 
 	// Optional fields with defaults
 	portStr := getEnv("DB_PORT", "3306")
-	if config.Driver == types.PostgreSQL {
+	switch config.Driver {
+	case types.PostgreSQL:
 		portStr = getEnv("DB_PORT", "5432")
+	case types.ClickHouse:
+		portStr = getEnv("DB_PORT", "9000")
 	}
 
 	port, err := strconv.Atoi(portStr)
@@ -71,6 +88,13 @@ func loadFromEnvInternal(loadEnvFile bool) (types.Config, error) {
 	config.Charset = getEnv("DB_CHARSET", "utf8mb4")
 	config.Timezone = getEnv("DB_TIMEZONE", "UTC")
 
+	normalizeTimesStr := getEnv("DB_NORMALIZE_TIMES", "false")
+	normalizeTimes, err := strconv.ParseBool(normalizeTimesStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_NORMALIZE_TIMES value: %s", normalizeTimesStr)
+	}
+	config.NormalizeTimes = normalizeTimes
+
 	// Connection pool settings
 	maxOpenConnsStr := getEnv("DB_MAX_OPEN_CONNS", "25")
 	maxOpenConns, err := strconv.Atoi(maxOpenConnsStr)
@@ -100,6 +124,91 @@ func loadFromEnvInternal(loadEnvFile bool) (types.Config, error) {
 	}
 	config.ConnMaxIdleTime = maxIdleTime
 
+	readOnlyStr := getEnv("DB_READ_ONLY", "false")
+	readOnly, err := strconv.ParseBool(readOnlyStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_READ_ONLY value: %s", readOnlyStr)
+	}
+	config.ReadOnly = readOnly
+
+	stmtCacheSizeStr := getEnv("DB_STMT_CACHE_SIZE", "0")
+	stmtCacheSize, err := strconv.Atoi(stmtCacheSizeStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_STMT_CACHE_SIZE value: %s", stmtCacheSizeStr)
+	}
+	config.StmtCacheSize = stmtCacheSize
+
+	config.TLSCACert = getEnv("DB_TLS_CA", "")
+	config.TLSCert = getEnv("DB_TLS_CERT", "")
+	config.TLSKey = getEnv("DB_TLS_KEY", "")
+	config.TLSServerName = getEnv("DB_TLS_SERVER_NAME", "")
+
+	skipVerifyStr := getEnv("DB_TLS_SKIP_VERIFY", "false")
+	skipVerify, err := strconv.ParseBool(skipVerifyStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_TLS_SKIP_VERIFY value: %s", skipVerifyStr)
+	}
+	config.TLSSkipVerify = skipVerify
+
+	maxConcurrentStr := getEnv("DB_MAX_CONCURRENT_QUERIES", "0")
+	maxConcurrent, err := strconv.Atoi(maxConcurrentStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_MAX_CONCURRENT_QUERIES value: %s", maxConcurrentStr)
+	}
+	config.MaxConcurrentQueries = maxConcurrent
+
+	queueTimeoutStr := getEnv("DB_QUEUE_TIMEOUT", "0s")
+	queueTimeout, err := time.ParseDuration(queueTimeoutStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_QUEUE_TIMEOUT value: %s", queueTimeoutStr)
+	}
+	config.QueueTimeout = queueTimeout
+
+	failureRateStr := getEnv("DB_CIRCUIT_FAILURE_RATE", "0")
+	failureRate, err := strconv.ParseFloat(failureRateStr, 64)
+	if err != nil || failureRate < 0 || failureRate > 1 {
+		return config, fmt.Errorf("invalid DB_CIRCUIT_FAILURE_RATE value: %s", failureRateStr)
+	}
+	config.CircuitBreaker.FailureRate = failureRate
+
+	slowThresholdStr := getEnv("DB_CIRCUIT_SLOW_THRESHOLD", "0s")
+	slowThreshold, err := time.ParseDuration(slowThresholdStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_CIRCUIT_SLOW_THRESHOLD value: %s", slowThresholdStr)
+	}
+	config.CircuitBreaker.SlowThreshold = slowThreshold
+
+	openTimeoutStr := getEnv("DB_CIRCUIT_OPEN_TIMEOUT", "30s")
+	openTimeout, err := time.ParseDuration(openTimeoutStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_CIRCUIT_OPEN_TIMEOUT value: %s", openTimeoutStr)
+	}
+	config.CircuitBreaker.OpenTimeout = openTimeout
+
+	budgetStr := getEnv("DB_COMPLEXITY_BUDGET", "0")
+	budget, err := strconv.Atoi(budgetStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_COMPLEXITY_BUDGET value: %s", budgetStr)
+	}
+	config.ComplexityBudget.Max = budget
+	config.ComplexityBudget.Mode = types.BudgetMode(getEnv("DB_COMPLEXITY_MODE", ""))
+
+	auroraStr := getEnv("DB_AURORA", "false")
+	aurora, err := strconv.ParseBool(auroraStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_AURORA value: %s", auroraStr)
+	}
+	config.Aurora.Enabled = aurora
+	config.Aurora.Endpoint = types.AuroraEndpoint(getEnv("DB_AURORA_ENDPOINT", ""))
+
+	vitessStr := getEnv("DB_VITESS", "false")
+	vitess, err := strconv.ParseBool(vitessStr)
+	if err != nil {
+		return config, fmt.Errorf("invalid DB_VITESS value: %s", vitessStr)
+	}
+	config.Vitess.Enabled = vitess
+	config.Vitess.Target = getEnv("DB_VITESS_TARGET", "")
+
 	return config, nil
 }
 
@@ -144,8 +253,10 @@ func LoadFromEnvWithDefaults(defaults types.Config) (types.Config, error) {
 
 // ValidateConfig validates the configuration
 func ValidateConfig(config types.Config) error {
-	if config.Driver != types.MySQL && config.Driver != types.PostgreSQL {
-		return fmt.Errorf("invalid driver: must be mysql or postgresql")
+	switch config.Driver {
+	case types.MySQL, types.PostgreSQL, types.ClickHouse:
+	default:
+		return fmt.Errorf("invalid driver: must be mysql, postgresql or clickhouse")
 	}
 
 	if config.Host == "" {
@@ -184,6 +295,26 @@ func ValidateConfig(config types.Config) error {
 		return fmt.Errorf("connection max idle time must be non-negative")
 	}
 
+	if config.StmtCacheSize < 0 {
+		return fmt.Errorf("statement cache size must be non-negative")
+	}
+
+	if config.Timezone != "" {
+		if _, err := time.LoadLocation(config.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s", config.Timezone)
+		}
+	}
+
+	if config.ComplexityBudget.Max < 0 {
+		return fmt.Errorf("complexity budget must be non-negative")
+	}
+
+	switch config.ComplexityBudget.Mode {
+	case "", types.BudgetReject, types.BudgetLog:
+	default:
+		return fmt.Errorf("invalid complexity budget mode: must be reject or log")
+	}
+
 	return nil
 }
 
@@ -234,6 +365,13 @@ func PrintConfig(config types.Config) {
 	fmt.Printf("  Max Idle Connections: %d\n", config.MaxIdleConns)
 	fmt.Printf("  Connection Max Lifetime: %s\n", config.ConnMaxLifetime)
 	fmt.Printf("  Connection Max Idle Time: %s\n", config.ConnMaxIdleTime)
+	fmt.Printf("  Read Only: %t\n", config.ReadOnly)
+	fmt.Printf("  Statement Cache Size: %d\n", config.StmtCacheSize)
+	if config.TLSCACert != "" || config.TLSCert != "" || config.TLSSkipVerify {
+		fmt.Printf("  TLS CA: %s\n", config.TLSCACert)
+		fmt.Printf("  TLS Client Cert: %s\n", config.TLSCert)
+		fmt.Printf("  TLS Skip Verify: %t\n", config.TLSSkipVerify)
+	}
 }
 
 func maskPassword(password string) string {
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// DetectAuroraEndpoint returns the kind of Aurora endpoint host names, or "" if host is not an RDS
+// host name.
+func DetectAuroraEndpoint(host string) types.AuroraEndpoint {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !strings.HasSuffix(host, ".rds.amazonaws.com") && !strings.HasSuffix(host, ".rds.amazonaws.com.cn") {
+		return ""
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	switch label := labels[1]; {
+	case strings.HasPrefix(label, "cluster-ro-"):
+		return types.AuroraReader
+	case strings.HasPrefix(label, "cluster-custom-"):
+		return types.AuroraCustom
+	case strings.HasPrefix(label, "cluster-"):
+		return types.AuroraWriter
+	default:
+		return types.AuroraInstance
+	}
+}
+
+// auroraFailover re-dials a connection after a failover error until its endpoint reaches the new
+// writer. Aurora moves the cluster endpoint's DNS record to the promoted instance within seconds,
+// but pooled connections keep talking to the old primary, now a reader, until they are replaced.
+type auroraFailover struct {
+	options   types.AuroraOptions
+	endpoint  types.AuroraEndpoint
+	reconnect func() error
+	isWriter  func(ctx context.Context) (bool, error)
+	running   atomic.Bool
+	failovers atomic.Int64
+}
+
+func newAuroraFailover(config types.Config, reconnect func() error, isWriter func(ctx context.Context) (bool, error)) *auroraFailover {
+	if !config.Aurora.Enabled {
+		return nil
+	}
+
+	options := config.Aurora
+	if options.FailoverTimeout <= 0 {
+		options.FailoverTimeout = time.Minute
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	endpoint := options.Endpoint
+	if endpoint == "" {
+		endpoint = DetectAuroraEndpoint(config.Host)
+	}
+	return &auroraFailover{options: options, endpoint: endpoint, reconnect: reconnect, isWriter: isWriter}
+}
+
+// check starts re-resolving the writer if err signals a failover and returns err wrapped with
+// types.ErrFailover in that case. Other errors are returned unchanged.
+func (f *auroraFailover) check(err error) error {
+	if f == nil || err == nil || !f.isFailoverError(err) {
+		return err
+	}
+	if f.running.CompareAndSwap(false, true) {
+		f.failovers.Add(1)
+		go func() {
+			defer f.running.Store(false)
+			f.resolve()
+		}()
+	}
+	return &types.DBError{Kind: types.ErrFailover, Err: err}
+}
+
+// resolve re-dials until the connection reaches a writer (or any instance, for reader and custom
+// endpoints) or FailoverTimeout passes. Each attempt replaces the whole pool, so no connection to
+// the old primary survives.
+func (f *auroraFailover) resolve() {
+	deadline := time.Now().Add(f.options.FailoverTimeout)
+	for {
+		if f.reconnect() == nil {
+			if f.endpoint == types.AuroraReader || f.endpoint == types.AuroraCustom {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), f.options.PollInterval+5*time.Second)
+			writer, err := f.isWriter(ctx)
+			cancel()
+			if err == nil && writer {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(f.options.PollInterval)
+	}
+}
+
+// isFailoverError reports whether err means the instance went away or is no longer the writer.
+func (f *auroraFailover) isFailoverError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1290, 1836, 1792: // ER_OPTION_PREVENTS_STATEMENT (--read-only), ER_READ_ONLY_MODE, ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION
+			return f.endpoint != types.AuroraReader
+		case 1053: // ER_SERVER_SHUTDOWN
+			return true
+		}
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "25006": // read_only_sql_transaction
+			return f.endpoint != types.AuroraReader
+		case pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03": // admin/crash shutdown, cannot_connect_now
+			return true
+		case strings.HasPrefix(pgErr.Code, "08"): // connection_exception
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		(errors.As(err, &netErr) && !netErr.Timeout())
+}
+
+// isAuroraWriter reports whether the pool reaches a writer instance.
+func (c *Connection) isAuroraWriter(ctx context.Context) (bool, error) {
+	db, _, _ := c.handles()
+	query := "SELECT @@innodb_read_only = 0"
+	if c.driver == types.PostgreSQL {
+		query = "SELECT NOT pg_is_in_recovery()"
+	}
+
+	var writer bool
+	if err := db.QueryRowContext(ctx, query).Scan(&writer); err != nil {
+		return false, err
+	}
+	return writer, nil
+}
+
+// AuroraEndpoint returns the kind of Aurora endpoint the connection uses, or "" outside Aurora mode.
+func (c *Connection) AuroraEndpoint() types.AuroraEndpoint {
+	if c.aurora == nil {
+		return ""
+	}
+	return c.aurora.endpoint
+}
+
+// Failovers returns how many failovers the connection has handled in Aurora mode.
+func (c *Connection) Failovers() int64 {
+	if c.aurora == nil {
+		return 0
+	}
+	return c.aurora.failovers.Load()
+}
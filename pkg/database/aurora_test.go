@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestDetectAuroraEndpoint(t *testing.T) {
+	tests := map[string]types.AuroraEndpoint{
+		"orders.cluster-c9akciq32.eu-west-1.rds.amazonaws.com":         types.AuroraWriter,
+		"orders.cluster-ro-c9akciq32.eu-west-1.rds.amazonaws.com":      types.AuroraReader,
+		"reports.cluster-custom-c9akciq32.eu-west-1.rds.amazonaws.com": types.AuroraCustom,
+		"orders-instance-1.c9akciq32.eu-west-1.rds.amazonaws.com":      types.AuroraInstance,
+		"db.internal.example.com":                                      "",
+	}
+	for host, want := range tests {
+		if got := DetectAuroraEndpoint(host); got != want {
+			t.Errorf("DetectAuroraEndpoint(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestAuroraFailoverErrors(t *testing.T) {
+	writer := &auroraFailover{endpoint: types.AuroraWriter}
+	reader := &auroraFailover{endpoint: types.AuroraReader}
+	readOnly := &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option"}
+
+	if !writer.isFailoverError(readOnly) || reader.isFailoverError(readOnly) {
+		t.Error("Expected read-only errors to signal a failover on writer endpoints only")
+	}
+	if !writer.isFailoverError(&pgconn.PgError{Code: "25006"}) || !reader.isFailoverError(&pgconn.PgError{Code: "57P01"}) {
+		t.Error("Expected PostgreSQL read-only and shutdown errors to signal a failover")
+	}
+	if !reader.isFailoverError(driver.ErrBadConn) {
+		t.Error("Expected lost connections to signal a failover")
+	}
+	if writer.isFailoverError(&mysql.MySQLError{Number: 1062}) {
+		t.Error("Expected duplicate keys not to signal a failover")
+	}
+
+	var disabled *auroraFailover
+	if err := disabled.check(readOnly); err != readOnly {
+		t.Errorf("Expected errors to pass through outside Aurora mode, got %v", err)
+	}
+}
+
+func TestAuroraFailoverReconnectsUntilWriter(t *testing.T) {
+	reconnects := 0
+	done := make(chan struct{})
+	failover := newAuroraFailover(types.Config{
+		Host:   "orders.cluster-c9akciq32.eu-west-1.rds.amazonaws.com",
+		Aurora: types.AuroraOptions{Enabled: true, PollInterval: time.Millisecond},
+	}, func() error {
+		reconnects++
+		return nil
+	}, func(context.Context) (bool, error) {
+		if reconnects < 3 {
+			return false, nil // DNS still points at the demoted primary
+		}
+		close(done)
+		return true, nil
+	})
+
+	err := failover.check(&mysql.MySQLError{Number: 1836, Message: "Running in read-only mode"})
+	if !errors.Is(err, types.ErrFailover) {
+		t.Fatalf("Expected ErrFailover, got %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the writer to be re-resolved")
+	}
+	for failover.running.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	if reconnects != 3 || failover.failovers.Load() != 1 {
+		t.Errorf("Expected 3 reconnects for 1 failover, got %d and %d", reconnects, failover.failovers.Load())
+	}
+}
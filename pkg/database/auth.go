@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// credentialsProvider returns the configured source of per-connection credentials, or nil when the
+// static Username and Password are used.
+func credentialsProvider(config types.Config) types.CredentialsProvider {
+	if config.CredentialsProvider != nil {
+		return config.CredentialsProvider
+	}
+	if provider := config.PasswordProvider; provider != nil {
+		username := config.Username
+		return func(ctx context.Context) (types.Credentials, error) {
+			password, err := provider(ctx)
+			return types.Credentials{Username: username, Password: password}, err
+		}
+	}
+	return nil
+}
+
+// passwordConnector is a MySQL connector that asks a provider for credentials every time
+// database/sql opens a new physical connection.
+type passwordConnector struct {
+	config   *mysql.Config
+	provider types.CredentialsProvider
+}
+
+// Connect implements driver.Connector.
+func (c *passwordConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	credentials, err := c.provider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database credentials: %w", err)
+	}
+
+	config := c.config.Clone()
+	if credentials.Username != "" {
+		config.User = credentials.Username
+	}
+	config.Passwd = credentials.Password
+	connector, err := mysql.NewConnector(config)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+// Driver implements driver.Connector.
+func (c *passwordConnector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
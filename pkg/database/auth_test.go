@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestPasswordConnectorPropagatesProviderErrors(t *testing.T) {
+	calls := 0
+	connector := &passwordConnector{
+		config: mysql.NewConfig(),
+		provider: credentialsProvider(types.Config{PasswordProvider: func(_ context.Context) (string, error) {
+			calls++
+			return "", errors.New("token expired")
+		}}),
+	}
+
+	_, err := connector.Connect(context.Background())
+	if err == nil || calls != 1 {
+		t.Fatalf("Expected the provider error after one call, got: %v (%d calls)", err, calls)
+	}
+}
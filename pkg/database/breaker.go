@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// circuitBreaker fails calls fast with ErrCircuitOpen once too many calls in a window failed or
+// were slow. After OpenTimeout a single probe call is let through: if it succeeds the circuit
+// closes, otherwise it stays open for another OpenTimeout.
+type circuitBreaker struct {
+	options     types.CircuitBreakerOptions
+	mu          sync.Mutex
+	state       types.CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	now         func() time.Time
+}
+
+// newCircuitBreaker returns a breaker for options, or nil when the breaker is disabled.
+func newCircuitBreaker(options types.CircuitBreakerOptions) *circuitBreaker {
+	if options.FailureRate <= 0 {
+		return nil
+	}
+	if options.MinRequests <= 0 {
+		options.MinRequests = 20
+	}
+	if options.Window <= 0 {
+		options.Window = 10 * time.Second
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = 30 * time.Second
+	}
+	return &circuitBreaker{options: options, state: types.CircuitClosed, now: time.Now}
+}
+
+// allow reports whether a call may proceed. Every allowed call must be followed by record.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case types.CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.options.OpenTimeout {
+			return types.ErrCircuitOpen
+		}
+		b.state = types.CircuitHalfOpen
+		b.probing = true
+		return nil
+	case types.CircuitHalfOpen:
+		if b.probing {
+			return types.ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record reports the outcome of a call let through by allow.
+func (b *circuitBreaker) record(err error, elapsed time.Duration) {
+	if b == nil {
+		return
+	}
+
+	failed := isConnectionFailure(err) ||
+		(b.options.SlowThreshold > 0 && elapsed > b.options.SlowThreshold)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.state == types.CircuitHalfOpen {
+		b.probing = false
+		if failed {
+			b.open(now)
+		} else {
+			b.state = types.CircuitClosed
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		return
+	}
+	if b.state == types.CircuitOpen {
+		return
+	}
+
+	if now.Sub(b.windowStart) > b.options.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= b.options.MinRequests && float64(b.failures)/float64(b.requests) >= b.options.FailureRate {
+		b.open(now)
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.state = types.CircuitOpen
+	b.openedAt = now
+	b.requests, b.failures = 0, 0
+}
+
+// currentState returns the breaker state, CircuitClosed when the breaker is disabled.
+func (b *circuitBreaker) currentState() types.CircuitState {
+	if b == nil {
+		return types.CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// isConnectionFailure reports whether err indicates an unhealthy database rather than a problem
+// with the statement. Errors reported by the server, missing rows and cancellations by the caller
+// do not count against the breaker.
+func isConnectionFailure(err error) bool {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, types.ErrReadOnly) || errors.Is(err, types.ErrShuttingDown) {
+		return false
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	return !errors.As(err, &pgErr)
+}
+
+// breakerRow records the outcome of a single-row query once it is scanned.
+type breakerRow struct {
+	types.Row
+	breaker *circuitBreaker
+	start   time.Time
+}
+
+func (r *breakerRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.breaker.record(err, time.Since(r.start))
+	return err
+}
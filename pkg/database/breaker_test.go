@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(types.CircuitBreakerOptions{FailureRate: 0.5, MinRequests: 4, OpenTimeout: time.Second})
+	breaker.now = func() time.Time { return now }
+
+	refused := errors.New("dial tcp: connection refused")
+	for _, err := range []error{nil, refused, nil, refused} {
+		if allowErr := breaker.allow(); allowErr != nil {
+			t.Fatalf("Expected calls to be allowed while closed, got: %v", allowErr)
+		}
+		breaker.record(err, time.Millisecond)
+	}
+	if breaker.currentState() != types.CircuitOpen {
+		t.Fatalf("Expected the circuit to open at a 50%% failure rate, got: %s", breaker.currentState())
+	}
+	if err := breaker.allow(); !errors.Is(err, types.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen while open, got: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("Expected a probe after the open timeout, got: %v", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, types.ErrCircuitOpen) {
+		t.Fatalf("Expected only one probe at a time, got: %v", err)
+	}
+	breaker.record(nil, time.Millisecond)
+	if breaker.currentState() != types.CircuitClosed {
+		t.Errorf("Expected a successful probe to close the circuit, got: %s", breaker.currentState())
+	}
+}
+
+func TestCircuitBreakerCountsSlowCalls(t *testing.T) {
+	breaker := newCircuitBreaker(types.CircuitBreakerOptions{FailureRate: 1, MinRequests: 2, SlowThreshold: 100 * time.Millisecond})
+
+	breaker.record(nil, time.Second)
+	breaker.record(nil, time.Second)
+	if breaker.currentState() != types.CircuitOpen {
+		t.Errorf("Expected slow calls to open the circuit, got: %s", breaker.currentState())
+	}
+}
+
+func TestCircuitBreakerIgnoresStatementErrors(t *testing.T) {
+	for _, err := range []error{sql.ErrNoRows, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}} {
+		if isConnectionFailure(err) {
+			t.Errorf("Expected %v not to count as a connection failure", err)
+		}
+	}
+	if !isConnectionFailure(errors.New("i/o timeout")) {
+		t.Error("Expected network errors to count as connection failures")
+	}
+}
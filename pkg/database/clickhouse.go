@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// clickHouseDriverName is the database/sql driver registered by github.com/ClickHouse/clickhouse-go/v2.
+// The module does not depend on it; programs using ClickHouse import it for its side effect.
+const clickHouseDriverName = "clickhouse"
+
+// connectClickHouse opens a pool through the clickhouse-go database/sql driver using the native protocol.
+func (c *Connection) connectClickHouse() (*Connection, error) {
+	if !driverRegistered(clickHouseDriverName) {
+		return nil, fmt.Errorf("ClickHouse driver is not registered: import _ \"github.com/ClickHouse/clickhouse-go/v2\"")
+	}
+
+	sqlDB, err := sql.Open(clickHouseDriverName, c.buildClickHouseDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ClickHouse connection: %w", err)
+	}
+
+	db := sqlx.NewDb(sqlDB, clickHouseDriverName)
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+
+	c.configurePool(db)
+	c.db = db
+	c.detectVersion()
+	return c, nil
+}
+
+func (c *Connection) buildClickHouseDSN() string {
+	query := url.Values{}
+	if mode := c.getSSLMode(); mode != "disable" {
+		query.Set("secure", "true")
+		if mode == "require" {
+			query.Set("skip_verify", "true")
+		}
+	}
+
+	dsn := url.URL{
+		Scheme:   "clickhouse",
+		User:     url.UserPassword(c.config.Username, c.config.Password),
+		Host:     net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port)),
+		Path:     "/" + c.config.Database,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+func driverRegistered(name string) bool {
+	for _, registered := range sql.Drivers() {
+		if registered == name {
+			return true
+		}
+	}
+	return false
+}
@@ -4,14 +4,22 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql" // MySQL driver
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/omarhamdy49/go-query-builder/pkg/optimization"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
@@ -28,107 +36,219 @@ func safeInt32(value int) int32 {
 
 // Connection represents a database connection that abstracts different database drivers.
 type Connection struct {
+	mu      sync.RWMutex
 	db      *sqlx.DB
 	pgxPool *pgxpool.Pool
 	driver  types.Driver
 	config  types.Config
+	stmts   *stmtCache
+	drain   drainTracker
+	limiter *optimization.ConcurrencyManager
+	breaker *circuitBreaker
+	aurora  *auroraFailover
+	version types.ServerVersion
+	loc     *time.Location
 }
 
 // NewConnection creates a new database connection based on the provided configuration.
 func NewConnection(config types.Config) (*Connection, error) {
+	loc, err := loadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
 	conn := &Connection{
 		driver: config.Driver,
 		config: config,
+		loc:    loc,
+	}
+	if config.MaxConcurrentQueries > 0 {
+		conn.limiter = optimization.NewConcurrencyManagerWithTimeout(config.MaxConcurrentQueries, config.QueueTimeout)
 	}
+	conn.breaker = newCircuitBreaker(config.CircuitBreaker)
+	conn.aurora = newAuroraFailover(config, conn.Reconnect, conn.isAuroraWriter)
 
 	switch config.Driver {
 	case types.MySQL:
 		return conn.connectMySQL()
 	case types.PostgreSQL:
 		return conn.connectPostgreSQL()
+	case types.ClickHouse:
+		return conn.connectClickHouse()
 	default:
 		return nil, fmt.Errorf("unsupported driver: %s", config.Driver)
 	}
 }
 
-func (c *Connection) connectMySQL() (*Connection, error) {
-	dsn := c.buildMySQLDSN()
-
-	db, err := sqlx.Connect("mysql", dsn)
+// Reconnect dials a fresh pool with the connection's configuration and swaps it in place, so
+// builders holding this connection recover without being recreated. The old pool is closed.
+func (c *Connection) Reconnect() error {
+	fresh, err := NewConnection(c.config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
+		return err
 	}
 
-	c.configurePool(db)
-	c.db = db
-	return c, nil
+	c.mu.Lock()
+	oldDB, oldPool, oldStmts := c.db, c.pgxPool, c.stmts
+	c.db, c.pgxPool, c.stmts = fresh.db, fresh.pgxPool, fresh.stmts
+	c.version = fresh.version
+	c.mu.Unlock()
+
+	closeHandles(oldDB, oldPool, oldStmts)
+	return nil
 }
 
-func (c *Connection) connectPostgreSQL() (*Connection, error) {
-	dsn := c.buildPostgreSQLDSN()
+// handles returns the current pool handles, which Reconnect may replace at any time.
+func (c *Connection) handles() (*sqlx.DB, *pgxpool.Pool, *stmtCache) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db, c.pgxPool, c.stmts
+}
 
-	db, err := sqlx.Connect("postgres", dsn)
+func closeHandles(db *sqlx.DB, pool *pgxpool.Pool, stmts *stmtCache) error {
+	var err error
+	if stmts != nil {
+		err = stmts.close()
+	}
+	if db != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	if pool != nil {
+		pool.Close()
+	}
+	return err
+}
+
+func (c *Connection) connectMySQL() (*Connection, error) {
+	tlsConfig, err := buildTLSConfig(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	mysqlConfig, err := mysql.ParseDSN(c.buildMySQLDSN())
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to PostgreSQL with sqlx: %w", err)
+		return nil, fmt.Errorf("failed to parse MySQL DSN: %w", err)
+	}
+	mysqlConfig.TLS = tlsConfig
+
+	var connector driver.Connector
+	if provider := credentialsProvider(c.config); provider != nil {
+		// IAM tokens are sent with the cleartext auth plugin, which is only safe over TLS.
+		mysqlConfig.AllowCleartextPasswords = c.config.PasswordProvider != nil
+		connector = &passwordConnector{config: mysqlConfig, provider: provider}
+	} else {
+		connector, err = mysql.NewConnector(mysqlConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure MySQL connector: %w", err)
+		}
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector), "mysql")
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
 	}
 
 	c.configurePool(db)
 	c.db = db
+	c.detectVersion()
+	c.setupStmtCache()
+	return c, nil
+}
 
+// connectPostgreSQL runs database/sql through the pgx pool, so queries use pgx's binary protocol and
+// per-connection statement cache while keeping the same Rows and Result interfaces.
+func (c *Connection) connectPostgreSQL() (*Connection, error) {
 	if err := c.setupPgxPool(); err != nil {
 		return nil, err
 	}
 
+	db := sqlx.NewDb(stdlib.OpenDBFromPool(c.pgxPool), "pgx")
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		c.pgxPool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	c.db = db
+	c.detectVersion()
 	return c, nil
 }
 
 func (c *Connection) buildMySQLDSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=%s",
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=%s",
 		c.config.Username,
 		c.config.Password,
 		c.config.Host,
 		c.config.Port,
-		c.config.Database,
+		c.mysqlDatabase(),
 		c.getCharset(),
-		c.getTimezone(),
-	)
-}
-
-func (c *Connection) buildPostgreSQLDSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
-		c.config.Host,
-		c.config.Port,
-		c.config.Username,
-		c.config.Password,
-		c.config.Database,
-		c.getSSLMode(),
-		c.getTimezone(),
+		url.QueryEscape(c.getTimezone()),
 	)
+	// Unknown DSN parameters are set as session variables when a connection is made.
+	if zone := mysqlTimeZone(c.config.Timezone); zone != "" {
+		dsn += "&time_zone=" + url.QueryEscape("'"+zone+"'")
+	}
+	return dsn
 }
 
 func (c *Connection) setupPgxPool() error {
-	pgxDSN := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s?sslmode=%s&timezone=%s",
-		c.config.Username,
-		c.config.Password,
-		c.config.Host,
-		c.config.Port,
-		c.config.Database,
-		c.getSSLMode(),
-		c.getTimezone(),
-	)
+	dsn := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(c.config.Username, c.config.Password),
+		Host:   net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port)),
+		Path:   "/" + c.config.Database,
+		RawQuery: url.Values{
+			"sslmode":  {c.getSSLMode()},
+			"timezone": {c.getTimezone()},
+		}.Encode(),
+	}
+	pgxDSN := dsn.String()
 
 	poolConfig, err := pgxpool.ParseConfig(pgxDSN)
 	if err != nil {
 		return fmt.Errorf("failed to parse pgx pool config: %w", err)
 	}
 
+	tlsConfig, err := buildTLSConfig(c.config)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		poolConfig.ConnConfig.TLSConfig = tlsConfig
+		poolConfig.ConnConfig.Fallbacks = nil
+	}
+
+	if provider := credentialsProvider(c.config); provider != nil {
+		poolConfig.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+			credentials, err := provider(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get database credentials: %w", err)
+			}
+			if credentials.Username != "" {
+				connConfig.User = credentials.Username
+			}
+			connConfig.Password = credentials.Password
+			return nil
+		}
+	}
+
 	maxOpenConns := c.getMaxOpenConns()
 	poolConfig.MaxConns = safeInt32(maxOpenConns)
 
 	maxIdleConns := c.getMaxIdleConns()
 	poolConfig.MinConns = safeInt32(maxIdleConns)
-	poolConfig.MaxConnLifetime = c.config.ConnMaxLifetime
-	poolConfig.MaxConnIdleTime = c.config.ConnMaxIdleTime
+	poolConfig.MaxConnLifetime = c.getConnMaxLifetime()
+	poolConfig.MaxConnIdleTime = c.getConnMaxIdleTime()
+
+	// pgx prepares and caches statements per connection itself.
+	if size := c.getStmtCacheSize(); size > 0 {
+		poolConfig.ConnConfig.StatementCacheCapacity = size
+	} else {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
@@ -146,6 +266,12 @@ func (c *Connection) configurePool(db *sqlx.DB) {
 	db.SetConnMaxIdleTime(c.getConnMaxIdleTime())
 }
 
+func (c *Connection) setupStmtCache() {
+	if size := c.getStmtCacheSize(); size > 0 {
+		c.stmts = newStmtCache(c.db, size)
+	}
+}
+
 func (c *Connection) getCharset() string {
 	if c.config.Charset == "" {
 		return "utf8mb4"
@@ -195,28 +321,204 @@ func (c *Connection) getConnMaxIdleTime() time.Duration {
 	return c.config.ConnMaxIdleTime
 }
 
+// getStmtCacheSize returns the prepared statement cache size. The cache is opt-in: zero disables it.
+func (c *Connection) getStmtCacheSize() int {
+	return max(c.config.StmtCacheSize, 0)
+}
+
 // QueryContext executes a query that returns rows, typically a SELECT.
 func (c *Connection) QueryContext(ctx context.Context, query string, args ...interface{}) (types.Rows, error) {
-	return c.db.QueryContext(ctx, query, args...)
+	if err := c.drain.enter(); err != nil {
+		return nil, err
+	}
+	if err := c.breaker.allow(); err != nil {
+		c.drain.leave()
+		return nil, err
+	}
+
+	start := time.Now()
+	rows, err := c.queryContext(ctx, query, c.bind(args)...)
+	c.breaker.record(err, time.Since(start))
+	if err != nil {
+		c.drain.leave()
+		return nil, c.aurora.check(ClassifyError(err))
+	}
+	return &trackedRows{Rows: classifiedRows{Rows: rows}, done: c.drain.leave}, nil
+}
+
+func (c *Connection) queryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db, _, stmts := c.handles()
+	if stmts == nil {
+		return db.QueryContext(ctx, query, args...)
+	}
+
+	entry, err := stmts.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmts.release(entry)
+	return entry.stmt.QueryContext(ctx, args...)
 }
 
 // QueryRowContext executes a query that is expected to return at most one row.
 func (c *Connection) QueryRowContext(ctx context.Context, query string, args ...interface{}) types.Row {
-	return c.db.QueryRowContext(ctx, query, args...)
+	if err := c.drain.enter(); err != nil {
+		return errRow{err: err}
+	}
+	if err := c.breaker.allow(); err != nil {
+		c.drain.leave()
+		return errRow{err: err}
+	}
+
+	start := time.Now()
+	row := &breakerRow{Row: c.queryRowContext(ctx, query, c.bind(args)...), breaker: c.breaker, start: start}
+	return &trackedRow{Row: classifiedRow{Row: row}, done: c.drain.leave}
+}
+
+func (c *Connection) queryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db, _, stmts := c.handles()
+	if stmts == nil {
+		return db.QueryRowContext(ctx, query, args...)
+	}
+
+	entry, err := stmts.acquire(ctx, query)
+	if err != nil {
+		// Let database/sql report the prepare error through Row.Scan.
+		return db.QueryRowContext(ctx, query, args...)
+	}
+	defer stmts.release(entry)
+	return entry.stmt.QueryRowContext(ctx, args...)
 }
 
 // ExecContext executes a query without returning any rows.
 func (c *Connection) ExecContext(ctx context.Context, query string, args ...interface{}) (types.Result, error) {
-	return c.db.ExecContext(ctx, query, args...)
+	if c.config.ReadOnly {
+		return nil, types.ErrReadOnly
+	}
+	if err := c.drain.enter(); err != nil {
+		return nil, err
+	}
+	defer c.drain.leave()
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := c.execContext(ctx, query, c.bind(args)...)
+	c.breaker.record(err, time.Since(start))
+	if err != nil {
+		return nil, c.aurora.check(ClassifyError(err))
+	}
+	return result, nil
+}
+
+func (c *Connection) execContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	db, _, stmts := c.handles()
+	if stmts == nil {
+		return db.ExecContext(ctx, query, args...)
+	}
+
+	entry, err := stmts.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmts.release(entry)
+	return entry.stmt.ExecContext(ctx, args...)
+}
+
+// CopyFrom bulk loads rows into table with PostgreSQL's COPY protocol through the pgx pool.
+func (c *Connection) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if c.config.ReadOnly {
+		return 0, types.ErrReadOnly
+	}
+
+	_, pool, _ := c.handles()
+	if pool == nil {
+		return 0, fmt.Errorf("COPY is only supported on PostgreSQL connections")
+	}
+	if err := c.drain.enter(); err != nil {
+		return 0, err
+	}
+	defer c.drain.leave()
+	if err := c.breaker.allow(); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	copied, err := pool.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+	c.breaker.record(err, time.Since(start))
+	return copied, ClassifyError(err)
+}
+
+// ConcurrencyLimiter returns the limiter enforcing MaxConcurrentQueries, or nil when unlimited.
+func (c *Connection) ConcurrencyLimiter() *optimization.ConcurrencyManager {
+	return c.limiter
+}
+
+// CircuitState returns the state of the connection's circuit breaker, closed when it is disabled.
+func (c *Connection) CircuitState() types.CircuitState {
+	return c.breaker.currentState()
+}
+
+// IsReadOnly returns true if the connection was configured as read-only.
+func (c *Connection) IsReadOnly() bool {
+	return c.config.ReadOnly
+}
+
+// ComplexityBudget returns the complexity budget of the statements built on the connection.
+func (c *Connection) ComplexityBudget() types.ComplexityBudget {
+	return c.config.ComplexityBudget
+}
+
+// Location returns the time zone of the connection's sessions, which date helpers such as
+// WhereToday use to determine the current date.
+func (c *Connection) Location() *time.Location {
+	return c.loc
+}
+
+// bind converts the time.Time arguments of a statement to the session time zone when
+// NormalizeTimes is set.
+func (c *Connection) bind(args []interface{}) []interface{} {
+	if !c.config.NormalizeTimes {
+		return args
+	}
+	return normalizeTimes(args, c.loc)
+}
+
+// track wraps tx in a Transaction that inherits the connection's settings and calls done once it
+// is committed or rolled back.
+func (c *Connection) track(tx *sqlx.Tx) *Transaction {
+	t := newTrackedTransaction(tx, c.driver, c.ServerVersion(), c.drain.leave)
+	t.budget = c.config.ComplexityBudget
+	t.loc = c.loc
+	t.normalize = c.config.NormalizeTimes
+	return t
 }
 
 // Begin starts a transaction with default options.
 func (c *Connection) Begin() (types.Tx, error) {
-	tx, err := c.db.Beginx()
-	if err != nil {
+	if c.config.ReadOnly {
+		return c.BeginTx(context.Background(), nil)
+	}
+
+	if err := c.drain.enter(); err != nil {
+		return nil, err
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		c.drain.leave()
 		return nil, err
 	}
-	return NewTransaction(tx, c.driver), nil
+
+	db, _, _ := c.handles()
+	start := time.Now()
+	tx, err := db.Beginx()
+	c.breaker.record(err, time.Since(start))
+	if err != nil {
+		c.drain.leave()
+		return nil, c.aurora.check(err)
+	}
+	return c.track(tx), nil
 }
 
 // BeginTx starts a transaction with the specified context and options.
@@ -226,12 +528,28 @@ func (c *Connection) BeginTx(ctx context.Context, opts *types.TxOptions) (types.
 		sqlOpts.Isolation = sql.IsolationLevel(opts.Isolation)
 		sqlOpts.ReadOnly = opts.ReadOnly
 	}
+	if c.config.ReadOnly {
+		sqlOpts.ReadOnly = true
+	}
 
-	tx, err := c.db.BeginTxx(ctx, sqlOpts)
-	if err != nil {
+	if err := c.drain.enter(); err != nil {
 		return nil, err
 	}
-	return NewTransaction(tx, c.driver), nil
+
+	if err := c.breaker.allow(); err != nil {
+		c.drain.leave()
+		return nil, err
+	}
+
+	db, _, _ := c.handles()
+	start := time.Now()
+	tx, err := db.BeginTxx(ctx, sqlOpts)
+	c.breaker.record(err, time.Since(start))
+	if err != nil {
+		c.drain.leave()
+		return nil, c.aurora.check(err)
+	}
+	return c.track(tx), nil
 }
 
 // Driver returns the database driver type.
@@ -239,56 +557,99 @@ func (c *Connection) Driver() types.Driver {
 	return c.driver
 }
 
+// ServerVersion returns the server version detected when the connection was opened, or the zero
+// value if it could not be read.
+func (c *Connection) ServerVersion() types.ServerVersion {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version
+}
+
+// detectVersion reads the server version. Failing to read it is not fatal: version-gated SQL then
+// falls back to syntax every supported server accepts.
+func (c *Connection) detectVersion() {
+	var raw string
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.db.QueryRowContext(ctx, "SELECT version()").Scan(&raw); err == nil {
+		c.version = types.ParseServerVersion(c.driver, raw)
+	}
+	if c.config.Vitess.Enabled {
+		c.version.Driver, c.version.Vitess = c.driver, true
+	}
+}
+
 // Close closes the database connection and releases resources.
 func (c *Connection) Close() error {
-	var err error
-	if c.db != nil {
-		err = c.db.Close()
+	return closeHandles(c.handles())
+}
+
+// Shutdown stops accepting new queries, waits for in-flight queries, open rows and transactions to
+// finish until ctx is done, then closes the connection. It returns ctx's error if draining timed out.
+func (c *Connection) Shutdown(ctx context.Context) error {
+	c.drain.stop()
+	drainErr := c.drain.wait(ctx)
+
+	if err := c.Close(); err != nil {
+		return err
 	}
-	if c.pgxPool != nil {
-		c.pgxPool.Close()
+	if drainErr != nil {
+		return fmt.Errorf("failed to drain in-flight queries: %w", drainErr)
 	}
-	return err
+	return nil
 }
 
 // Ping verifies a connection to the database is still alive.
 func (c *Connection) Ping() error {
-	if c.db != nil {
-		return c.db.Ping()
+	return c.PingContext(context.Background())
+}
+
+// PingContext verifies a connection to the database is still alive, honoring the context deadline.
+func (c *Connection) PingContext(ctx context.Context) error {
+	db, pool, _ := c.handles()
+	if pool != nil {
+		return pool.Ping(ctx)
 	}
-	if c.pgxPool != nil {
-		return c.pgxPool.Ping(context.Background())
+	if db != nil {
+		return db.PingContext(ctx)
 	}
 	return fmt.Errorf("no connection available")
 }
 
 // Stats returns database statistics including connection pool information.
 func (c *Connection) Stats() types.DBStats {
-	if c.db != nil {
-		stats := c.db.Stats()
+	db, pool, stmts := c.handles()
+	if pool != nil {
+		stat := pool.Stat()
 		return types.DBStats{
+			OpenConnections: int(stat.TotalConns()),
+			InUse:           int(stat.AcquiredConns()),
+			Idle:            int(stat.IdleConns()),
+		}
+	}
+	if db != nil {
+		stats := db.Stats()
+		result := types.DBStats{
 			OpenConnections: stats.OpenConnections,
 			InUse:           stats.InUse,
 			Idle:            stats.Idle,
 		}
-	}
-	if c.pgxPool != nil {
-		stat := c.pgxPool.Stat()
-		return types.DBStats{
-			OpenConnections: int(stat.TotalConns()),
-			InUse:           int(stat.AcquiredConns()),
-			Idle:            int(stat.IdleConns()),
+		if stmts != nil {
+			result.PreparedStmts = stmts.len()
 		}
+		return result
 	}
 	return types.DBStats{}
 }
 
 // PgxPool returns the underlying pgx connection pool for PostgreSQL connections.
 func (c *Connection) PgxPool() *pgxpool.Pool {
-	return c.pgxPool
+	_, pool, _ := c.handles()
+	return pool
 }
 
 // SqlxDB returns the underlying sqlx database connection.
 func (c *Connection) SqlxDB() *sqlx.DB {
-	return c.db
+	db, _, _ := c.handles()
+	return db
 }
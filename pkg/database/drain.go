@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// drainTracker counts in-flight work on a connection so Shutdown can wait for it to finish.
+type drainTracker struct {
+	mu      sync.Mutex
+	active  int
+	closing bool
+	idle    chan struct{}
+}
+
+// enter registers a new unit of work, failing once the connection is shutting down.
+func (d *drainTracker) enter() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closing {
+		return types.ErrShuttingDown
+	}
+	d.active++
+	return nil
+}
+
+// leave marks a unit of work from enter as finished.
+func (d *drainTracker) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active--
+	if d.active == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// stop rejects new work from now on.
+func (d *drainTracker) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closing = true
+}
+
+// wait blocks until no work is in flight or ctx is done.
+func (d *drainTracker) wait(ctx context.Context) error {
+	d.mu.Lock()
+	if d.active == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// trackedRows releases its drain slot when the rows are closed.
+type trackedRows struct {
+	types.Rows
+	once sync.Once
+	done func()
+}
+
+func (r *trackedRows) Close() error {
+	err := r.Rows.Close()
+	r.once.Do(r.done)
+	return err
+}
+
+func (r *trackedRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	return types.RowColumnTypes(r.Rows)
+}
+
+// trackedRow releases its drain slot once the row is scanned.
+type trackedRow struct {
+	types.Row
+	once sync.Once
+	done func()
+}
+
+func (r *trackedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.once.Do(r.done)
+	return err
+}
+
+// errRow is a Row whose Scan always fails, used when a query is rejected before it runs.
+type errRow struct{ err error }
+
+func (r errRow) Scan(_ ...interface{}) error {
+	return r.err
+}
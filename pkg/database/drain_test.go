@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestDrainTrackerWaitsForInFlightWork(t *testing.T) {
+	var d drainTracker
+	if err := d.enter(); err != nil {
+		t.Fatalf("Expected enter to succeed, got: %v", err)
+	}
+
+	d.stop()
+	if err := d.enter(); !errors.Is(err, types.ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown after stop, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected wait to time out with work in flight, got: %v", err)
+	}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		d.leave()
+	}()
+	if err := d.wait(context.Background()); err != nil {
+		t.Errorf("Expected wait to return once work finished, got: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var (
+	mysqlDuplicateKeyPattern = regexp.MustCompile("for key '([^']+)'")
+	mysqlConstraintPattern   = regexp.MustCompile("CONSTRAINT `([^`]+)`")
+)
+
+// ClassifyError wraps a MySQL or PostgreSQL driver error in a types.DBError so callers can test it
+// with errors.Is against ErrDuplicateKey, ErrDeadlock and the other sentinels instead of matching
+// error messages. Errors that do not fall into a category are returned unchanged.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dbErr *types.DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &types.DBError{Kind: types.ErrNotFound, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &types.DBError{Kind: types.ErrTimeout, Err: err}
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return classifyMySQLError(mysqlErr, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return classifyPostgresError(pgErr, err)
+	}
+
+	return err
+}
+
+func classifyMySQLError(mysqlErr *mysql.MySQLError, err error) error {
+	classified := &types.DBError{Code: strconv.Itoa(int(mysqlErr.Number)), Err: err}
+
+	switch mysqlErr.Number {
+	case 1062, 1586: // ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		classified.Kind = types.ErrDuplicateKey
+		if match := mysqlDuplicateKeyPattern.FindStringSubmatch(mysqlErr.Message); match != nil {
+			classified.Constraint = match[1]
+		}
+	case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED (and _2 variants)
+		classified.Kind = types.ErrForeignKeyViolation
+		if match := mysqlConstraintPattern.FindStringSubmatch(mysqlErr.Message); match != nil {
+			classified.Constraint = match[1]
+		}
+	case 1213: // ER_LOCK_DEADLOCK
+		classified.Kind = types.ErrDeadlock
+	case 1205, 3024: // ER_LOCK_WAIT_TIMEOUT, ER_QUERY_TIMEOUT
+		classified.Kind = types.ErrTimeout
+	case 1105: // ER_UNKNOWN_ERROR, which Vitess uses for its own errors
+		return classifyVitessError(mysqlErr, err)
+	default:
+		return err
+	}
+	return classified
+}
+
+func classifyPostgresError(pgErr *pgconn.PgError, err error) error {
+	classified := &types.DBError{Code: pgErr.Code, Constraint: pgErr.ConstraintName, Table: pgErr.TableName, Err: err}
+
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		classified.Kind = types.ErrDuplicateKey
+	case "23503": // foreign_key_violation
+		classified.Kind = types.ErrForeignKeyViolation
+	case "40P01": // deadlock_detected
+		classified.Kind = types.ErrDeadlock
+	case "40001": // serialization_failure
+		classified.Kind = types.ErrSerializationFailure
+	case "57014", "55P03": // query_canceled (statement_timeout), lock_not_available (lock_timeout)
+		classified.Kind = types.ErrTimeout
+	default:
+		return err
+	}
+	return classified
+}
+
+// classifiedRows classifies the errors reported while iterating rows.
+type classifiedRows struct {
+	types.Rows
+}
+
+func (r classifiedRows) Scan(dest ...interface{}) error {
+	return ClassifyError(r.Rows.Scan(dest...))
+}
+
+func (r classifiedRows) Err() error {
+	return ClassifyError(r.Rows.Err())
+}
+
+func (r classifiedRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	return types.RowColumnTypes(r.Rows)
+}
+
+// classifiedRow classifies the error reported by Scan, including ErrNotFound for missing rows.
+type classifiedRow struct {
+	types.Row
+}
+
+func (r classifiedRow) Scan(dest ...interface{}) error {
+	return ClassifyError(r.Row.Scan(dest...))
+}
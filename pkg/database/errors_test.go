@@ -0,0 +1,181 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		kind       error
+		constraint string
+	}{
+		{"no rows", sql.ErrNoRows, types.ErrNotFound, ""},
+		{"deadline", context.DeadlineExceeded, types.ErrTimeout, ""},
+		{"mysql duplicate", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'users.users_email_unique'"}, types.ErrDuplicateKey, "users.users_email_unique"},
+		{"mysql foreign key", &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`app`.`orders`, CONSTRAINT `orders_user_id_fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"}, types.ErrForeignKeyViolation, "orders_user_id_fk"},
+		{"mysql deadlock", &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, types.ErrDeadlock, ""},
+		{"mysql lock wait", &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, types.ErrTimeout, ""},
+		{"postgres duplicate", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, types.ErrDuplicateKey, "users_email_key"},
+		{"postgres foreign key", &pgconn.PgError{Code: "23503", ConstraintName: "orders_user_id_fkey"}, types.ErrForeignKeyViolation, "orders_user_id_fkey"},
+		{"postgres deadlock", &pgconn.PgError{Code: "40P01"}, types.ErrDeadlock, ""},
+		{"postgres serialization", &pgconn.PgError{Code: "40001"}, types.ErrSerializationFailure, ""},
+		{"postgres statement timeout", &pgconn.PgError{Code: "57014"}, types.ErrTimeout, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifyError(fmt.Errorf("failed to execute query: %w", tt.err))
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("Expected %v, got: %v", tt.kind, err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected the driver error to stay reachable, got: %v", err)
+			}
+
+			var dbErr *types.DBError
+			if !errors.As(err, &dbErr) || dbErr.Constraint != tt.constraint {
+				t.Errorf("Expected constraint %q, got: %+v", tt.constraint, dbErr)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorLeavesOtherErrors(t *testing.T) {
+	syntaxErr := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	if err := ClassifyError(syntaxErr); err != error(syntaxErr) {
+		t.Errorf("Expected unclassified errors to be returned unchanged, got: %v", err)
+	}
+}
+
+// fakePostgres serves just enough of the PostgreSQL wire protocol for pgx to connect. Statements
+// mentioning "users" fail with a unique violation on users_email_key; others succeed without rows.
+func fakePostgres(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on a local port: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakePostgres(conn)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func serveFakePostgres(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(kind byte, body []byte) {
+		msg := []byte{kind, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(msg[1:], uint32(len(body)+4))
+		_, _ = conn.Write(append(msg, body...))
+	}
+	ready := func() { send('Z', []byte{'I'}) }
+	respond := func(query string) {
+		if strings.Contains(query, "users") {
+			send('E', []byte("SERROR\x00VERROR\x00C23505\x00Mduplicate key value violates unique constraint\x00tusers\x00nusers_email_key\x00\x00"))
+		} else {
+			send('C', []byte("SELECT 0\x00"))
+		}
+		ready()
+	}
+
+	// The startup message has no type byte; an SSL request comes first when TLS is preferred.
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(binary.BigEndian.Uint32(header[:4]))-8); err != nil {
+			return
+		}
+		if binary.BigEndian.Uint32(header[4:]) != 80877103 {
+			break
+		}
+		_, _ = conn.Write([]byte{'N'})
+	}
+	send('R', []byte{0, 0, 0, 0})
+	ready()
+
+	var parsed string
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(length[:])-4)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		switch kind {
+		case 'Q':
+			respond(string(body))
+		case 'P':
+			parsed = string(body)
+		case 'S':
+			respond(parsed)
+			parsed = ""
+		case 'X':
+			return
+		}
+	}
+}
+
+func TestClassifyErrorThroughPgxPool(t *testing.T) {
+	port := fakePostgres(t)
+	conn, err := NewConnection(types.Config{
+		Driver:   types.PostgreSQL,
+		Host:     "127.0.0.1",
+		Port:     port,
+		Database: "app",
+		Username: "app",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatalf("NewConnection failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	for name, args := range map[string][]interface{}{"simple protocol": nil, "extended protocol": {"a@b.c"}} {
+		query := "INSERT INTO users (email) VALUES ('a@b.c')"
+		if args != nil {
+			query = "INSERT INTO users (email) VALUES ($1)"
+		}
+		_, err := conn.ExecContext(ctx, query, args...)
+
+		var dbErr *types.DBError
+		var pgErr *pgconn.PgError
+		if !errors.Is(err, types.ErrDuplicateKey) || !errors.As(err, &dbErr) || dbErr.Constraint != "users_email_key" || dbErr.Table != "users" {
+			t.Errorf("%s: expected a classified duplicate key on users_email_key, got %v", name, err)
+		} else if !errors.As(err, &pgErr) {
+			t.Errorf("%s: expected the pgx error to stay reachable, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Reconnector is a connection the health checker can ping and re-dial.
+type Reconnector interface {
+	PingContext(ctx context.Context) error
+	Reconnect() error
+}
+
+// HealthChecker pings connections on an interval, marks failing ones unhealthy and re-dials them
+// with exponential backoff until they recover.
+type HealthChecker struct {
+	options types.HealthCheckOptions
+	mu      sync.Mutex
+	targets map[string]*healthTarget
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type healthTarget struct {
+	conn   Reconnector
+	status types.HealthStatus
+}
+
+// NewHealthChecker creates a HealthChecker, filling in defaults for unset options.
+func NewHealthChecker(options types.HealthCheckOptions) *HealthChecker {
+	if options.Interval <= 0 {
+		options.Interval = 30 * time.Second
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = time.Second
+	}
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = time.Minute
+		if options.MaxBackoff < options.MinBackoff {
+			options.MaxBackoff = options.MinBackoff
+		}
+	}
+
+	return &HealthChecker{
+		options: options,
+		targets: make(map[string]*healthTarget),
+	}
+}
+
+// Add registers a connection under name. Connections start out healthy until the first check.
+func (h *HealthChecker) Add(name string, conn Reconnector) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.targets[name] = &healthTarget{conn: conn, status: types.HealthStatus{Healthy: true}}
+}
+
+// Remove stops checking the named connection.
+func (h *HealthChecker) Remove(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.targets, name)
+}
+
+// Start runs checks in the background every Interval until Stop is called.
+func (h *HealthChecker) Start() {
+	h.mu.Lock()
+	if h.stop != nil {
+		h.mu.Unlock()
+		return
+	}
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+	stop, done := h.stop, h.done
+	h.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(h.options.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				h.CheckNow(context.Background())
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks and waits for a running round to finish.
+func (h *HealthChecker) Stop() {
+	h.mu.Lock()
+	stop, done := h.stop, h.done
+	h.stop, h.done = nil, nil
+	h.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// CheckNow pings every connection once, re-dialing unhealthy ones whose backoff has elapsed.
+func (h *HealthChecker) CheckNow(ctx context.Context) {
+	h.mu.Lock()
+	names := make([]string, 0, len(h.targets))
+	for name := range h.targets {
+		names = append(names, name)
+	}
+	h.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		h.check(ctx, name)
+	}
+}
+
+func (h *HealthChecker) check(ctx context.Context, name string) {
+	h.mu.Lock()
+	target, ok := h.targets[name]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	status := target.status
+	h.mu.Unlock()
+
+	now := time.Now()
+	if !status.Healthy && now.Before(status.NextReconnect) {
+		return
+	}
+
+	if !status.Healthy {
+		if err := target.conn.Reconnect(); err != nil {
+			status.ConsecutiveFailures++
+			status.LastCheck = now
+			status.LastError = err.Error()
+			status.NextReconnect = now.Add(h.backoff(status.ConsecutiveFailures))
+			h.update(name, target, status)
+			return
+		}
+		status.Reconnects++
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, h.options.Timeout)
+	err := target.conn.PingContext(pingCtx)
+	cancel()
+
+	status.LastCheck = now
+	status.Latency = time.Since(now)
+	if err != nil {
+		status.Healthy = false
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+		status.NextReconnect = now.Add(h.backoff(status.ConsecutiveFailures))
+	} else {
+		status.Healthy = true
+		status.ConsecutiveFailures = 0
+		status.LastError = ""
+		status.NextReconnect = time.Time{}
+	}
+	h.update(name, target, status)
+}
+
+// update stores status unless the connection was removed or replaced during the check.
+func (h *HealthChecker) update(name string, target *healthTarget, status types.HealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.targets[name] == target {
+		target.status = status
+	}
+}
+
+// backoff returns MinBackoff doubled for every failure after the first, capped at MaxBackoff.
+func (h *HealthChecker) backoff(failures int) time.Duration {
+	delay := h.options.MinBackoff
+	for i := 1; i < failures && delay < h.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > h.options.MaxBackoff {
+		delay = h.options.MaxBackoff
+	}
+	return delay
+}
+
+// Report returns the last known status of every registered connection.
+func (h *HealthChecker) Report() map[string]types.HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := make(map[string]types.HealthStatus, len(h.targets))
+	for name, target := range h.targets {
+		report[name] = target.status
+	}
+	return report
+}
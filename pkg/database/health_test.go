@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+type fakeReconnector struct {
+	pingErr      error
+	reconnectErr error
+	reconnects   int
+}
+
+func (f *fakeReconnector) PingContext(_ context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeReconnector) Reconnect() error {
+	f.reconnects++
+	if f.reconnectErr == nil {
+		f.pingErr = nil
+	}
+	return f.reconnectErr
+}
+
+func TestHealthCheckerReconnectsWithBackoff(t *testing.T) {
+	checker := NewHealthChecker(types.HealthCheckOptions{MinBackoff: 50 * time.Millisecond, MaxBackoff: 200 * time.Millisecond})
+	conn := &fakeReconnector{pingErr: errors.New("connection refused")}
+	checker.Add("primary", conn)
+
+	checker.CheckNow(context.Background())
+	status := checker.Report()["primary"]
+	if status.Healthy || status.ConsecutiveFailures != 1 || status.LastError != "connection refused" {
+		t.Fatalf("Expected primary to be unhealthy after a failed ping, got: %+v", status)
+	}
+
+	// The backoff has not elapsed yet, so no reconnect is attempted.
+	checker.CheckNow(context.Background())
+	if conn.reconnects != 0 {
+		t.Fatalf("Expected no reconnect before the backoff elapsed, got: %d", conn.reconnects)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	checker.CheckNow(context.Background())
+	status = checker.Report()["primary"]
+	if !status.Healthy || status.Reconnects != 1 || status.ConsecutiveFailures != 0 {
+		t.Errorf("Expected primary to recover after reconnecting, got: %+v", status)
+	}
+}
+
+func TestHealthCheckerBackoffIsCapped(t *testing.T) {
+	checker := NewHealthChecker(types.HealthCheckOptions{MinBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := checker.backoff(i + 1); got != want {
+			t.Errorf("Expected backoff %s after %d failures, got: %s", want, i+1, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// stmtCache keeps the most recently used prepared statements of a connection, keyed by SQL.
+type stmtCache struct {
+	mu     sync.Mutex
+	db     *sqlx.DB
+	size   int
+	items  map[string]*list.Element
+	order  *list.List
+	closed bool
+}
+
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db *sqlx.DB, size int) *stmtCache {
+	return &stmtCache{
+		db:    db,
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// acquire returns the cached statement for query, preparing it on a miss. Callers must release it.
+func (c *stmtCache) acquire(ctx context.Context, query string) (*stmtEntry, error) {
+	if entry := c.lookup(query); entry != nil {
+		return entry, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		// Not cached; release closes it.
+		c.mu.Unlock()
+		return &stmtEntry{query: query, stmt: stmt, refs: 1, evicted: true}, nil
+	}
+	if elem, ok := c.items[query]; ok {
+		// Another goroutine prepared the same statement first.
+		entry := elem.Value.(*stmtEntry)
+		entry.refs++
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		stmt.Close()
+		return entry, nil
+	}
+
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.order.PushFront(entry)
+	var stale []*sql.Stmt
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		old := oldest.Value.(*stmtEntry)
+		c.order.Remove(oldest)
+		delete(c.items, old.query)
+		old.evicted = true
+		if old.refs == 0 {
+			stale = append(stale, old.stmt)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, s := range stale {
+		s.Close()
+	}
+	return entry, nil
+}
+
+func (c *stmtCache) lookup(query string) *stmtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[query]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*stmtEntry)
+	entry.refs++
+	c.order.MoveToFront(elem)
+	return entry
+}
+
+// release gives back a statement from acquire, closing it if it was evicted while in use.
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	entry.refs--
+	closeNow := entry.evicted && entry.refs == 0
+	c.mu.Unlock()
+
+	if closeNow {
+		entry.stmt.Close()
+	}
+}
+
+// len returns the number of cached statements.
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// close closes every cached statement and stops caching new ones.
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	c.closed = true
+	var stmts []*sql.Stmt
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*stmtEntry)
+		entry.evicted = true
+		if entry.refs == 0 {
+			stmts = append(stmts, entry.stmt)
+		}
+	}
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.mu.Unlock()
+
+	var firstErr error
+	for _, stmt := range stmts {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestStmtCacheIsOptIn(t *testing.T) {
+	conn := &Connection{config: types.Config{Driver: types.MySQL}}
+	conn.setupStmtCache()
+	if conn.stmts != nil {
+		t.Fatal("Expected no statement cache without StmtCacheSize")
+	}
+
+	conn = &Connection{config: types.Config{Driver: types.MySQL, StmtCacheSize: 20}}
+	conn.setupStmtCache()
+	if conn.stmts == nil || conn.stmts.size != 20 {
+		t.Fatalf("Expected a cache of 20 statements, got %+v", conn.stmts)
+	}
+}
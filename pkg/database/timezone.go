@@ -0,0 +1,66 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// loadLocation resolves the configured time zone, UTC when none is set.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// mysqlTimeZone returns the value of MySQL's time_zone session variable for a zone name, or "" to
+// keep the server's when no zone or Local is configured. UTC is sent as an offset, which works
+// without the server's time zone tables.
+func mysqlTimeZone(name string) string {
+	switch name {
+	case "UTC":
+		return "+00:00"
+	case "", "Local":
+		return ""
+	default:
+		return name
+	}
+}
+
+// normalizeTimes returns args with time.Time values converted to loc. args is copied, not
+// modified, and only when it holds a time.
+func normalizeTimes(args []interface{}, loc *time.Location) []interface{} {
+	if loc == nil {
+		return args
+	}
+
+	var normalized []interface{}
+	for i, arg := range args {
+		var t time.Time
+		switch v := arg.(type) {
+		case time.Time:
+			t = v
+		case *time.Time:
+			if v == nil {
+				continue
+			}
+			t = *v
+		default:
+			continue
+		}
+
+		if normalized == nil {
+			normalized = make([]interface{}, len(args))
+			copy(normalized, args)
+		}
+		normalized[i] = t.In(loc)
+	}
+	if normalized == nil {
+		return args
+	}
+	return normalized
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestMySQLSessionTimeZone(t *testing.T) {
+	tests := []struct {
+		timezone string
+		loc      string
+		zone     string
+	}{
+		{"", "UTC", ""},
+		{"UTC", "UTC", "'+00:00'"},
+		{"Local", "Local", ""},
+		{"America/New_York", "America/New_York", "'America/New_York'"},
+	}
+
+	for _, tt := range tests {
+		conn := &Connection{config: types.Config{Database: "shop", Timezone: tt.timezone}}
+		if tt.loc != "UTC" && tt.loc != "Local" {
+			if _, err := time.LoadLocation(tt.loc); err != nil {
+				t.Skipf("time zone database unavailable: %v", err)
+			}
+		}
+
+		parsed, err := mysql.ParseDSN(conn.buildMySQLDSN())
+		if err != nil {
+			t.Fatalf("%q: invalid DSN: %v", tt.timezone, err)
+		}
+		if parsed.DBName != "shop" || parsed.Loc.String() != tt.loc || parsed.Params["time_zone"] != tt.zone {
+			t.Errorf("%q: expected loc %s and time_zone %s, got %s, %s, %q", tt.timezone, tt.loc, tt.zone, parsed.DBName, parsed.Loc, parsed.Params["time_zone"])
+		}
+	}
+}
+
+func TestNormalizeTimes(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	local := time.Date(2024, 3, 1, 8, 30, 0, 0, tokyo)
+
+	args := []interface{}{1, local, &local, (*time.Time)(nil)}
+	normalized := normalizeTimes(args, time.UTC)
+
+	if got := normalized[1].(time.Time); got.Location() != time.UTC || !got.Equal(local) || got.Hour() != 23 {
+		t.Errorf("Expected the same instant in UTC, got %v", got)
+	}
+	if got := normalized[2].(time.Time); got.Location() != time.UTC || !got.Equal(local) {
+		t.Errorf("Expected the pointer to be converted, got %v", got)
+	}
+	if args[1].(time.Time).Location() != tokyo {
+		t.Error("Expected the arguments not to be modified")
+	}
+
+	plain := []interface{}{1, "a"}
+	if got := normalizeTimes(plain, time.UTC); &got[0] != &plain[0] {
+		t.Error("Expected arguments without times to be returned as is")
+	}
+
+	conn := &Connection{config: types.Config{NormalizeTimes: false}, loc: time.UTC}
+	if got := conn.bind(args); got[1].(time.Time).Location() != tokyo {
+		t.Error("Expected no conversion without NormalizeTimes")
+	}
+}
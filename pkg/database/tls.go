@@ -0,0 +1,53 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// buildTLSConfig returns the TLS configuration described by the connection's TLS fields, or nil
+// when none are set and the driver's sslmode handling applies.
+func buildTLSConfig(config types.Config) (*tls.Config, error) {
+	if config.TLSCACert == "" && config.TLSCert == "" && config.TLSKey == "" &&
+		config.TLSServerName == "" && !config.TLSSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         config.TLSServerName,
+		InsecureSkipVerify: config.TLSSkipVerify, // #nosec G402 -- explicit opt-in via TLSSkipVerify
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = config.Host
+	}
+
+	if config.TLSCACert != "" {
+		pem, err := os.ReadFile(config.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in TLS CA file %s", config.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.TLSCert != "" || config.TLSKey != "" {
+		if config.TLSCert == "" || config.TLSKey == "" {
+			return nil, fmt.Errorf("TLS client certificate and key must be provided together")
+		}
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
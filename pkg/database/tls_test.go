@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestBuildTLSConfig(t *testing.T) {
+	tlsConfig, err := buildTLSConfig(types.Config{Host: "db.internal"})
+	if err != nil || tlsConfig != nil {
+		t.Fatalf("Expected no TLS config without TLS fields, got: %v, %v", tlsConfig, err)
+	}
+
+	tlsConfig, err = buildTLSConfig(types.Config{Host: "db.internal", TLSSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !tlsConfig.InsecureSkipVerify || tlsConfig.ServerName != "db.internal" {
+		t.Errorf("Expected skip-verify config for db.internal, got: %+v", tlsConfig)
+	}
+
+	if _, err := buildTLSConfig(types.Config{TLSCert: "client.pem"}); err == nil {
+		t.Error("Expected an error for a client certificate without a key")
+	}
+	if _, err := buildTLSConfig(types.Config{TLSCACert: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+}
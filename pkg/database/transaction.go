@@ -3,6 +3,8 @@ package database
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 	"github.com/jmoiron/sqlx"
@@ -10,8 +12,14 @@ import (
 
 // Transaction wraps a database transaction with additional functionality.
 type Transaction struct {
-	tx     *sqlx.Tx
-	driver types.Driver
+	tx        *sqlx.Tx
+	driver    types.Driver
+	version   types.ServerVersion
+	budget    types.ComplexityBudget
+	loc       *time.Location
+	normalize bool // convert time.Time arguments to loc
+	once      sync.Once
+	done      func()
 }
 
 // NewTransaction creates a new Transaction wrapper.
@@ -22,19 +30,60 @@ func NewTransaction(tx *sqlx.Tx, driver types.Driver) *Transaction {
 	}
 }
 
+// newTrackedTransaction creates a Transaction that calls done once it is committed or rolled back.
+func newTrackedTransaction(tx *sqlx.Tx, driver types.Driver, version types.ServerVersion, done func()) *Transaction {
+	return &Transaction{
+		tx:      tx,
+		driver:  driver,
+		version: version,
+		done:    done,
+	}
+}
+
+// ServerVersion returns the version of the server the transaction runs on, if it was detected.
+func (t *Transaction) ServerVersion() types.ServerVersion {
+	return t.version
+}
+
+// ComplexityBudget returns the complexity budget of the connection the transaction runs on.
+func (t *Transaction) ComplexityBudget() types.ComplexityBudget {
+	return t.budget
+}
+
+// Location returns the session time zone of the connection the transaction runs on.
+func (t *Transaction) Location() *time.Location {
+	return t.loc
+}
+
+// bind converts the time.Time arguments of a statement like Connection.bind.
+func (t *Transaction) bind(args []interface{}) []interface{} {
+	if !t.normalize {
+		return args
+	}
+	return normalizeTimes(args, t.loc)
+}
+
 // QueryContext executes a query that returns rows within the transaction context.
 func (t *Transaction) QueryContext(ctx context.Context, query string, args ...interface{}) (types.Rows, error) {
-	return t.tx.QueryContext(ctx, query, args...)
+	rows, err := t.tx.QueryContext(ctx, query, t.bind(args)...)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+	return classifiedRows{Rows: rows}, nil
 }
 
 // QueryRowContext executes a query that is expected to return at most one row within the transaction.
 func (t *Transaction) QueryRowContext(ctx context.Context, query string, args ...interface{}) types.Row {
-	return t.tx.QueryRowContext(ctx, query, args...)
+	return classifiedRow{Row: t.tx.QueryRowContext(ctx, query, t.bind(args)...)}
 }
 
 // ExecContext executes a query without returning any rows within the transaction.
 func (t *Transaction) ExecContext(ctx context.Context, query string, args ...interface{}) (types.Result, error) {
-	return t.tx.ExecContext(ctx, query, args...)
+	result, err := t.tx.ExecContext(ctx, query, t.bind(args)...)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+	return result, nil
 }
 
 // Begin returns an error as nested transactions are not supported.
@@ -49,12 +98,22 @@ func (t *Transaction) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx,
 
 // Commit commits the transaction.
 func (t *Transaction) Commit() error {
-	return t.tx.Commit()
+	err := t.tx.Commit()
+	t.finish()
+	return ClassifyError(err)
 }
 
 // Rollback aborts the transaction.
 func (t *Transaction) Rollback() error {
-	return t.tx.Rollback()
+	err := t.tx.Rollback()
+	t.finish()
+	return err
+}
+
+func (t *Transaction) finish() {
+	if t.done != nil {
+		t.once.Do(t.done)
+	}
 }
 
 // Driver returns the database driver type for this transaction.
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var twoPhaseIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// twoPhaseTx is a transaction on a dedicated connection that can be prepared for a two-phase
+// commit, using XA transactions on MySQL and PREPARE TRANSACTION on PostgreSQL.
+type twoPhaseTx struct {
+	conn     *sql.Conn
+	driver   types.Driver
+	id       string
+	ended    bool // MySQL: XA END was issued
+	prepared bool
+	once     sync.Once
+	done     func()
+}
+
+// BeginTwoPhase starts a transaction identified by id that can be prepared and later committed or
+// rolled back together with transactions on other connections. PostgreSQL requires
+// max_prepared_transactions > 0, MySQL a user with the XA_RECOVER_ADMIN privilege for recovery.
+func (c *Connection) BeginTwoPhase(ctx context.Context, id string) (types.TwoPhaseTx, error) {
+	if !twoPhaseIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid two-phase transaction id %q", id)
+	}
+	if c.config.ReadOnly {
+		return nil, types.ErrReadOnly
+	}
+	if err := c.ServerVersion().Require(types.FeatureTwoPhaseCommit); err != nil {
+		return nil, err
+	}
+	if err := c.drain.enter(); err != nil {
+		return nil, err
+	}
+
+	db, _, _ := c.handles()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		c.drain.leave()
+		return nil, ClassifyError(err)
+	}
+	return beginTwoPhase(ctx, conn, c.driver, id, c.drain.leave)
+}
+
+func beginTwoPhase(ctx context.Context, conn *sql.Conn, driver types.Driver, id string, done func()) (*twoPhaseTx, error) {
+	tx := &twoPhaseTx{conn: conn, driver: driver, id: id, done: done}
+
+	start := "BEGIN"
+	if driver == types.MySQL {
+		start = "XA START " + tx.xid()
+	}
+	if _, err := conn.ExecContext(ctx, start); err != nil {
+		tx.finish()
+		return nil, fmt.Errorf("failed to begin two-phase transaction: %w", ClassifyError(err))
+	}
+	return tx, nil
+}
+
+func (t *twoPhaseTx) xid() string {
+	return "'" + t.id + "'"
+}
+
+// ID returns the global transaction id.
+func (t *twoPhaseTx) ID() string {
+	return t.id
+}
+
+// QueryContext executes a query that returns rows within the transaction.
+func (t *twoPhaseTx) QueryContext(ctx context.Context, query string, args ...interface{}) (types.Rows, error) {
+	rows, err := t.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+	return classifiedRows{Rows: rows}, nil
+}
+
+// QueryRowContext executes a query that is expected to return at most one row within the transaction.
+func (t *twoPhaseTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) types.Row {
+	return classifiedRow{Row: t.conn.QueryRowContext(ctx, query, args...)}
+}
+
+// ExecContext executes a query without returning any rows within the transaction.
+func (t *twoPhaseTx) ExecContext(ctx context.Context, query string, args ...interface{}) (types.Result, error) {
+	result, err := t.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+	return result, nil
+}
+
+// Begin returns an error as nested transactions are not supported.
+func (t *twoPhaseTx) Begin() (types.Tx, error) {
+	return nil, fmt.Errorf("cannot start a transaction within a transaction")
+}
+
+// BeginTx returns an error as nested transactions are not supported.
+func (t *twoPhaseTx) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx, error) {
+	return nil, fmt.Errorf("cannot start a transaction within a transaction")
+}
+
+// Prepare runs the first phase: the changes are made durable but stay invisible until Commit.
+func (t *twoPhaseTx) Prepare(ctx context.Context) error {
+	if t.driver == types.MySQL {
+		if err := t.endXA(ctx); err != nil {
+			return err
+		}
+		if _, err := t.conn.ExecContext(ctx, "XA PREPARE "+t.xid()); err != nil {
+			return fmt.Errorf("failed to prepare transaction %s: %w", t.id, ClassifyError(err))
+		}
+	} else if _, err := t.conn.ExecContext(ctx, "PREPARE TRANSACTION "+t.xid()); err != nil {
+		return fmt.Errorf("failed to prepare transaction %s: %w", t.id, ClassifyError(err))
+	}
+
+	t.prepared = true
+	return nil
+}
+
+// Commit commits the transaction, in one phase if it was not prepared.
+func (t *twoPhaseTx) Commit() error {
+	defer t.finish()
+	ctx := context.Background()
+
+	var err error
+	switch {
+	case t.driver != types.MySQL && t.prepared:
+		_, err = t.conn.ExecContext(ctx, "COMMIT PREPARED "+t.xid())
+	case t.driver != types.MySQL:
+		_, err = t.conn.ExecContext(ctx, "COMMIT")
+	case t.prepared:
+		_, err = t.conn.ExecContext(ctx, "XA COMMIT "+t.xid())
+	default:
+		if err = t.endXA(ctx); err == nil {
+			_, err = t.conn.ExecContext(ctx, "XA COMMIT "+t.xid()+" ONE PHASE")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction %s: %w", t.id, ClassifyError(err))
+	}
+	return nil
+}
+
+// Rollback aborts the transaction, prepared or not.
+func (t *twoPhaseTx) Rollback() error {
+	defer t.finish()
+	ctx := context.Background()
+
+	var err error
+	switch {
+	case t.driver != types.MySQL && t.prepared:
+		_, err = t.conn.ExecContext(ctx, "ROLLBACK PREPARED "+t.xid())
+	case t.driver != types.MySQL:
+		_, err = t.conn.ExecContext(ctx, "ROLLBACK")
+	default:
+		if err = t.endXA(ctx); err == nil {
+			_, err = t.conn.ExecContext(ctx, "XA ROLLBACK "+t.xid())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to roll back transaction %s: %w", t.id, err)
+	}
+	return nil
+}
+
+// endXA issues XA END once, which MySQL requires before XA PREPARE, COMMIT and ROLLBACK.
+func (t *twoPhaseTx) endXA(ctx context.Context) error {
+	if t.ended {
+		return nil
+	}
+	if _, err := t.conn.ExecContext(ctx, "XA END "+t.xid()); err != nil {
+		return fmt.Errorf("failed to end transaction %s: %w", t.id, ClassifyError(err))
+	}
+	t.ended = true
+	return nil
+}
+
+func (t *twoPhaseTx) finish() {
+	t.once.Do(func() {
+		_ = t.conn.Close()
+		if t.done != nil {
+			t.done()
+		}
+	})
+}
+
+// Driver returns the database driver type for this transaction.
+func (t *twoPhaseTx) Driver() types.Driver {
+	return t.driver
+}
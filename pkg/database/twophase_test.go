@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// recordingDriver is a database/sql driver that accepts every statement and records it.
+type recordingDriver struct {
+	statements []string
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.d, query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.statements = append(s.d.statements, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func openTwoPhase(t *testing.T, dbDriver types.Driver) (*twoPhaseTx, *recordingDriver, *bool) {
+	t.Helper()
+	recorder := &recordingDriver{}
+	sql.Register(t.Name(), recorder)
+	db, err := sql.Open(t.Name(), "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	released := false
+	tx, err := beginTwoPhase(context.Background(), conn, dbDriver, "qb_1_0", func() { released = true })
+	if err != nil {
+		t.Fatalf("beginTwoPhase failed: %v", err)
+	}
+	return tx, recorder, &released
+}
+
+func TestTwoPhaseMySQLPrepareAndCommit(t *testing.T) {
+	tx, recorder, released := openTwoPhase(t, types.MySQL)
+	ctx := context.Background()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE accounts SET balance = 0"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Prepare(ctx); err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	want := []string{"XA START 'qb_1_0'", "UPDATE accounts SET balance = 0", "XA END 'qb_1_0'", "XA PREPARE 'qb_1_0'", "XA COMMIT 'qb_1_0'"}
+	if !reflect.DeepEqual(recorder.statements, want) {
+		t.Errorf("Expected %v, got %v", want, recorder.statements)
+	}
+	if !*released {
+		t.Error("Expected the connection to be released after commit")
+	}
+}
+
+func TestTwoPhaseMySQLOnePhaseRollback(t *testing.T) {
+	tx, recorder, _ := openTwoPhase(t, types.MySQL)
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	want := []string{"XA START 'qb_1_0'", "XA END 'qb_1_0'", "XA ROLLBACK 'qb_1_0'"}
+	if !reflect.DeepEqual(recorder.statements, want) {
+		t.Errorf("Expected %v, got %v", want, recorder.statements)
+	}
+}
+
+func TestTwoPhasePostgresRollbackPrepared(t *testing.T) {
+	tx, recorder, _ := openTwoPhase(t, types.PostgreSQL)
+
+	if err := tx.Prepare(context.Background()); err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	want := []string{"BEGIN", "PREPARE TRANSACTION 'qb_1_0'", "ROLLBACK PREPARED 'qb_1_0'"}
+	if !reflect.DeepEqual(recorder.statements, want) {
+		t.Errorf("Expected %v, got %v", want, recorder.statements)
+	}
+}
+
+func TestBeginTwoPhaseRejectsInvalidID(t *testing.T) {
+	c := &Connection{}
+	if _, err := c.BeginTwoPhase(context.Background(), "x'; DROP TABLE users; --"); err == nil {
+		t.Error("Expected an invalid id to be rejected")
+	}
+}
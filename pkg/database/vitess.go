@@ -0,0 +1,72 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var (
+	vitessCodePattern  = regexp.MustCompile(`code = (\w+)`)
+	vitessErrnoPattern = regexp.MustCompile(`\(errno (\d+)\)`)
+)
+
+// mysqlDatabase returns the database name for the MySQL DSN, with the Vitess tablet target
+// appended so queries are routed to it (e.g. "commerce@replica").
+func (c *Connection) mysqlDatabase() string {
+	if !c.config.Vitess.Enabled || c.config.Vitess.Target == "" {
+		return c.config.Database
+	}
+	target := c.config.Vitess.Target
+	if !strings.HasPrefix(target, "@") {
+		target = "@" + target
+	}
+	return c.config.Database + target
+}
+
+// classifyVitessError classifies the errors Vitess reports as MySQL error 1105 with the gRPC code
+// and the underlying MySQL error number in the message, e.g. "target: commerce.0.primary:
+// vttablet: rpc error: code = ResourceExhausted desc = Row count exceeded 100000 (errno 10001)".
+func classifyVitessError(mysqlErr *mysql.MySQLError, err error) error {
+	if match := vitessErrnoPattern.FindStringSubmatch(mysqlErr.Message); match != nil {
+		if number, convErr := strconv.Atoi(match[1]); convErr == nil && number != 1105 && number <= 0xFFFF {
+			inner := *mysqlErr
+			inner.Number = uint16(number)
+			if classified, ok := classifyMySQLError(&inner, err).(*types.DBError); ok {
+				return classified
+			}
+		}
+	}
+
+	match := vitessCodePattern.FindStringSubmatch(mysqlErr.Message)
+	if match == nil {
+		return err
+	}
+
+	classified := &types.DBError{Code: match[1], Err: err}
+	switch match[1] {
+	case "ResourceExhausted":
+		classified.Kind = types.ErrResourceExhausted
+	case "DeadlineExceeded":
+		classified.Kind = types.ErrTimeout
+	case "Unavailable":
+		classified.Kind = types.ErrFailover
+	case "FailedPrecondition":
+		// Raised while a shard is being reparented or resharded.
+		if !strings.Contains(mysqlErr.Message, "not serving") && !strings.Contains(mysqlErr.Message, "no healthy tablet") {
+			return err
+		}
+		classified.Kind = types.ErrFailover
+	case "Aborted":
+		if !strings.Contains(mysqlErr.Message, "exceeded timeout") {
+			return err
+		}
+		classified.Kind = types.ErrTimeout
+	default:
+		return err
+	}
+	return classified
+}
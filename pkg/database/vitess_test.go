@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestVitessTargetRouting(t *testing.T) {
+	conn := &Connection{config: types.Config{Database: "commerce", Vitess: types.VitessOptions{Enabled: true, Target: "replica"}}}
+	if got := conn.mysqlDatabase(); got != "commerce@replica" {
+		t.Errorf("Expected commerce@replica, got %q", got)
+	}
+
+	conn.config.Vitess.Target = "@primary"
+	parsed, err := mysql.ParseDSN(conn.buildMySQLDSN())
+	if err != nil || parsed.DBName != "commerce@primary" {
+		t.Errorf("Expected the DSN to select commerce@primary, got %v (%v)", parsed, err)
+	}
+
+	conn.config.Vitess.Enabled = false
+	if got := conn.mysqlDatabase(); got != "commerce" {
+		t.Errorf("Expected the target to be ignored outside Vitess mode, got %q", got)
+	}
+}
+
+func TestClassifyVitessErrors(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    error
+		code    string
+	}{
+		{"target: commerce.0.primary: vttablet: rpc error: code = ResourceExhausted desc = Row count exceeded 100000 (errno 10001) (sqlstate HY000)", types.ErrResourceExhausted, "ResourceExhausted"},
+		{"target: commerce.-80.primary: vttablet: rpc error: code = AlreadyExists desc = Duplicate entry '7' for key 'users.PRIMARY' (errno 1062) (sqlstate 23000)", types.ErrDuplicateKey, "1062"},
+		{"target: commerce.0.primary: no healthy tablet available for 'keyspace:\"commerce\" shard:\"0\" tablet_type:PRIMARY'", nil, ""},
+		{"target: commerce.0.primary: vttablet: rpc error: code = Unavailable desc = connection refused", types.ErrFailover, "Unavailable"},
+		{"vttablet: rpc error: code = FailedPrecondition desc = operation not allowed in state NOT_SERVING", nil, ""},
+		{"vttablet: rpc error: code = FailedPrecondition desc = primary is not serving, there is a reparent operation in progress", types.ErrFailover, "FailedPrecondition"},
+		{"vttablet: rpc error: code = Aborted desc = transaction 1694: ended at 2024-05-01 12:00:00 UTC (exceeded timeout: 20s)", types.ErrTimeout, "Aborted"},
+		{"unknown error", nil, ""},
+	}
+
+	for _, tt := range tests {
+		original := &mysql.MySQLError{Number: 1105, Message: tt.message}
+		err := ClassifyError(original)
+		if tt.kind == nil {
+			if err != original {
+				t.Errorf("Expected %q to stay unclassified, got %v", tt.message, err)
+			}
+			continue
+		}
+
+		var dbErr *types.DBError
+		if !errors.As(err, &dbErr) || !errors.Is(err, tt.kind) || dbErr.Code != tt.code || !errors.Is(err, original) {
+			t.Errorf("Unexpected classification of %q: %#v", tt.message, err)
+		}
+	}
+}
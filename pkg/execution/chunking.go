@@ -5,24 +5,122 @@ package execution
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
+var chunkColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SetChunkOrder sets the columns Chunk, Each, Lazy and the keyset variants order by when the call
+// names none, overriding the primary key registered for the table. Several columns form a
+// composite key. With no columns the table's primary key is used again.
+func (e *QueryExecutor) SetChunkOrder(columns ...string) *QueryExecutor {
+	e.chunkOrder = columns
+	return e
+}
+
+// chunkColumns returns the non-empty columns of explicit, falling back to the executor's chunk
+// order and then to the primary key columns registered for table ("id" by default).
+func (e *QueryExecutor) chunkColumns(table string, explicit []string) ([]string, error) {
+	var columns []string
+	for _, column := range explicit {
+		if column != "" {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		columns = e.chunkOrder
+	}
+	if len(columns) == 0 {
+		return schema.KeyColumns(table), nil
+	}
+
+	for _, column := range columns {
+		if !chunkColumnPattern.MatchString(column) {
+			return nil, fmt.Errorf("invalid chunk column %q", column)
+		}
+	}
+	return columns, nil
+}
+
+// orderByColumns orders a clone of qb by columns.
+func orderByColumns(qb QueryBuilderInterface, columns []string) types.QueryBuilder {
+	ordered := qb.Clone()
+	for _, column := range columns {
+		ordered = ordered.OrderBy(column)
+	}
+	return ordered
+}
+
+// resultKey returns the key of column in a result row: its name without a table qualifier.
+func resultKey(column string) string {
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		return column[i+1:]
+	}
+	return column
+}
+
+// lastKey returns the values of columns in the last row of collection.
+func lastKey(collection types.Collection, columns []string) []interface{} {
+	last := collection.ToSlice()[collection.Count()-1]
+	key := make([]interface{}, len(columns))
+	for i, column := range columns {
+		key[i] = last[resultKey(column)]
+	}
+	return key
+}
+
+// afterKey restricts qb to the rows that sort after key on columns. A composite key is compared
+// column by column, as (a > ?) OR (a = ? AND b > ?), which every driver supports and indexes on
+// (a, b) can serve.
+func afterKey(qb types.QueryBuilder, columns []string, key []interface{}) types.QueryBuilder {
+	if len(columns) == 1 {
+		return qb.Where(columns[0], ">", key[0])
+	}
+
+	var parts []string
+	var bindings []interface{}
+	for i, column := range columns {
+		var conditions []string
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, columns[j]+" = ?")
+			bindings = append(bindings, key[j])
+		}
+		conditions = append(conditions, column+" > ?")
+		bindings = append(bindings, key[i])
+		parts = append(parts, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return qb.WhereRaw("("+strings.Join(parts, " OR ")+")", bindings...)
+}
+
 // Chunk processes query results in chunks of the specified size using OFFSET-based pagination.
-func (e *QueryExecutor) Chunk(ctx context.Context, qb QueryBuilderInterface, size int, callback types.ChunkFunc) error {
+// Chunks are ordered by columns, or by the executor's chunk order or the table's primary key when
+// none are given.
+func (e *QueryExecutor) Chunk(ctx context.Context, qb QueryBuilderInterface, size int, callback types.ChunkFunc, columns ...string) error {
 	if size <= 0 {
 		return fmt.Errorf("chunk size must be positive")
 	}
 
+	if e.IsStreaming() {
+		return e.streamChunk(ctx, qb, size, callback)
+	}
+
+	orderBy, err := e.chunkColumns(qb.GetTable(), columns)
+	if err != nil {
+		return err
+	}
+
 	offset := 0
-	orderBy := "id"
 
 	for {
-		clone := qb.Clone()
-		chunkQB := clone.OrderBy(orderBy).Limit(size).Offset(offset)
+		chunkQB := orderByColumns(qb, orderBy).Limit(size).Offset(offset)
 		
-		collection, err := e.Get(ctx, chunkQB.(QueryBuilderInterface))
+		// A chunk holds at most size rows, so MaxRows does not apply to it.
+		collection, err := e.get(ctx, chunkQB.(QueryBuilderInterface), 0, false)
 		if err != nil {
 			return fmt.Errorf("failed to get chunk: %w", err)
 		}
@@ -45,28 +143,66 @@ func (e *QueryExecutor) Chunk(ctx context.Context, qb QueryBuilderInterface, siz
 	return nil
 }
 
-// ChunkByID processes query results in chunks based on ID ordering.
+// streamChunk reads the whole result through a single streaming cursor and groups rows into chunks.
+func (e *QueryExecutor) streamChunk(ctx context.Context, qb QueryBuilderInterface, size int, callback types.ChunkFunc) error {
+	cursor, err := e.Cursor(ctx, qb)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cursor.Close() }()
+
+	batch := make([]map[string]interface{}, 0, size)
+	for cursor.Next() {
+		row, err := cursor.ScanMap()
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		batch = append(batch, row)
+		if len(batch) == size {
+			if err := callback(types.NewCollection(batch)); err != nil {
+				return fmt.Errorf("chunk callback error: %w", err)
+			}
+			batch = make([]map[string]interface{}, 0, size)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+
+	if len(batch) > 0 {
+		if err := callback(types.NewCollection(batch)); err != nil {
+			return fmt.Errorf("chunk callback error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ChunkByID processes query results in chunks based on ID ordering. Each chunk continues after the
+// last key of the previous one instead of using OFFSET. The key is the given columns, or the
+// executor's chunk order or the table's primary key; several columns form a composite key.
 func (e *QueryExecutor) ChunkByID(ctx context.Context, qb QueryBuilderInterface, size int, callback types.ChunkFunc, column ...string) error {
 	if size <= 0 {
 		return fmt.Errorf("chunk size must be positive")
 	}
 
-	idColumn := "id"
-	if len(column) > 0 && column[0] != "" {
-		idColumn = column[0]
+	keyColumns, err := e.chunkColumns(qb.GetTable(), column)
+	if err != nil {
+		return err
 	}
 
-	var lastID interface{}
+	var lastID []interface{}
 
 	for {
-		clone := qb.Clone()
-		chunkQB := clone.OrderBy(idColumn).Limit(size)
+		chunkQB := orderByColumns(qb, keyColumns).Limit(size)
 		
 		if lastID != nil {
-			chunkQB = chunkQB.Where(idColumn, ">", lastID)
+			chunkQB = afterKey(chunkQB, keyColumns, lastID)
 		}
 
-		collection, err := e.Get(ctx, chunkQB.(QueryBuilderInterface))
+		collection, err := e.get(ctx, chunkQB.(QueryBuilderInterface), 0, false)
 		if err != nil {
 			return fmt.Errorf("failed to get chunk by id: %w", err)
 		}
@@ -83,13 +219,81 @@ func (e *QueryExecutor) ChunkByID(ctx context.Context, qb QueryBuilderInterface,
 			break
 		}
 
-		lastItem := collection.ToSlice()[collection.Count()-1]
-		lastID = lastItem[idColumn]
+		lastID = lastKey(collection, keyColumns)
 	}
 
 	return nil
 }
 
+// ChunkConcurrent fetches chunks one after another by keyset like ChunkByID, but runs callback on up
+// to workers chunks at once. The first failing callback stops the fetching and the chunks not yet
+// started; the failures of callbacks already running are collected too and returned as a
+// *types.BatchError whose item indexes are chunk numbers and keys the chunks' first keys (of the
+// first key column).
+// Callbacks must be safe to run concurrently.
+func (e *QueryExecutor) ChunkConcurrent(ctx context.Context, qb QueryBuilderInterface, size, workers int, callback types.ChunkFunc, column ...string) error {
+	if size <= 0 {
+		return fmt.Errorf("chunk size must be positive")
+	}
+	if workers <= 0 {
+		return fmt.Errorf("worker count must be positive")
+	}
+
+	keyColumns, err := e.chunkColumns(qb.GetTable(), column)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		index int
+		chunk types.Collection
+	}
+	jobs := make(chan job)
+
+	var mu sync.Mutex
+	batchErr := types.NewBatchError("concurrent chunk", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := callback(j.chunk); err != nil {
+					mu.Lock()
+					batchErr.Add(j.index, j.chunk.First()[resultKey(keyColumns[0])], fmt.Errorf("chunk callback error: %w", err))
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+	chunks := 0
+	fetchErr := e.ChunkByID(ctx, qb, size, func(chunk types.Collection) error {
+		select {
+		case jobs <- job{index: chunks, chunk: chunk}:
+			chunks++
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, keyColumns...)
+	close(jobs)
+	wg.Wait()
+
+	batchErr.Total = chunks
+	if batchErr.HasErrors() {
+		return batchErr
+	}
+	return fetchErr
+}
+
 // Each iterates over query results one item at a time using chunking.
 func (e *QueryExecutor) Each(ctx context.Context, qb QueryBuilderInterface, callback types.LazyFunc, chunkSize ...int) error {
 	size := 1000
@@ -131,13 +335,18 @@ func (e *QueryExecutor) Lazy(ctx context.Context, qb QueryBuilderInterface, chun
 		size = chunkSize[0]
 	}
 
+	orderBy, err := e.chunkColumns(qb.GetTable(), nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return &LazyCollection{
 		executor: e,
 		qb:       qb,
 		ctx:      ctx,
 		size:     size,
 		offset:   0,
-		orderBy:  "id",
+		orderBy:  orderBy,
 	}, nil
 }
 
@@ -148,9 +357,9 @@ func (e *QueryExecutor) LazyByID(ctx context.Context, qb QueryBuilderInterface,
 		size = chunkSize[0]
 	}
 
-	idColumn := "id"
-	if column != "" {
-		idColumn = column
+	keyColumns, err := e.chunkColumns(qb.GetTable(), []string{column})
+	if err != nil {
+		return nil, err
 	}
 
 	return &LazyCollection{
@@ -158,9 +367,8 @@ func (e *QueryExecutor) LazyByID(ctx context.Context, qb QueryBuilderInterface,
 		qb:           qb,
 		ctx:          ctx,
 		size:         size,
-		orderBy:      idColumn,
+		orderBy:      keyColumns,
 		useIDCursor:  true,
-		idColumn:     idColumn,
 	}, nil
 }
 
@@ -171,10 +379,9 @@ type LazyCollection struct {
 	ctx          context.Context
 	size         int
 	offset       int
-	orderBy      string
+	orderBy      []string
 	useIDCursor  bool
-	idColumn     string
-	lastID       interface{}
+	lastID       []interface{}
 	currentBatch types.Collection
 	batchIndex   int
 	finished     bool
@@ -225,12 +432,10 @@ func (lc *LazyCollection) Each(callback types.LazyFunc) error {
 }
 
 func (lc *LazyCollection) loadNextBatch() error {
-	clone := lc.qb.Clone()
-	
 	if lc.useIDCursor {
-		batchQB := clone.OrderBy(lc.idColumn).Limit(lc.size)
+		batchQB := orderByColumns(lc.qb, lc.orderBy).Limit(lc.size)
 		if lc.lastID != nil {
-			batchQB = batchQB.Where(lc.idColumn, ">", lc.lastID)
+			batchQB = afterKey(batchQB, lc.orderBy, lc.lastID)
 		}
 		
 		collection, err := lc.executor.Get(lc.ctx, batchQB.(QueryBuilderInterface))
@@ -241,11 +446,10 @@ func (lc *LazyCollection) loadNextBatch() error {
 		lc.currentBatch = collection
 		
 		if !collection.IsEmpty() {
-			lastItem := collection.ToSlice()[collection.Count()-1]
-			lc.lastID = lastItem[lc.idColumn]
+			lc.lastID = lastKey(collection, lc.orderBy)
 		}
 	} else {
-		batchQB := clone.OrderBy(lc.orderBy).Limit(lc.size).Offset(lc.offset)
+		batchQB := orderByColumns(lc.qb, lc.orderBy).Limit(lc.size).Offset(lc.offset)
 		
 		collection, err := lc.executor.Get(lc.ctx, batchQB.(QueryBuilderInterface))
 		if err != nil {
@@ -280,7 +484,6 @@ func (lc *LazyCollection) Filter(_ func(map[string]interface{}) bool) *LazyColle
 		size:         lc.size,
 		orderBy:      lc.orderBy,
 		useIDCursor:  lc.useIDCursor,
-		idColumn:     lc.idColumn,
 		// Note: In a full implementation, you'd need to handle filtering properly
 		// For now, we'll return the original collection
 	}
@@ -296,7 +499,6 @@ func (lc *LazyCollection) Map(_ func(map[string]interface{}) map[string]interfac
 		size:         lc.size,
 		orderBy:      lc.orderBy,
 		useIDCursor:  lc.useIDCursor,
-		idColumn:     lc.idColumn,
 		// Note: In a full implementation, you'd need to handle mapping properly
 		// For now, we'll return the original collection
 	}
@@ -304,12 +506,17 @@ func (lc *LazyCollection) Map(_ func(map[string]interface{}) map[string]interfac
 
 // Cursor creates a database cursor for streaming large result sets.
 func (e *QueryExecutor) Cursor(ctx context.Context, qb QueryBuilderInterface) (*Cursor, error) {
+	qb, err := applyPolicies(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+
 	sql, bindings, err := qb.ToSQL()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build SQL: %w", err)
 	}
 
-	rows, err := e.executor.QueryContext(ctx, sql, bindings...)
+	rows, err := e.query(ctx, qb.GetTable(), sql, bindings...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -323,13 +530,67 @@ func (e *QueryExecutor) Cursor(ctx context.Context, qb QueryBuilderInterface) (*
 	return &Cursor{
 		rows:    rows,
 		columns: columns,
+		cast:    schema.RowCaster(qb.GetTable()),
+		masks:   schema.Masks(ctx, qb.GetTable()),
 	}, nil
 }
 
+// Stream sends the query results one row at a time on the returned channel, which holds at most
+// bufferSize rows (default 100), so a slow consumer holds back the reads instead of the rows piling
+// up in memory. The row channel is closed when the results are exhausted, after which the error
+// channel yields the first error, if any, and is closed. Consumers that stop early must cancel ctx
+// to release the cursor.
+func (e *QueryExecutor) Stream(ctx context.Context, qb QueryBuilderInterface, bufferSize ...int) (<-chan map[string]interface{}, <-chan error) {
+	size := 100
+	if len(bufferSize) > 0 && bufferSize[0] > 0 {
+		size = bufferSize[0]
+	}
+
+	rows := make(chan map[string]interface{}, size)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(rows)
+		if err := e.stream(ctx, qb, rows); err != nil {
+			errs <- err
+		}
+	}()
+	return rows, errs
+}
+
+func (e *QueryExecutor) stream(ctx context.Context, qb QueryBuilderInterface, rows chan<- map[string]interface{}) error {
+	cursor, err := e.Cursor(ctx, qb)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cursor.Close() }()
+
+	for cursor.Next() {
+		row, err := cursor.ScanMap()
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		select {
+		case rows <- row:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return nil
+}
+
 // Cursor provides low-level access to query results with streaming support.
 type Cursor struct {
 	rows    types.Rows
 	columns []string
+	cast    func(map[string]interface{}) error
+	masks   map[string]types.MaskFunc
+	scanner *RowScanner
 	closed  bool
 }
 
@@ -341,8 +602,12 @@ func (c *Cursor) Next() bool {
 	return c.rows.Next()
 }
 
-// Scan copies the columns in the current row into the provided destination values.
+// Scan copies the columns in the current row into the provided destination values. It fails with
+// types.ErrMaskedColumns when the table masks columns for the cursor's context; use ScanMap then.
 func (c *Cursor) Scan(dest ...interface{}) error {
+	if len(c.masks) > 0 {
+		return fmt.Errorf("%w: use ScanMap", types.ErrMaskedColumns)
+	}
 	return c.rows.Scan(dest...)
 }
 
@@ -353,26 +618,24 @@ func (c *Cursor) ScanStruct(_ interface{}) error {
 
 // ScanMap scans the current row into a map with column names as keys.
 func (c *Cursor) ScanMap() (map[string]interface{}, error) {
-	values := make([]interface{}, len(c.columns))
-	valuePtrs := make([]interface{}, len(c.columns))
-	
-	for i := range c.columns {
-		valuePtrs[i] = &values[i]
+	if c.scanner == nil {
+		scanner, err := NewRowScanner(c.rows)
+		if err != nil {
+			return nil, err
+		}
+		c.scanner = scanner
 	}
 
-	if err := c.rows.Scan(valuePtrs...); err != nil {
+	result, err := c.scanner.Scan(c.rows)
+	if err != nil {
 		return nil, err
 	}
-
-	result := make(map[string]interface{})
-	for i, col := range c.columns {
-		val := values[i]
-		if b, ok := val.([]byte); ok {
-			result[col] = string(b)
-		} else {
-			result[col] = val
+	if c.cast != nil {
+		if err := c.cast(result); err != nil {
+			return nil, err
 		}
 	}
+	schema.MaskRow(c.masks, result)
 
 	return result, nil
 }
@@ -391,7 +654,132 @@ func (c *Cursor) Err() error {
 	return c.rows.Err()
 }
 
-// Columns returns the names of all columns in the result set.
+// Columns returns the names of all columns in the result set, except those dropped by masks.
 func (c *Cursor) Columns() []string {
-	return c.columns
-}
\ No newline at end of file
+	if len(c.masks) == 0 {
+		return c.columns
+	}
+	columns := make([]string, 0, len(c.columns))
+	for _, column := range c.columns {
+		if mask, masked := c.masks[column]; !masked || mask != nil {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// SnapshotChunk processes query results in chunks inside a single read-only REPEATABLE READ
+// transaction so that every chunk observes the same consistent snapshot of the data.
+// On PostgreSQL the chunks are fetched through a server-side cursor.
+func (e *QueryExecutor) SnapshotChunk(ctx context.Context, qb QueryBuilderInterface, size int, callback types.ChunkFunc) error {
+	if size <= 0 {
+		return fmt.Errorf("chunk size must be positive")
+	}
+
+	if e.driver == types.PostgreSQL {
+		return e.ServerCursorChunk(ctx, qb, size, callback)
+	}
+
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := executor.BeginTx(ctx, snapshotTxOptions())
+	if err != nil {
+		return fmt.Errorf("failed to begin snapshot transaction: %w", err)
+	}
+
+	txExecutor := e.withTx(tx)
+	if err := txExecutor.Chunk(ctx, qb, size, callback); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit snapshot transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ServerCursorChunk streams query results from a PostgreSQL server-side cursor, fetching size rows
+// at a time inside a read-only REPEATABLE READ transaction. Memory usage stays bounded on both the
+// client and the server regardless of the result size.
+func (e *QueryExecutor) ServerCursorChunk(ctx context.Context, qb QueryBuilderInterface, size int, callback types.ChunkFunc) error {
+	if e.driver != types.PostgreSQL {
+		return fmt.Errorf("server-side cursors are only supported for PostgreSQL")
+	}
+
+	if size <= 0 {
+		return fmt.Errorf("chunk size must be positive")
+	}
+
+	qb, err := applyPolicies(ctx, qb)
+	if err != nil {
+		return err
+	}
+
+	query, bindings, err := qb.ToSQL()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := executor.BeginTx(ctx, snapshotTxOptions())
+	if err != nil {
+		return fmt.Errorf("failed to begin cursor transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	txExecutor := e.withTx(tx)
+	if _, err := txExecutor.execStatement(ctx, qb.GetTable(), "DECLARE qb_cursor NO SCROLL CURSOR FOR "+query, bindings...); err != nil {
+		return fmt.Errorf("failed to declare cursor: %w", err)
+	}
+
+	fetchSQL := fmt.Sprintf("FETCH FORWARD %d FROM qb_cursor", size)
+
+	for {
+		rows, err := txExecutor.query(ctx, qb.GetTable(), fetchSQL)
+		if err != nil {
+			return fmt.Errorf("failed to fetch from cursor: %w", err)
+		}
+
+		collection, err := txExecutor.scanRows(ctx, qb.GetTable(), rows, 0)
+		_ = rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if collection.IsEmpty() {
+			break
+		}
+
+		if err := callback(collection); err != nil {
+			return fmt.Errorf("chunk callback error: %w", err)
+		}
+
+		if collection.Count() < size {
+			break
+		}
+	}
+
+	if _, err := txExecutor.execStatement(ctx, qb.GetTable(), "CLOSE qb_cursor"); err != nil {
+		return fmt.Errorf("failed to close cursor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cursor transaction: %w", err)
+	}
+
+	return nil
+}
+
+func snapshotTxOptions() *types.TxOptions {
+	return &types.TxOptions{
+		Isolation: types.RepeatableRead,
+		ReadOnly:  true,
+	}
+}
@@ -0,0 +1,96 @@
+package execution
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+type traceparentKey struct{}
+
+// WithTraceparent returns a context whose statements carry traceparent in their SQL comment.
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+func traceparentFromContext(ctx context.Context) string {
+	traceparent, _ := ctx.Value(traceparentKey{}).(string)
+	return traceparent
+}
+
+var traceparentFunc atomic.Pointer[func(ctx context.Context) string]
+
+// SetTraceparentFunc replaces how the W3C traceparent of a statement is read from its context,
+// e.g. to take it from the active OpenTelemetry span. Nil restores WithTraceparent.
+func SetTraceparentFunc(fn func(ctx context.Context) string) {
+	if fn == nil {
+		traceparentFunc.Store(nil)
+		return
+	}
+	traceparentFunc.Store(&fn)
+}
+
+// ParseCommentTags parses "key=value,key2=value2" into tags. Pairs without a key are ignored.
+func ParseCommentTags(tags string) map[string]string {
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(tags, ",") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); key != "" {
+			parsed[key] = strings.TrimSpace(value)
+		}
+	}
+	return parsed
+}
+
+// SetComment tags every statement run by this executor with a sqlcommenter comment built from tags.
+func (e *QueryExecutor) SetComment(tags map[string]string) *QueryExecutor {
+	e.comment = tags
+	return e
+}
+
+// annotate prefixes query with the WithTag tags of ctx, the executor's comment tags and the
+// traceparent from ctx.
+func (e *QueryExecutor) annotate(ctx context.Context, query string) string {
+	traceparent := traceparentFromContext(ctx)
+	if fn := traceparentFunc.Load(); fn != nil {
+		traceparent = (*fn)(ctx)
+	}
+	contextTags := TagsFromContext(ctx)
+	if len(e.comment) == 0 && len(contextTags) == 0 && traceparent == "" {
+		return query
+	}
+
+	tags := make(map[string]string, len(contextTags)+len(e.comment)+1)
+	for key, value := range contextTags {
+		tags[key] = value
+	}
+	for key, value := range e.comment {
+		tags[key] = value
+	}
+	if traceparent != "" {
+		tags["traceparent"] = traceparent
+	}
+	return FormatComment(tags) + " " + query
+}
+
+// FormatComment renders tags as a sqlcommenter comment: keys sorted, keys and values URL-encoded
+// and values single-quoted, so no tag can close the comment or inject SQL.
+func FormatComment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = commentEscape(key) + "='" + commentEscape(tags[key]) + "'"
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+func commentEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
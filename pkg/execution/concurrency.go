@@ -0,0 +1,195 @@
+package execution
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/optimization"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var globalLimiter atomic.Pointer[optimization.ConcurrencyManager]
+
+// SetGlobalConcurrencyLimit caps the number of queries running at once across every connection.
+// Queries that wait longer than queueTimeout for a slot fail with types.ErrQueueTimeout; a zero
+// timeout waits as long as the query's context allows. A limit of zero or less removes the cap.
+func SetGlobalConcurrencyLimit(maxConcurrent int, queueTimeout time.Duration) {
+	if maxConcurrent <= 0 {
+		globalLimiter.Store(nil)
+		return
+	}
+	globalLimiter.Store(optimization.NewConcurrencyManagerWithTimeout(maxConcurrent, queueTimeout))
+}
+
+// GlobalConcurrencyStats returns the metrics of the global limit, or zero stats when none is set.
+func GlobalConcurrencyStats() optimization.ConcurrencyStats {
+	if limiter := globalLimiter.Load(); limiter != nil {
+		return limiter.Stats()
+	}
+	return optimization.ConcurrencyStats{}
+}
+
+// concurrencyLimited is implemented by connections configured with MaxConcurrentQueries.
+type concurrencyLimited interface {
+	ConcurrencyLimiter() *optimization.ConcurrencyManager
+}
+
+// acquire takes a slot from the global and the connection limiter, returning a function that gives
+// both back.
+func (e *QueryExecutor) acquire(ctx context.Context, executor types.QueryExecutor) (func(), error) {
+	var limiters []*optimization.ConcurrencyManager
+	if limiter := globalLimiter.Load(); limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+	if limited, ok := executor.(concurrencyLimited); ok {
+		if limiter := limited.ConcurrencyLimiter(); limiter != nil {
+			limiters = append(limiters, limiter)
+		}
+	}
+
+	for i, limiter := range limiters {
+		if err := limiter.Acquire(ctx); err != nil {
+			for _, held := range limiters[:i] {
+				held.Release()
+			}
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for _, limiter := range limiters {
+				limiter.Release()
+			}
+		})
+	}, nil
+}
+
+// query runs a query against table under the hooks and concurrency limits. The slot is held until
+// the rows are closed.
+func (e *QueryExecutor) query(ctx context.Context, table string, query string, args ...interface{}) (types.Rows, error) {
+	if err := runHooks(ctx, newQueryEvent(table, query, args)); err != nil {
+		return nil, err
+	}
+
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	release, err := e.acquire(ctx, executor)
+	if err != nil {
+		return nil, err
+	}
+
+	stmtCtx, cancel := statementContext(ctx)
+	started := time.Now()
+	rows, err := executor.QueryContext(stmtCtx, e.annotate(ctx, query), args...)
+	if err != nil {
+		cancel()
+		release()
+		e.record(ctx, table, query, args, started, -1, err)
+		return nil, err
+	}
+
+	limited := &limitedRows{Rows: rows}
+	limited.done = func() {
+		cancel()
+		release()
+		e.record(ctx, table, query, args, started, limited.read, rows.Err())
+	}
+	return limited, nil
+}
+
+// queryWrite runs a write that returns rows, such as an upsert with RETURNING, refusing it on
+// read-only builders and connections like exec does.
+func (e *QueryExecutor) queryWrite(ctx context.Context, table string, query string, args ...interface{}) (types.Rows, error) {
+	if e.readOnly {
+		return nil, types.ErrReadOnly
+	}
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conn, ok := executor.(interface{ IsReadOnly() bool }); ok && conn.IsReadOnly() {
+		return nil, types.ErrReadOnly
+	}
+	return e.query(ctx, table, query, args...)
+}
+
+// queryRow runs a single-row query against table under the hooks and concurrency limits. The slot
+// is held until Scan.
+func (e *QueryExecutor) queryRow(ctx context.Context, table string, query string, args ...interface{}) types.Row {
+	if err := runHooks(ctx, newQueryEvent(table, query, args)); err != nil {
+		return errRow{err: err}
+	}
+
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	release, err := e.acquire(ctx, executor)
+	if err != nil {
+		return errRow{err: err}
+	}
+	stmtCtx, cancel := statementContext(ctx)
+	started := time.Now()
+	row := executor.QueryRowContext(stmtCtx, e.annotate(ctx, query), args...)
+	return &limitedRow{Row: row, done: func(err error) {
+		cancel()
+		release()
+		rows := int64(1)
+		if err != nil {
+			rows = 0
+		}
+		e.record(ctx, table, query, args, started, rows, err)
+	}}
+}
+
+// limitedRows counts the rows read and calls done once when closed.
+type limitedRows struct {
+	types.Rows
+	read int64
+	once sync.Once
+	done func()
+}
+
+func (r *limitedRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	return types.RowColumnTypes(r.Rows)
+}
+
+func (r *limitedRows) Next() bool {
+	if r.Rows.Next() {
+		r.read++
+		return true
+	}
+	return false
+}
+
+func (r *limitedRows) Close() error {
+	err := r.Rows.Close()
+	r.once.Do(r.done)
+	return err
+}
+
+// limitedRow calls done with the Scan error once scanned.
+type limitedRow struct {
+	types.Row
+	once sync.Once
+	done func(err error)
+}
+
+func (r *limitedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.once.Do(func() { r.done(err) })
+	return err
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(_ ...interface{}) error {
+	return r.err
+}
@@ -0,0 +1,90 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+type connectionKey struct{}
+
+type timeoutKey struct{}
+
+type tagsKey struct{}
+
+// ConnectionResolver returns the executor and driver of a named connection, for executors that
+// honor WithConnection.
+type ConnectionResolver func(name string) (types.QueryExecutor, types.Driver, error)
+
+// WithConnection returns a context whose statements run on the named connection instead of the
+// builder's own. Statements inside a transaction stay on the transaction.
+func WithConnection(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, connectionKey{}, name)
+}
+
+// ConnectionFromContext returns the connection set by WithConnection, or "".
+func ConnectionFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(connectionKey{}).(string)
+	return name
+}
+
+// WithTimeout returns a context that gives each statement run with it at most timeout, unlike
+// context.WithTimeout, whose deadline covers everything done with the context.
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// WithTag returns a context whose statements carry key=value in their SQL comment, alongside the
+// tags of earlier WithTag calls. Tags set on the builder with Comment take precedence.
+func WithTag(ctx context.Context, key, value string) context.Context {
+	parent := TagsFromContext(ctx)
+	tags := make(map[string]string, len(parent)+1)
+	for k, v := range parent {
+		tags[k] = v
+	}
+	tags[key] = value
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// TagsFromContext returns the tags set by WithTag. The map must not be modified.
+func TagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	return tags
+}
+
+// SetConnectionResolver makes statements run with a WithConnection context go to the connection
+// resolver returns. Without a resolver WithConnection is ignored.
+func (e *QueryExecutor) SetConnectionResolver(resolver ConnectionResolver) *QueryExecutor {
+	e.resolver = resolver
+	return e
+}
+
+// connection returns the executor a statement run with ctx goes to.
+func (e *QueryExecutor) connection(ctx context.Context) (types.QueryExecutor, error) {
+	name := ConnectionFromContext(ctx)
+	if name == "" || e.resolver == nil {
+		return e.executor, nil
+	}
+	if _, inTx := e.executor.(types.Tx); inTx {
+		return e.executor, nil
+	}
+
+	executor, driver, err := e.resolver(name)
+	if err != nil {
+		return nil, err
+	}
+	if driver != e.driver {
+		return nil, fmt.Errorf("connection '%s' uses %s but the query was built for %s", name, driver, e.driver)
+	}
+	return executor, nil
+}
+
+// statementContext applies the WithTimeout of ctx to a single statement.
+func statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, ok := ctx.Value(timeoutKey{}).(time.Duration); ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
@@ -3,47 +3,229 @@ package execution
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
+const (
+	defaultInsertBatchSize = 1000
+	maxBindParams          = 65535
+)
+
 // QueryExecutor handles the execution of database queries built by query builders.
 type QueryExecutor struct {
-	executor types.QueryExecutor
-	driver   types.Driver
+	executor   types.QueryExecutor
+	driver     types.Driver
+	streaming  bool
+	readOnly   bool
+	comment    map[string]string
+	chunkOrder []string
+	resolver   ConnectionResolver
+	debug      bool
+	lastDebug  *atomic.Pointer[types.DebugInfo]
 }
 
 // NewQueryExecutor creates a new QueryExecutor with the specified executor and driver.
 func NewQueryExecutor(executor types.QueryExecutor, driver types.Driver) *QueryExecutor {
+	e := &QueryExecutor{
+		executor:  executor,
+		driver:    driver,
+		lastDebug: &atomic.Pointer[types.DebugInfo]{},
+	}
+	if ro, ok := executor.(interface{ IsReadOnly() bool }); ok {
+		e.readOnly = ro.IsReadOnly()
+	}
+	return e
+}
+
+// withTx returns a copy of the executor that runs every statement in tx. The copy keeps the
+// executor's settings and shares its DebugInfo.
+func (e *QueryExecutor) withTx(tx types.Tx) *QueryExecutor {
 	return &QueryExecutor{
-		executor: executor,
-		driver:   driver,
+		executor:   tx,
+		driver:     e.driver,
+		streaming:  e.streaming,
+		readOnly:   e.readOnly,
+		comment:    e.comment,
+		chunkOrder: e.chunkOrder,
+		resolver:   e.resolver,
+		debug:      e.debug,
+		lastDebug:  e.lastDebug,
+	}
+}
+
+// SetReadOnly enables or disables read-only mode. In read-only mode every write returns types.ErrReadOnly
+// without reaching the database.
+func (e *QueryExecutor) SetReadOnly(enabled bool) *QueryExecutor {
+	e.readOnly = enabled
+	return e
+}
+
+// ServerVersion returns the version of the server behind the executor, or the zero value when the
+// executor does not know it.
+func (e *QueryExecutor) ServerVersion() types.ServerVersion {
+	if versioned, ok := e.executor.(interface{ ServerVersion() types.ServerVersion }); ok {
+		return versioned.ServerVersion()
+	}
+	return types.ServerVersion{}
+}
+
+// IsReadOnly returns true if writes are rejected by this executor.
+func (e *QueryExecutor) IsReadOnly() bool {
+	return e.readOnly
+}
+
+// exec runs a write statement against table, rejecting it in read-only mode.
+func (e *QueryExecutor) exec(ctx context.Context, table string, query string, args ...interface{}) (types.Result, error) {
+	if e.readOnly {
+		return nil, types.ErrReadOnly
+	}
+	return e.execStatement(ctx, table, query, args...)
+}
+
+// execStatement runs a statement against table under the hooks, concurrency limits and statement
+// timeout, without the read-only check, for statements that do not write such as cursor commands.
+func (e *QueryExecutor) execStatement(ctx context.Context, table string, query string, args ...interface{}) (types.Result, error) {
+	if err := runHooks(ctx, newQueryEvent(table, query, args)); err != nil {
+		return nil, err
+	}
+
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	release, err := e.acquire(ctx, executor)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	stmtCtx, cancel := statementContext(ctx)
+	defer cancel()
+
+	started := time.Now()
+	result, err := executor.ExecContext(stmtCtx, e.annotate(ctx, query), args...)
+	affected := int64(-1)
+	if err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			affected = n
+		}
 	}
+	e.record(ctx, table, query, args, started, affected, err)
+	return result, err
+}
+
+// SetStreaming enables or disables streaming row reads for the Chunk, Each and Cursor paths on MySQL.
+//
+// When enabled, Chunk runs a single query and reads rows from the server as they are consumed
+// instead of issuing one LIMIT/OFFSET query per chunk, so the server never has to materialize and
+// re-sort the full result for every page. The underlying connection stays pinned to the query until
+// the last row has been read or the cursor is closed: it cannot be returned to the pool or reused
+// for other statements in the meantime, so callbacks must not issue queries on the same transaction
+// and a slow consumer holds a connection (and its server thread) for the whole iteration.
+func (e *QueryExecutor) SetStreaming(enabled bool) *QueryExecutor {
+	e.streaming = enabled
+	return e
+}
+
+// IsStreaming returns true if streaming row reads are enabled for this executor.
+func (e *QueryExecutor) IsStreaming() bool {
+	return e.streaming && e.driver == types.MySQL
 }
 
 // QueryBuilderInterface defines the methods required by query builders for execution.
 type QueryBuilderInterface interface {
 	ToSQL() (string, []interface{}, error)
+	ToUpdateSQL(values map[string]interface{}) (string, []interface{}, error)
+	ToDeleteSQL() (string, []interface{}, error)
+	ToInsertUsingSQL(columns []string, sub types.QueryBuilder) (string, []interface{}, error)
 	Clone() types.QueryBuilder
 	GetTable() string
 }
 
+// policed is implemented by builders that enforce row-level security policies.
+type policed interface {
+	ApplyPolicies(ctx context.Context) (types.QueryBuilder, error)
+}
+
+// applyPolicies returns qb restricted by the row-level security policies of its table for ctx.
+func applyPolicies(ctx context.Context, qb QueryBuilderInterface) (QueryBuilderInterface, error) {
+	p, ok := qb.(policed)
+	if !ok {
+		return qb, nil
+	}
+	restricted, err := p.ApplyPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped, ok := restricted.(QueryBuilderInterface)
+	if !ok {
+		return nil, fmt.Errorf("policies of %s returned an unsupported builder %T", qb.GetTable(), restricted)
+	}
+	return scoped, nil
+}
+
+// columnValidated is implemented by builders that validate the column names INSERT and UPDATE
+// write into the statement.
+type columnValidated interface {
+	ValidateColumns(columns ...string) error
+}
+
+// checkColumns validates columns with the ValidateColumns of qb, if it has one.
+func checkColumns(qb QueryBuilderInterface, columns []string) error {
+	if v, ok := qb.(columnValidated); ok {
+		return v.ValidateColumns(columns...)
+	}
+	return nil
+}
+
 // Get executes the query and returns all matching rows as a collection.
 func (e *QueryExecutor) Get(ctx context.Context, qb QueryBuilderInterface) (types.Collection, error) {
+	limit, truncate := maxRows(qb)
+	return e.get(ctx, qb, limit, truncate)
+}
+
+// get executes the query and scans at most limit rows, all of them when limit is 0. Rows past
+// limit are dropped when truncate is set and fail the query with types.ErrTooManyRows otherwise.
+func (e *QueryExecutor) get(ctx context.Context, qb QueryBuilderInterface, limit int, truncate bool) (types.Collection, error) {
+	qb, err := applyPolicies(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+
 	sql, bindings, err := qb.ToSQL()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build SQL: %w", err)
 	}
 
-	rows, err := e.executor.QueryContext(ctx, sql, bindings...)
+	rows, err := e.query(ctx, qb.GetTable(), sql, bindings...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	return e.scanRows(rows)
+	collection, err := e.scanRows(ctx, qb.GetTable(), rows, limit)
+	if truncate && errors.Is(err, types.ErrTooManyRows) {
+		logTruncated(ctx, qb.GetTable(), limit)
+		return collection, nil
+	}
+	return collection, err
+}
+
+// maxRows returns the MaxRows limit of a builder and whether it truncates, if the builder has one.
+func maxRows(qb QueryBuilderInterface) (int, bool) {
+	if guard, ok := qb.(interface{ GetMaxRows() (int, bool) }); ok {
+		return guard.GetMaxRows()
+	}
+	return 0, false
 }
 
 // First executes the query and returns the first matching row.
@@ -57,16 +239,25 @@ func (e *QueryExecutor) First(ctx context.Context, qb QueryBuilderInterface) (ma
 	}
 
 	if collection.IsEmpty() {
-		return nil, sql.ErrNoRows
+		return nil, &types.DBError{Kind: types.ErrNotFound, Err: sql.ErrNoRows}
 	}
 
 	return collection.First(), nil
 }
 
-// Find finds a record by its primary key ID.
+// Find finds a record by its primary key, "id" unless the table registered another one. The ID
+// of a composite key is a map from column to value or a []interface{} in key order.
 func (e *QueryExecutor) Find(ctx context.Context, qb QueryBuilderInterface, id interface{}) (map[string]interface{}, error) {
-	clone := qb.Clone()
-	findQB := clone.Where("id", id).Limit(1)
+	key, err := schema.KeyValues(qb.GetTable(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	findQB := qb.Clone()
+	for i, column := range schema.KeyColumns(qb.GetTable()) {
+		findQB = findQB.Where(column, key[i])
+	}
+	findQB = findQB.Limit(1)
 	
 	collection, err := e.Get(ctx, findQB.(QueryBuilderInterface))
 	if err != nil {
@@ -74,12 +265,60 @@ func (e *QueryExecutor) Find(ctx context.Context, qb QueryBuilderInterface, id i
 	}
 
 	if collection.IsEmpty() {
-		return nil, sql.ErrNoRows
+		return nil, &types.DBError{Kind: types.ErrNotFound, Err: sql.ErrNoRows}
 	}
 
 	return collection.First(), nil
 }
 
+// FindOrFail finds a record by its primary key like Find, failing with a *types.NotFoundError
+// naming the table, key column and ID when there is none.
+func (e *QueryExecutor) FindOrFail(ctx context.Context, qb QueryBuilderInterface, id interface{}) (map[string]interface{}, error) {
+	row, err := e.Find(ctx, qb, id)
+	if errors.Is(err, types.ErrNotFound) {
+		columns := schema.KeyColumns(qb.GetTable())
+		key, _ := schema.KeyValues(qb.GetTable(), id)
+		notFound := &types.NotFoundError{Table: qb.GetTable(), Key: strings.Join(columns, ", "), ID: key}
+		if len(key) == 1 {
+			notFound.ID = key[0]
+		}
+		return nil, notFound
+	}
+	return row, err
+}
+
+// FindMany returns the records whose primary key is one of ids, in no particular order. IDs
+// without a record are skipped; no query runs when ids is empty.
+func (e *QueryExecutor) FindMany(ctx context.Context, qb QueryBuilderInterface, ids []interface{}) (types.Collection, error) {
+	if len(ids) == 0 {
+		return types.NewCollection(nil), nil
+	}
+
+	keys := make([][]interface{}, len(ids))
+	for i, id := range ids {
+		key, err := schema.KeyValues(qb.GetTable(), id)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+
+	return e.Get(ctx, whereKeyIn(qb.Clone(), schema.KeyColumns(qb.GetTable()), keys).(QueryBuilderInterface))
+}
+
+// whereKeyIn restricts qb to the rows whose key columns equal one of keys, with a plain IN for a
+// single column and a tuple IN for a composite key.
+func whereKeyIn(qb types.QueryBuilder, columns []string, keys [][]interface{}) types.QueryBuilder {
+	if len(columns) > 1 {
+		return qb.WhereTupleIn(columns, keys)
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key[0]
+	}
+	return qb.WhereIn(columns[0], values)
+}
+
 // Pluck returns all values from a single column as a slice.
 func (e *QueryExecutor) Pluck(ctx context.Context, qb QueryBuilderInterface, column string) ([]interface{}, error) {
 	clone := qb.Clone()
@@ -100,6 +339,34 @@ func (e *QueryExecutor) Count(ctx context.Context, qb QueryBuilderInterface) (in
 		return 0, err
 	}
 	
+	return countValue(result)
+}
+
+// CountRows returns the number of rows the query returns by counting it as a subquery, so grouped,
+// distinct and unioned queries count their result rows rather than the rows of the first group.
+func (e *QueryExecutor) CountRows(ctx context.Context, qb QueryBuilderInterface) (int64, error) {
+	qb, err := applyPolicies(ctx, qb)
+	if err != nil {
+		return 0, err
+	}
+
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build count SQL: %w", err)
+	}
+
+	row := e.queryRow(ctx, qb.GetTable(), "SELECT COUNT(*) FROM ("+sql+") AS count_query", bindings...)
+
+	var result interface{}
+	if err := row.Scan(&result); err != nil {
+		return 0, fmt.Errorf("failed to scan count result: %w", err)
+	}
+
+	return countValue(result)
+}
+
+// countValue converts the result of a COUNT query to int64.
+func countValue(result interface{}) (int64, error) {
 	if count, ok := result.(int64); ok {
 		return count, nil
 	}
@@ -152,6 +419,11 @@ func (e *QueryExecutor) Max(ctx context.Context, qb QueryBuilderInterface, colum
 }
 
 func (e *QueryExecutor) aggregate(ctx context.Context, qb QueryBuilderInterface, fn types.AggregateFunction, column string) (interface{}, error) {
+	qb, err := applyPolicies(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+
 	clone := qb.Clone()
 	aggregateQB := clone.SelectRaw(fmt.Sprintf("%s(%s) as aggregate", fn, column))
 	
@@ -160,13 +432,24 @@ func (e *QueryExecutor) aggregate(ctx context.Context, qb QueryBuilderInterface,
 		return nil, fmt.Errorf("failed to build aggregate SQL: %w", err)
 	}
 
-	row := e.executor.QueryRowContext(ctx, sql, bindings...)
+	row := e.queryRow(ctx, qb.GetTable(), sql, bindings...)
 	
 	var result interface{}
 	if err := row.Scan(&result); err != nil {
 		return nil, fmt.Errorf("failed to scan aggregate result: %w", err)
 	}
 
+	// MIN and MAX return a value of the column itself, so they are masked like it.
+	if mask, masked := schema.Masks(ctx, qb.GetTable())[column]; masked && (fn == types.Min || fn == types.Max) && result != nil {
+		if mask == nil {
+			return nil, fmt.Errorf("%w: %s", types.ErrMaskedColumns, column)
+		}
+		if b, ok := result.([]byte); ok {
+			result = string(b)
+		}
+		result = mask(result)
+	}
+
 	return result, nil
 }
 
@@ -180,12 +463,20 @@ func (e *QueryExecutor) Insert(ctx context.Context, qb QueryBuilderInterface, va
 	if table == "" {
 		return fmt.Errorf("no table specified for insert")
 	}
+	if err := schema.CheckGuarded(table, values); err != nil {
+		return err
+	}
+	if err := checkColumns(qb, sortedColumns(values)); err != nil {
+		return err
+	}
+	values = schema.StampInsert(table, values, time.Now())
 
 	columns := make([]string, 0, len(values))
 	bindings := make([]interface{}, 0, len(values))
 	placeholders := make([]string, 0, len(values))
 
-	for column, value := range values {
+	for _, column := range sortedColumns(values) {
+		value := values[column]
 		columns = append(columns, column)
 		bindings = append(bindings, value)
 		placeholders = append(placeholders, e.getPlaceholder(len(bindings)))
@@ -194,9 +485,9 @@ func (e *QueryExecutor) Insert(ctx context.Context, qb QueryBuilderInterface, va
 	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
 		table,
 		joinColumns(columns),
-		joinStrings(placeholders, ", "))
+		strings.Join(placeholders, ", "))
 
-	_, err := e.executor.ExecContext(ctx, sql, bindings...)
+	_, err := e.exec(ctx, table, sql, bindings...)
 	if err != nil {
 		return fmt.Errorf("failed to execute insert: %w", err)
 	}
@@ -206,6 +497,13 @@ func (e *QueryExecutor) Insert(ctx context.Context, qb QueryBuilderInterface, va
 
 // InsertBatch executes a batch INSERT statement with multiple rows of values.
 func (e *QueryExecutor) InsertBatch(ctx context.Context, qb QueryBuilderInterface, values []map[string]interface{}) error {
+	return e.InsertBatchWithOptions(ctx, qb, values, types.BulkInsertOptions{})
+}
+
+// InsertBatchWithOptions inserts rows in sub-batches of options.BatchSize rows, running them in a single
+// transaction when more than one statement is needed. With options.UseCopy on a pgx-backed PostgreSQL
+// connection the rows are loaded with the COPY protocol instead.
+func (e *QueryExecutor) InsertBatchWithOptions(ctx context.Context, qb QueryBuilderInterface, values []map[string]interface{}, options types.BulkInsertOptions) error {
 	if len(values) == 0 {
 		return fmt.Errorf("no values provided for batch insert")
 	}
@@ -215,112 +513,287 @@ func (e *QueryExecutor) InsertBatch(ctx context.Context, qb QueryBuilderInterfac
 		return fmt.Errorf("no table specified for insert")
 	}
 
-	firstRow := values[0]
-	columns := make([]string, 0, len(firstRow))
-	for column := range firstRow {
-		columns = append(columns, column)
+	if e.readOnly {
+		return types.ErrReadOnly
 	}
 
-	var allBindings []interface{}
-	var valueSets []string
+	now := time.Now()
+	stamped := make([]map[string]interface{}, len(values))
+	for i, row := range values {
+		if err := schema.CheckGuarded(table, row); err != nil {
+			return err
+		}
+		stamped[i] = schema.StampInsert(table, row, now)
+	}
+	values = stamped
 
-	for _, row := range values {
-		var rowBindings []interface{}
-		var rowPlaceholders []string
-		
-		for _, column := range columns {
-			value, exists := row[column]
-			if !exists {
-				value = nil
+	columns := sortedColumns(values[0])
+	if err := checkColumns(qb, columns); err != nil {
+		return err
+	}
+
+	if options.UseCopy && e.driver == types.PostgreSQL {
+		executor, err := e.connection(ctx)
+		if err != nil {
+			return err
+		}
+		if copier, ok := executor.(copyFromer); ok {
+			rows := make([][]interface{}, len(values))
+			for i, row := range values {
+				rows[i] = rowValues(row, columns)
+			}
+			if err := runHooks(ctx, newQueryEvent(table, "COPY "+table, nil)); err != nil {
+				return err
 			}
-			rowBindings = append(rowBindings, value)
-			rowPlaceholders = append(rowPlaceholders, e.getPlaceholder(len(allBindings)+len(rowBindings)))
+
+			release, err := e.acquire(ctx, executor)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			stmtCtx, cancel := statementContext(ctx)
+			defer cancel()
+
+			started := time.Now()
+			copied, err := copier.CopyFrom(stmtCtx, table, columns, rows)
+			e.record(ctx, table, "COPY "+table, nil, started, copied, err)
+			if err != nil {
+				return fmt.Errorf("failed to copy rows: %w", err)
+			}
+			return nil
+		}
+	}
+
+	size := insertBatchSize(options.BatchSize, len(columns))
+	if len(values) <= size {
+		sql, bindings := e.compileInsertRows(table, columns, values)
+		if _, err := e.exec(ctx, table, sql, bindings...); err != nil {
+			return fmt.Errorf("failed to execute batch insert: %w", err)
+		}
+		return nil
+	}
+
+	// Already inside a transaction: the sub-batches are atomic as part of it.
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return err
+	}
+	if _, inTx := executor.(types.Tx); inTx {
+		return e.insertChunks(ctx, table, columns, values, size)
+	}
+
+	tx, err := executor.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin batch insert transaction: %w", err)
+	}
+	if err := e.withTx(tx).insertChunks(ctx, table, columns, values, size); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch insert: %w", err)
+	}
+	return nil
+}
+
+// copyFromer is implemented by connections that support bulk loading with PostgreSQL's COPY protocol.
+type copyFromer interface {
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error)
+}
+
+// insertChunks inserts values in statements of at most size rows on the executor's connection.
+func (e *QueryExecutor) insertChunks(ctx context.Context, table string, columns []string, values []map[string]interface{}, size int) error {
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+
+		sql, bindings := e.compileInsertRows(table, columns, values[start:end])
+		if _, err := e.exec(ctx, table, sql, bindings...); err != nil {
+			return fmt.Errorf("failed to execute batch insert (rows %d-%d): %w", start, end-1, err)
+		}
+	}
+	return nil
+}
+
+func (e *QueryExecutor) compileInsertRows(table string, columns []string, values []map[string]interface{}) (string, []interface{}) {
+	allBindings := make([]interface{}, 0, len(values)*len(columns))
+	valueSets := make([]string, 0, len(values))
+
+	for _, row := range values {
+		rowPlaceholders := make([]string, 0, len(columns))
+		for _, value := range rowValues(row, columns) {
+			allBindings = append(allBindings, value)
+			rowPlaceholders = append(rowPlaceholders, e.getPlaceholder(len(allBindings)))
 		}
-		
-		allBindings = append(allBindings, rowBindings...)
-		valueSets = append(valueSets, "("+joinStrings(rowPlaceholders, ", ")+")")
+		valueSets = append(valueSets, "("+strings.Join(rowPlaceholders, ", ")+")")
 	}
 
 	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
 		table,
 		joinColumns(columns),
-		joinStrings(valueSets, ", "))
+		strings.Join(valueSets, ", "))
 
-	_, err := e.executor.ExecContext(ctx, sql, allBindings...)
-	if err != nil {
-		return fmt.Errorf("failed to execute batch insert: %w", err)
+	return sql, allBindings
+}
+
+// rowValues returns the row's values in column order, using nil for missing columns.
+func rowValues(row map[string]interface{}, columns []string) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = row[column]
 	}
+	return values
+}
 
-	return nil
+// insertBatchSize returns the number of rows per INSERT statement, keeping each statement under the
+// 65535 bind parameter limit shared by MySQL and PostgreSQL.
+func insertBatchSize(requested, columns int) int {
+	size := requested
+	if size <= 0 {
+		size = defaultInsertBatchSize
+	}
+	if columns > 0 && size*columns > maxBindParams {
+		size = maxBindParams / columns
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
 }
 
-// Update executes an UPDATE statement and returns the number of affected rows.
-func (e *QueryExecutor) Update(ctx context.Context, qb QueryBuilderInterface, values map[string]interface{}) (int64, error) {
+// InsertBatchPartial inserts each row independently and keeps going when a row fails. It returns the
+// number of inserted rows and, if any row failed, a *types.BatchError carrying the failed row indexes.
+func (e *QueryExecutor) InsertBatchPartial(ctx context.Context, qb QueryBuilderInterface, values []map[string]interface{}) (int64, error) {
 	if len(values) == 0 {
-		return 0, fmt.Errorf("no values provided for update")
+		return 0, fmt.Errorf("no values provided for batch insert")
 	}
 
-	table := qb.GetTable()
-	if table == "" {
-		return 0, fmt.Errorf("no table specified for update")
+	if e.readOnly {
+		return 0, types.ErrReadOnly
 	}
 
-	setParts := make([]string, 0, len(values))
-	bindings := make([]interface{}, 0, len(values))
+	var inserted int64
+	batchErr := types.NewBatchError("insert batch partial", len(values))
 
-	for column, value := range values {
-		setParts = append(setParts, fmt.Sprintf("%s = %s", column, e.getPlaceholder(len(bindings)+1)))
-		bindings = append(bindings, value)
+	for i, row := range values {
+		if err := e.Insert(ctx, qb, row); err != nil {
+			batchErr.Add(i, nil, err)
+			continue
+		}
+		inserted++
 	}
 
-	sql := fmt.Sprintf("UPDATE %s SET %s", table, joinStrings(setParts, ", "))
+	return inserted, batchErr.ErrorOrNil()
+}
 
-	whereSQL, whereBindings, err := e.buildWhereClause(qb)
+// InsertUsing executes an INSERT INTO ... SELECT statement and returns the number of rows inserted.
+func (e *QueryExecutor) InsertUsing(ctx context.Context, qb QueryBuilderInterface, columns []string, sub types.QueryBuilder) (int64, error) {
+	if err := checkColumns(qb, columns); err != nil {
+		return 0, err
+	}
+	sql, bindings, err := qb.ToInsertUsingSQL(columns, sub)
 	if err != nil {
-		return 0, fmt.Errorf("failed to build where clause: %w", err)
+		return 0, fmt.Errorf("failed to build insert SQL: %w", err)
 	}
 
-	if whereSQL != "" {
-		sql += " WHERE " + whereSQL
-		bindings = append(bindings, whereBindings...)
+	return e.execAffected(ctx, "insert", qb.GetTable(), sql, bindings)
+}
+
+// Update executes an UPDATE statement and returns the number of affected rows.
+func (e *QueryExecutor) Update(ctx context.Context, qb QueryBuilderInterface, values map[string]interface{}) (int64, error) {
+	if err := schema.CheckGuarded(qb.GetTable(), values); err != nil {
+		return 0, err
 	}
+	if column := versionColumn(qb); column != "" {
+		return e.versionedUpdate(ctx, qb, values, column)
+	}
+	return e.execUpdate(ctx, qb, values, "update")
+}
 
-	result, err := e.executor.ExecContext(ctx, sql, bindings...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to execute update: %w", err)
+// versionColumn returns the optimistic locking column of a builder, if it has one.
+func versionColumn(qb QueryBuilderInterface) string {
+	if v, ok := qb.(interface{ GetVersionColumn() string }); ok {
+		return v.GetVersionColumn()
 	}
+	return ""
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+// versionedUpdate updates the rows of qb that still have the version in values[column], setting
+// column to the next version, and fails with types.ErrStaleRecord when none do.
+func (e *QueryExecutor) versionedUpdate(ctx context.Context, qb QueryBuilderInterface, values map[string]interface{}, column string) (int64, error) {
+	expected, ok := values[column]
+	if !ok {
+		return 0, fmt.Errorf("%w: versioned update of %s needs the current %s", types.ErrInvalidParameter, qb.GetTable(), column)
 	}
 
-	return rowsAffected, nil
+	next := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		next[key] = value
+	}
+	next[column] = types.Raw(column + " + 1")
+
+	affected, err := e.execUpdate(ctx, qb.Clone().Where(column, expected).(QueryBuilderInterface), next, "update")
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, fmt.Errorf("%w: %s with %s %v", types.ErrStaleRecord, qb.GetTable(), column, expected)
+	}
+	return affected, nil
 }
 
 // Delete executes a DELETE statement and returns the number of affected rows.
 func (e *QueryExecutor) Delete(ctx context.Context, qb QueryBuilderInterface) (int64, error) {
-	table := qb.GetTable()
-	if table == "" {
-		return 0, fmt.Errorf("no table specified for delete")
+	qb, err := applyPolicies(ctx, qb)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := e.checkMaxAffected(ctx, qb); err != nil {
+		return 0, err
+	}
+
+	sql, bindings, err := qb.ToDeleteSQL()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete SQL: %w", err)
 	}
 
-	sql := fmt.Sprintf("DELETE FROM %s", table)
-	var bindings []interface{}
+	return e.execAffected(ctx, "delete", qb.GetTable(), sql, bindings)
+}
 
-	whereSQL, whereBindings, err := e.buildWhereClause(qb)
+// execUpdate compiles and runs an UPDATE through the builder, checking the MaxAffected limit first.
+// The table's update timestamp is set unless values set it.
+func (e *QueryExecutor) execUpdate(ctx context.Context, qb QueryBuilderInterface, values map[string]interface{}, action string) (int64, error) {
+	qb, err := applyPolicies(ctx, qb)
 	if err != nil {
-		return 0, fmt.Errorf("failed to build where clause: %w", err)
+		return 0, err
 	}
 
-	if whereSQL != "" {
-		sql += " WHERE " + whereSQL
-		bindings = append(bindings, whereBindings...)
+	if err := checkColumns(qb, sortedColumns(values)); err != nil {
+		return 0, err
 	}
 
-	result, err := e.executor.ExecContext(ctx, sql, bindings...)
+	values = schema.StampUpdate(qb.GetTable(), values, time.Now())
+	sql, bindings, err := qb.ToUpdateSQL(values)
 	if err != nil {
-		return 0, fmt.Errorf("failed to execute delete: %w", err)
+		return 0, fmt.Errorf("failed to build %s SQL: %w", action, err)
+	}
+
+	if err := e.checkMaxAffected(ctx, qb); err != nil {
+		return 0, err
+	}
+
+	return e.execAffected(ctx, action, qb.GetTable(), sql, bindings)
+}
+
+// execAffected runs a write statement and returns the number of affected rows.
+func (e *QueryExecutor) execAffected(ctx context.Context, action string, table string, sql string, bindings []interface{}) (int64, error) {
+	result, err := e.exec(ctx, table, sql, bindings...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute %s: %w", action, err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -331,35 +804,34 @@ func (e *QueryExecutor) Delete(ctx context.Context, qb QueryBuilderInterface) (i
 	return rowsAffected, nil
 }
 
-func (e *QueryExecutor) scanRows(rows types.Rows) (types.Collection, error) {
-	columns, err := rows.Columns()
+// scanRows reads rows into a collection, applying the casts registered for table and the masks
+// that apply to ctx. With a limit above zero it stops once rows has more than limit rows and
+// returns the first limit of them with types.ErrTooManyRows.
+func (e *QueryExecutor) scanRows(ctx context.Context, table string, rows types.Rows, limit int) (types.Collection, error) {
+	scanner, err := NewRowScanner(rows)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
+	cast := schema.RowCaster(table)
+	masks := schema.Masks(ctx, table)
 
 	var results []map[string]interface{}
 	
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		
-		for i := range columns {
-			valuePtrs[i] = &values[i]
+		if limit > 0 && len(results) == limit {
+			return types.NewCollection(results), fmt.Errorf("%w: more than %d", types.ErrTooManyRows, limit)
 		}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
+		row, err := scanner.Scan(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
+		if cast != nil {
+			if err := cast(row); err != nil {
+				return nil, err
 			}
 		}
+		schema.MaskRow(masks, row)
 		results = append(results, row)
 	}
 
@@ -370,26 +842,29 @@ func (e *QueryExecutor) scanRows(rows types.Rows) (types.Collection, error) {
 	return types.NewCollection(results), nil
 }
 
-func (e *QueryExecutor) buildWhereClause(qb QueryBuilderInterface) (string, []interface{}, error) {
-	sql, bindings, err := qb.ToSQL()
-	if err != nil {
-		return "", nil, err
+// checkMaxAffected counts the rows a mutation would touch and fails if there are more than the
+// builder's MaxAffected limit. The count and the mutation are separate statements, so run both in a
+// transaction if concurrent writers could push the row count over the limit in between.
+func (e *QueryExecutor) checkMaxAffected(ctx context.Context, qb QueryBuilderInterface) error {
+	guard, ok := qb.(interface{ GetMaxAffected() *int })
+	if !ok || guard.GetMaxAffected() == nil {
+		return nil
 	}
-	
-	whereStart := findWhereClause(sql)
-	if whereStart == -1 {
-		return "", nil, nil
+	limit := int64(*guard.GetMaxAffected())
+
+	count, err := e.Count(ctx, qb)
+	if err != nil {
+		return fmt.Errorf("failed to count affected rows: %w", err)
 	}
-	
-	whereClause := sql[whereStart+6:]
-	if orderBy := findOrderByClause(whereClause); orderBy != -1 {
-		whereClause = whereClause[:orderBy]
+	// Count ignores LIMIT, but a bounded mutation touches at most that many of the matching rows.
+	if bounded, ok := qb.(interface{ GetLimit() *int }); ok && bounded.GetLimit() != nil {
+		count = min(count, int64(*bounded.GetLimit()))
 	}
-	if groupBy := findGroupByClause(whereClause); groupBy != -1 {
-		whereClause = whereClause[:groupBy]
+	if count > limit {
+		return fmt.Errorf("%w: %d rows match, limit is %d", types.ErrMaxAffectedExceeded, count, limit)
 	}
-	
-	return whereClause, bindings, nil
+
+	return nil
 }
 
 func (e *QueryExecutor) getPlaceholder(position int) string {
@@ -401,65 +876,17 @@ func (e *QueryExecutor) getPlaceholder(position int) string {
 	}
 }
 
-func findWhereClause(sql string) int {
-	return findKeyword(sql, "WHERE")
-}
-
-func findOrderByClause(sql string) int {
-	return findKeyword(sql, "ORDER BY")
-}
-
-func findGroupByClause(sql string) int {
-	return findKeyword(sql, "GROUP BY")
-}
-
-func findKeyword(sql, keyword string) int {
-	upperSQL := toUpper(sql)
-	return indexOf(upperSQL, keyword)
-}
-
-func joinColumns(columns []string) string {
-	return joinStrings(columns, ", ")
-}
-
-func joinStrings(strs []string, _ string) string {
-	separator := ", "
-	if len(strs) == 0 {
-		return ""
-	}
-	if len(strs) == 1 {
-		return strs[0]
-	}
-	result := strs[0]
-	for i := 1; i < len(strs); i++ {
-		result += separator + strs[i]
+// sortedColumns returns the keys of a row in a stable order, so the same set of columns always
+// compiles to the same SQL text and can reuse a prepared statement.
+func sortedColumns(row map[string]interface{}) []string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
 	}
-	return result
+	sort.Strings(columns)
+	return columns
 }
 
-func toUpper(s string) string {
-	result := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'a' && c <= 'z' {
-			c = c - 'a' + 'A'
-		}
-		result[i] = c
-	}
-	return string(result)
+func joinColumns(columns []string) string {
+	return strings.Join(columns, ", ")
 }
-
-func indexOf(s, substr string) int {
-	if len(substr) == 0 {
-		return 0
-	}
-	if len(substr) > len(s) {
-		return -1
-	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
-	}
-	return -1
-}
\ No newline at end of file
@@ -0,0 +1,171 @@
+package execution
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// ExportCSV streams the query results to w as CSV through a cursor, one row at a time.
+func (e *QueryExecutor) ExportCSV(ctx context.Context, qb QueryBuilderInterface, w io.Writer, options types.ExportOptions) error {
+	cursor, err := e.Cursor(ctx, qb)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cursor.Close() }()
+
+	writer := csv.NewWriter(w)
+	if options.Delimiter != 0 {
+		writer.Comma = options.Delimiter
+	}
+
+	columns := cursor.Columns()
+	if !options.NoHeader {
+		if err := writer.Write(columns); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	record := make([]string, len(columns))
+	err = exportRows(cursor, func(values []interface{}) error {
+		for i, value := range values {
+			record[i] = csvValue(value, options)
+		}
+		return writer.Write(record)
+	})
+	writer.Flush()
+	if err != nil {
+		return err
+	}
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// ExportNDJSON streams the query results to w as newline-delimited JSON objects, keeping the
+// column order of the query.
+func (e *QueryExecutor) ExportNDJSON(ctx context.Context, qb QueryBuilderInterface, w io.Writer, options types.ExportOptions) error {
+	cursor, err := e.Cursor(ctx, qb)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cursor.Close() }()
+
+	columns := cursor.Columns()
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		if keys[i], err = json.Marshal(column); err != nil {
+			return fmt.Errorf("failed to encode column name: %w", err)
+		}
+	}
+
+	buffered := bufio.NewWriter(w)
+	err = exportRows(cursor, func(values []interface{}) error {
+		buffered.WriteByte('{')
+		for i, value := range values {
+			if i > 0 {
+				buffered.WriteByte(',')
+			}
+			encoded, err := json.Marshal(jsonValue(value, options))
+			if err != nil {
+				return fmt.Errorf("failed to encode column %s: %w", columns[i], err)
+			}
+			buffered.Write(keys[i])
+			buffered.WriteByte(':')
+			buffered.Write(encoded)
+		}
+		buffered.WriteString("}\n")
+		return nil
+	})
+	if flushErr := buffered.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to write NDJSON: %w", flushErr)
+	}
+	return err
+}
+
+// exportRows scans every row of cursor and passes its values, masked, to write.
+func exportRows(cursor *Cursor, write func(values []interface{}) error) error {
+	values := make([]interface{}, len(cursor.columns))
+	pointers := make([]interface{}, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	visible, buffer := values, make([]interface{}, 0, len(values))
+
+	for cursor.Next() {
+		if err := cursor.rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		if len(cursor.masks) > 0 {
+			visible = maskValues(cursor.columns, values, cursor.masks, buffer[:0])
+		}
+		if err := write(visible); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	return nil
+}
+
+// maskValues appends the values of the columns masks does not drop to dst, masked.
+func maskValues(columns []string, values []interface{}, masks map[string]types.MaskFunc, dst []interface{}) []interface{} {
+	for i, column := range columns {
+		mask, masked := masks[column]
+		switch {
+		case !masked:
+			dst = append(dst, values[i])
+		case mask == nil:
+		case values[i] == nil:
+			dst = append(dst, nil)
+		default:
+			value := values[i]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			dst = append(dst, mask(value))
+		}
+	}
+	return dst
+}
+
+func csvValue(value interface{}, options types.ExportOptions) string {
+	switch v := value.(type) {
+	case nil:
+		return options.Null
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(timeFormat(options))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonValue(value interface{}, options types.ExportOptions) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(timeFormat(options))
+	default:
+		return v
+	}
+}
+
+func timeFormat(options types.ExportOptions) string {
+	if options.TimeFormat == "" {
+		return time.RFC3339Nano
+	}
+	return options.TimeFormat
+}
@@ -0,0 +1,82 @@
+package execution
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var hooks struct {
+	mu      sync.RWMutex
+	entries []*hookEntry
+}
+
+type hookEntry struct {
+	hook types.QueryHook
+}
+
+// AddHook registers a hook that runs before every statement on every connection, in the order
+// hooks were added. Hooks implementing types.QueryObserver are also told about every finished
+// statement. The returned function unregisters it.
+func AddHook(hook types.QueryHook) (remove func()) {
+	entry := &hookEntry{hook: hook}
+
+	hooks.mu.Lock()
+	hooks.entries = append(hooks.entries, entry)
+	hooks.mu.Unlock()
+
+	return func() {
+		hooks.mu.Lock()
+		defer hooks.mu.Unlock()
+		for i, e := range hooks.entries {
+			if e == entry {
+				hooks.entries = append(hooks.entries[:i:i], hooks.entries[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// runHooks calls every registered hook, stopping at the first error.
+func runHooks(ctx context.Context, event types.QueryEvent) error {
+	hooks.mu.RLock()
+	entries := hooks.entries
+	hooks.mu.RUnlock()
+
+	for _, entry := range entries {
+		if err := entry.hook.BeforeQuery(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observe reports a finished statement to every registered hook that is a types.QueryObserver.
+func observe(ctx context.Context, table string, query string, args []interface{}, started time.Time, rows int64, err error) {
+	hooks.mu.RLock()
+	entries := hooks.entries
+	hooks.mu.RUnlock()
+
+	var entry *types.QueryLog
+	for _, e := range entries {
+		observer, ok := e.hook.(types.QueryObserver)
+		if !ok {
+			continue
+		}
+		if entry == nil {
+			entry = &types.QueryLog{Table: table, SQL: query, Bindings: args, Duration: time.Since(started), Rows: rows, Err: err}
+		}
+		observer.AfterQuery(ctx, *entry)
+	}
+}
+
+func newQueryEvent(table string, query string, args []interface{}) types.QueryEvent {
+	operation := ""
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToLower(fields[0])
+	}
+	return types.QueryEvent{Table: table, Operation: operation, SQL: query, Bindings: args}
+}
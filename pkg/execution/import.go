@@ -0,0 +1,228 @@
+package execution
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// importColumnPattern guards column names taken from file headers when no mapping is given.
+var importColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// importRow is one parsed input row, or the reason it was rejected.
+type importRow struct {
+	line   int
+	values map[string]interface{}
+	err    error
+}
+
+// ImportCSV reads CSV with a header row from r and inserts it in batches, with COPY on PostgreSQL
+// and multi-row INSERT statements otherwise. Rows that fail to parse or validate are skipped and
+// listed in the report.
+func (e *QueryExecutor) ImportCSV(ctx context.Context, qb QueryBuilderInterface, r io.Reader, options types.ImportOptions) (*types.ImportReport, error) {
+	reader := csv.NewReader(r)
+	if options.Delimiter != 0 {
+		reader.Comma = options.Delimiter
+	}
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, field := range header {
+		if columns[i], err = importColumn(field, options); err != nil {
+			return nil, err
+		}
+	}
+
+	nulls := make(map[string]bool, len(options.NullValues))
+	for _, value := range options.NullValues {
+		nulls[value] = true
+	}
+
+	next := func() (importRow, bool, error) {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return importRow{}, false, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return importRow{line: parseErr.StartLine, err: err}, true, nil
+		}
+		if err != nil {
+			return importRow{}, false, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]interface{}, len(columns))
+		for i, field := range record {
+			if columns[i] == "" {
+				continue
+			}
+			if nulls[field] {
+				values[columns[i]] = nil
+			} else {
+				values[columns[i]] = field
+			}
+		}
+		return importRow{line: line, values: values}, true, nil
+	}
+
+	return e.importRows(ctx, qb, next, options)
+}
+
+// ImportNDJSON reads one JSON object per line from r and inserts them like ImportCSV. Nested
+// objects and arrays are stored as JSON text; fields missing from a row are inserted as NULL.
+func (e *QueryExecutor) ImportNDJSON(ctx context.Context, qb QueryBuilderInterface, r io.Reader, options types.ImportOptions) (*types.ImportReport, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+
+	next := func() (importRow, bool, error) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(text))
+			decoder.UseNumber()
+			var object map[string]interface{}
+			if err := decoder.Decode(&object); err != nil {
+				return importRow{line: line, err: fmt.Errorf("invalid JSON: %w", err)}, true, nil
+			}
+
+			values := make(map[string]interface{}, len(object))
+			for field, value := range object {
+				column, err := importColumn(field, options)
+				if err != nil {
+					return importRow{line: line, err: err}, true, nil
+				}
+				if column != "" {
+					values[column] = jsonImportValue(value)
+				}
+			}
+			return importRow{line: line, values: values}, true, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return importRow{}, false, fmt.Errorf("failed to read NDJSON: %w", err)
+		}
+		return importRow{}, false, nil
+	}
+
+	return e.importRows(ctx, qb, next, options)
+}
+
+// importColumn returns the table column of a file field, or "" when the field is skipped.
+func importColumn(field string, options types.ImportOptions) (string, error) {
+	if options.Columns != nil {
+		return options.Columns[field], nil
+	}
+	if !importColumnPattern.MatchString(field) {
+		return "", fmt.Errorf("invalid column name %q in import; map it with ImportOptions.Columns", field)
+	}
+	return field, nil
+}
+
+func jsonImportValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return v
+	}
+}
+
+// importRows validates the rows returned by next and inserts them in batches.
+func (e *QueryExecutor) importRows(ctx context.Context, qb QueryBuilderInterface, next func() (importRow, bool, error), options types.ImportOptions) (*types.ImportReport, error) {
+	size := options.BatchSize
+	if size <= 0 {
+		size = 1000
+	}
+
+	report := &types.ImportReport{}
+	batch := make([]map[string]interface{}, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		fillMissingColumns(batch)
+		err := e.InsertBatchWithOptions(ctx, qb, batch, types.BulkInsertOptions{BatchSize: len(batch), UseCopy: true})
+		if err != nil {
+			return fmt.Errorf("failed to import rows: %w", err)
+		}
+		report.Inserted += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		row, ok, err := next()
+		if err != nil {
+			return report, err
+		}
+		if !ok {
+			break
+		}
+
+		if row.err == nil && options.Validate != nil {
+			row.err = options.Validate(row.values)
+		}
+		if row.err == nil && len(row.values) == 0 {
+			row.err = fmt.Errorf("row has no importable columns")
+		}
+		if row.err != nil {
+			report.Rejected = append(report.Rejected, types.RejectedRow{Line: row.line, Err: row.err})
+			if options.MaxRejected > 0 && len(report.Rejected) > options.MaxRejected {
+				return report, fmt.Errorf("import aborted: more than %d rows rejected", options.MaxRejected)
+			}
+			continue
+		}
+
+		batch = append(batch, row.values)
+		if len(batch) == size {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, flush()
+}
+
+// fillMissingColumns gives every row of batch the same columns, using NULL for missing ones, so
+// the batch can be inserted with one column list.
+func fillMissingColumns(batch []map[string]interface{}) {
+	columns := make(map[string]bool)
+	for _, row := range batch {
+		for column := range row {
+			columns[column] = true
+		}
+	}
+	for _, row := range batch {
+		for column := range columns {
+			if _, ok := row[column]; !ok {
+				row[column] = nil
+			}
+		}
+	}
+}
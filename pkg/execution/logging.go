@@ -0,0 +1,246 @@
+package execution
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Redacted replaces the bindings of sensitive columns in query logs.
+const Redacted = "[REDACTED]"
+
+// DefaultSensitiveColumns are redacted when SetLogger is called without a column list.
+var DefaultSensitiveColumns = []string{
+	"password", "password_hash", "secret", "token", "access_token", "refresh_token", "api_key",
+}
+
+type queryLogger struct {
+	logger    types.Logger
+	sensitive map[string]bool
+}
+
+var activeLogger atomic.Pointer[queryLogger]
+
+var sqlFormatter atomic.Pointer[func(string) string]
+
+// SetSQLFormatter sets how SQL is rendered in query logs, debug info and ToRawSQL, for example
+// sqlformat.Format for multi-line output. Nil restores the SQL as executed.
+func SetSQLFormatter(format func(sql string) string) {
+	if format == nil {
+		sqlFormatter.Store(nil)
+		return
+	}
+	sqlFormatter.Store(&format)
+}
+
+// FormatSQL renders sql with the formatter set by SetSQLFormatter, if any.
+func FormatSQL(sql string) string {
+	if format := sqlFormatter.Load(); format != nil {
+		return (*format)(sql)
+	}
+	return sql
+}
+
+// SetLogger sends a QueryLog for every statement on every connection to logger, or stops logging
+// when logger is nil. Bindings compared with or written to one of sensitiveColumns are replaced
+// with Redacted; DefaultSensitiveColumns is used when none are given.
+func SetLogger(logger types.Logger, sensitiveColumns ...string) {
+	if logger == nil {
+		activeLogger.Store(nil)
+		return
+	}
+
+	if len(sensitiveColumns) == 0 {
+		sensitiveColumns = DefaultSensitiveColumns
+	}
+	sensitive := make(map[string]bool, len(sensitiveColumns))
+	for _, column := range sensitiveColumns {
+		sensitive[strings.ToLower(column)] = true
+	}
+	activeLogger.Store(&queryLogger{logger: logger, sensitive: sensitive})
+}
+
+// logQuery reports a finished statement to the active logger, if any.
+func logQuery(ctx context.Context, table string, query string, args []interface{}, started time.Time, rows int64, err error) {
+	active := activeLogger.Load()
+	if active == nil {
+		return
+	}
+
+	active.logger.LogQuery(ctx, types.QueryLog{
+		Table:    table,
+		SQL:      FormatSQL(query),
+		Bindings: redactBindings(query, args, active.sensitive),
+		Duration: time.Since(started),
+		Rows:     rows,
+		Err:      err,
+	})
+}
+
+// logTruncated reports to the active logger, if any, that a result of table was cut to limit rows.
+func logTruncated(ctx context.Context, table string, limit int) {
+	if active := activeLogger.Load(); active != nil {
+		active.logger.LogQuery(ctx, types.QueryLog{Table: table, Rows: int64(limit), Truncated: true})
+	}
+}
+
+// record reports a finished statement to the logger and the observing hooks and, in debug mode, keeps it for DebugInfo.
+func (e *QueryExecutor) record(ctx context.Context, table string, query string, args []interface{}, started time.Time, rows int64, err error) {
+	logQuery(ctx, table, query, args, started, rows, err)
+	observe(ctx, table, query, args, started, rows, err)
+	if !e.debug {
+		return
+	}
+
+	info := &types.DebugInfo{
+		SQL:      FormatSQL(e.annotate(ctx, query)),
+		Bindings: args,
+		Duration: time.Since(started),
+		Driver:   e.driver,
+		Rows:     rows,
+	}
+	if err != nil {
+		info.Error = err.Error()
+	}
+	e.lastDebug.Store(info)
+}
+
+// SetDebug enables or disables capturing the last executed statement for DebugInfo.
+func (e *QueryExecutor) SetDebug(enabled bool) *QueryExecutor {
+	e.debug = enabled
+	return e
+}
+
+// DebugInfo returns the SQL, bindings, duration, driver and outcome of the last statement executed
+// in debug mode, or nil if none ran yet.
+func (e *QueryExecutor) DebugInfo() *types.DebugInfo {
+	return e.lastDebug.Load()
+}
+
+// redactBindings returns a copy of args with the bindings of sensitive columns replaced.
+func redactBindings(query string, args []interface{}, sensitive map[string]bool) []interface{} {
+	if len(args) == 0 {
+		return args
+	}
+
+	redacted := make([]interface{}, len(args))
+	copy(redacted, args)
+	for i, column := range bindingColumns(query, len(args)) {
+		if sensitive[column] {
+			redacted[i] = Redacted
+		}
+	}
+	return redacted
+}
+
+// bindingColumns guesses the column each placeholder of query is bound to: the column list for
+// INSERT ... VALUES, otherwise the closest identifier before the placeholder (a = ?, a IN (?, ?),
+// a BETWEEN ? AND ?). Columns are lower-cased without table prefix or quotes.
+func bindingColumns(query string, count int) []string {
+	columns := make([]string, count)
+
+	if insertColumns := insertColumnList(query); len(insertColumns) > 0 {
+		for i := range columns {
+			columns[i] = insertColumns[i%len(insertColumns)]
+		}
+		return columns
+	}
+
+	lastIdent := ""
+	position := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			// Skip string literals, including '' escapes.
+			for i++; i < len(query); i++ {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return columns
+			}
+			lastIdent = strings.ToLower(query[i+1 : i+1+end])
+			i += end + 1
+		case c == '?':
+			if position < count {
+				columns[position] = lastIdent
+			}
+			position++
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n >= 1 && n <= count {
+				columns[n-1] = lastIdent
+			}
+			i = j - 1
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(query) && (query[j] == '_' || query[j] == '.' || isDigit(query[j]) || unicode.IsLetter(rune(query[j]))) {
+				j++
+			}
+			word := strings.ToLower(query[i:j])
+			if dot := strings.LastIndexByte(word, '.'); dot >= 0 {
+				word = word[dot+1:]
+			}
+			if !placeholderKeywords[word] {
+				lastIdent = word
+			}
+			i = j - 1
+		}
+	}
+	return columns
+}
+
+// placeholderKeywords may sit between a column and its placeholders without replacing the column.
+var placeholderKeywords = map[string]bool{
+	"and": true, "in": true, "not": true, "like": true, "ilike": true, "between": true,
+	"is": true, "any": true, "all": true, "some": true,
+}
+
+// insertColumnList returns the column list of an INSERT/REPLACE ... VALUES statement.
+func insertColumnList(query string) []string {
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(upper, "INSERT") && !strings.HasPrefix(upper, "REPLACE") {
+		return nil
+	}
+	if !strings.Contains(upper, "VALUES") {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(query)
+	openParen := strings.IndexByte(trimmed, '(')
+	closeParen := strings.IndexByte(trimmed, ')')
+	if openParen < 0 || closeParen < openParen || openParen > strings.Index(upper, "VALUES") {
+		return nil
+	}
+
+	parts := strings.Split(trimmed[openParen+1:closeParen], ",")
+	columns := make([]string, len(parts))
+	for i, part := range parts {
+		column := strings.ToLower(strings.Trim(strings.TrimSpace(part), "`\""))
+		if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+			column = column[dot+1:]
+		}
+		columns[i] = column
+	}
+	return columns
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,54 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/optimization"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// DefaultParallelism is the number of builders Parallel runs at once when no limit is given.
+const DefaultParallelism = 8
+
+// Parallel runs Get on every builder concurrently, at most as many at a time as limiter allows
+// (DefaultParallelism when nil), and merges the rows in builder order. Builders may target
+// different connections, e.g. one per shard. When some builders fail the rows of the others are
+// still returned, together with a *types.BatchError whose item indexes are the failed builders.
+func Parallel(ctx context.Context, builders []types.QueryBuilder, limiter *optimization.ConcurrencyManager) (types.Collection, error) {
+	if limiter == nil {
+		limiter = optimization.NewConcurrencyManager(DefaultParallelism)
+	}
+
+	results := make([]types.Collection, len(builders))
+	errs := make([]error, len(builders))
+
+	var wg sync.WaitGroup
+	for i, qb := range builders {
+		wg.Add(1)
+		go func(i int, qb types.QueryBuilder) {
+			defer wg.Done()
+			errs[i] = limiter.ExecuteWithConcurrencyLimit(ctx, func() error {
+				rows, err := qb.Get(ctx)
+				results[i] = rows
+				return err
+			})
+		}(i, qb)
+	}
+	wg.Wait()
+
+	var merged []map[string]interface{}
+	batchErr := types.NewBatchError("parallel query", len(builders))
+	for i, err := range errs {
+		if err != nil {
+			batchErr.Add(i, nil, fmt.Errorf("failed to execute query: %w", err))
+			continue
+		}
+		if results[i] != nil {
+			merged = append(merged, results[i].ToSlice()...)
+		}
+	}
+
+	return types.NewCollection(merged), batchErr.ErrorOrNil()
+}
@@ -0,0 +1,91 @@
+package execution
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NumberPlaceholders rewrites ? placeholders to PostgreSQL's $n form, numbered after position, and
+// returns the last number used. Quoted strings and identifiers, comments and dollar-quoted bodies
+// are left untouched. ?? is written as a literal ?, so raw SQL can use the jsonb operators ?, ?|
+// and ?& as ??, ??| and ??&.
+func NumberPlaceholders(sql string, position int) (string, int) {
+	if !strings.Contains(sql, "?") {
+		return sql, position
+	}
+
+	var b strings.Builder
+	b.Grow(len(sql) + 4*strings.Count(sql, "?"))
+
+	var digits [20]byte
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteString(sql[i : i+end])
+			i += end - 1
+			continue
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(sql[i : i+end])
+			i += end - 1
+			continue
+		case ch == '$':
+			if tag := dollarQuoteTag(sql[i:]); tag != "" {
+				end := strings.Index(sql[i+len(tag):], tag)
+				if end < 0 {
+					end = len(sql) - i
+				} else {
+					end += 2 * len(tag)
+				}
+				b.WriteString(sql[i : i+end])
+				i += end - 1
+				continue
+			}
+		case ch == '?' && i+1 < len(sql) && sql[i+1] == '?':
+			b.WriteByte('?')
+			i++
+			continue
+		case ch == '?':
+			position++
+			b.WriteByte('$')
+			b.Write(strconv.AppendInt(digits[:0], int64(position), 10))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+
+	return b.String(), position
+}
+
+// dollarQuoteTag returns the $$ or $tag$ opening a dollar-quoted string at the start of sql, or "".
+// A $ followed by a digit is a parameter, not a tag.
+func dollarQuoteTag(sql string) string {
+	for i := 1; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '$':
+			return sql[:i+1]
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || i > 1 && ch >= '0' && ch <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
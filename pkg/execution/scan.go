@@ -0,0 +1,71 @@
+package execution
+
+import (
+	"strings"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// RowScanner reads rows into maps keyed by column name. It reuses its scan buffers from row to row
+// and decides once per column, from the column types the rows report, how to convert the values
+// the driver returns: []byte becomes a string except in binary columns such as BLOB and BYTEA.
+type RowScanner struct {
+	columns  []string
+	binary   []bool
+	values   []interface{}
+	pointers []interface{}
+}
+
+// NewRowScanner prepares a scanner for the columns of rows.
+func NewRowScanner(rows types.Rows) (*RowScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RowScanner{
+		columns:  columns,
+		binary:   make([]bool, len(columns)),
+		values:   make([]interface{}, len(columns)),
+		pointers: make([]interface{}, len(columns)),
+	}
+	for i := range s.values {
+		s.pointers[i] = &s.values[i]
+	}
+
+	columnTypes, err := types.RowColumnTypes(rows)
+	if err == nil && len(columnTypes) == len(columns) {
+		for i, columnType := range columnTypes {
+			s.binary[i] = isBinary(columnType.DatabaseTypeName())
+		}
+	}
+	return s, nil
+}
+
+// Columns returns the column names of the rows.
+func (s *RowScanner) Columns() []string {
+	return s.columns
+}
+
+// Scan reads the current row of rows into a new map.
+func (s *RowScanner) Scan(rows types.Rows) (map[string]interface{}, error) {
+	if err := rows.Scan(s.pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(s.columns))
+	for i, column := range s.columns {
+		value := s.values[i]
+		if b, ok := value.([]byte); ok && !s.binary[i] {
+			value = string(b)
+		}
+		row[column] = value
+		s.values[i] = nil
+	}
+	return row, nil
+}
+
+func isBinary(databaseType string) bool {
+	databaseType = strings.ToUpper(databaseType)
+	return strings.Contains(databaseType, "BLOB") || strings.Contains(databaseType, "BINARY") || databaseType == "BYTEA"
+}
@@ -6,184 +6,68 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
 // UpdateJSON updates a JSON field at a specific path.
 func (e *QueryExecutor) UpdateJSON(ctx context.Context, qb QueryBuilderInterface, column string, path string, value interface{}) (int64, error) {
-	table := qb.GetTable()
-	if table == "" {
-		return 0, fmt.Errorf("no table specified for update")
-	}
-
-	var sql string
-	var bindings []interface{}
+	var expr types.Expression
 
 	switch e.driver {
 	case types.MySQL:
-		sql = fmt.Sprintf("UPDATE %s SET %s = JSON_SET(%s, %s, %s)", 
-			table, column, column, e.getPlaceholder(1), e.getPlaceholder(2))
-		bindings = append(bindings, path, value)
+		expr = types.Raw(fmt.Sprintf("JSON_SET(%s, ?, ?)", column), path, value)
 	case types.PostgreSQL:
-		sql = fmt.Sprintf("UPDATE %s SET %s = jsonb_set(%s, %s, %s)", 
-			table, column, column, e.getPlaceholder(1), e.getPlaceholder(2))
-		pathArray := strings.Split(strings.Trim(path, "$"), ".")
 		jsonValue, _ := json.Marshal(value)
-		bindings = append(bindings, fmt.Sprintf("{%s}", strings.Join(pathArray, ",")), string(jsonValue))
+		expr = types.Raw(fmt.Sprintf("jsonb_set(%s, ?, ?)", column), postgresJSONPath(path), string(jsonValue))
 	default:
 		return 0, fmt.Errorf("JSON updates not supported for driver: %s", e.driver)
 	}
 
-	whereSQL, whereBindings, err := e.buildWhereClause(qb)
-	if err != nil {
-		return 0, fmt.Errorf("failed to build where clause: %w", err)
-	}
-
-	if whereSQL != "" {
-		sql += " WHERE " + whereSQL
-		bindings = append(bindings, whereBindings...)
-	}
-
-	result, err := e.executor.ExecContext(ctx, sql, bindings...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to execute JSON update: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	return rowsAffected, nil
+	return e.execUpdate(ctx, qb, map[string]interface{}{column: expr}, "JSON update")
 }
 
 // UpdateJSONRemove removes a JSON field at a specific path.
 func (e *QueryExecutor) UpdateJSONRemove(ctx context.Context, qb QueryBuilderInterface, column string, path string) (int64, error) {
-	table := qb.GetTable()
-	if table == "" {
-		return 0, fmt.Errorf("no table specified for update")
-	}
-
-	var sql string
-	var bindings []interface{}
+	var expr types.Expression
 
 	switch e.driver {
 	case types.MySQL:
-		sql = fmt.Sprintf("UPDATE %s SET %s = JSON_REMOVE(%s, %s)", 
-			table, column, column, e.getPlaceholder(1))
-		bindings = append(bindings, path)
+		expr = types.Raw(fmt.Sprintf("JSON_REMOVE(%s, ?)", column), path)
 	case types.PostgreSQL:
-		sql = fmt.Sprintf("UPDATE %s SET %s = %s - %s", 
-			table, column, column, e.getPlaceholder(1))
-		pathArray := strings.Split(strings.Trim(path, "$"), ".")
-		bindings = append(bindings, fmt.Sprintf("{%s}", strings.Join(pathArray, ",")))
+		expr = types.Raw(fmt.Sprintf("%s #- ?", column), postgresJSONPath(path))
 	default:
 		return 0, fmt.Errorf("JSON updates not supported for driver: %s", e.driver)
 	}
 
-	whereSQL, whereBindings, err := e.buildWhereClause(qb)
-	if err != nil {
-		return 0, fmt.Errorf("failed to build where clause: %w", err)
-	}
-
-	if whereSQL != "" {
-		sql += " WHERE " + whereSQL
-		bindings = append(bindings, whereBindings...)
-	}
-
-	result, err := e.executor.ExecContext(ctx, sql, bindings...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to execute JSON remove: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	return rowsAffected, nil
+	return e.execUpdate(ctx, qb, map[string]interface{}{column: expr}, "JSON remove")
 }
 
 // Increment increases the value of a numeric column by the specified amount (default 1).
 func (e *QueryExecutor) Increment(ctx context.Context, qb QueryBuilderInterface, column string, value ...interface{}) (int64, error) {
-	amount := 1
-	if len(value) > 0 {
-		if v, ok := value[0].(int); ok {
-			amount = v
-		}
-	}
-
-	table := qb.GetTable()
-	if table == "" {
-		return 0, fmt.Errorf("no table specified for increment")
-	}
-
-	sql := fmt.Sprintf("UPDATE %s SET %s = %s + %s", 
-		table, column, column, e.getPlaceholder(1))
-	bindings := []interface{}{amount}
-
-	whereSQL, whereBindings, err := e.buildWhereClause(qb)
-	if err != nil {
-		return 0, fmt.Errorf("failed to build where clause: %w", err)
-	}
-
-	if whereSQL != "" {
-		sql += " WHERE " + whereSQL
-		bindings = append(bindings, whereBindings...)
-	}
-
-	result, err := e.executor.ExecContext(ctx, sql, bindings...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to execute increment: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	return rowsAffected, nil
+	expr := types.Raw(fmt.Sprintf("%s + ?", column), stepAmount(value))
+	return e.execUpdate(ctx, qb, map[string]interface{}{column: expr}, "increment")
 }
 
 // Decrement decreases the value of a numeric column by the specified amount (default 1).
 func (e *QueryExecutor) Decrement(ctx context.Context, qb QueryBuilderInterface, column string, value ...interface{}) (int64, error) {
-	amount := 1
+	expr := types.Raw(fmt.Sprintf("%s - ?", column), stepAmount(value))
+	return e.execUpdate(ctx, qb, map[string]interface{}{column: expr}, "decrement")
+}
+
+func stepAmount(value []interface{}) int {
 	if len(value) > 0 {
 		if v, ok := value[0].(int); ok {
-			amount = v
+			return v
 		}
 	}
+	return 1
+}
 
-	table := qb.GetTable()
-	if table == "" {
-		return 0, fmt.Errorf("no table specified for decrement")
-	}
-
-	sql := fmt.Sprintf("UPDATE %s SET %s = %s - %s", 
-		table, column, column, e.getPlaceholder(1))
-	bindings := []interface{}{amount}
-
-	whereSQL, whereBindings, err := e.buildWhereClause(qb)
-	if err != nil {
-		return 0, fmt.Errorf("failed to build where clause: %w", err)
-	}
-
-	if whereSQL != "" {
-		sql += " WHERE " + whereSQL
-		bindings = append(bindings, whereBindings...)
-	}
-
-	result, err := e.executor.ExecContext(ctx, sql, bindings...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to execute decrement: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	return rowsAffected, nil
+// postgresJSONPath converts a $.a.b style path into PostgreSQL's {a,b} text array form.
+func postgresJSONPath(path string) string {
+	pathArray := strings.Split(strings.Trim(strings.TrimPrefix(path, "$"), "."), ".")
+	return fmt.Sprintf("{%s}", strings.Join(pathArray, ","))
 }
 
 // UpdateOrCreate updates a record matching the attributes or creates a new one with the combined values.
@@ -254,4 +138,84 @@ func (e *QueryExecutor) UpdateOrInsert(ctx context.Context, qb QueryBuilderInter
 	}
 
 	return e.Insert(ctx, qb, insertValues)
-}
\ No newline at end of file
+}
+// UpdateBatch updates many rows with a single statement. Each row holds the primary key columns of
+// the table, which pick the row to update, and the new values of other columns:
+//
+//	UPDATE t SET a = CASE WHEN id = ? THEN ? WHEN id = ? THEN ? ELSE a END WHERE id IN (?, ?)
+//
+// Composite keys are matched as tuples. A column a row does not set keeps its value in that row.
+// The builder's own conditions further restrict the rows updated.
+func (e *QueryExecutor) UpdateBatch(ctx context.Context, qb QueryBuilderInterface, rows []map[string]interface{}) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	table := qb.GetTable()
+	if column := versionColumn(qb); column != "" {
+		return 0, fmt.Errorf("batch update of %s does not support versioning on %s", table, column)
+	}
+
+	keyColumns := schema.KeyColumns(table)
+	keys := make([][]interface{}, len(rows))
+	set := make(map[string]bool)
+	for i, row := range rows {
+		if err := schema.CheckGuarded(table, row); err != nil {
+			return 0, err
+		}
+
+		key := make([]interface{}, len(keyColumns))
+		for j, column := range keyColumns {
+			value, ok := row[column]
+			if !ok {
+				return 0, fmt.Errorf("%w: row %d of the batch update of %s has no %s", types.ErrInvalidParameter, i, table, column)
+			}
+			key[j] = value
+		}
+		if len(keyColumns) == 1 {
+			value, err := schema.KeyValue(table, key[0])
+			if err != nil {
+				return 0, err
+			}
+			key[0] = value
+		}
+		keys[i] = key
+
+		for column := range row {
+			set[column] = true
+		}
+	}
+	for _, column := range keyColumns {
+		delete(set, column)
+	}
+	if len(set) == 0 {
+		return 0, fmt.Errorf("no values provided for batch update")
+	}
+
+	match := strings.Join(keyColumns, " = ? AND ") + " = ?"
+	values := make(map[string]interface{}, len(set))
+	for column := range set {
+		var sql strings.Builder
+		var bindings []interface{}
+		sql.WriteString("CASE")
+		for i, row := range rows {
+			value, ok := row[column]
+			if !ok {
+				continue
+			}
+			sql.WriteString(" WHEN " + match + " THEN ")
+			bindings = append(bindings, keys[i]...)
+			if expr, ok := value.(types.Expression); ok {
+				sql.WriteString(expr.SQL)
+				bindings = append(bindings, expr.Bindings...)
+			} else {
+				sql.WriteString("?")
+				bindings = append(bindings, value)
+			}
+		}
+		sql.WriteString(" ELSE " + column + " END")
+		values[column] = types.Raw(sql.String(), bindings...)
+	}
+
+	return e.execUpdate(ctx, whereKeyIn(qb.Clone(), keyColumns, keys).(QueryBuilderInterface), values, "batch update")
+}
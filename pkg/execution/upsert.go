@@ -3,37 +3,187 @@ package execution
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
-// Upsert inserts new records or updates existing ones based on conflict resolution.
-func (e *QueryExecutor) Upsert(ctx context.Context, qb QueryBuilderInterface, values []map[string]interface{}, options types.UpsertOptions) error {
+// insertedColumn is the column PostgreSQL upserts return to tell inserted rows from updated ones.
+const insertedColumn = "qb_inserted"
+
+// Upsert inserts new records or updates existing ones based on conflict resolution. Without a
+// ConflictTarget the conflict is detected on the table's primary key columns. The result counts
+// the inserted and updated rows: always on PostgreSQL, and on MySQL for single-row upserts.
+//
+// Rows are sent in statements of options.BatchSize rows, kept under the bind parameter limit. When
+// more than one statement is needed they run in a single transaction.
+func (e *QueryExecutor) Upsert(ctx context.Context, qb QueryBuilderInterface, values []map[string]interface{}, options types.UpsertOptions) (*types.UpsertResult, error) {
 	if len(values) == 0 {
-		return fmt.Errorf("no values provided for upsert")
+		return nil, fmt.Errorf("no values provided for upsert")
 	}
 
 	table := qb.GetTable()
 	if table == "" {
-		return fmt.Errorf("no table specified for upsert")
+		return nil, fmt.Errorf("no table specified for upsert")
+	}
+	for _, row := range values {
+		if err := schema.CheckGuarded(table, row); err != nil {
+			return nil, err
+		}
+	}
+	if len(options.ConflictTarget) == 0 {
+		options.ConflictTarget = schema.KeyColumns(table)
+	}
+	for _, columns := range [][]string{sortedColumns(values[0]), options.ConflictTarget, options.UpdateColumns, expressionColumns(options.UpdateExpressions), returningColumns(options.Returning)} {
+		if err := checkColumns(qb, columns); err != nil {
+			return nil, err
+		}
 	}
 
-	switch e.driver {
-	case types.MySQL:
+	if e.driver != types.MySQL && e.driver != types.PostgreSQL {
+		return nil, fmt.Errorf("upsert not supported for driver: %s", e.driver)
+	}
+
+	size := upsertBatchSize(options, len(values[0]))
+	if len(values) <= size {
+		return e.upsertRows(ctx, table, values, options)
+	}
+	if e.readOnly {
+		return nil, types.ErrReadOnly
+	}
+
+	// Already inside a transaction: the statements are atomic as part of it.
+	executor, err := e.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, inTx := executor.(types.Tx); inTx {
+		return e.upsertChunks(ctx, table, values, options, size)
+	}
+
+	tx, err := executor.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin upsert transaction: %w", err)
+	}
+	result, err := e.withTx(tx).upsertChunks(ctx, table, values, options, size)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit upsert: %w", err)
+	}
+	return result, nil
+}
+
+// upsertBatchSize returns the number of rows per upsert statement, keeping each statement's row
+// and update bindings under the bind parameter limit.
+func upsertBatchSize(options types.UpsertOptions, columns int) int {
+	size := insertBatchSize(options.BatchSize, columns)
+	extra := len(options.UpdateWhere.Bindings)
+	for _, expr := range options.UpdateExpressions {
+		extra += len(expr.Bindings)
+	}
+	if columns > 0 && size*columns+extra > maxBindParams {
+		size = max((maxBindParams-extra)/columns, 1)
+	}
+	return size
+}
+
+// upsertChunks upserts values in statements of size rows and adds up their results.
+func (e *QueryExecutor) upsertChunks(ctx context.Context, table string, values []map[string]interface{}, options types.UpsertOptions, size int) (*types.UpsertResult, error) {
+	total := &types.UpsertResult{}
+	var returned []map[string]interface{}
+	for start := 0; start < len(values); start += size {
+		end := min(start+size, len(values))
+		result, err := e.upsertRows(ctx, table, values[start:end], options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert rows %d-%d: %w", start, end-1, err)
+		}
+
+		total.Affected += result.Affected
+		if total.Inserted < 0 || result.Inserted < 0 {
+			total.Inserted, total.Updated = -1, -1
+		} else {
+			total.Inserted += result.Inserted
+			total.Updated += result.Updated
+		}
+		if result.Rows != nil {
+			returned = append(returned, result.Rows.ToSlice()...)
+		}
+	}
+	if len(options.Returning) > 0 {
+		total.Rows = types.NewCollection(returned)
+	}
+	return total, nil
+}
+
+// upsertRows upserts values in a single statement.
+func (e *QueryExecutor) upsertRows(ctx context.Context, table string, values []map[string]interface{}, options types.UpsertOptions) (*types.UpsertResult, error) {
+	if e.driver == types.MySQL {
 		return e.upsertMySQL(ctx, table, values, options)
-	case types.PostgreSQL:
-		return e.upsertPostgreSQL(ctx, table, values, options)
-	default:
-		return fmt.Errorf("upsert not supported for driver: %s", e.driver)
 	}
+	return e.upsertPostgreSQL(ctx, table, values, options)
 }
 
-func (e *QueryExecutor) upsertMySQL(ctx context.Context, table string, values []map[string]interface{}, options types.UpsertOptions) error {
-	firstRow := values[0]
-	columns := make([]string, 0, len(firstRow))
-	for column := range firstRow {
+// expressionColumns returns the columns of UpdateExpressions in a stable order.
+func expressionColumns(expressions map[string]types.Expression) []string {
+	columns := make([]string, 0, len(expressions))
+	for column := range expressions {
 		columns = append(columns, column)
 	}
+	sort.Strings(columns)
+	return columns
+}
+
+// updateAssignments returns the SET list of an upsert: column = inserted for the update columns,
+// with inserted a format taking the column name, and column = expression for the update
+// expressions, which replace update columns of the same name. It also returns the bindings of the
+// expressions, with their placeholders numbered after position on PostgreSQL.
+func (e *QueryExecutor) updateAssignments(updateColumns []string, inserted string, expressions map[string]types.Expression, position int) ([]string, []interface{}, int) {
+	var parts []string
+	for _, column := range updateColumns {
+		if _, ok := expressions[column]; !ok {
+			parts = append(parts, fmt.Sprintf("%s = "+inserted, column, column))
+		}
+	}
+
+	var bindings []interface{}
+	for _, column := range expressionColumns(expressions) {
+		expr := expressions[column]
+		sql := expr.SQL
+		if e.driver == types.PostgreSQL {
+			sql, position = NumberPlaceholders(sql, position)
+		}
+		parts = append(parts, column+" = "+sql)
+		bindings = append(bindings, expr.Bindings...)
+	}
+	return parts, bindings, position
+}
+
+// returningColumns returns the named columns of a RETURNING list, without "*".
+func returningColumns(returning []string) []string {
+	var columns []string
+	for _, column := range returning {
+		if column != "*" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func (e *QueryExecutor) upsertMySQL(ctx context.Context, table string, values []map[string]interface{}, options types.UpsertOptions) (*types.UpsertResult, error) {
+	if len(options.Returning) > 0 {
+		return nil, fmt.Errorf("%w: RETURNING on MySQL upserts", types.ErrUnsupportedFeature)
+	}
+	if options.UpdateWhere.SQL != "" {
+		return nil, fmt.Errorf("%w: conditional updates in MySQL upserts", types.ErrUnsupportedFeature)
+	}
+
+	firstRow := values[0]
+	columns := sortedColumns(firstRow)
 
 	var allBindings []interface{}
 	var valueSets []string
@@ -52,40 +202,59 @@ func (e *QueryExecutor) upsertMySQL(ctx context.Context, table string, values []
 		}
 		
 		allBindings = append(allBindings, rowBindings...)
-		valueSets = append(valueSets, "("+joinStrings(rowPlaceholders, ", ")+")")
+		valueSets = append(valueSets, "("+strings.Join(rowPlaceholders, ", ")+")")
 	}
 
 	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
 		table,
 		joinColumns(columns),
-		joinStrings(valueSets, ", "))
+		strings.Join(valueSets, ", "))
 
 	updateColumns := options.UpdateColumns
 	if len(updateColumns) == 0 {
 		updateColumns = columns
 	}
 
-	var updateParts []string
-	for _, column := range updateColumns {
-		updateParts = append(updateParts, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	// MySQL 8.0.20 deprecated VALUES() in favour of a row alias; older servers and MariaDB only
+	// have VALUES().
+	inserted := "VALUES(%s)"
+	if e.ServerVersion().Supports(types.FeatureInsertAlias) {
+		sql += " AS new"
+		inserted = "new.%s"
 	}
 
-	sql += " ON DUPLICATE KEY UPDATE " + joinStrings(updateParts, ", ")
+	updateParts, updateBindings, _ := e.updateAssignments(updateColumns, inserted, options.UpdateExpressions, 0)
+	allBindings = append(allBindings, updateBindings...)
 
-	_, err := e.executor.ExecContext(ctx, sql, allBindings...)
+	sql += " ON DUPLICATE KEY UPDATE " + strings.Join(updateParts, ", ")
+
+	result, err := e.exec(ctx, table, sql, allBindings...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute MySQL upsert: %w", err)
+	}
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to execute MySQL upsert: %w", err)
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
-	return nil
+	// MySQL reports 1 for an inserted row, 2 for an updated one and 0 for a row left unchanged, so
+	// only a single row's count can be split.
+	if len(values) > 1 {
+		return &types.UpsertResult{Affected: affected, Inserted: -1, Updated: -1}, nil
+	}
+	switch affected {
+	case 1:
+		return &types.UpsertResult{Affected: 1, Inserted: 1}, nil
+	case 2:
+		return &types.UpsertResult{Affected: 1, Updated: 1}, nil
+	default:
+		return &types.UpsertResult{}, nil
+	}
 }
 
-func (e *QueryExecutor) upsertPostgreSQL(ctx context.Context, table string, values []map[string]interface{}, options types.UpsertOptions) error {
+func (e *QueryExecutor) upsertPostgreSQL(ctx context.Context, table string, values []map[string]interface{}, options types.UpsertOptions) (*types.UpsertResult, error) {
 	firstRow := values[0]
-	columns := make([]string, 0, len(firstRow))
-	for column := range firstRow {
-		columns = append(columns, column)
-	}
+	columns := sortedColumns(firstRow)
 
 	var allBindings []interface{}
 	var valueSets []string
@@ -106,22 +275,29 @@ func (e *QueryExecutor) upsertPostgreSQL(ctx context.Context, table string, valu
 		}
 		
 		allBindings = append(allBindings, rowBindings...)
-		valueSets = append(valueSets, "("+joinStrings(rowPlaceholders, ", ")+")")
+		valueSets = append(valueSets, "("+strings.Join(rowPlaceholders, ", ")+")")
 	}
 
 	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
 		table,
 		joinColumns(columns),
-		joinStrings(valueSets, ", "))
+		strings.Join(valueSets, ", "))
 
 	conflictTarget := options.ConflictTarget
-	if len(conflictTarget) == 0 {
-		return fmt.Errorf("conflict target must be specified for PostgreSQL upsert")
-	}
-
 	sql += fmt.Sprintf(" ON CONFLICT (%s)", joinColumns(conflictTarget))
 
-	switch options.ConflictAction {
+	// UpdateExpressions and UpdateWhere only apply to DO UPDATE, which they imply when no action is
+	// given.
+	action := options.ConflictAction
+	updating := len(options.UpdateExpressions) > 0 || options.UpdateWhere.SQL != ""
+	if action == "" && updating {
+		action = types.DoUpdate
+	}
+	if updating && action != types.DoUpdate {
+		return nil, fmt.Errorf("%w: UpdateExpressions and UpdateWhere require ConflictAction DoUpdate, got %q", types.ErrInvalidParameter, action)
+	}
+
+	switch action {
 	case types.DoNothing:
 		sql += " DO NOTHING"
 	case types.DoUpdate:
@@ -142,12 +318,17 @@ func (e *QueryExecutor) upsertPostgreSQL(ctx context.Context, table string, valu
 			}
 		}
 
-		if len(updateColumns) > 0 {
-			var updateParts []string
-			for _, column := range updateColumns {
-				updateParts = append(updateParts, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		updateParts, updateBindings, position := e.updateAssignments(updateColumns, "EXCLUDED.%s", options.UpdateExpressions, bindingPos-1)
+		if len(updateParts) > 0 {
+			sql += " DO UPDATE SET " + strings.Join(updateParts, ", ")
+			allBindings = append(allBindings, updateBindings...)
+			if where := options.UpdateWhere; where.SQL != "" {
+				condition, _ := NumberPlaceholders(where.SQL, position)
+				sql += " WHERE " + condition
+				allBindings = append(allBindings, where.Bindings...)
 			}
-			sql += " DO UPDATE SET " + joinStrings(updateParts, ", ")
+		} else if options.UpdateWhere.SQL != "" {
+			return nil, fmt.Errorf("%w: UpdateWhere without columns to update", types.ErrInvalidParameter)
 		} else {
 			sql += " DO NOTHING"
 		}
@@ -155,12 +336,45 @@ func (e *QueryExecutor) upsertPostgreSQL(ctx context.Context, table string, valu
 		sql += " DO NOTHING"
 	}
 
-	_, err := e.executor.ExecContext(ctx, sql, allBindings...)
+	// xmax is zero on a row version created by an insert and set on one created by an update.
+	returning := append(append([]string(nil), options.Returning...), "(xmax = 0) AS "+insertedColumn)
+	sql += " RETURNING " + joinColumns(returning)
+
+	rows, err := e.queryWrite(ctx, table, sql, allBindings...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute PostgreSQL upsert: %w", err)
+	}
+	collection, err := e.scanRows(ctx, table, rows, 0)
+	_ = rows.Close()
 	if err != nil {
-		return fmt.Errorf("failed to execute PostgreSQL upsert: %w", err)
+		return nil, fmt.Errorf("failed to read PostgreSQL upsert results: %w", err)
 	}
 
-	return nil
+	result := &types.UpsertResult{Affected: int64(collection.Count())}
+	for _, row := range collection.ToSlice() {
+		if isTrue(row[insertedColumn]) {
+			result.Inserted++
+		}
+		delete(row, insertedColumn)
+	}
+	result.Updated = result.Affected - result.Inserted
+	if len(options.Returning) > 0 {
+		result.Rows = collection
+	}
+	return result, nil
+}
+
+// isTrue reports whether a scanned boolean column is true, in any of the forms drivers return.
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "t" || v == "true"
+	case []byte:
+		return string(v) == "t" || string(v) == "true"
+	}
+	return false
 }
 
 // InsertOrIgnore inserts records but ignores duplicates without raising an error.
@@ -172,8 +386,16 @@ func (e *QueryExecutor) InsertOrIgnore(ctx context.Context, qb QueryBuilderInter
 
 	switch v := values.(type) {
 	case map[string]interface{}:
+		if err := checkColumns(qb, sortedColumns(v)); err != nil {
+			return err
+		}
 		return e.insertOrIgnoreSingle(ctx, table, v)
 	case []map[string]interface{}:
+		if len(v) > 0 {
+			if err := checkColumns(qb, sortedColumns(v[0])); err != nil {
+				return err
+			}
+		}
 		return e.insertOrIgnoreBatch(ctx, table, v)
 	default:
 		return fmt.Errorf("invalid values type for insert or ignore")
@@ -189,7 +411,8 @@ func (e *QueryExecutor) insertOrIgnoreSingle(ctx context.Context, table string,
 	bindings := make([]interface{}, 0, len(values))
 	placeholders := make([]string, 0, len(values))
 
-	for column, value := range values {
+	for _, column := range sortedColumns(values) {
+		value := values[column]
 		columns = append(columns, column)
 		bindings = append(bindings, value)
 		placeholders = append(placeholders, e.getPlaceholder(len(bindings)))
@@ -201,17 +424,17 @@ func (e *QueryExecutor) insertOrIgnoreSingle(ctx context.Context, table string,
 		sql = fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES (%s)",
 			table,
 			joinColumns(columns),
-			joinStrings(placeholders, ", "))
+			strings.Join(placeholders, ", "))
 	case types.PostgreSQL:
 		sql = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING",
 			table,
 			joinColumns(columns),
-			joinStrings(placeholders, ", "))
+			strings.Join(placeholders, ", "))
 	default:
 		return fmt.Errorf("insert or ignore not supported for driver: %s", e.driver)
 	}
 
-	_, err := e.executor.ExecContext(ctx, sql, bindings...)
+	_, err := e.exec(ctx, table, sql, bindings...)
 	if err != nil {
 		return fmt.Errorf("failed to execute insert or ignore: %w", err)
 	}
@@ -225,10 +448,7 @@ func (e *QueryExecutor) insertOrIgnoreBatch(ctx context.Context, table string, v
 	}
 
 	firstRow := values[0]
-	columns := make([]string, 0, len(firstRow))
-	for column := range firstRow {
-		columns = append(columns, column)
-	}
+	columns := sortedColumns(firstRow)
 
 	var allBindings []interface{}
 	var valueSets []string
@@ -247,7 +467,7 @@ func (e *QueryExecutor) insertOrIgnoreBatch(ctx context.Context, table string, v
 		}
 		
 		allBindings = append(allBindings, rowBindings...)
-		valueSets = append(valueSets, "("+joinStrings(rowPlaceholders, ", ")+")")
+		valueSets = append(valueSets, "("+strings.Join(rowPlaceholders, ", ")+")")
 	}
 
 	var sql string
@@ -256,17 +476,17 @@ func (e *QueryExecutor) insertOrIgnoreBatch(ctx context.Context, table string, v
 		sql = fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES %s",
 			table,
 			joinColumns(columns),
-			joinStrings(valueSets, ", "))
+			strings.Join(valueSets, ", "))
 	case types.PostgreSQL:
 		sql = fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON CONFLICT DO NOTHING",
 			table,
 			joinColumns(columns),
-			joinStrings(valueSets, ", "))
+			strings.Join(valueSets, ", "))
 	default:
 		return fmt.Errorf("insert or ignore not supported for driver: %s", e.driver)
 	}
 
-	_, err := e.executor.ExecContext(ctx, sql, allBindings...)
+	_, err := e.exec(ctx, table, sql, allBindings...)
 	if err != nil {
 		return fmt.Errorf("failed to execute batch insert or ignore: %w", err)
 	}
@@ -288,12 +508,16 @@ func (e *QueryExecutor) Replace(ctx context.Context, qb QueryBuilderInterface, v
 	if len(values) == 0 {
 		return fmt.Errorf("no values provided for replace")
 	}
+	if err := checkColumns(qb, sortedColumns(values)); err != nil {
+		return err
+	}
 
 	columns := make([]string, 0, len(values))
 	bindings := make([]interface{}, 0, len(values))
 	placeholders := make([]string, 0, len(values))
 
-	for column, value := range values {
+	for _, column := range sortedColumns(values) {
+		value := values[column]
 		columns = append(columns, column)
 		bindings = append(bindings, value)
 		placeholders = append(placeholders, "?")
@@ -302,9 +526,9 @@ func (e *QueryExecutor) Replace(ctx context.Context, qb QueryBuilderInterface, v
 	sql := fmt.Sprintf("REPLACE INTO %s (%s) VALUES (%s)",
 		table,
 		joinColumns(columns),
-		joinStrings(placeholders, ", "))
+		strings.Join(placeholders, ", "))
 
-	_, err := e.executor.ExecContext(ctx, sql, bindings...)
+	_, err := e.exec(ctx, table, sql, bindings...)
 	if err != nil {
 		return fmt.Errorf("failed to execute replace: %w", err)
 	}
@@ -0,0 +1,190 @@
+// Package factory builds and inserts rows for tests from per-table definitions.
+//
+//	factory.Use(db, types.MySQL)
+//	factory.Define("users", factory.Attributes{
+//		"name":   factory.Sequence(func(n int) interface{} { return fmt.Sprintf("user%d", n) }),
+//		"status": "active",
+//	})
+//
+//	users, err := factory.Factory("users").Count(50).Create(ctx)
+//	admin := factory.Factory("users").State(factory.Attributes{"role": "admin"}).MakeOne()
+package factory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/query"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Attributes maps columns to values. A value may be a Sequence, a func() interface{} or a
+// func(n int) interface{}, which is called for every row built.
+type Attributes map[string]interface{}
+
+// Sequence generates a value from the 1-based sequence number of the row within its table, which
+// keeps increasing across calls so unique columns stay unique.
+type Sequence func(n int) interface{}
+
+// Registry holds factory definitions and the database rows are created in.
+type Registry struct {
+	mu          sync.Mutex
+	executor    types.QueryExecutor
+	driver      types.Driver
+	definitions map[string]Attributes
+	sequences   map[string]int
+}
+
+// NewRegistry creates a registry that inserts rows through executor.
+func NewRegistry(executor types.QueryExecutor, driver types.Driver) *Registry {
+	return &Registry{
+		executor:    executor,
+		driver:      driver,
+		definitions: make(map[string]Attributes),
+		sequences:   make(map[string]int),
+	}
+}
+
+var defaultRegistry = NewRegistry(nil, types.MySQL)
+
+// Use sets the database the package-level factories create rows in.
+func Use(executor types.QueryExecutor, driver types.Driver) {
+	defaultRegistry.Use(executor, driver)
+}
+
+// Define registers the default attributes of table in the package-level registry.
+func Define(table string, defaults Attributes) {
+	defaultRegistry.Define(table, defaults)
+}
+
+// Factory starts building rows for table from the package-level registry.
+func Factory(table string) *Builder {
+	return defaultRegistry.Factory(table)
+}
+
+// Use sets the database rows are created in.
+func (r *Registry) Use(executor types.QueryExecutor, driver types.Driver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executor = executor
+	r.driver = driver
+}
+
+// Define registers the default attributes of table, replacing any earlier definition.
+func (r *Registry) Define(table string, defaults Attributes) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.definitions[table] = defaults
+}
+
+// Factory starts building rows for table.
+func (r *Registry) Factory(table string) *Builder {
+	return &Builder{registry: r, table: table, count: 1}
+}
+
+// next reserves n sequence numbers for table and returns the first.
+func (r *Registry) next(table string, n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	first := r.sequences[table] + 1
+	r.sequences[table] += n
+	return first
+}
+
+// Builder builds rows for one table.
+type Builder struct {
+	registry *Registry
+	table    string
+	count    int
+	states   []Attributes
+}
+
+// Count sets how many rows are built.
+func (b *Builder) Count(n int) *Builder {
+	b.count = n
+	return b
+}
+
+// State overrides attributes of the definition. Later states win over earlier ones.
+func (b *Builder) State(overrides Attributes) *Builder {
+	b.states = append(b.states, overrides)
+	return b
+}
+
+// Make builds the rows without inserting them.
+func (b *Builder) Make() []map[string]interface{} {
+	b.registry.mu.Lock()
+	defaults := b.registry.definitions[b.table]
+	b.registry.mu.Unlock()
+
+	first := b.registry.next(b.table, b.count)
+	rows := make([]map[string]interface{}, b.count)
+	for i := range rows {
+		row := make(map[string]interface{}, len(defaults))
+		for _, attributes := range append([]Attributes{defaults}, b.states...) {
+			for _, column := range sortedColumns(attributes) {
+				row[column] = generate(attributes[column], first+i)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// MakeOne builds a single row without inserting it.
+func (b *Builder) MakeOne() map[string]interface{} {
+	return b.Count(1).Make()[0]
+}
+
+// Create builds the rows and inserts them with InsertBatch, returning the inserted rows.
+func (b *Builder) Create(ctx context.Context) ([]map[string]interface{}, error) {
+	b.registry.mu.Lock()
+	executor, driver := b.registry.executor, b.registry.driver
+	b.registry.mu.Unlock()
+	if executor == nil {
+		return nil, fmt.Errorf("failed to create %s rows: no database configured, call Use first", b.table)
+	}
+
+	rows := b.Make()
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	if err := query.Table(executor, driver, b.table).InsertBatch(ctx, rows); err != nil {
+		return nil, fmt.Errorf("failed to create %s rows: %w", b.table, err)
+	}
+	return rows, nil
+}
+
+// CreateOne builds and inserts a single row.
+func (b *Builder) CreateOne(ctx context.Context) (map[string]interface{}, error) {
+	rows, err := b.Count(1).Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// sortedColumns returns the columns of attributes in order, so generators run deterministically.
+func sortedColumns(attributes Attributes) []string {
+	columns := make([]string, 0, len(attributes))
+	for column := range attributes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func generate(value interface{}, n int) interface{} {
+	switch v := value.(type) {
+	case Sequence:
+		return v(n)
+	case func(int) interface{}:
+		return v(n)
+	case func() interface{}:
+		return v()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,49 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/querybuildertest"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestMakeAppliesDefaultsSequencesAndStates(t *testing.T) {
+	registry := NewRegistry(nil, types.MySQL)
+	registry.Define("users", Attributes{
+		"email":  Sequence(func(n int) interface{} { return fmt.Sprintf("user%d@example.com", n) }),
+		"status": "active",
+	})
+
+	rows := registry.Factory("users").Count(2).Make()
+	if len(rows) != 2 || rows[0]["email"] != "user1@example.com" || rows[1]["email"] != "user2@example.com" {
+		t.Fatalf("Unexpected rows: %v", rows)
+	}
+
+	admin := registry.Factory("users").State(Attributes{"status": "admin"}).MakeOne()
+	if admin["status"] != "admin" || admin["email"] != "user3@example.com" {
+		t.Errorf("Expected the state to override defaults and the sequence to continue, got %v", admin)
+	}
+}
+
+func TestCreateInsertsBatch(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	registry := NewRegistry(db, types.MySQL)
+	registry.Define("users", Attributes{"name": func() interface{} { return "Ada" }})
+
+	rows, err := registry.Factory("users").Count(50).Create(context.Background())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(rows) != 50 {
+		t.Errorf("Expected 50 rows, got %d", len(rows))
+	}
+	db.AssertQueried(t, "users", querybuildertest.Operation("insert"))
+}
+
+func TestCreateWithoutDatabase(t *testing.T) {
+	if _, err := NewRegistry(nil, types.MySQL).Factory("users").Create(context.Background()); err == nil {
+		t.Error("Expected an error when no database is configured")
+	}
+}
@@ -0,0 +1,230 @@
+// Package filter binds REST-style filter parameters to Where clauses through a per-endpoint schema
+// of allowed fields and operators:
+//
+//	?filter[status]=active&filter[age][gte]=18&filter[name][like]=om*
+//
+//	schema := filter.Schema{
+//		"status": {Operators: []filter.Operator{filter.Eq, filter.In}},
+//		"age":    {Type: filter.Int, Operators: []filter.Operator{filter.Gte, filter.Lte}},
+//		"name":   {Operators: []filter.Operator{filter.Like}},
+//	}
+//	qb, err := schema.Apply(querybuilder.QB().Table("users"), r.URL.Query())
+//
+// Columns come from the schema, never from the request, and values are always bound, so filters
+// cannot inject SQL. Anything outside the schema is rejected with types.ErrInvalidParameter.
+package filter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Param is the query parameter filters are read from.
+const Param = "filter"
+
+// Operator is a filter operator as written in the query string.
+type Operator string
+
+// Filter operators.
+const (
+	Eq    Operator = "eq"   // filter[f]=v or filter[f][eq]=v
+	Ne    Operator = "ne"   // filter[f][ne]=v
+	Gt    Operator = "gt"   // filter[f][gt]=v
+	Gte   Operator = "gte"  // filter[f][gte]=v
+	Lt    Operator = "lt"   // filter[f][lt]=v
+	Lte   Operator = "lte"  // filter[f][lte]=v
+	Like  Operator = "like" // filter[f][like]=om* ("*" is a wildcard; without one, matches substrings)
+	In    Operator = "in"   // filter[f][in]=a,b,c
+	NotIn Operator = "nin"  // filter[f][nin]=a,b,c
+	Null  Operator = "null" // filter[f][null]=true for IS NULL, false for IS NOT NULL
+)
+
+var sqlOperators = map[Operator]string{Eq: "=", Ne: "!=", Gt: ">", Gte: ">=", Lt: "<", Lte: "<="}
+
+// Type is the type filter values are parsed as.
+type Type int
+
+// Value types.
+const (
+	String Type = iota
+	Int
+	Float
+	Bool
+	Time // RFC 3339 timestamps or YYYY-MM-DD dates
+)
+
+// Field describes a filterable field.
+type Field struct {
+	Column    string     // column to filter; defaults to the field name
+	Type      Type       // type values are parsed as
+	Operators []Operator // allowed operators; defaults to Eq
+}
+
+// Schema maps the field names clients may filter on to their definitions.
+type Schema map[string]Field
+
+// Condition is a parsed filter.
+type Condition struct {
+	Field    string
+	Column   string
+	Operator Operator
+	Value    interface{} // parsed value; []interface{} for In and NotIn, bool for Null
+}
+
+var paramPattern = regexp.MustCompile(`^` + Param + `\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// Parse reads the filter parameters of values, sorted by field and operator.
+func (s Schema) Parse(values url.Values) ([]Condition, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, Param+"[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	conditions := make([]Condition, 0, len(keys))
+	for _, key := range keys {
+		match := paramPattern.FindStringSubmatch(key)
+		if match == nil {
+			return nil, fmt.Errorf("%w: malformed filter %q", types.ErrInvalidParameter, key)
+		}
+		condition, err := s.parse(match[1], Operator(match[2]), values.Get(key))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+func (s Schema) parse(name string, op Operator, raw string) (Condition, error) {
+	field, ok := s[name]
+	if !ok {
+		return Condition{}, fmt.Errorf("%w: cannot filter by %q", types.ErrInvalidParameter, name)
+	}
+	if op == "" {
+		op = Eq
+	}
+	if !field.allows(op) {
+		return Condition{}, fmt.Errorf("%w: operator %q is not allowed for %q", types.ErrInvalidParameter, op, name)
+	}
+
+	condition := Condition{Field: name, Column: field.Column, Operator: op}
+	if condition.Column == "" {
+		condition.Column = name
+	}
+
+	switch op {
+	case Null:
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: filter[%s][null] must be true or false", types.ErrInvalidParameter, name)
+		}
+		condition.Value = isNull
+	case In, NotIn:
+		parts := strings.Split(raw, ",")
+		list := make([]interface{}, len(parts))
+		for i, part := range parts {
+			value, err := field.Type.parse(strings.TrimSpace(part))
+			if err != nil {
+				return Condition{}, fmt.Errorf("%w: filter[%s]: %v", types.ErrInvalidParameter, name, err)
+			}
+			list[i] = value
+		}
+		condition.Value = list
+	case Like:
+		condition.Value = likePattern(raw)
+	default:
+		value, err := field.Type.parse(raw)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: filter[%s]: %v", types.ErrInvalidParameter, name, err)
+		}
+		condition.Value = value
+	}
+	return condition, nil
+}
+
+func (f Field) allows(op Operator) bool {
+	if len(f.Operators) == 0 {
+		return op == Eq
+	}
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Type) parse(raw string) (interface{}, error) {
+	switch t {
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Float:
+		return strconv.ParseFloat(raw, 64)
+	case Bool:
+		return strconv.ParseBool(raw)
+	case Time:
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	default:
+		return raw, nil
+	}
+}
+
+// likePattern escapes LIKE wildcards in raw and turns "*" into "%". Without a "*", the pattern
+// matches raw anywhere in the value.
+func likePattern(raw string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(raw)
+	if !strings.Contains(escaped, "*") {
+		return "%" + escaped + "%"
+	}
+	return strings.ReplaceAll(escaped, "*", "%")
+}
+
+// Apply parses the filter parameters of values and adds them to qb as Where clauses.
+func (s Schema) Apply(qb types.QueryBuilder, values url.Values) (types.QueryBuilder, error) {
+	conditions, err := s.Parse(values)
+	if err != nil {
+		return nil, err
+	}
+	for _, condition := range conditions {
+		qb = condition.Apply(qb)
+	}
+	return qb, nil
+}
+
+// FromRequest applies the filter parameters of r's query string to qb.
+func (s Schema) FromRequest(r *http.Request, qb types.QueryBuilder) (types.QueryBuilder, error) {
+	return s.Apply(qb, r.URL.Query())
+}
+
+// Apply adds the condition to qb.
+func (c Condition) Apply(qb types.QueryBuilder) types.QueryBuilder {
+	switch c.Operator {
+	case Null:
+		if c.Value == true {
+			return qb.WhereNull(c.Column)
+		}
+		return qb.WhereNotNull(c.Column)
+	case In:
+		return qb.WhereIn(c.Column, c.Value.([]interface{}))
+	case NotIn:
+		return qb.WhereNotIn(c.Column, c.Value.([]interface{}))
+	case Like:
+		return qb.Where(c.Column, "LIKE", c.Value)
+	default:
+		return qb.Where(c.Column, sqlOperators[c.Operator], c.Value)
+	}
+}
@@ -0,0 +1,71 @@
+package filter
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/query"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var userSchema = Schema{
+	"status": {Operators: []Operator{Eq, In}},
+	"age":    {Type: Int, Operators: []Operator{Gte, Lte}},
+	"name":   {Column: "users.name", Operators: []Operator{Like}},
+	"banned": {Column: "banned_at", Operators: []Operator{Null}},
+}
+
+func TestApplyBuildsWhereClauses(t *testing.T) {
+	values, _ := url.ParseQuery("filter[status]=active&filter[age][gte]=18&filter[name][like]=om*&filter[banned][null]=true")
+
+	qb, err := userSchema.Apply(query.NewBuilder(nil, types.MySQL).From("users"), values)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+
+	for _, fragment := range []string{"age >= ?", "banned_at IS NULL", "users.name LIKE ?", "status = ?"} {
+		if !strings.Contains(sql, fragment) {
+			t.Errorf("Expected %q in %s", fragment, sql)
+		}
+	}
+	if len(bindings) != 3 || bindings[0] != int64(18) || bindings[1] != "om%" || bindings[2] != "active" {
+		t.Errorf("Unexpected bindings: %v", bindings)
+	}
+}
+
+func TestParseInAndLikeEscaping(t *testing.T) {
+	values := url.Values{"filter[status][in]": {"active,pending"}, "filter[name][like]": {"50%_off"}}
+
+	conditions, err := userSchema.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := conditions[0].Value; got != `%50\%\_off%` {
+		t.Errorf("Expected escaped substring pattern, got %v", got)
+	}
+	if list := conditions[1].Value.([]interface{}); len(list) != 2 || list[1] != "pending" {
+		t.Errorf("Unexpected IN values: %v", list)
+	}
+}
+
+func TestParseRejectsFiltersOutsideSchema(t *testing.T) {
+	for _, raw := range []string{
+		"filter[password]=x",
+		"filter[age]=18",
+		"filter[age][gte]=old",
+		"filter[status][like]=a*",
+		"filter[banned][null]=maybe",
+		"filter[name][like][x]=1",
+	} {
+		values, _ := url.ParseQuery(raw)
+		if _, err := userSchema.Parse(values); !errors.Is(err, types.ErrInvalidParameter) {
+			t.Errorf("%s: expected ErrInvalidParameter, got %v", raw, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package graphql helps GraphQL resolvers query through the builder: Paginate maps Relay connection
+// arguments (first/after/last/before) to keyset pagination, and Loader batches per-parent lookups
+// into one WhereIn query to avoid N+1 queries.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// ConnectionArgs are the Relay connection arguments of a field.
+type ConnectionArgs struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+}
+
+// ConnectionOptions controls Paginate.
+type ConnectionOptions struct {
+	CursorColumn    string // unique, sortable column cursors are built from (default "id")
+	DefaultPageSize int    // page size when neither first nor last is given (default 20)
+	MaxPageSize     int    // first and last are capped to this (default 100)
+}
+
+// Edge is one node of a connection with its cursor.
+type Edge struct {
+	Cursor string                 `json:"cursor"`
+	Node   map[string]interface{} `json:"node"`
+}
+
+// PageInfo is the Relay PageInfo of a connection.
+type PageInfo struct {
+	HasNextPage     bool   `json:"hasNextPage"`
+	HasPreviousPage bool   `json:"hasPreviousPage"`
+	StartCursor     string `json:"startCursor,omitempty"`
+	EndCursor       string `json:"endCursor,omitempty"`
+}
+
+// Connection is a page of nodes in Relay connection form.
+type Connection struct {
+	Edges    []Edge   `json:"edges"`
+	PageInfo PageInfo `json:"pageInfo"`
+}
+
+// Paginate returns the page of qb selected by args, ordered by the cursor column. Forward pages
+// (first/after) and backward pages (last/before) are both read with a single keyset query.
+// Invalid arguments are reported as types.ErrInvalidParameter.
+func Paginate(ctx context.Context, qb types.QueryBuilder, args ConnectionArgs, opts ConnectionOptions) (*Connection, error) {
+	opts = opts.withDefaults()
+	if args.First != nil && args.Last != nil {
+		return nil, fmt.Errorf("%w: first and last cannot be combined", types.ErrInvalidParameter)
+	}
+
+	backward := args.Last != nil || (args.Before != nil && args.First == nil)
+	size := opts.DefaultPageSize
+	switch {
+	case args.First != nil:
+		size = *args.First
+	case args.Last != nil:
+		size = *args.Last
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("%w: first and last must not be negative", types.ErrInvalidParameter)
+	}
+	size = min(size, opts.MaxPageSize)
+
+	page := qb.Clone()
+	column := opts.CursorColumn
+	for _, bound := range []struct {
+		cursor   *string
+		operator string
+	}{{args.After, ">"}, {args.Before, "<"}} {
+		if bound.cursor == nil {
+			continue
+		}
+		value, err := DecodeCursor(*bound.cursor)
+		if err != nil {
+			return nil, err
+		}
+		page = page.Where(column, bound.operator, value)
+	}
+
+	direction := types.Asc
+	if backward {
+		direction = types.Desc
+	}
+	rows, err := page.OrderBy(column, direction).Limit(size + 1).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := rows.ToSlice()
+	hasMore := len(nodes) > size
+	if hasMore {
+		nodes = nodes[:size]
+	}
+	if backward {
+		for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		}
+	}
+
+	conn := &Connection{Edges: make([]Edge, len(nodes))}
+	for i, node := range nodes {
+		cursor, err := EncodeCursor(node[column])
+		if err != nil {
+			return nil, err
+		}
+		conn.Edges[i] = Edge{Cursor: cursor, Node: node}
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	if backward {
+		conn.PageInfo.HasPreviousPage = hasMore
+		conn.PageInfo.HasNextPage = args.Before != nil
+	} else {
+		conn.PageInfo.HasNextPage = hasMore
+		conn.PageInfo.HasPreviousPage = args.After != nil
+	}
+	return conn, nil
+}
+
+func (opts ConnectionOptions) withDefaults() ConnectionOptions {
+	if opts.CursorColumn == "" {
+		opts.CursorColumn = "id"
+	}
+	if opts.MaxPageSize < 1 {
+		opts.MaxPageSize = 100
+	}
+	if opts.DefaultPageSize < 1 {
+		opts.DefaultPageSize = 20
+	}
+	opts.DefaultPageSize = min(opts.DefaultPageSize, opts.MaxPageSize)
+	return opts
+}
+
+// EncodeCursor returns the opaque cursor of a cursor column value.
+func EncodeCursor(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// DecodeCursor returns the cursor column value of a cursor made by EncodeCursor. Integers decode
+// as int64 and other numbers as float64.
+func DecodeCursor(cursor string) (interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", types.ErrInvalidParameter)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", types.ErrInvalidParameter)
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case string, bool:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%w: malformed cursor", types.ErrInvalidParameter)
+	}
+}
@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/querybuildertest"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func intPtr(n int) *int { return &n }
+
+func strPtr(s string) *string { return &s }
+
+func TestPaginateForward(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	db.On(querybuildertest.Table("posts")).Return(
+		map[string]interface{}{"id": int64(11)},
+		map[string]interface{}{"id": int64(12)},
+		map[string]interface{}{"id": int64(13)},
+	)
+	after, _ := EncodeCursor(10)
+
+	conn, err := Paginate(context.Background(), db.Table("posts"), ConnectionArgs{First: intPtr(2), After: &after}, ConnectionOptions{})
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if len(conn.Edges) != 2 || !conn.PageInfo.HasNextPage || !conn.PageInfo.HasPreviousPage {
+		t.Errorf("Unexpected connection: %+v", conn)
+	}
+	if value, _ := DecodeCursor(conn.PageInfo.EndCursor); value != int64(12) {
+		t.Errorf("Expected end cursor for id 12, got %v", value)
+	}
+	db.AssertQueried(t, "posts", querybuildertest.SQLContains("id > ?"), querybuildertest.SQLContains("ORDER BY id ASC LIMIT 3"),
+		querybuildertest.WithBinding(int64(10)))
+}
+
+func TestPaginateBackwardReversesRows(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	db.On(querybuildertest.Table("posts")).Return(
+		map[string]interface{}{"id": int64(9)},
+		map[string]interface{}{"id": int64(8)},
+	)
+	before, _ := EncodeCursor(10)
+
+	conn, err := Paginate(context.Background(), db.Table("posts"), ConnectionArgs{Last: intPtr(2), Before: &before}, ConnectionOptions{})
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if conn.Edges[0].Node["id"] != int64(8) || conn.PageInfo.HasPreviousPage || !conn.PageInfo.HasNextPage {
+		t.Errorf("Unexpected connection: %+v", conn)
+	}
+	db.AssertQueried(t, "posts", querybuildertest.SQLContains("ORDER BY id DESC"))
+}
+
+func TestPaginateRejectsInvalidArguments(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	for _, args := range []ConnectionArgs{
+		{First: intPtr(1), Last: intPtr(1)},
+		{First: intPtr(-1)},
+		{After: strPtr("not a cursor!")},
+	} {
+		if _, err := Paginate(context.Background(), db.Table("posts"), args, ConnectionOptions{}); !errors.Is(err, types.ErrInvalidParameter) {
+			t.Errorf("Expected ErrInvalidParameter for %+v, got %v", args, err)
+		}
+	}
+}
+
+func TestLoaderBatchesKeys(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	db.On(querybuildertest.Table("users")).Return(
+		map[string]interface{}{"id": int64(1), "name": "Ada"},
+		map[string]interface{}{"id": int64(2), "name": "Grace"},
+	)
+	loader := NewLoader(func() types.QueryBuilder { return db.Table("users") }, "id", LoaderOptions{Wait: 50 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	names := make([]interface{}, 3)
+	for i, key := range []int{1, 2, 3} {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			user, err := loader.Load(context.Background(), key)
+			if err != nil {
+				t.Errorf("Load failed: %v", err)
+				return
+			}
+			if user != nil {
+				names[i] = user["name"]
+			}
+		}(i, key)
+	}
+	wg.Wait()
+
+	if names[0] != "Ada" || names[1] != "Grace" || names[2] != nil {
+		t.Errorf("Unexpected names: %v", names)
+	}
+	db.AssertQueryCount(t, 1)
+
+	if _, err := loader.Load(context.Background(), 1); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	db.AssertQueryCount(t, 1)
+}
+
+func TestLoaderDispatchesFullBatches(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	loader := NewLoader(func() types.QueryBuilder { return db.Table("users") }, "id", LoaderOptions{MaxBatch: 1, Wait: time.Hour})
+
+	if _, err := loader.LoadAll(context.Background(), 1); err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	db.AssertQueryCount(t, 1)
+}
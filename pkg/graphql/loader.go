@@ -0,0 +1,141 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// LoaderOptions controls batching.
+type LoaderOptions struct {
+	Wait     time.Duration // how long to collect keys before querying (default 1ms)
+	MaxBatch int           // keys per query; a full batch is queried immediately (default 100)
+}
+
+// Loader batches lookups of rows by column into one WhereIn query, dataloader style: Load calls
+// made while a batch collects keys share its query. Results are cached by key for the loader's
+// lifetime, so create one loader per request.
+//
+//	authors := graphql.NewLoader(func() types.QueryBuilder { return qb.Table("users") }, "id", graphql.LoaderOptions{})
+//	author, err := authors.Load(ctx, post["author_id"])
+type Loader struct {
+	query   func() types.QueryBuilder
+	column  string
+	options LoaderOptions
+
+	mu      sync.Mutex
+	pending *batch
+	cache   map[string]*batch
+}
+
+type batch struct {
+	ctx  context.Context
+	keys []interface{}
+	once sync.Once
+	done chan struct{}
+	rows map[string][]map[string]interface{}
+	err  error
+}
+
+// NewLoader creates a loader that looks up rows of query() by column.
+func NewLoader(query func() types.QueryBuilder, column string, options LoaderOptions) *Loader {
+	if options.Wait <= 0 {
+		options.Wait = time.Millisecond
+	}
+	if options.MaxBatch < 1 {
+		options.MaxBatch = 100
+	}
+	return &Loader{query: query, column: column, options: options, cache: make(map[string]*batch)}
+}
+
+// Load returns the first row whose column equals key, or nil if there is none.
+func (l *Loader) Load(ctx context.Context, key interface{}) (map[string]interface{}, error) {
+	rows, err := l.LoadAll(ctx, key)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// LoadAll returns every row whose column equals key, for one-to-many relations.
+func (l *Loader) LoadAll(ctx context.Context, key interface{}) ([]map[string]interface{}, error) {
+	id := keyOf(key)
+	b := l.enqueue(ctx, id, key)
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.rows[id], nil
+}
+
+// Clear forgets cached results.
+func (l *Loader) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache = make(map[string]*batch)
+}
+
+// enqueue adds key to the pending batch unless it is cached, and returns the batch answering it.
+func (l *Loader) enqueue(ctx context.Context, id string, key interface{}) *batch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.cache[id]; ok {
+		return b
+	}
+
+	b := l.pending
+	if b == nil {
+		b = &batch{ctx: context.WithoutCancel(ctx), done: make(chan struct{})}
+		l.pending = b
+		time.AfterFunc(l.options.Wait, func() { l.dispatch(b) })
+	}
+	b.keys = append(b.keys, key)
+	l.cache[id] = b
+
+	if len(b.keys) >= l.options.MaxBatch {
+		l.pending = nil
+		go l.dispatch(b)
+	}
+	return b
+}
+
+// dispatch runs the query of b once.
+func (l *Loader) dispatch(b *batch) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	b.once.Do(func() {
+		defer close(b.done)
+
+		rows, err := l.query().WhereIn(l.column, b.keys).Get(b.ctx)
+		if err != nil {
+			b.err = fmt.Errorf("failed to load %s: %w", l.column, err)
+			return
+		}
+		b.rows = make(map[string][]map[string]interface{}, len(b.keys))
+		for _, row := range rows.ToSlice() {
+			id := keyOf(row[l.column])
+			b.rows[id] = append(b.rows[id], row)
+		}
+	})
+}
+
+// keyOf normalizes keys so that, for example, int 1 from a resolver matches int64 1 from a row.
+func keyOf(key interface{}) string {
+	if b, ok := key.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(key)
+}
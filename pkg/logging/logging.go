@@ -0,0 +1,156 @@
+// Package logging adapts common structured loggers to types.Logger, for use with
+// querybuilder.SetLogger.
+//
+// Every statement is logged at info level with the table, SQL, bindings (sensitive ones already
+// redacted), duration and row count; failed statements are logged at error level with the error.
+// Results cut short by MaxRowsTruncate are logged at warn level with the table and row count.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+const (
+	queryMessage       = "query"
+	failedQueryMessage = "query failed"
+	truncatedMessage   = "query result truncated"
+)
+
+// SlogLogger logs queries to a log/slog logger.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a SlogLogger, using slog.Default() when logger is nil.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+// LogQuery implements types.Logger.
+func (l *SlogLogger) LogQuery(ctx context.Context, entry types.QueryLog) {
+	if entry.Truncated {
+		l.logger.LogAttrs(ctx, slog.LevelWarn, truncatedMessage, slog.String("table", entry.Table), slog.Int64("rows", entry.Rows))
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("table", entry.Table),
+		slog.String("sql", entry.SQL),
+		slog.Any("bindings", entry.Bindings),
+		slog.Duration("duration", entry.Duration),
+		slog.Int64("rows", entry.Rows),
+	}
+	if entry.Err != nil {
+		l.logger.LogAttrs(ctx, slog.LevelError, failedQueryMessage, append(attrs, slog.String("error", entry.Err.Error()))...)
+		return
+	}
+	l.logger.LogAttrs(ctx, slog.LevelInfo, queryMessage, attrs...)
+}
+
+// SugaredLogger is the subset of *zap.SugaredLogger used by ZapLogger, so this package does not
+// depend on zap. Pass zapLogger.Sugar().
+type SugaredLogger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// ZapLogger logs queries to a zap sugared logger.
+type ZapLogger struct {
+	logger SugaredLogger
+}
+
+// NewZapLogger creates a ZapLogger.
+func NewZapLogger(logger SugaredLogger) *ZapLogger {
+	return &ZapLogger{logger: logger}
+}
+
+// LogQuery implements types.Logger.
+func (l *ZapLogger) LogQuery(_ context.Context, entry types.QueryLog) {
+	if entry.Truncated {
+		l.logger.Warnw(truncatedMessage, "table", entry.Table, "rows", entry.Rows)
+		return
+	}
+	keysAndValues := []interface{}{
+		"table", entry.Table,
+		"sql", entry.SQL,
+		"bindings", entry.Bindings,
+		"duration", entry.Duration,
+		"rows", entry.Rows,
+	}
+	if entry.Err != nil {
+		l.logger.Errorw(failedQueryMessage, append(keysAndValues, "error", entry.Err)...)
+		return
+	}
+	l.logger.Infow(queryMessage, keysAndValues...)
+}
+
+// ZerologLogger writes queries as JSON lines in zerolog's default format (level, time, message
+// fields), so they can share an output with a zerolog logger without this package depending on it.
+type ZerologLogger struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewZerologLogger creates a ZerologLogger writing to w, typically the writer given to zerolog.New.
+func NewZerologLogger(w io.Writer) *ZerologLogger {
+	return &ZerologLogger{writer: w}
+}
+
+type zerologEntry struct {
+	Level      string        `json:"level"`
+	Table      string        `json:"table,omitempty"`
+	SQL        string        `json:"sql"`
+	Bindings   []interface{} `json:"bindings,omitempty"`
+	DurationMs float64       `json:"duration"`
+	Rows       int64         `json:"rows"`
+	Error      string        `json:"error,omitempty"`
+	Time       string        `json:"time"`
+	Message    string        `json:"message"`
+}
+
+// LogQuery implements types.Logger.
+func (l *ZerologLogger) LogQuery(_ context.Context, entry types.QueryLog) {
+	record := zerologEntry{
+		Level:      "info",
+		Table:      entry.Table,
+		SQL:        entry.SQL,
+		Bindings:   entry.Bindings,
+		DurationMs: float64(entry.Duration) / float64(time.Millisecond),
+		Rows:       entry.Rows,
+		Time:       time.Now().Format(time.RFC3339),
+		Message:    queryMessage,
+	}
+	switch {
+	case entry.Truncated:
+		record.Level = "warn"
+		record.Message = truncatedMessage
+	case entry.Err != nil:
+		record.Level = "error"
+		record.Error = entry.Err.Error()
+		record.Message = failedQueryMessage
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		// Bindings that cannot be encoded are logged with their default formatting.
+		record.Bindings = []interface{}{fmt.Sprint(entry.Bindings...)}
+		if line, err = json.Marshal(record); err != nil {
+			return
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.writer.Write(append(line, '\n'))
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var entry = types.QueryLog{
+	Table:    "users",
+	SQL:      "SELECT * FROM users WHERE id = ?",
+	Bindings: []interface{}{42},
+	Duration: 3 * time.Millisecond,
+	Rows:     1,
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	failed := entry
+	failed.Err = errors.New("connection refused")
+	logger.LogQuery(context.Background(), failed)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got: %s", buf.String())
+	}
+	if record["level"] != "ERROR" || record["msg"] != failedQueryMessage || record["error"] != "connection refused" || record["table"] != "users" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+
+	buf.Reset()
+	logger.LogQuery(context.Background(), types.QueryLog{Table: "users", Rows: 100, Truncated: true})
+	record = nil
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got: %s", buf.String())
+	}
+	if _, hasSQL := record["sql"]; record["level"] != "WARN" || record["msg"] != truncatedMessage || hasSQL {
+		t.Errorf("Unexpected truncation record: %v", record)
+	}
+}
+
+type sugared struct {
+	calls []string
+}
+
+func (s *sugared) Infow(msg string, keysAndValues ...interface{}) {
+	s.calls = append(s.calls, "info:"+msg)
+}
+
+func (s *sugared) Warnw(msg string, keysAndValues ...interface{}) {
+	s.calls = append(s.calls, "warn:"+msg)
+}
+
+func (s *sugared) Errorw(msg string, keysAndValues ...interface{}) {
+	s.calls = append(s.calls, "error:"+msg)
+}
+
+func TestZapLogger(t *testing.T) {
+	sugar := &sugared{}
+	NewZapLogger(sugar).LogQuery(context.Background(), entry)
+	NewZapLogger(sugar).LogQuery(context.Background(), types.QueryLog{Table: "users", Rows: 100, Truncated: true})
+
+	if len(sugar.calls) != 2 || sugar.calls[0] != "info:query" || sugar.calls[1] != "warn:"+truncatedMessage {
+		t.Errorf("Expected an info and a warn call, got: %v", sugar.calls)
+	}
+}
+
+func TestZerologLogger(t *testing.T) {
+	var buf bytes.Buffer
+	NewZerologLogger(&buf).LogQuery(context.Background(), entry)
+
+	line := buf.String()
+	if !strings.HasSuffix(line, "\n") || !strings.Contains(line, `"level":"info"`) || !strings.Contains(line, `"message":"query"`) {
+		t.Errorf("Expected a zerolog-style JSON line, got: %s", line)
+	}
+}
@@ -0,0 +1,171 @@
+// Package named provides a registry of named, versioned query definitions.
+//
+// Queries are registered under keys of the form "name@version" (for example "users.byStatus@v2").
+// Callers resolve a query by name and get the latest version, unless the name is pinned to an
+// older version. Pinning is the compatibility mode used during rolling deploys: new instances
+// register the new version but keep serving the pinned one until every instance knows about it,
+// after which the pin is removed.
+package named
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// DefaultVersion is the version assigned to keys registered without an explicit version.
+const DefaultVersion = "v1"
+
+// QueryFunc applies a named query definition to a query builder.
+type QueryFunc func(qb types.QueryBuilder, args ...interface{}) types.QueryBuilder
+
+// Registry stores versioned named queries and the versions pinned for compatibility.
+type Registry struct {
+	mu      sync.RWMutex
+	queries map[string]map[string]QueryFunc
+	pinned  map[string]string
+}
+
+// NewRegistry creates an empty named query registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		queries: make(map[string]map[string]QueryFunc),
+		pinned:  make(map[string]string),
+	}
+}
+
+// ParseKey splits a "name@version" key into its name and version, defaulting the version to v1.
+func ParseKey(key string) (string, string) {
+	name, version, found := strings.Cut(key, "@")
+	if !found || version == "" {
+		return name, DefaultVersion
+	}
+	return name, version
+}
+
+// Register adds a query definition under the given "name@version" key.
+func (r *Registry) Register(key string, fn QueryFunc) error {
+	if fn == nil {
+		return fmt.Errorf("query definition for %s cannot be nil", key)
+	}
+
+	name, version := ParseKey(key)
+	if name == "" {
+		return fmt.Errorf("query name cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, exists := r.queries[name]
+	if !exists {
+		versions = make(map[string]QueryFunc)
+		r.queries[name] = versions
+	}
+
+	if _, exists := versions[version]; exists {
+		return fmt.Errorf("query %s@%s is already registered", name, version)
+	}
+
+	versions[version] = fn
+	return nil
+}
+
+// Pin makes unversioned lookups of name resolve to version until Unpin is called.
+func (r *Registry) Pin(name, version string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.queries[name][version]; !exists {
+		return fmt.Errorf("query %s@%s is not registered", name, version)
+	}
+
+	r.pinned[name] = version
+	return nil
+}
+
+// Unpin removes the compatibility pin so unversioned lookups resolve to the latest version.
+func (r *Registry) Unpin(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pinned, name)
+}
+
+// Resolve returns the query definition and resolved version for a key. A key without a version
+// resolves to the pinned version if one is set, otherwise to the latest registered version.
+func (r *Registry) Resolve(key string) (QueryFunc, string, error) {
+	name, version := ParseKey(key)
+	explicit := strings.Contains(key, "@")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions, exists := r.queries[name]
+	if !exists {
+		return nil, "", fmt.Errorf("query %s is not registered", name)
+	}
+
+	if !explicit {
+		if pinned, ok := r.pinned[name]; ok {
+			version = pinned
+		} else {
+			version = latestVersion(versions)
+		}
+	}
+
+	fn, exists := versions[version]
+	if !exists {
+		return nil, "", fmt.Errorf("query %s@%s is not registered", name, version)
+	}
+
+	return fn, version, nil
+}
+
+// Apply resolves the key and applies the query definition to the builder.
+func (r *Registry) Apply(key string, qb types.QueryBuilder, args ...interface{}) (types.QueryBuilder, error) {
+	fn, _, err := r.Resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	return fn(qb, args...), nil
+}
+
+// Versions returns the registered versions of a query, oldest first.
+func (r *Registry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]string, 0, len(r.queries[name]))
+	for version := range r.queries[name] {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+func latestVersion(versions map[string]QueryFunc) string {
+	latest := ""
+	for version := range versions {
+		if latest == "" || compareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// compareVersions orders "vN" versions numerically and falls back to lexical order.
+func compareVersions(a, b string) int {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil {
+		return na - nb
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,77 @@
+package named
+
+import (
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func byStatus(status string) QueryFunc {
+	return func(qb types.QueryBuilder, _ ...interface{}) types.QueryBuilder {
+		return qb.Where("status", status)
+	}
+}
+
+func TestRegistryResolvesLatestVersion(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register("users.byStatus", byStatus("v1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := registry.Register("users.byStatus@v2", byStatus("v2")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := registry.Register("users.byStatus@v10", byStatus("v10")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, version, err := registry.Resolve("users.byStatus")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if version != "v10" {
+		t.Errorf("Expected latest version v10, got %s", version)
+	}
+
+	_, version, err = registry.Resolve("users.byStatus@v2")
+	if err != nil || version != "v2" {
+		t.Errorf("Expected explicit version v2, got %s (%v)", version, err)
+	}
+}
+
+func TestRegistryPinServesCompatibleVersion(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register("users.byStatus@v1", byStatus("v1"))
+	_ = registry.Register("users.byStatus@v2", byStatus("v2"))
+
+	if err := registry.Pin("users.byStatus", "v1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, version, _ := registry.Resolve("users.byStatus")
+	if version != "v1" {
+		t.Errorf("Expected pinned version v1, got %s", version)
+	}
+
+	registry.Unpin("users.byStatus")
+	_, version, _ = registry.Resolve("users.byStatus")
+	if version != "v2" {
+		t.Errorf("Expected latest version v2 after unpin, got %s", version)
+	}
+
+	if err := registry.Pin("users.byStatus", "v3"); err == nil {
+		t.Error("Expected error when pinning an unknown version")
+	}
+}
+
+func TestRegistryRejectsDuplicates(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register("users.byStatus@v1", byStatus("v1"))
+
+	if err := registry.Register("users.byStatus", byStatus("v1")); err == nil {
+		t.Error("Expected error for duplicate registration")
+	}
+
+	if _, _, err := registry.Resolve("orders.recent"); err == nil {
+		t.Error("Expected error for unknown query")
+	}
+}
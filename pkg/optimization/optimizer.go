@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
@@ -175,6 +176,15 @@ func NewQueryOptimizer(config types.QueryOptimization) *QueryOptimizer {
 	}
 }
 
+// SQLCacheStats returns the statistics of the compiled statement cache set with SetSQLCache, or
+// zero stats when there is none.
+func (qo *QueryOptimizer) SQLCacheStats() SQLCacheStats {
+	if cache := ActiveSQLCache(); cache != nil {
+		return cache.Stats()
+	}
+	return SQLCacheStats{}
+}
+
 // GenerateCacheKey creates a cache key from SQL and bindings
 // GenerateCacheKey creates a unique cache key from SQL query and bindings.
 func (qo *QueryOptimizer) GenerateCacheKey(sql string, bindings []any) string {
@@ -350,14 +360,39 @@ type QueryStats struct {
 // ConcurrencyManager manages concurrent query execution
 // ConcurrencyManager controls the number of concurrent query executions.
 type ConcurrencyManager struct {
-	semaphore chan struct{}
+	semaphore    chan struct{}
+	queueTimeout time.Duration
+	waiting      atomic.Int64
+	acquired     atomic.Int64
+	timedOut     atomic.Int64
+	waitNanos    atomic.Int64
+}
+
+// ConcurrencyStats reports the state and history of a ConcurrencyManager.
+type ConcurrencyStats struct {
+	MaxConcurrency int           `json:"max_concurrency"`
+	Active         int           `json:"active"`
+	Waiting        int64         `json:"waiting"`
+	Acquired       int64         `json:"acquired"`
+	TimedOut       int64         `json:"timed_out"`
+	TotalWait      time.Duration `json:"total_wait"`
 }
 
 // NewConcurrencyManager creates a concurrency manager with max concurrent queries
 // NewConcurrencyManager creates a concurrency manager with the specified maximum concurrent operations.
 func NewConcurrencyManager(maxConcurrency int) *ConcurrencyManager {
+	return NewConcurrencyManagerWithTimeout(maxConcurrency, 0)
+}
+
+// NewConcurrencyManagerWithTimeout creates a concurrency manager whose Acquire gives up with
+// types.ErrQueueTimeout after waiting queueTimeout for a slot. A zero timeout waits for the context.
+func NewConcurrencyManagerWithTimeout(maxConcurrency int, queueTimeout time.Duration) *ConcurrencyManager {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
 	return &ConcurrencyManager{
-		semaphore: make(chan struct{}, maxConcurrency),
+		semaphore:    make(chan struct{}, maxConcurrency),
+		queueTimeout: queueTimeout,
 	}
 }
 
@@ -366,7 +401,30 @@ func NewConcurrencyManager(maxConcurrency int) *ConcurrencyManager {
 func (cm *ConcurrencyManager) Acquire(ctx context.Context) error {
 	select {
 	case cm.semaphore <- struct{}{}:
+		cm.acquired.Add(1)
+		return nil
+	default:
+	}
+
+	cm.waiting.Add(1)
+	defer cm.waiting.Add(-1)
+	start := time.Now()
+	defer func() { cm.waitNanos.Add(int64(time.Since(start))) }()
+
+	var timeout <-chan time.Time
+	if cm.queueTimeout > 0 {
+		timer := time.NewTimer(cm.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case cm.semaphore <- struct{}{}:
+		cm.acquired.Add(1)
 		return nil
+	case <-timeout:
+		cm.timedOut.Add(1)
+		return fmt.Errorf("%w after %s", types.ErrQueueTimeout, cm.queueTimeout)
 	case <-ctx.Done():
 		return ctx.Err()
 	}
@@ -378,6 +436,18 @@ func (cm *ConcurrencyManager) Release() {
 	<-cm.semaphore
 }
 
+// Stats returns the current number of active and waiting operations and cumulative counters.
+func (cm *ConcurrencyManager) Stats() ConcurrencyStats {
+	return ConcurrencyStats{
+		MaxConcurrency: cap(cm.semaphore),
+		Active:         len(cm.semaphore),
+		Waiting:        cm.waiting.Load(),
+		Acquired:       cm.acquired.Load(),
+		TimedOut:       cm.timedOut.Load(),
+		TotalWait:      time.Duration(cm.waitNanos.Load()),
+	}
+}
+
 // ExecuteWithConcurrencyLimit executes function with concurrency control
 // ExecuteWithConcurrencyLimit executes a function with concurrency control.
 func (cm *ConcurrencyManager) ExecuteWithConcurrencyLimit(ctx context.Context, fn func() error) error {
@@ -387,4 +457,4 @@ func (cm *ConcurrencyManager) ExecuteWithConcurrencyLimit(ctx context.Context, f
 	defer cm.Release()
 	
 	return fn()
-}
\ No newline at end of file
+}
@@ -0,0 +1,117 @@
+package optimization
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// SQLCache holds compiled SELECT statements keyed by the shape of the builder that produced them,
+// so builders that differ only in their bound values skip compilation. It keeps the most recently
+// used statements up to its size.
+type SQLCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type sqlCacheEntry struct {
+	key string
+	sql string
+}
+
+// SQLCacheStats reports how often compiled statements were reused.
+type SQLCacheStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// HitRate returns the share of lookups answered from the cache, between 0 and 1.
+func (s SQLCacheStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+var activeSQLCache atomic.Pointer[SQLCache]
+
+// NewSQLCache creates a cache holding up to size compiled statements.
+func NewSQLCache(size int) *SQLCache {
+	return &SQLCache{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// SetSQLCache makes builders look up and store their compiled SELECT statements in cache. A nil
+// cache turns caching off, the default.
+func SetSQLCache(cache *SQLCache) {
+	activeSQLCache.Store(cache)
+}
+
+// ActiveSQLCache returns the cache set with SetSQLCache, or nil.
+func ActiveSQLCache() *SQLCache {
+	return activeSQLCache.Load()
+}
+
+// Get returns the statement stored under key.
+func (c *SQLCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	var sql string
+	element, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(element)
+		sql = element.Value.(*sqlCacheEntry).sql
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return "", false
+	}
+	c.hits.Add(1)
+	return sql, true
+}
+
+// Set stores sql under key, evicting the least recently used statement when the cache is full.
+func (c *SQLCache) Set(key, sql string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*sqlCacheEntry).sql = sql
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sqlCacheEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&sqlCacheEntry{key: key, sql: sql})
+}
+
+// Clear removes every statement and resets the statistics.
+func (c *SQLCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.hits.Store(0)
+	c.misses.Store(0)
+}
+
+// Stats returns the number of cached statements and the hits and misses so far.
+func (c *SQLCache) Stats() SQLCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return SQLCacheStats{Entries: entries, Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
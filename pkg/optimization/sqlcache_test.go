@@ -0,0 +1,34 @@
+package optimization
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSQLCacheConcurrentGetAndSet(t *testing.T) {
+	cache := NewSQLCache(4)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				cache.Set(fmt.Sprintf("shape-%d", j%6), fmt.Sprintf("SELECT %d", i))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if sql, ok := cache.Get(fmt.Sprintf("shape-%d", j%6)); ok && sql == "" {
+					t.Error("Expected a cached statement")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := cache.Stats(); stats.Entries > 4 || stats.Hits+stats.Misses != 1600 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"context"
 	"math"
 
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
@@ -141,13 +143,14 @@ func (p *Paginator) SimplePaginate(ctx context.Context, qb QueryBuilderInterface
 	}, nil
 }
 
-// CursorPaginate executes cursor-based pagination for efficient handling of large datasets.
+// CursorPaginate executes cursor-based pagination for efficient handling of large datasets. The
+// cursor column defaults to the primary key registered for the table.
 func (p *Paginator) CursorPaginate(ctx context.Context, qb QueryBuilderInterface, cursor string, perPage int, cursorColumn ...string) (*CursorPaginationResult, error) {
 	if perPage < 1 {
 		perPage = 15
 	}
 
-	column := "id"
+	column := schema.Lookup(qb.GetTable()).PrimaryKey
 	if len(cursorColumn) > 0 && cursorColumn[0] != "" {
 		column = cursorColumn[0]
 	}
@@ -215,38 +218,30 @@ func (p *Paginator) getData(ctx context.Context, qb QueryBuilderInterface) (type
 	}
 	defer func() { _ = rows.Close() }()
 
-	return p.scanRows(rows)
+	return p.scanRows(ctx, qb.GetTable(), rows)
 }
 
-func (p *Paginator) scanRows(rows types.Rows) (types.Collection, error) {
-	columns, err := rows.Columns()
+func (p *Paginator) scanRows(ctx context.Context, table string, rows types.Rows) (types.Collection, error) {
+	scanner, err := execution.NewRowScanner(rows)
 	if err != nil {
 		return nil, err
 	}
+	cast := schema.RowCaster(table)
+	masks := schema.Masks(ctx, table)
 
 	var results []map[string]any
 	
 	for rows.Next() {
-		values := make([]any, len(columns))
-		valuePtrs := make([]any, len(columns))
-		
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
+		row, err := scanner.Scan(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		row := make(map[string]any)
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
+		if cast != nil {
+			if err := cast(row); err != nil {
+				return nil, err
 			}
 		}
+		schema.MaskRow(masks, row)
 		results = append(results, row)
 	}
 
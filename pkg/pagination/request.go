@@ -0,0 +1,134 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// RequestOptions controls how FromRequest reads pagination and sorting parameters.
+type RequestOptions struct {
+	DefaultPerPage   int                  // page size when per_page is absent (default 15)
+	MaxPerPage       int                  // larger page sizes are capped to this (default 100)
+	Sortable         []string             // columns clients may sort by; sorting is rejected when empty
+	DefaultSort      string               // column to sort by when sort is absent
+	DefaultDirection types.OrderDirection // direction when direction is absent (default ASC)
+
+	// Parameter names, defaulting to page, per_page, sort and direction.
+	PageParam      string
+	PerPageParam   string
+	SortParam      string
+	DirectionParam string
+}
+
+// PageRequest is the pagination and sorting requested by a client.
+type PageRequest struct {
+	Page      int
+	PerPage   int
+	Sort      string
+	Direction types.OrderDirection
+}
+
+// FromRequest reads page, per_page, sort and direction from the query string of r. Sorting by
+// "-column" is shorthand for descending order. Malformed values and columns missing from
+// opts.Sortable are rejected with types.ErrInvalidParameter; page sizes above opts.MaxPerPage are
+// capped.
+func FromRequest(r *http.Request, opts RequestOptions) (*PageRequest, error) {
+	opts = opts.withDefaults()
+	query := r.URL.Query()
+
+	req := &PageRequest{Page: 1, PerPage: opts.DefaultPerPage, Sort: opts.DefaultSort, Direction: opts.DefaultDirection}
+
+	if value := query.Get(opts.PageParam); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("%w: %s must be a positive integer", types.ErrInvalidParameter, opts.PageParam)
+		}
+		req.Page = page
+	}
+
+	if value := query.Get(opts.PerPageParam); value != "" {
+		perPage, err := strconv.Atoi(value)
+		if err != nil || perPage < 1 {
+			return nil, fmt.Errorf("%w: %s must be a positive integer", types.ErrInvalidParameter, opts.PerPageParam)
+		}
+		req.PerPage = min(perPage, opts.MaxPerPage)
+	}
+
+	if value := query.Get(opts.DirectionParam); value != "" {
+		switch strings.ToUpper(value) {
+		case string(types.Asc):
+			req.Direction = types.Asc
+		case string(types.Desc):
+			req.Direction = types.Desc
+		default:
+			return nil, fmt.Errorf("%w: %s must be asc or desc", types.ErrInvalidParameter, opts.DirectionParam)
+		}
+	}
+
+	if value := query.Get(opts.SortParam); value != "" {
+		if column, ok := strings.CutPrefix(value, "-"); ok {
+			value = column
+			req.Direction = types.Desc
+		}
+		if !opts.sortable(value) {
+			return nil, fmt.Errorf("%w: cannot sort by %q", types.ErrInvalidParameter, value)
+		}
+		req.Sort = value
+	}
+
+	return req, nil
+}
+
+func (opts RequestOptions) withDefaults() RequestOptions {
+	if opts.DefaultPerPage < 1 {
+		opts.DefaultPerPage = 15
+	}
+	if opts.MaxPerPage < 1 {
+		opts.MaxPerPage = 100
+	}
+	opts.DefaultPerPage = min(opts.DefaultPerPage, opts.MaxPerPage)
+	if opts.DefaultDirection == "" {
+		opts.DefaultDirection = types.Asc
+	}
+	if opts.PageParam == "" {
+		opts.PageParam = "page"
+	}
+	if opts.PerPageParam == "" {
+		opts.PerPageParam = "per_page"
+	}
+	if opts.SortParam == "" {
+		opts.SortParam = "sort"
+	}
+	if opts.DirectionParam == "" {
+		opts.DirectionParam = "direction"
+	}
+	return opts
+}
+
+func (opts RequestOptions) sortable(column string) bool {
+	for _, allowed := range opts.Sortable {
+		if allowed == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply adds the requested ordering to qb.
+func (req *PageRequest) Apply(qb types.QueryBuilder) types.QueryBuilder {
+	if req.Sort == "" {
+		return qb
+	}
+	return qb.OrderBy(req.Sort, req.Direction)
+}
+
+// Paginate applies the requested ordering to qb and returns the requested page, ready to encode
+// as JSON.
+func (req *PageRequest) Paginate(ctx context.Context, qb types.QueryBuilder) (types.PaginationResult, error) {
+	return req.Apply(qb).Paginate(ctx, req.Page, req.PerPage)
+}
@@ -0,0 +1,69 @@
+package pagination_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/pagination"
+	"github.com/omarhamdy49/go-query-builder/pkg/querybuildertest"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var userOptions = pagination.RequestOptions{MaxPerPage: 50, Sortable: []string{"name", "created_at"}}
+
+func TestFromRequestReadsParameters(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users?page=3&per_page=500&sort=-created_at", nil)
+
+	req, err := pagination.FromRequest(r, userOptions)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if req.Page != 3 || req.PerPage != 50 || req.Sort != "created_at" || req.Direction != types.Desc {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+}
+
+func TestFromRequestDefaults(t *testing.T) {
+	req, err := pagination.FromRequest(httptest.NewRequest("GET", "/users", nil), pagination.RequestOptions{DefaultSort: "id"})
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if req.Page != 1 || req.PerPage != 15 || req.Sort != "id" || req.Direction != types.Asc {
+		t.Errorf("Unexpected defaults: %+v", req)
+	}
+}
+
+func TestFromRequestRejectsInvalidParameters(t *testing.T) {
+	for _, target := range []string{
+		"/users?page=0",
+		"/users?per_page=abc",
+		"/users?direction=sideways",
+		"/users?sort=password",
+		"/users?sort=name%3BDROP%20TABLE%20users",
+	} {
+		if _, err := pagination.FromRequest(httptest.NewRequest("GET", target, nil), userOptions); !errors.Is(err, types.ErrInvalidParameter) {
+			t.Errorf("%s: expected ErrInvalidParameter, got %v", target, err)
+		}
+	}
+}
+
+func TestPageRequestPaginate(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	db.On(querybuildertest.SQLContains("count(")).ReturnValue(int64(40))
+	db.On(querybuildertest.SQLContains("order by")).Return(map[string]interface{}{"id": int64(11)})
+
+	req, err := pagination.FromRequest(httptest.NewRequest("GET", "/users?page=2&per_page=10&sort=name", nil), userOptions)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	result, err := req.Paginate(context.Background(), db.Table("users"))
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if result.Meta.CurrentPage != 2 || result.Meta.LastPage != 4 || result.Count() != 1 {
+		t.Errorf("Unexpected result meta: %+v", result.Meta)
+	}
+	db.AssertQueried(t, "users", querybuildertest.SQLContains("ORDER BY name ASC"))
+}
@@ -0,0 +1,124 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/optimization"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func benchmarkRows(n int) mockRows {
+	rows := mockRows{columns: []string{"id", "name", "email", "status", "created_at"}}
+	for i := 0; i < n; i++ {
+		rows.data = append(rows.data, []interface{}{
+			int64(i),
+			[]byte(fmt.Sprintf("user %d", i)),
+			[]byte(fmt.Sprintf("user%d@example.com", i)),
+			[]byte("active"),
+			[]byte("2024-05-01 12:00:00"),
+		})
+	}
+	return rows
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, n := range []int{100, 10000} {
+		b.Run(fmt.Sprintf("rows=%d", n), func(b *testing.B) {
+			executor := &rowsExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, rows: benchmarkRows(n)}
+			qb := Table(executor, types.MySQL, "users")
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := qb.Get(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStream(b *testing.B) {
+	executor := &rowsExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, rows: benchmarkRows(10000)}
+	qb := Table(executor, types.MySQL, "users")
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, errs := qb.Stream(ctx)
+		for range rows {
+		}
+		if err := <-errs; err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToSQL(b *testing.B) {
+	build := func(n int) types.QueryBuilder {
+		return Table(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL, "users").
+			Select("id", "name", "email").
+			Where("status", "active").
+			Where("age", ">", n).
+			WhereIn("role", []interface{}{"admin", "editor"}).
+			OrderBy("name", "asc").
+			Limit(50)
+	}
+
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			if cached {
+				optimization.SetSQLCache(optimization.NewSQLCache(100))
+				defer optimization.SetSQLCache(nil)
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := build(i).ToSQL(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCompileSelect(b *testing.B) {
+	ids := make([]interface{}, 500)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	shapes := map[string]func() types.QueryBuilder{
+		"simple": func() types.QueryBuilder {
+			return Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "users").Where("id", 1).Limit(1)
+		},
+		"many_wheres": func() types.QueryBuilder {
+			qb := Table(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL, "users").
+				Select("users.id", "users.name").
+				Join("posts", "posts.user_id", "=", "users.id")
+			for i := 0; i < 20; i++ {
+				qb = qb.Where(fmt.Sprintf("users.c%d", i), ">", i)
+			}
+			return qb.GroupBy("users.id").Having("COUNT(*)", ">", 1).OrderBy("users.name", "asc").Limit(50).Offset(100)
+		},
+		"large_in": func() types.QueryBuilder {
+			return Table(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL, "users").WhereIn("id", ids)
+		},
+	}
+
+	for _, name := range []string{"simple", "many_wheres", "large_in"} {
+		qb := shapes[name]().(*Builder)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := qb.compiler.CompileSelect(qb); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
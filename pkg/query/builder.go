@@ -5,12 +5,26 @@ package query
 import (
 	"context"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/omarhamdy49/go-query-builder/pkg/clauses"
 	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
+// trashedMode selects how soft-deleted rows take part in a query.
+type trashedMode int
+
+const (
+	excludeTrashed trashedMode = iota
+	withTrashed
+	onlyTrashed
+)
+
 // Builder provides a fluent interface for building SQL queries.
 // It supports SELECT, INSERT, UPDATE, DELETE operations with various clauses.
 type Builder struct {
@@ -28,8 +42,23 @@ type Builder struct {
 	offsetValue *int
 	distinct    bool
 	lock        *types.LockType
+	tableSample *float64
+	final       bool
+	readOnly    bool
+	fullTable   bool
+	immutable   bool
+	trashed     trashedMode
+	forceDelete bool
+	withoutPolicies bool
+	policed     bool
+	policies    []*clauses.WhereClause
+	comment     map[string]string
+	maxAffected *int
+	versionColumn string
+	maxRows     int
+	truncate    bool
+	resolver    execution.ConnectionResolver
 	scopes      []types.ScopeFunc
-	bindings    []interface{}
 	compiler    *SQLCompiler
 	execEngine   *execution.QueryExecutor
 }
@@ -47,7 +76,6 @@ func NewBuilder(executor types.QueryExecutor, driver types.Driver) *Builder {
 		havings:     make([]*clauses.HavingClause, 0),
 		unions:      make([]*clauses.UnionClause, 0),
 		scopes:      make([]types.ScopeFunc, 0),
-		bindings:    make([]interface{}, 0),
 		compiler:    NewSQLCompiler(driver),
 		execEngine:   execution.NewQueryExecutor(executor, driver),
 	}
@@ -75,8 +103,9 @@ func (qb *Builder) Clone() types.QueryBuilder {
 		havings:   make([]*clauses.HavingClause, len(qb.havings)),
 		unions:    make([]*clauses.UnionClause, len(qb.unions)),
 		scopes:    make([]types.ScopeFunc, len(qb.scopes)),
-		bindings:  make([]interface{}, len(qb.bindings)),
 		distinct:  qb.distinct,
+		final:     qb.final,
+		fullTable: qb.fullTable,
 		compiler:  NewSQLCompiler(qb.driver),
 		execEngine: execution.NewQueryExecutor(qb.executor, qb.driver),
 	}
@@ -89,7 +118,6 @@ func (qb *Builder) Clone() types.QueryBuilder {
 	copy(clone.havings, qb.havings)
 	copy(clone.unions, qb.unions)
 	copy(clone.scopes, qb.scopes)
-	copy(clone.bindings, qb.bindings)
 
 	if qb.limitValue != nil {
 		limitCopy := *qb.limitValue
@@ -103,18 +131,90 @@ func (qb *Builder) Clone() types.QueryBuilder {
 		lockCopy := *qb.lock
 		clone.lock = &lockCopy
 	}
+	if qb.readOnly {
+		clone.ReadOnly()
+	}
+	if qb.compiler.debug {
+		clone.Debug()
+	}
+	if qb.comment != nil {
+		clone.comment = make(map[string]string, len(qb.comment))
+		for key, value := range qb.comment {
+			clone.comment[key] = value
+		}
+		clone.execEngine.SetComment(clone.comment)
+	}
+	if qb.resolver != nil {
+		clone.SetConnectionResolver(qb.resolver)
+	}
+	if qb.maxAffected != nil {
+		maxCopy := *qb.maxAffected
+		clone.maxAffected = &maxCopy
+	}
+	if qb.tableSample != nil {
+		sampleCopy := *qb.tableSample
+		clone.tableSample = &sampleCopy
+	}
+	clone.versionColumn = qb.versionColumn
+	clone.maxRows = qb.maxRows
+	clone.truncate = qb.truncate
+	clone.immutable = qb.immutable
+	clone.trashed = qb.trashed
+	clone.withoutPolicies = qb.withoutPolicies
+	clone.policed = qb.policed
+	clone.policies = qb.policies
 
 	return clone
 }
 
+// Immutable returns a copy of the builder in copy-on-write mode: every chained call on it returns a
+// new builder and leaves the receiver unchanged, like squirrel, so a base query can be built once
+// and shared between requests and goroutines. Pending scopes are applied to the copy first.
+func (qb *Builder) Immutable() types.QueryBuilder {
+	clone := qb.Clone().(*Builder)
+	clone.applyScopes()
+	clone.immutable = true
+	return clone
+}
+
+// IsImmutable reports whether chained calls return new builders instead of modifying this one.
+func (qb *Builder) IsImmutable() bool {
+	return qb.immutable
+}
+
+// mutable returns the builder a chained call modifies: qb itself, or a copy in immutable mode.
+func (qb *Builder) mutable() *Builder {
+	if !qb.immutable {
+		return qb
+	}
+	return qb.Clone().(*Builder)
+}
+
+// edit runs fn on a copy of the builder that fn may modify in place, even in immutable mode, and
+// returns the copy. Outside immutable mode fn modifies qb itself.
+func (qb *Builder) edit(fn func(*Builder)) *Builder {
+	if !qb.immutable {
+		fn(qb)
+		return qb
+	}
+
+	clone := qb.Clone().(*Builder)
+	clone.immutable = false
+	fn(clone)
+	clone.immutable = true
+	return clone
+}
+
 // From sets the table name for the query.
 func (qb *Builder) From(table string) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.table = table
 	return qb
 }
 
 // Select specifies the columns to be selected in the query.
 func (qb *Builder) Select(columns ...string) types.QueryBuilder {
+	qb = qb.mutable()
 	for _, column := range columns {
 		qb.selects = append(qb.selects, clauses.NewSelectClause(column))
 	}
@@ -123,25 +223,35 @@ func (qb *Builder) Select(columns ...string) types.QueryBuilder {
 
 // SelectRaw adds raw SQL to the SELECT clause with optional bindings.
 func (qb *Builder) SelectRaw(raw string, bindings ...interface{}) types.QueryBuilder {
-	qb.selects = append(qb.selects, clauses.NewSelectRawClause(raw))
-	qb.bindings = append(qb.bindings, bindings...)
+	qb = qb.mutable()
+	qb.selects = append(qb.selects, clauses.NewSelectRawClauseWithBindings(raw, bindings...))
+	return qb
+}
+
+// ClearSelect removes all selected columns so the query selects * again.
+func (qb *Builder) ClearSelect() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.selects = make([]*clauses.SelectClause, 0)
 	return qb
 }
 
 // SelectAs selects a column with an alias.
 func (qb *Builder) SelectAs(column, alias string) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.selects = append(qb.selects, clauses.NewSelectAsClause(column, alias))
 	return qb
 }
 
 // Distinct adds the DISTINCT keyword to the query to eliminate duplicate results.
 func (qb *Builder) Distinct() types.QueryBuilder {
+	qb = qb.mutable()
 	qb.distinct = true
 	return qb
 }
 
 // Where adds a basic WHERE clause to the query.
 func (qb *Builder) Where(column string, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := qb.parseWhereArgs(column, args...)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -150,6 +260,7 @@ func (qb *Builder) Where(column string, args ...interface{}) types.QueryBuilder
 
 // OrWhere adds an OR WHERE clause to the query.
 func (qb *Builder) OrWhere(column string, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := qb.parseWhereArgs(column, args...)
 	clause.SetBoolean(types.Or)
 	qb.wheres = append(qb.wheres, clause)
@@ -158,6 +269,7 @@ func (qb *Builder) OrWhere(column string, args ...interface{}) types.QueryBuilde
 
 // WhereNot adds a WHERE NOT clause to the query.
 func (qb *Builder) WhereNot(column string, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := qb.parseWhereNotArgs(column, args...)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -166,6 +278,7 @@ func (qb *Builder) WhereNot(column string, args ...interface{}) types.QueryBuild
 
 // OrWhereNot adds an OR WHERE NOT clause to the query.
 func (qb *Builder) OrWhereNot(column string, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := qb.parseWhereNotArgs(column, args...)
 	clause.SetBoolean(types.Or)
 	qb.wheres = append(qb.wheres, clause)
@@ -174,24 +287,32 @@ func (qb *Builder) OrWhereNot(column string, args ...interface{}) types.QueryBui
 
 // WhereRaw adds raw SQL to the WHERE clause with optional bindings.
 func (qb *Builder) WhereRaw(raw string, bindings ...interface{}) types.QueryBuilder {
-	clause := clauses.NewWhereRawClause(raw)
+	qb = qb.mutable()
+	clause := clauses.NewWhereRawClause(raw, bindings...)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
-	qb.bindings = append(qb.bindings, bindings...)
+	return qb
+}
+
+// ClearWhere removes all WHERE conditions and their bindings.
+func (qb *Builder) ClearWhere() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.wheres = make([]*clauses.WhereClause, 0)
 	return qb
 }
 
 // OrWhereRaw adds raw SQL to the WHERE clause with OR logic and optional bindings.
 func (qb *Builder) OrWhereRaw(raw string, bindings ...interface{}) types.QueryBuilder {
-	clause := clauses.NewWhereRawClause(raw)
+	qb = qb.mutable()
+	clause := clauses.NewWhereRawClause(raw, bindings...)
 	clause.SetBoolean(types.Or)
 	qb.wheres = append(qb.wheres, clause)
-	qb.bindings = append(qb.bindings, bindings...)
 	return qb
 }
 
 // WhereBetween adds a BETWEEN clause to the query.
 func (qb *Builder) WhereBetween(column string, values []interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(values) != 2 {
 		return qb
 	}
@@ -203,6 +324,7 @@ func (qb *Builder) WhereBetween(column string, values []interface{}) types.Query
 
 // WhereNotBetween adds a NOT BETWEEN clause to the query.
 func (qb *Builder) WhereNotBetween(column string, values []interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(values) != 2 {
 		return qb
 	}
@@ -212,17 +334,92 @@ func (qb *Builder) WhereNotBetween(column string, values []interface{}) types.Qu
 	return qb
 }
 
-// WhereIn adds an IN clause to the query.
-func (qb *Builder) WhereIn(column string, values []interface{}) types.QueryBuilder {
-	clause := clauses.NewWhereInClause(column, values, false)
+// WhereBetweenColumns adds a clause checking that a value lies between two columns (inclusive).
+func (qb *Builder) WhereBetweenColumns(value interface{}, lowColumn, highColumn string) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereBetweenColumnsClause(value, lowColumn, highColumn)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// WhereIn adds an IN clause to the query. values may be a slice of any type, such as []int,
+// []string or []uuid.UUID.
+func (qb *Builder) WhereIn(column string, values interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereInClause(column, inValues(values), false)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// WhereNotIn adds a NOT IN clause to the query. values may be a slice of any type, like WhereIn.
+func (qb *Builder) WhereNotIn(column string, values interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereInClause(column, inValues(values), true)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// inValues returns the elements of an IN list. Common slice types are converted without
+// reflection. Arrays such as uuid.UUID and []byte are single values, not lists.
+func inValues(values interface{}) []interface{} {
+	switch v := values.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case []byte:
+		return []interface{}{v}
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = value
+		}
+		return list
+	case []int:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = value
+		}
+		return list
+	case []int64:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = value
+		}
+		return list
+	}
+
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice {
+		return []interface{}{values}
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list
+}
+
+// WhereIntegerInRaw adds an IN clause with the integers written into the SQL instead of bound as
+// parameters, for ID lists too large for the driver's parameter limit. The values are integers, so
+// they cannot inject SQL. The statement bypasses the compiled SQL cache.
+func (qb *Builder) WhereIntegerInRaw(column string, values []int64) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereIntegerInClause(column, values)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
 	return qb
 }
 
-// WhereNotIn adds a NOT IN clause to the query.
-func (qb *Builder) WhereNotIn(column string, values []interface{}) types.QueryBuilder {
-	clause := clauses.NewWhereInClause(column, values, true)
+// WhereTupleIn adds a clause matching rows whose columns, compared as a tuple, equal one of tuples,
+// as in (tenant_id, sku) IN ((?, ?), (?, ?)). Each tuple lists a value per column, in order. An
+// empty list matches no rows.
+func (qb *Builder) WhereTupleIn(columns []string, tuples [][]interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereTupleInClause(columns, tuples)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
 	return qb
@@ -230,6 +427,7 @@ func (qb *Builder) WhereNotIn(column string, values []interface{}) types.QueryBu
 
 // WhereNull adds an IS NULL clause to the query.
 func (qb *Builder) WhereNull(column string) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := clauses.NewWhereNullClause(column, false)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -238,6 +436,7 @@ func (qb *Builder) WhereNull(column string) types.QueryBuilder {
 
 // WhereNotNull adds an IS NOT NULL clause to the query.
 func (qb *Builder) WhereNotNull(column string) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := clauses.NewWhereNullClause(column, true)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -246,6 +445,7 @@ func (qb *Builder) WhereNotNull(column string) types.QueryBuilder {
 
 // WhereExists adds an EXISTS clause with a subquery to the query.
 func (qb *Builder) WhereExists(query types.QueryBuilder) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := clauses.NewWhereExistsClause(query, false)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -254,6 +454,7 @@ func (qb *Builder) WhereExists(query types.QueryBuilder) types.QueryBuilder {
 
 // WhereNotExists adds a NOT EXISTS clause with a subquery to the query.
 func (qb *Builder) WhereNotExists(query types.QueryBuilder) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := clauses.NewWhereExistsClause(query, true)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -343,11 +544,13 @@ func (qb *Builder) RightJoin(table, first string, args ...interface{}) types.Que
 
 // CrossJoin adds a CROSS JOIN clause to the query.
 func (qb *Builder) CrossJoin(table string) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.joins = append(qb.joins, clauses.NewCrossJoinClause(table))
 	return qb
 }
 
 func (qb *Builder) addJoin(joinType types.JoinType, table, first string, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	var operator types.Operator
 	var second string
 
@@ -370,6 +573,7 @@ func (qb *Builder) addJoin(joinType types.JoinType, table, first string, args ..
 
 // OrderBy adds an ORDER BY clause to the query.
 func (qb *Builder) OrderBy(column string, direction ...types.OrderDirection) types.QueryBuilder {
+	qb = qb.mutable()
 	dir := types.Asc
 	if len(direction) > 0 {
 		dir = direction[0]
@@ -384,14 +588,77 @@ func (qb *Builder) OrderByDesc(column string) types.QueryBuilder {
 	return qb.OrderBy(column, types.Desc)
 }
 
+// Latest orders the results by the given column descending, defaulting to the table's creation
+// timestamp column (created_at unless registered otherwise).
+func (qb *Builder) Latest(column ...string) types.QueryBuilder {
+	return qb.OrderBy(qb.timestampColumn(column), types.Desc)
+}
+
+// Oldest orders the results by the given column ascending, defaulting to the table's creation
+// timestamp column.
+func (qb *Builder) Oldest(column ...string) types.QueryBuilder {
+	return qb.OrderBy(qb.timestampColumn(column), types.Asc)
+}
+
+func (qb *Builder) timestampColumn(column []string) string {
+	if len(column) > 0 && column[0] != "" {
+		return column[0]
+	}
+	return schema.CreatedAtColumn(qb.table)
+}
+
+// Reorder removes all ORDER BY clauses.
+func (qb *Builder) Reorder() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.orders = make([]*clauses.OrderClause, 0)
+	return qb
+}
+
 // OrderByRaw adds raw SQL to the ORDER BY clause.
 func (qb *Builder) OrderByRaw(raw string) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.orders = append(qb.orders, clauses.NewOrderRawClause(raw))
 	return qb
 }
 
+// OrderByNullsFirst adds an ORDER BY clause that places NULL values first.
+func (qb *Builder) OrderByNullsFirst(column string, direction ...types.OrderDirection) types.QueryBuilder {
+	return qb.OrderByMany([]types.OrderSpec{{Column: column, Direction: firstDirection(direction), Nulls: types.NullsFirst}})
+}
+
+// OrderByNullsLast adds an ORDER BY clause that places NULL values last.
+func (qb *Builder) OrderByNullsLast(column string, direction ...types.OrderDirection) types.QueryBuilder {
+	return qb.OrderByMany([]types.OrderSpec{{Column: column, Direction: firstDirection(direction), Nulls: types.NullsLast}})
+}
+
+// OrderByMany adds several ORDER BY clauses in the given order. Empty directions default to ascending.
+func (qb *Builder) OrderByMany(orders []types.OrderSpec) types.QueryBuilder {
+	qb = qb.mutable()
+	for _, order := range orders {
+		dir := order.Direction
+		if dir == "" {
+			dir = types.Asc
+		}
+
+		if order.Nulls != "" {
+			qb.orders = append(qb.orders, clauses.NewOrderNullsClause(order.Column, dir, order.Nulls))
+		} else {
+			qb.orders = append(qb.orders, clauses.NewOrderClause(order.Column, dir))
+		}
+	}
+	return qb
+}
+
+func firstDirection(direction []types.OrderDirection) types.OrderDirection {
+	if len(direction) > 0 {
+		return direction[0]
+	}
+	return types.Asc
+}
+
 // GroupBy adds a GROUP BY clause to the query.
 func (qb *Builder) GroupBy(columns ...string) types.QueryBuilder {
+	qb = qb.mutable()
 	for _, column := range columns {
 		qb.groups = append(qb.groups, clauses.NewGroupClause(column))
 	}
@@ -400,6 +667,7 @@ func (qb *Builder) GroupBy(columns ...string) types.QueryBuilder {
 
 // GroupByRaw adds raw SQL to the GROUP BY clause.
 func (qb *Builder) GroupByRaw(raw string) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.groups = append(qb.groups, clauses.NewGroupRawClause(raw))
 	return qb
 }
@@ -414,23 +682,52 @@ func (qb *Builder) OrHaving(column string, args ...interface{}) types.QueryBuild
 	return qb.addHaving(column, types.Or, args...)
 }
 
-// HavingRaw adds raw SQL to the HAVING clause.
-func (qb *Builder) HavingRaw(raw string) types.QueryBuilder {
-	clause := clauses.NewHavingRawClause(raw)
+// HavingRaw adds raw SQL to the HAVING clause, binding its placeholders to the given values.
+func (qb *Builder) HavingRaw(raw string, bindings ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewHavingRawClause(raw, bindings...)
 	clause.SetBoolean(types.And)
 	qb.havings = append(qb.havings, clause)
 	return qb
 }
 
-// OrHavingRaw adds raw SQL to the HAVING clause with OR logic.
-func (qb *Builder) OrHavingRaw(raw string) types.QueryBuilder {
-	clause := clauses.NewHavingRawClause(raw)
+// OrHavingRaw adds raw SQL to the HAVING clause with OR logic, binding its placeholders to the given values.
+func (qb *Builder) OrHavingRaw(raw string, bindings ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewHavingRawClause(raw, bindings...)
 	clause.SetBoolean(types.Or)
 	qb.havings = append(qb.havings, clause)
 	return qb
 }
 
+// HavingCount filters groups by their number of rows, e.g. HavingCount(">", 5) for COUNT(*) > 5.
+// The arguments are those of Having.
+func (qb *Builder) HavingCount(args ...interface{}) types.QueryBuilder {
+	return qb.addHaving(fmt.Sprintf("%s(*)", types.Count), types.And, args...)
+}
+
+// HavingSum filters groups by the sum of column, e.g. HavingSum("total", ">=", 1000).
+func (qb *Builder) HavingSum(column string, args ...interface{}) types.QueryBuilder {
+	return qb.addHaving(fmt.Sprintf("%s(%s)", types.Sum, column), types.And, args...)
+}
+
+// HavingAvg filters groups by the average of column.
+func (qb *Builder) HavingAvg(column string, args ...interface{}) types.QueryBuilder {
+	return qb.addHaving(fmt.Sprintf("%s(%s)", types.Avg, column), types.And, args...)
+}
+
+// HavingMin filters groups by the smallest value of column.
+func (qb *Builder) HavingMin(column string, args ...interface{}) types.QueryBuilder {
+	return qb.addHaving(fmt.Sprintf("%s(%s)", types.Min, column), types.And, args...)
+}
+
+// HavingMax filters groups by the largest value of column.
+func (qb *Builder) HavingMax(column string, args ...interface{}) types.QueryBuilder {
+	return qb.addHaving(fmt.Sprintf("%s(%s)", types.Max, column), types.And, args...)
+}
+
 func (qb *Builder) addHaving(column string, boolean types.BooleanOperator, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(args) == 0 {
 		return qb
 	}
@@ -443,10 +740,10 @@ func (qb *Builder) addHaving(column string, boolean types.BooleanOperator, args
 		operator = types.OpEqual
 		value = args[0]
 	case 2:
-		operator = types.Operator(fmt.Sprintf("%v", args[0]))
+		operator = types.Operator(strings.TrimSpace(fmt.Sprintf("%v", args[0])))
 		value = args[1]
 	default:
-		operator = types.Operator(fmt.Sprintf("%v", args[0]))
+		operator = types.Operator(strings.TrimSpace(fmt.Sprintf("%v", args[0])))
 		value = args[1]
 	}
 
@@ -458,16 +755,67 @@ func (qb *Builder) addHaving(column string, boolean types.BooleanOperator, args
 
 // Limit adds a LIMIT clause to the query.
 func (qb *Builder) Limit(limit int) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.limitValue = &limit
 	return qb
 }
 
 // Offset adds an OFFSET clause to the query.
 func (qb *Builder) Offset(offset int) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.offsetValue = &offset
 	return qb
 }
 
+// InRandomOrder orders the results randomly.
+func (qb *Builder) InRandomOrder() types.QueryBuilder {
+	qb = qb.mutable()
+	switch qb.driver {
+	case types.PostgreSQL:
+		return qb.OrderByRaw("RANDOM()")
+	case types.ClickHouse:
+		return qb.OrderByRaw("rand()")
+	}
+	return qb.OrderByRaw("RAND()")
+}
+
+// Sample returns n rows picked uniformly at random.
+func (qb *Builder) Sample(n int) types.QueryBuilder {
+	return qb.InRandomOrder().Limit(n)
+}
+
+// SamplePercent returns roughly percent% of the table's rows. PostgreSQL uses TABLESAMPLE BERNOULLI
+// and ClickHouse the SAMPLE clause (the table needs a sampling key), which avoid sorting the whole
+// table; MySQL falls back to filtering on RAND().
+func (qb *Builder) SamplePercent(percent float64) types.QueryBuilder {
+	qb = qb.mutable()
+	if qb.driver == types.PostgreSQL || qb.driver == types.ClickHouse {
+		qb.tableSample = &percent
+		return qb
+	}
+
+	clause := clauses.NewWhereRawClause("RAND() < " + formatFloat(percent/100))
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// Final adds ClickHouse's FINAL modifier, which merges the rows of ReplacingMergeTree and similar
+// engines at read time so only their final versions are returned. Other drivers ignore it.
+func (qb *Builder) Final() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.final = true
+	return qb
+}
+
+// ClearLimit removes the LIMIT and OFFSET from the query.
+func (qb *Builder) ClearLimit() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.limitValue = nil
+	qb.offsetValue = nil
+	return qb
+}
+
 // Take is an alias for Limit that sets the maximum number of records to retrieve.
 func (qb *Builder) Take(limit int) types.QueryBuilder {
 	return qb.Limit(limit)
@@ -480,18 +828,45 @@ func (qb *Builder) Skip(offset int) types.QueryBuilder {
 
 // Union adds a UNION clause to combine results with another query.
 func (qb *Builder) Union(query types.QueryBuilder) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.unions = append(qb.unions, clauses.NewUnionClause(query))
 	return qb
 }
 
 // UnionAll adds a UNION ALL clause to combine results with another query including duplicates.
 func (qb *Builder) UnionAll(query types.QueryBuilder) types.QueryBuilder {
+	qb = qb.mutable()
 	qb.unions = append(qb.unions, clauses.NewUnionAllClause(query))
 	return qb
 }
 
+// Intersect adds an INTERSECT clause keeping only the rows also returned by query. MySQL servers
+// known to predate 8.0.31 get an equivalent EXISTS query, which needs plain selected columns on
+// both sides.
+func (qb *Builder) Intersect(query types.QueryBuilder) types.QueryBuilder {
+	qb = qb.mutable()
+	qb.unions = append(qb.unions, clauses.NewIntersectClause(query))
+	return qb
+}
+
+// Except adds an EXCEPT clause removing the rows returned by query, emulated with NOT EXISTS on
+// MySQL servers known to predate 8.0.31 like Intersect.
+func (qb *Builder) Except(query types.QueryBuilder) types.QueryBuilder {
+	qb = qb.mutable()
+	qb.unions = append(qb.unions, clauses.NewExceptClause(query))
+	return qb
+}
+
+// UnionOrderBy orders the combined result of the query and its unions. On a query with unions,
+// OrderBy, Limit and Offset always apply to the combined result; each side keeps its own ordering
+// and limit inside its parentheses. UnionOrderBy is the same as OrderBy but makes that explicit.
+func (qb *Builder) UnionOrderBy(column string, direction ...types.OrderDirection) types.QueryBuilder {
+	return qb.OrderBy(column, direction...)
+}
+
 // ForUpdate adds a FOR UPDATE lock clause to the query.
 func (qb *Builder) ForUpdate() types.QueryBuilder {
+	qb = qb.mutable()
 	lock := types.ForUpdate
 	qb.lock = &lock
 	return qb
@@ -499,6 +874,7 @@ func (qb *Builder) ForUpdate() types.QueryBuilder {
 
 // ForShare adds a FOR SHARE lock clause to the query.
 func (qb *Builder) ForShare() types.QueryBuilder {
+	qb = qb.mutable()
 	lock := types.ForShare
 	qb.lock = &lock
 	return qb
@@ -522,24 +898,144 @@ func (qb *Builder) Unless(condition bool, callback types.ConditionalFunc) types.
 
 // Tap applies the callback function without modifying the query and returns the builder.
 func (qb *Builder) Tap(callback types.ConditionalFunc) types.QueryBuilder {
-	callback(qb)
-	return qb
+	return qb.edit(func(b *Builder) { callback(b) })
 }
 
-// Scope applies one or more scope functions to the query builder.
+// Scope applies one or more scope functions to the query builder. They run when the query is
+// compiled, or right away on an immutable builder, which is never modified afterwards.
 func (qb *Builder) Scope(scopes ...types.ScopeFunc) types.QueryBuilder {
+	if qb.immutable {
+		return qb.edit(func(b *Builder) {
+			b.scopes = append(b.scopes, scopes...)
+			b.applyScopes()
+		})
+	}
 	qb.scopes = append(qb.scopes, scopes...)
 	return qb
 }
 
-// Debug enables debug mode for the query builder to capture SQL compilation info.
+// ReadOnly makes every write through this builder and its clones return types.ErrReadOnly.
+func (qb *Builder) ReadOnly() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.readOnly = true
+	qb.execEngine.SetReadOnly(true)
+	return qb
+}
+
+// Comment tags the builder's statements with a leading sqlcommenter-style SQL comment built from
+// "key=value" pairs separated by commas, e.g. Comment("service=checkout,endpoint=POST /orders"),
+// so slow query logs can be traced back to the call site. Repeated calls add to the tags.
+func (qb *Builder) Comment(tags string) types.QueryBuilder {
+	qb = qb.mutable()
+	if qb.comment == nil {
+		qb.comment = make(map[string]string)
+	}
+	for key, value := range execution.ParseCommentTags(tags) {
+		qb.comment[key] = value
+	}
+	qb.execEngine.SetComment(qb.comment)
+	return qb
+}
+
+// SetConnectionResolver lets a context from execution.WithConnection send the builder's statements
+// to another connection, looked up by resolver. It is set by the root package's Table.
+func (qb *Builder) SetConnectionResolver(resolver execution.ConnectionResolver) *Builder {
+	qb.resolver = resolver
+	qb.execEngine.SetConnectionResolver(resolver)
+	return qb
+}
+
+// AllowFullTableMutation lets Update and Delete run without a WHERE clause. Without it, such calls
+// return types.ErrUnconditionedMutation.
+func (qb *Builder) AllowFullTableMutation() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.fullTable = true
+	return qb
+}
+
+// AllowsFullTableMutation returns true if Update and Delete may run without a WHERE clause.
+func (qb *Builder) AllowsFullTableMutation() bool {
+	return qb.fullTable
+}
+
+// MaxAffected makes Update and Delete count the matching rows first and fail with
+// types.ErrMaxAffectedExceeded, without writing anything, when more than n rows would be touched.
+func (qb *Builder) MaxAffected(n int) types.QueryBuilder {
+	qb = qb.mutable()
+	qb.maxAffected = &n
+	return qb
+}
+
+// GetMaxAffected returns the MaxAffected limit for the query, if any.
+func (qb *Builder) GetMaxAffected() *int {
+	return qb.maxAffected
+}
+
+// WithVersioning turns on optimistic locking for Update: values must hold the version the caller
+// read in column, the update only matches the row while it still has that version, and column is
+// incremented in the same statement. An update that matches no row fails with
+// types.ErrStaleRecord.
+func (qb *Builder) WithVersioning(column string) types.QueryBuilder {
+	qb = qb.mutable()
+	qb.versionColumn = column
+	return qb
+}
+
+// GetVersionColumn returns the column set by WithVersioning, or "".
+func (qb *Builder) GetVersionColumn() string {
+	return qb.versionColumn
+}
+
+// MaxRows makes Get fail with types.ErrTooManyRows as soon as it scans more than n rows, instead
+// of materializing the whole result. It applies to everything that reads through Get, such as
+// First, Find, FindMany and Pluck; Cursor, Chunk and Lazy stream and ignore it.
+func (qb *Builder) MaxRows(n int) types.QueryBuilder {
+	qb = qb.mutable()
+	qb.maxRows = n
+	qb.truncate = false
+	return qb
+}
+
+// MaxRowsTruncate is like MaxRows, but Get returns the first n rows instead of failing and reports
+// the truncation to the logger set with SetLogger.
+func (qb *Builder) MaxRowsTruncate(n int) types.QueryBuilder {
+	qb = qb.mutable()
+	qb.maxRows = n
+	qb.truncate = true
+	return qb
+}
+
+// GetMaxRows returns the MaxRows limit for the query, 0 if there is none, and whether rows past it
+// are dropped rather than failing the query.
+func (qb *Builder) GetMaxRows() (n int, truncate bool) {
+	return qb.maxRows, qb.truncate
+}
+
+// Debug enables debug mode: the builder captures the SQL, bindings, duration and driver of each
+// compilation and execution, readable with GetDebugInfo.
 func (qb *Builder) Debug() types.QueryBuilder {
+	qb = qb.mutable()
 	qb.compiler.Debug()
+	qb.execEngine.SetDebug(true)
 	return qb
 }
 
+// GetDebugInfo returns the last statement executed in debug mode or, if nothing ran yet, the last
+// compiled one. It returns nil unless Debug was called.
+func (qb *Builder) GetDebugInfo() *types.DebugInfo {
+	if info := qb.execEngine.DebugInfo(); info != nil {
+		return info
+	}
+	return qb.compiler.GetDebugInfo()
+}
+
 func (qb *Builder) applyScopes() {
-	for _, scope := range qb.scopes {
+	if len(qb.scopes) == 0 {
+		return
+	}
+	scopes := qb.scopes
+	qb.scopes = make([]types.ScopeFunc, 0)
+	for _, scope := range scopes {
 		scope(qb)
 	}
 }
@@ -547,7 +1043,28 @@ func (qb *Builder) applyScopes() {
 // ToSQL compiles the query builder into a SQL string and bindings.
 func (qb *Builder) ToSQL() (string, []interface{}, error) {
 	qb.applyScopes()
-	return qb.compiler.CompileSelect(qb)
+	return qb.guard("select")(qb.compiler.CompileSelect(qb))
+}
+
+// ToUpdateSQL compiles the query into an UPDATE statement that sets the given values.
+func (qb *Builder) ToUpdateSQL(values map[string]interface{}) (string, []interface{}, error) {
+	return qb.guard("update")(qb.compiler.CompileUpdate(qb, values))
+}
+
+// ToInsertUsingSQL compiles an INSERT INTO ... SELECT statement for the given columns and subquery.
+func (qb *Builder) ToInsertUsingSQL(columns []string, sub types.QueryBuilder) (string, []interface{}, error) {
+	return qb.compiler.CompileInsertUsing(qb, columns, sub)
+}
+
+// ToDeleteSQL compiles the query into a DELETE statement, or into an UPDATE that stamps the soft
+// delete column when the table registered one.
+func (qb *Builder) ToDeleteSQL() (string, []interface{}, error) {
+	if column := schema.Lookup(qb.table).SoftDelete; column != "" && !qb.forceDelete {
+		now := time.Now()
+		values := schema.StampUpdate(qb.table, map[string]interface{}{column: now}, now)
+		return qb.guard("delete")(qb.compiler.CompileUpdate(qb, values))
+	}
+	return qb.guard("delete")(qb.compiler.CompileDelete(qb))
 }
 
 // Get executes the query and returns all results as a collection.
@@ -565,6 +1082,18 @@ func (qb *Builder) Find(ctx context.Context, id interface{}) (map[string]interfa
 	return qb.execEngine.Find(ctx, qb, id)
 }
 
+// FindOrFail retrieves a record by its primary key ID, failing with a *types.NotFoundError when
+// there is none.
+func (qb *Builder) FindOrFail(ctx context.Context, id interface{}) (map[string]interface{}, error) {
+	return qb.execEngine.FindOrFail(ctx, qb, id)
+}
+
+// FindMany retrieves the records whose primary key is one of ids, a slice of any element type.
+// Missing IDs are skipped.
+func (qb *Builder) FindMany(ctx context.Context, ids interface{}) (types.Collection, error) {
+	return qb.execEngine.FindMany(ctx, qb, inValues(ids))
+}
+
 // Pluck retrieves all values from a single column as a slice.
 func (qb *Builder) Pluck(ctx context.Context, column string) ([]interface{}, error) {
 	return qb.execEngine.Pluck(ctx, qb, column)
@@ -575,6 +1104,43 @@ func (qb *Builder) Count(ctx context.Context) (int64, error) {
 	return qb.execEngine.Count(ctx, qb)
 }
 
+// ExportCSV streams the results to w as CSV without loading them into memory.
+func (qb *Builder) ExportCSV(ctx context.Context, w io.Writer, options ...types.ExportOptions) error {
+	return qb.execEngine.ExportCSV(ctx, qb, w, exportOptions(options))
+}
+
+// ExportNDJSON streams the results to w as newline-delimited JSON without loading them into memory.
+func (qb *Builder) ExportNDJSON(ctx context.Context, w io.Writer, options ...types.ExportOptions) error {
+	return qb.execEngine.ExportNDJSON(ctx, qb, w, exportOptions(options))
+}
+
+// Stream sends the results one row at a time over a bounded channel, so arbitrarily large results
+// can be processed with backpressure. Read the error channel after the row channel is closed, and
+// cancel ctx to stop early.
+func (qb *Builder) Stream(ctx context.Context, bufferSize ...int) (<-chan map[string]interface{}, <-chan error) {
+	return qb.execEngine.Stream(ctx, qb, bufferSize...)
+}
+
+// SnapshotChunk processes the results in chunks of size rows inside a single read-only REPEATABLE
+// READ transaction, so every chunk sees the same snapshot of the data. PostgreSQL fetches the chunks
+// through a server-side cursor.
+func (qb *Builder) SnapshotChunk(ctx context.Context, size int, callback types.ChunkFunc) error {
+	return qb.execEngine.SnapshotChunk(ctx, qb, size, callback)
+}
+
+// ServerCursorChunk processes the results in chunks of size rows fetched from a PostgreSQL
+// server-side cursor inside a read-only REPEATABLE READ transaction. Other drivers fail.
+func (qb *Builder) ServerCursorChunk(ctx context.Context, size int, callback types.ChunkFunc) error {
+	return qb.execEngine.ServerCursorChunk(ctx, qb, size, callback)
+}
+
+func exportOptions(options []types.ExportOptions) types.ExportOptions {
+	if len(options) > 0 {
+		return options[0]
+	}
+	return types.ExportOptions{}
+}
+
 // Paginate executes a paginated query with full metadata including total count.
 func (qb *Builder) Paginate(ctx context.Context, page int, perPage int) (types.PaginationResult, error) {
 	// Validate input parameters
@@ -589,16 +1155,7 @@ func (qb *Builder) Paginate(ctx context.Context, page int, perPage int) (types.P
 	offset := (page - 1) * perPage
 
 	// Get total count using a clone to avoid affecting the original query
-	countQuery := qb.Clone()
-	// Remove limit and offset for count query
-	countQuery.(*Builder).limitValue = nil
-	countQuery.(*Builder).offsetValue = nil
-	// Clear selects for count query to avoid issues with GROUP BY
-	if len(qb.groups) == 0 {
-		countQuery.(*Builder).selects = make([]*clauses.SelectClause, 0)
-	}
-
-	total, err := countQuery.Count(ctx)
+	total, err := qb.countTotal(ctx)
 	if err != nil {
 		return types.PaginationResult{}, fmt.Errorf("failed to get total count: %w", err)
 	}
@@ -647,6 +1204,17 @@ func (qb *Builder) Paginate(ctx context.Context, page int, perPage int) (types.P
 	return result, nil
 }
 
+// countTotal counts the rows of the query without its limit and order. Grouped, distinct and
+// unioned queries return one row per group or distinct value, so they are counted as a subquery;
+// plain queries keep the cheaper SELECT count(*).
+func (qb *Builder) countTotal(ctx context.Context) (int64, error) {
+	countQuery := qb.Clone().ClearLimit().Reorder().(*Builder)
+	if len(qb.groups) > 0 || len(qb.havings) > 0 || len(qb.unions) > 0 || qb.distinct {
+		return qb.execEngine.CountRows(ctx, countQuery)
+	}
+	return countQuery.ClearSelect().Count(ctx)
+}
+
 // SimplePaginate executes a paginated query without calculating total count for better performance.
 func (qb *Builder) SimplePaginate(ctx context.Context, page int, perPage int) (types.PaginationResult, error) {
 	// Validate input parameters
@@ -775,16 +1343,115 @@ func (qb *Builder) InsertBatch(ctx context.Context, values []map[string]interfac
 	return qb.execEngine.InsertBatch(ctx, qb, values)
 }
 
+// InsertBatchWithOptions executes a batch INSERT split into sub-batches, optionally using COPY on PostgreSQL.
+func (qb *Builder) InsertBatchWithOptions(ctx context.Context, values []map[string]interface{}, options types.BulkInsertOptions) error {
+	return qb.execEngine.InsertBatchWithOptions(ctx, qb, values, options)
+}
+
+// InsertBatchPartial inserts rows one by one, continuing past failures, and reports failed rows as a *types.BatchError.
+func (qb *Builder) InsertBatchPartial(ctx context.Context, values []map[string]interface{}) (int64, error) {
+	return qb.execEngine.InsertBatchPartial(ctx, qb, values)
+}
+
+// InsertUsing inserts the rows selected by sub into the given columns and returns the number of rows inserted.
+func (qb *Builder) InsertUsing(ctx context.Context, columns []string, sub types.QueryBuilder) (int64, error) {
+	return qb.execEngine.InsertUsing(ctx, qb, columns, sub)
+}
+
+// Upsert inserts values, updating the rows that conflict with existing ones as options say, and
+// reports how many rows were inserted and updated.
+func (qb *Builder) Upsert(ctx context.Context, values []map[string]interface{}, options types.UpsertOptions) (*types.UpsertResult, error) {
+	return qb.execEngine.Upsert(ctx, qb, values, options)
+}
+
+// ImportCSV bulk-inserts the rows of a CSV file with a header row into the table. Rows that fail
+// to parse or validate are skipped and reported.
+func (qb *Builder) ImportCSV(ctx context.Context, r io.Reader, options ...types.ImportOptions) (*types.ImportReport, error) {
+	return qb.execEngine.ImportCSV(ctx, qb, r, importOptions(options))
+}
+
+// ImportNDJSON bulk-inserts newline-delimited JSON objects into the table like ImportCSV.
+func (qb *Builder) ImportNDJSON(ctx context.Context, r io.Reader, options ...types.ImportOptions) (*types.ImportReport, error) {
+	return qb.execEngine.ImportNDJSON(ctx, qb, r, importOptions(options))
+}
+
+func importOptions(options []types.ImportOptions) types.ImportOptions {
+	if len(options) > 0 {
+		return options[0]
+	}
+	return types.ImportOptions{}
+}
+
 // Update executes an UPDATE query and returns the number of affected rows.
 func (qb *Builder) Update(ctx context.Context, values map[string]interface{}) (int64, error) {
 	return qb.execEngine.Update(ctx, qb, values)
 }
 
+// UpdateBatch updates many rows in one statement, each row naming its primary key columns and
+// the values to set. It returns the number of affected rows.
+func (qb *Builder) UpdateBatch(ctx context.Context, rows []map[string]interface{}) (int64, error) {
+	return qb.execEngine.UpdateBatch(ctx, qb, rows)
+}
+
 // Delete executes a DELETE query and returns the number of affected rows.
 func (qb *Builder) Delete(ctx context.Context) (int64, error) {
 	return qb.execEngine.Delete(ctx, qb)
 }
 
+// WithTrashed includes soft-deleted rows, which are otherwise excluded from every query on a table
+// with a soft delete column.
+func (qb *Builder) WithTrashed() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.trashed = withTrashed
+	return qb
+}
+
+// OnlyTrashed restricts the query to soft-deleted rows.
+func (qb *Builder) OnlyTrashed() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.trashed = onlyTrashed
+	return qb
+}
+
+// ForceDelete removes the matching rows with a DELETE even when the table soft deletes.
+func (qb *Builder) ForceDelete(ctx context.Context) (int64, error) {
+	clone := qb.Clone().(*Builder)
+	clone.forceDelete = true
+	return clone.execEngine.Delete(ctx, clone)
+}
+
+// Restore clears the soft delete column of the matching rows, soft-deleted ones only unless
+// WithTrashed was called, and returns the number of restored rows.
+func (qb *Builder) Restore(ctx context.Context) (int64, error) {
+	column := schema.Lookup(qb.table).SoftDelete
+	if column == "" {
+		return 0, fmt.Errorf("table %s has no soft delete column", qb.table)
+	}
+
+	clone := qb.Clone().(*Builder)
+	if clone.trashed == excludeTrashed {
+		clone.trashed = onlyTrashed
+	}
+	return clone.execEngine.Update(ctx, clone, map[string]interface{}{column: nil})
+}
+
+// softDeleteCondition returns the condition that applies the table's soft delete column to the
+// query, or "" when the table has none or WithTrashed was called. The column is qualified when the
+// query joins other tables.
+func (qb *Builder) softDeleteCondition() string {
+	column := schema.Lookup(qb.table).SoftDelete
+	if column == "" || qb.trashed == withTrashed {
+		return ""
+	}
+	if len(qb.joins) > 0 {
+		column = tableQualifier(qb.table) + "." + column
+	}
+	if qb.trashed == onlyTrashed {
+		return column + " IS NOT NULL"
+	}
+	return column + " IS NULL"
+}
+
 // GetTable returns the table name for the query.
 func (qb *Builder) GetTable() string {
 	return qb.table
@@ -835,6 +1502,16 @@ func (qb *Builder) GetOffset() *int {
 	return qb.offsetValue
 }
 
+// GetTableSample returns the TABLESAMPLE percentage for the query, if any.
+func (qb *Builder) GetTableSample() *float64 {
+	return qb.tableSample
+}
+
+// IsFinal reports whether the query has ClickHouse's FINAL modifier.
+func (qb *Builder) IsFinal() bool {
+	return qb.final
+}
+
 // IsDistinct returns true if the query has the DISTINCT modifier.
 func (qb *Builder) IsDistinct() bool {
 	return qb.distinct
@@ -845,9 +1522,12 @@ func (qb *Builder) GetLock() *types.LockType {
 	return qb.lock
 }
 
-// GetBindings returns the parameter bindings for the query.
+// GetBindings returns the parameter bindings for the query, in the order of their placeholders in
+// the compiled SELECT. Each clause keeps its own bindings, so they follow the clauses' positions.
 func (qb *Builder) GetBindings() []interface{} {
-	return qb.bindings
+	qb.applyScopes()
+	_, bindings := qb.compiler.compileSelect(qb)
+	return bindings
 }
 
 // GetDriver returns the database driver type.
@@ -2,8 +2,22 @@ package query
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
-
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/optimization"
+	"github.com/omarhamdy49/go-query-builder/pkg/ratelimit"
+	"github.com/omarhamdy49/go-query-builder/pkg/schema"
+	"github.com/omarhamdy49/go-query-builder/pkg/security"
+	"github.com/omarhamdy49/go-query-builder/pkg/sqlformat"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
@@ -138,6 +152,70 @@ func TestWhereIn(t *testing.T) {
 	}
 }
 
+// testUUID stands in for uuid.UUID, a byte array bound as a single value.
+type testUUID [16]byte
+
+func TestWhereInTypedSlices(t *testing.T) {
+	a, b := testUUID{1}, testUUID{2}
+	tests := []struct {
+		values interface{}
+		want   []interface{}
+	}{
+		{[]int{1, 2}, []interface{}{1, 2}},
+		{[]int64{3}, []interface{}{int64(3)}},
+		{[]string{"admin", "editor"}, []interface{}{"admin", "editor"}},
+		{[]testUUID{a, b}, []interface{}{a, b}},
+		{[]uint8{7}, []interface{}{[]byte{7}}},
+		{a, []interface{}{a}},
+		{"admin", []interface{}{"admin"}},
+	}
+
+	for _, tt := range tests {
+		qb := Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "users").WhereNotIn("id", tt.values)
+		_, bindings, err := qb.ToSQL()
+		if err != nil {
+			t.Fatalf("ToSQL failed: %v", err)
+		}
+		if !reflect.DeepEqual(bindings, tt.want) {
+			t.Errorf("%T: expected bindings %v, got %v", tt.values, tt.want, bindings)
+		}
+	}
+}
+
+func TestWhereInEmptyList(t *testing.T) {
+	users := func() types.QueryBuilder {
+		return Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "users").Where("active", true)
+	}
+
+	tests := []struct {
+		qb   types.QueryBuilder
+		want string
+	}{
+		{users().WhereIn("id", []interface{}{}), "SELECT * FROM users WHERE active = ? AND 1 = 0"},
+		{users().WhereNotIn("id", nil), "SELECT * FROM users WHERE active = ? AND 1 = 1"},
+		{users().WhereIntegerInRaw("id", nil), "SELECT * FROM users WHERE active = ? AND 1 = 0"},
+		{users().WhereIntegerInRaw("id", []int64{3, -1, 42}), "SELECT * FROM users WHERE active = ? AND id IN (3, -1, 42)"},
+	}
+	for _, tt := range tests {
+		sql, bindings, err := tt.qb.ToSQL()
+		if err != nil {
+			t.Fatalf("ToSQL failed: %v", err)
+		}
+		if sql != tt.want || len(bindings) != 1 {
+			t.Errorf("Expected %s with one binding, got %s %v", tt.want, sql, bindings)
+		}
+	}
+
+	ids := make([]int64, 70000)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	sql, bindings, err := users().WhereIntegerInRaw("id", ids).ToSQL()
+	if err != nil || len(bindings) != 1 || !strings.HasSuffix(sql, ", 69999)") {
+		t.Errorf("Expected the IDs inlined past the parameter limit, got %d bindings, %v", len(bindings), err)
+	}
+}
+
 func TestWhereBetween(t *testing.T) {
 	executor := &MockExecutor{driver: types.MySQL}
 	qb := NewBuilder(executor, types.MySQL)
@@ -302,22 +380,57 @@ func TestPostgreSQLPlaceholders(t *testing.T) {
 	qb := NewBuilder(executor, types.PostgreSQL)
 	qb.table = "users"
 
-	qb.Where("age", ">", 18).Where("status", "active")
-	
-	// Note: This test would need the compiler to properly handle PostgreSQL placeholders
-	// The current implementation doesn't fully support $1, $2 style placeholders yet
+	sub := NewBuilder(executor, types.PostgreSQL)
+	sub.table = "orders"
+	sub.WhereRaw("orders.user_id = users.id").Where("total", ">", 100)
+
+	qb.Where("age", ">", 18).WhereExists(sub).WhereRaw("note <> 'what?'").Where("status", "active")
+
 	sql, bindings, err := qb.ToSQL()
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	// For now, we just check that it doesn't error
-	if sql == "" {
-		t.Error("Expected non-empty SQL")
+	expectedSQL := "SELECT * FROM users WHERE age > $1 AND EXISTS (SELECT * FROM orders WHERE orders.user_id = users.id AND total > $2) AND note <> 'what?' AND status = $3"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
 	}
-	
-	if len(bindings) != 2 {
-		t.Errorf("Expected 2 bindings, got %d", len(bindings))
+
+	if len(bindings) != 3 {
+		t.Errorf("Expected 3 bindings, got %d", len(bindings))
+	}
+}
+
+func TestCompileUpdateAndDelete(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "users"
+	qb.Where("id", 7)
+
+	sql, bindings, err := qb.ToUpdateSQL(map[string]interface{}{"visits": types.Raw("visits + ?", 2)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedSQL := "UPDATE users SET visits = visits + $1 WHERE id = $2"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+	if len(bindings) != 2 || bindings[0] != 2 || bindings[1] != 7 {
+		t.Errorf("Expected bindings [2 7], got: %v", bindings)
+	}
+
+	sql, _, err = qb.ToDeleteSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sql != "DELETE FROM users WHERE id = $1" {
+		t.Errorf("Unexpected delete SQL: %s", sql)
+	}
+
+	all := NewBuilder(executor, types.PostgreSQL)
+	all.table = "users"
+	if _, _, err := all.ToDeleteSQL(); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Errorf("Expected ErrUnconditionedMutation, got: %v", err)
 	}
 }
 
@@ -368,4 +481,2685 @@ func TestConditionalQueries(t *testing.T) {
 	if len(bindings) != len(expectedBindings) {
 		t.Errorf("Expected %d bindings, got %d", len(expectedBindings), len(bindings))
 	}
-}
\ No newline at end of file
+}
+func TestWhereArrayOperators(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "posts"
+
+	qb.WhereArrayContains("tags", []string{"go", "sql"}).
+		WhereArrayOverlaps("categories", []int64{1, 2}).
+		WhereAnyValue("editors", "=", "omar")
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, fragment := range []string{"tags @> ", "categories && ", " = ANY(editors)"} {
+		if !strings.Contains(sql, fragment) {
+			t.Errorf("Expected SQL to contain %q, got: %s", fragment, sql)
+		}
+	}
+
+	if len(bindings) != 3 {
+		t.Fatalf("Expected 3 bindings, got %d", len(bindings))
+	}
+
+	if _, ok := bindings[0].(driver.Valuer); !ok {
+		t.Errorf("Expected array binding to implement driver.Valuer, got %T", bindings[0])
+	}
+
+	if bindings[2] != "omar" {
+		t.Errorf("Expected scalar ANY binding, got %v", bindings[2])
+	}
+}
+
+func TestArrayConditionsAreChecked(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	posts := func() types.QueryBuilder {
+		return Table(executor, types.MySQL, "posts").Where("a", 1)
+	}
+
+	for name, qb := range map[string]types.QueryBuilder{
+		"contains": posts().WhereArrayContains("tags", []string{"go"}).Where("b", 2),
+		"overlaps": posts().WhereArrayOverlaps("tags", []string{"go"}),
+		"any":      Table(executor, types.MySQL, "posts").WhereAnyValue("tags", "=", "go"),
+		"exists":   posts().WhereExists(Table(executor, types.MySQL, "tags").WhereArrayContains("names", []string{"go"})),
+	} {
+		if sql, _, err := qb.ToSQL(); !errors.Is(err, types.ErrUnsupportedFeature) {
+			t.Errorf("%s: expected ErrUnsupportedFeature on MySQL, got %q, %v", name, sql, err)
+		}
+	}
+	if _, err := posts().WhereArrayContains("tags", []string{"go"}).Delete(context.Background()); !errors.Is(err, types.ErrUnsupportedFeature) {
+		t.Errorf("Expected ErrUnsupportedFeature from Delete, got %v", err)
+	}
+
+	pg := &MockExecutor{driver: types.PostgreSQL}
+	injected := Table(pg, types.PostgreSQL, "posts").WhereAnyValue("tags", "= 1 OR 1=1 --", "go")
+	if sql, _, err := injected.ToSQL(); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for an unknown operator, got %q, %v", sql, err)
+	}
+	for _, operator := range []string{"=", "<>", "!=", "<", "<=", ">", ">="} {
+		if _, _, err := Table(pg, types.PostgreSQL, "posts").WhereAnyValue("tags", operator, 3).ToSQL(); err != nil {
+			t.Errorf("Expected %s to compile, got %v", operator, err)
+		}
+	}
+	ch := &MockExecutor{driver: types.ClickHouse}
+	for _, operator := range []string{"IS NULL", "IN", "NOT IN", "BETWEEN", "LIKE", "ILIKE", "EXISTS"} {
+		for _, executor := range []*MockExecutor{pg, ch} {
+			qb := Table(executor, executor.driver, "posts").WhereAnyValue("tags", operator, "go")
+			if sql, _, err := qb.ToSQL(); !errors.Is(err, types.ErrInvalidParameter) {
+				t.Errorf("Expected ErrInvalidParameter for %s on %s, got %q, %v", operator, executor.driver, sql, err)
+			}
+		}
+	}
+}
+
+func TestClickHouseDialect(t *testing.T) {
+	executor := &MockExecutor{driver: types.ClickHouse}
+	qb := Table(executor, types.ClickHouse, "events")
+	qb.Final().SamplePercent(10).
+		WhereArrayContains("tags", []string{"go", "sql"}).
+		WhereArrayOverlaps("categories", []int64{1, 2}).
+		WhereAnyValue("editors", "=", "omar").
+		ForUpdate().
+		Offset(20)
+
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := "SELECT * FROM events FINAL SAMPLE 0.1 WHERE hasAll(tags, ?) AND hasAny(categories, ?) AND has(editors, ?) " +
+		"LIMIT 18446744073709551615 OFFSET 20"
+	if sql != want {
+		t.Errorf("Unexpected SQL:\n got: %s\nwant: %s", sql, want)
+	}
+	if _, ok := bindings[0].([]string); !ok || len(bindings) != 3 {
+		t.Errorf("Expected slices to be bound as is, got %#v", bindings)
+	}
+
+	update := NewBuilder(executor, types.ClickHouse)
+	update.From("events").Where("id", 7)
+	sql, _, err = update.compiler.CompileUpdate(update, map[string]interface{}{"status": "done"})
+	if err != nil || sql != "ALTER TABLE events UPDATE status = ? WHERE id = ?" {
+		t.Errorf("Unexpected UPDATE: %s (%v)", sql, err)
+	}
+
+	remove := NewBuilder(executor, types.ClickHouse)
+	remove.From("events").Where("id", 7).Limit(1)
+	if _, _, err := remove.compiler.CompileDelete(remove); err == nil {
+		t.Error("Expected an error for DELETE with LIMIT on ClickHouse")
+	}
+}
+
+func TestGeoDistanceMySQL(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "stores"
+
+	qb.Select("name").
+		SelectDistance("location", 30.0444, 31.2357, "distance").
+		WhereDistanceWithin("location", 30.0444, 31.2357, 5000).
+		OrderByDistance("location", 30.0444, 31.2357)
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT name, ST_Distance_Sphere(location, ST_SRID(POINT(31.2357, 30.0444), 4326)) AS distance FROM stores " +
+		"WHERE ST_Distance_Sphere(location, ST_SRID(POINT(31.2357, 30.0444), 4326)) <= 5000 " +
+		"ORDER BY ST_Distance_Sphere(location, ST_SRID(POINT(31.2357, 30.0444), 4326)) ASC"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	if len(bindings) != 0 {
+		t.Errorf("Expected no bindings, got: %v", bindings)
+	}
+}
+
+func TestGeoDistancePostgreSQL(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "stores"
+
+	qb.WhereDistanceWithin("location", 30.0444, 31.2357, 5000)
+	sql, _, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT * FROM stores WHERE ST_DWithin(location::geography, " +
+		"ST_SetSRID(ST_MakePoint(31.2357, 30.0444), 4326)::geography, 5000)"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+}
+
+func TestGeoDistanceUnsupportedDriver(t *testing.T) {
+	executor := &MockExecutor{driver: types.ClickHouse}
+	_, _, err := Table(executor, types.ClickHouse, "stores").WhereDistanceWithin("location", 30.0444, 31.2357, 5000).ToSQL()
+	if !errors.Is(err, types.ErrUnsupportedFeature) {
+		t.Errorf("Expected ErrUnsupportedFeature, got %v", err)
+	}
+}
+
+func TestWhereFullTextOptions(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "posts"
+
+	qb.Where("status", "published").
+		WhereFullText([]string{"title", "body"}, "+golang -java", types.FullTextOptions{Mode: types.FullTextBoolean})
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT * FROM posts WHERE status = ? AND MATCH(title, body) AGAINST(? IN BOOLEAN MODE)"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	if len(bindings) != 2 || bindings[1] != "+golang -java" {
+		t.Errorf("Expected search term as second binding, got: %v", bindings)
+	}
+}
+
+func TestOrderByRelevance(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "posts"
+
+	options := types.FullTextOptions{Mode: types.FullTextWebsearch, Language: "english"}
+	qb.WhereFullText([]string{"title"}, "go tutorial", options).
+		OrderByRelevance([]string{"title"}, "go tutorial", options)
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, fragment := range []string{
+		"SELECT *, ts_rank(to_tsvector('english', title), websearch_to_tsquery('english', ",
+		"AS relevance FROM posts",
+		"WHERE to_tsvector('english', title) @@ websearch_to_tsquery('english', ",
+		"ORDER BY relevance DESC",
+	} {
+		if !strings.Contains(sql, fragment) {
+			t.Errorf("Expected SQL to contain %q, got: %s", fragment, sql)
+		}
+	}
+
+	if len(bindings) != 2 {
+		t.Errorf("Expected 2 bindings, got %d", len(bindings))
+	}
+
+	sql, _, err = Table(executor, types.PostgreSQL, "posts").
+		OrderByRelevance([]string{"title"}, "go", options).
+		OrderByRelevance([]string{"body"}, "go", options).ToSQL()
+	if err != nil || !strings.Contains(sql, "AS relevance, ts_rank(") || !strings.HasSuffix(sql, "AS relevance_2 FROM posts ORDER BY relevance DESC, relevance_2 DESC") {
+		t.Errorf("Expected a distinct alias per relevance score, got %s, %v", sql, err)
+	}
+
+	invalid := types.FullTextOptions{Language: "english'); DROP TABLE posts; --"}
+	for name, qb := range map[string]types.QueryBuilder{
+		"WhereFullText":    Table(executor, types.PostgreSQL, "posts").WhereFullText([]string{"title"}, "go", invalid),
+		"OrderByRelevance": Table(executor, types.PostgreSQL, "posts").OrderByRelevance([]string{"title"}, "go", invalid),
+	} {
+		if _, _, err := qb.ToSQL(); !errors.Is(err, types.ErrInvalidParameter) {
+			t.Errorf("%s: expected ErrInvalidParameter for an invalid language, got %v", name, err)
+		}
+	}
+}
+
+func TestLikeHelpersEscapeWildcards(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+
+	qb.WhereStartsWith("name", "50%_off").WhereContains("email", `a\b`).WhereLike("code", "A%")
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT * FROM users WHERE LOWER(name) LIKE LOWER(?) AND LOWER(email) LIKE LOWER(?) AND code LIKE ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	expectedBindings := []interface{}{`50\%\_off%`, `%a\\b%`, "A%"}
+	for i, expected := range expectedBindings {
+		if bindings[i] != expected {
+			t.Errorf("Expected binding %d to be %v, got %v", i, expected, bindings[i])
+		}
+	}
+}
+
+func TestLikeHelpersPostgreSQL(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "users"
+
+	qb.WhereEndsWith("email", "@example.com")
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(sql, "WHERE email ILIKE ") {
+		t.Errorf("Expected ILIKE clause, got: %s", sql)
+	}
+
+	if len(bindings) != 1 || bindings[0] != "%@example.com" {
+		t.Errorf("Expected escaped suffix binding, got: %v", bindings)
+	}
+}
+
+func TestWhereBetweenColumnsAndDates(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "promotions"
+
+	from := time.Date(2024, 1, 1, 15, 30, 0, 0, time.UTC)
+	qb.Where("active", true).
+		WhereBetweenColumns(42, "min_qty", "max_qty").
+		WhereDateBetween("starts_at", from, "2024-01-31")
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT * FROM promotions WHERE active = ? AND ? BETWEEN min_qty AND max_qty AND DATE(starts_at) BETWEEN ? AND ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	expectedBindings := []interface{}{true, 42, "2024-01-01", "2024-01-31"}
+	for i, expected := range expectedBindings {
+		if bindings[i] != expected {
+			t.Errorf("Expected binding %d to be %v, got %v", i, expected, bindings[i])
+		}
+	}
+}
+
+func TestRelativeDateWheres(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "sessions"
+
+	qb.WhereWithinLast("created_at", 7*24*time.Hour).WhereOlderThan("last_seen_at", 30*time.Minute)
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT * FROM sessions WHERE created_at >= NOW() - INTERVAL ? MICROSECOND AND last_seen_at < NOW() - INTERVAL ? MICROSECOND"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	if bindings[0] != (7 * 24 * time.Hour).Microseconds() || bindings[1] != (30 * time.Minute).Microseconds() {
+		t.Errorf("Unexpected bindings: %v", bindings)
+	}
+
+	pgQB := NewBuilder(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL)
+	pgQB.table = "sessions"
+	pgSQL, _, _ := pgQB.WhereWithinLast("created_at", time.Hour).ToSQL()
+	if !strings.Contains(pgSQL, "* INTERVAL '1 microsecond'") {
+		t.Errorf("Expected PostgreSQL interval arithmetic, got: %s", pgSQL)
+	}
+}
+
+func TestOrderByNulls(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "tasks"
+
+	qb.OrderByNullsLast("due_at").OrderByMany([]types.OrderSpec{
+		{Column: "priority", Direction: types.Desc},
+		{Column: "assigned_at", Nulls: types.NullsFirst},
+	})
+	sql, _, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedSQL := "SELECT * FROM tasks ORDER BY ISNULL(due_at) ASC, due_at ASC, priority DESC, ISNULL(assigned_at) DESC, assigned_at ASC"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	pgQB := NewBuilder(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL)
+	pgQB.table = "tasks"
+	pgSQL, _, _ := pgQB.OrderByNullsLast("due_at", types.Desc).ToSQL()
+	if pgSQL != "SELECT * FROM tasks ORDER BY due_at DESC NULLS LAST" {
+		t.Errorf("Unexpected PostgreSQL SQL: %s", pgSQL)
+	}
+}
+
+func TestRandomOrderAndSample(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+
+	sql, _, _ := qb.Sample(5).ToSQL()
+	expectedSQL := "SELECT * FROM users ORDER BY RAND() LIMIT 5"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	mysqlQB := NewBuilder(executor, types.MySQL)
+	mysqlQB.table = "users"
+	sql, _, _ = mysqlQB.SamplePercent(10).ToSQL()
+	expectedSQL = "SELECT * FROM users WHERE RAND() < 0.1"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	pgQB := NewBuilder(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL)
+	pgQB.table = "users"
+	sql, _, _ = pgQB.SamplePercent(2.5).InRandomOrder().ToSQL()
+	expectedSQL = "SELECT * FROM users TABLESAMPLE BERNOULLI (2.5) ORDER BY RANDOM()"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+}
+
+func TestClauseResets(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	base := NewBuilder(executor, types.MySQL)
+	base.table = "users"
+	base.SelectRaw("LENGTH(name) > ? AS long_name", 10).
+		WhereRaw("age > ?", 18).
+		OrderBy("name").
+		Limit(10).
+		Offset(20)
+
+	sql, bindings, _ := base.Clone().ClearSelect().ClearLimit().Reorder().ToSQL()
+	expectedSQL := "SELECT * FROM users WHERE age > ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+	if len(bindings) != 1 || bindings[0] != 18 {
+		t.Errorf("Expected bindings [18], got: %v", bindings)
+	}
+
+	sql, bindings, _ = base.Clone().ClearWhere().Where("id", 1).ToSQL()
+	expectedSQL = "SELECT LENGTH(name) > ? AS long_name FROM users WHERE id = ? ORDER BY name ASC LIMIT 10 OFFSET 20"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+	if len(bindings) != 2 || bindings[0] != 10 || bindings[1] != 1 {
+		t.Errorf("Expected bindings [10 1], got: %v", bindings)
+	}
+}
+
+func TestLatestAndOldest(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "posts"
+
+	sql, _, _ := qb.Latest().Oldest("id").ToSQL()
+	expectedSQL := "SELECT * FROM posts ORDER BY created_at DESC, id ASC"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+}
+
+func TestMacros(t *testing.T) {
+	defer FlushMacros()
+
+	Macro("wherePublished", func(qb types.QueryBuilder, args ...interface{}) types.QueryBuilder {
+		status := "published"
+		if len(args) > 0 {
+			status = args[0].(string)
+		}
+		return qb.Where("status", status).WhereNotNull("published_at")
+	})
+
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "posts"
+
+	sql, bindings, _ := qb.Call("wherePublished", "featured").Limit(5).ToSQL()
+	expectedSQL := "SELECT * FROM posts WHERE status = ? AND published_at IS NOT NULL LIMIT 5"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+	if len(bindings) != 1 || bindings[0] != "featured" {
+		t.Errorf("Expected bindings [featured], got: %v", bindings)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Call to panic for an unknown macro")
+		}
+	}()
+	qb.Call("missing")
+}
+
+func TestNamedScopes(t *testing.T) {
+	defer FlushScopes()
+
+	RegisterScope("users", "adults", func(qb types.QueryBuilder, args ...interface{}) types.QueryBuilder {
+		return qb.Where("age", ">=", args[0])
+	})
+	RegisterScope("users", "active", func(qb types.QueryBuilder, args ...interface{}) types.QueryBuilder {
+		return qb.Where("status", "active")
+	})
+
+	if names := Scopes("users"); len(names) != 2 || names[0] != "active" || names[1] != "adults" {
+		t.Errorf("Expected scopes [active adults], got: %v", names)
+	}
+
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	qb.UseScope("adults", 18).UseScope("active")
+
+	// Compiling twice must not apply the scopes twice.
+	_, _, _ = qb.ToSQL()
+	sql, bindings, _ := qb.ToSQL()
+	expectedSQL := "SELECT * FROM users WHERE age >= ? AND status = ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+	if len(bindings) != 2 || bindings[0] != 18 {
+		t.Errorf("Expected bindings [18 active], got: %v", bindings)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected UseScope to panic for a scope registered on another table")
+		}
+	}()
+	posts := NewBuilder(executor, types.MySQL)
+	posts.table = "posts"
+	posts.UseScope("adults", 18)
+}
+
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	qb.ReadOnly()
+
+	if err := qb.Insert(context.Background(), map[string]interface{}{"name": "x"}); !errors.Is(err, types.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Insert, got: %v", err)
+	}
+
+	clone := qb.Clone().Where("id", 1)
+	if _, err := clone.Delete(context.Background()); !errors.Is(err, types.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Delete on clone, got: %v", err)
+	}
+	if _, err := clone.Update(context.Background(), map[string]interface{}{"name": "y"}); !errors.Is(err, types.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Update on clone, got: %v", err)
+	}
+}
+
+type mockResult struct{ rows int64 }
+
+func (r mockResult) LastInsertId() (int64, error) { return 0, nil }
+func (r mockResult) RowsAffected() (int64, error) { return r.rows, nil }
+
+type mockRow struct{ value interface{} }
+
+func (r mockRow) Scan(dest ...interface{}) error {
+	*(dest[0].(*interface{})) = r.value
+	return nil
+}
+
+type recordingExecutor struct {
+	MockExecutor
+	execs   []string
+	args    [][]interface{}
+	queries []string
+	count   int64
+}
+
+func (r *recordingExecutor) QueryRowContext(_ context.Context, query string, _ ...interface{}) types.Row {
+	r.queries = append(r.queries, query)
+	return mockRow{value: r.count}
+}
+
+func (r *recordingExecutor) ExecContext(_ context.Context, query string, args ...interface{}) (types.Result, error) {
+	r.execs = append(r.execs, query)
+	r.args = append(r.args, args)
+	return mockResult{rows: 3}, nil
+}
+
+func (r *recordingExecutor) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx, error) {
+	return &recordingTx{recordingExecutor: r}, nil
+}
+
+type recordingTx struct {
+	*recordingExecutor
+	committed bool
+}
+
+func (t *recordingTx) Commit() error {
+	t.committed = true
+	t.execs = append(t.execs, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	t.execs = append(t.execs, "ROLLBACK")
+	return nil
+}
+
+func TestUnconditionedMutationGuard(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+
+	if _, err := qb.Clone().Delete(context.Background()); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Errorf("Expected ErrUnconditionedMutation from Delete, got: %v", err)
+	}
+	if _, err := qb.Clone().Update(context.Background(), map[string]interface{}{"active": false}); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Errorf("Expected ErrUnconditionedMutation from Update, got: %v", err)
+	}
+	if len(executor.execs) != 0 {
+		t.Fatalf("Expected no statements to run, got: %v", executor.execs)
+	}
+
+	affected, err := qb.Clone().AllowFullTableMutation().Delete(context.Background())
+	if err != nil || affected != 3 {
+		t.Errorf("Expected full table delete to run, got: %d, %v", affected, err)
+	}
+	if len(executor.execs) != 1 || executor.execs[0] != "DELETE FROM users" {
+		t.Errorf("Unexpected statements: %v", executor.execs)
+	}
+}
+
+func TestMaxAffectedGuard(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, count: 12}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "orders"
+	qb.Where("status", "pending").MaxAffected(10)
+
+	_, err := qb.Clone().Update(context.Background(), map[string]interface{}{"status": "cancelled"})
+	if !errors.Is(err, types.ErrMaxAffectedExceeded) {
+		t.Fatalf("Expected ErrMaxAffectedExceeded, got: %v", err)
+	}
+	if len(executor.execs) != 0 {
+		t.Errorf("Expected no statements to run, got: %v", executor.execs)
+	}
+	expectedCount := "SELECT COUNT(*) as aggregate FROM orders WHERE status = ?"
+	if len(executor.queries) != 1 || executor.queries[0] != expectedCount {
+		t.Errorf("Expected count query %s, got: %v", expectedCount, executor.queries)
+	}
+
+	executor.count = 10
+	if _, err := qb.Clone().Delete(context.Background()); err != nil {
+		t.Errorf("Expected delete within the limit to run, got: %v", err)
+	}
+	if len(executor.execs) != 1 {
+		t.Errorf("Expected one statement to run, got: %v", executor.execs)
+	}
+
+	executor.count = 500
+	if _, err := qb.Clone().OrderBy("id").Limit(10).Delete(context.Background()); err != nil {
+		t.Errorf("Expected a delete limited to the cap to run, got: %v", err)
+	}
+	if _, err := qb.Clone().Limit(11).Delete(context.Background()); !errors.Is(err, types.ErrMaxAffectedExceeded) {
+		t.Errorf("Expected a limit above the cap to be rejected, got: %v", err)
+	}
+}
+
+func TestBoundedMutations(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "logs"
+	qb.Where("level", "debug").OrderBy("id").Limit(1000)
+
+	if _, err := qb.Delete(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expectedSQL := "DELETE FROM logs WHERE level = ? ORDER BY id ASC LIMIT 1000"
+	if executor.execs[0] != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, executor.execs[0])
+	}
+
+	pgExecutor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	pgQB := NewBuilder(pgExecutor, types.PostgreSQL)
+	pgQB.table = "logs"
+	pgQB.OrderBy("id").Limit(500)
+
+	if _, err := pgQB.Clone().Delete(context.Background()); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Fatalf("Expected a LIMIT not to count as a condition, got: %v", err)
+	}
+	if _, err := pgQB.Clone().Update(context.Background(), map[string]interface{}{"level": "info"}); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Fatalf("Expected a LIMIT not to count as a condition, got: %v", err)
+	}
+	if _, err := pgQB.AllowFullTableMutation().Delete(context.Background()); err != nil {
+		t.Fatalf("Expected an allowed bounded delete without WHERE to run, got: %v", err)
+	}
+	expectedSQL = "DELETE FROM logs WHERE logs.ctid IN (SELECT logs.ctid FROM logs ORDER BY id ASC LIMIT 500)"
+	if pgExecutor.execs[0] != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, pgExecutor.execs[0])
+	}
+}
+
+func TestMutationsWithJoins(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	qb.Join("bans", "users.id", "bans.user_id").Where("bans.active", true)
+
+	sql, bindings, err := qb.ToUpdateSQL(map[string]interface{}{"users.status": "banned"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedSQL := "UPDATE users INNER JOIN bans ON users.id = bans.user_id SET users.status = ? WHERE bans.active = ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+	if len(bindings) != 2 || bindings[0] != "banned" || bindings[1] != true {
+		t.Errorf("Expected bindings [banned true], got: %v", bindings)
+	}
+
+	sql, _, _ = qb.ToDeleteSQL()
+	expectedSQL = "DELETE users FROM users INNER JOIN bans ON users.id = bans.user_id WHERE bans.active = ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	pgExecutor := &MockExecutor{driver: types.PostgreSQL}
+	pgQB := NewBuilder(pgExecutor, types.PostgreSQL)
+	pgQB.table = "users"
+	pgQB.Join("bans", "users.id", "bans.user_id").Where("bans.active", true)
+
+	sql, _, _ = pgQB.ToUpdateSQL(map[string]interface{}{"status": "banned"})
+	expectedSQL = "UPDATE users SET status = $1 FROM bans WHERE users.id = bans.user_id AND (bans.active = $2)"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	sql, _, _ = pgQB.ToDeleteSQL()
+	expectedSQL = "DELETE FROM users USING bans WHERE users.id = bans.user_id AND (bans.active = $1)"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+
+	outer := NewBuilder(pgExecutor, types.PostgreSQL)
+	outer.table = "users"
+	outer.LeftJoin("logins", "users.id", "logins.user_id").WhereNull("logins.id")
+
+	sql, _, _ = outer.ToDeleteSQL()
+	expectedSQL = "DELETE FROM users WHERE users.ctid IN (SELECT users.ctid FROM users LEFT JOIN logins ON users.id = logins.user_id WHERE logins.id IS NULL)"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+}
+
+func TestInsertUsing(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	archive := NewBuilder(executor, types.PostgreSQL)
+	archive.table = "orders_archive"
+
+	old := NewBuilder(executor, types.PostgreSQL)
+	old.table = "orders"
+	old.Select("id", "total").Where("created_at", "<", "2020-01-01")
+
+	inserted, err := archive.InsertUsing(context.Background(), []string{"id", "total"}, old)
+	if err != nil || inserted != 3 {
+		t.Fatalf("Expected 3 rows inserted without error, got: %d, %v", inserted, err)
+	}
+
+	expectedSQL := "INSERT INTO orders_archive (id, total) SELECT id, total FROM orders WHERE created_at < $1"
+	if executor.execs[0] != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, executor.execs[0])
+	}
+}
+
+// failingQuery is a foreign query builder whose SQL cannot be compiled.
+type failingQuery struct{ types.QueryBuilder }
+
+func (failingQuery) ToSQL() (string, []interface{}, error) {
+	return "", nil, errors.New("cannot compile")
+}
+
+func TestSubqueryErrorsArePropagated(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	archive := Table(executor, types.PostgreSQL, "orders_archive")
+
+	if _, err := archive.InsertUsing(context.Background(), []string{"id"}, failingQuery{}); err == nil || !strings.Contains(err.Error(), "cannot compile") {
+		t.Errorf("Expected the subquery error from InsertUsing, got %v", err)
+	}
+	if _, _, err := Table(executor, types.PostgreSQL, "orders").WhereExists(failingQuery{}).ToSQL(); err == nil {
+		t.Error("Expected the subquery error from WhereExists")
+	}
+	if len(executor.execs) != 0 {
+		t.Errorf("Expected no statements to run, got %v", executor.execs)
+	}
+}
+
+func TestRebindLeavesLiteralQuestionMarks(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"data ?? 'admin' AND id = ?", "data ? 'admin' AND id = $1"},
+		{"tags ??| array['a'] AND tags ??& ? AND x = '?'", "tags ?| array['a'] AND tags ?& $1 AND x = '?'"},
+		{"id = ? -- why?\nAND name = ?", "id = $1 -- why?\nAND name = $2"},
+		{"/* ? */ id = ?", "/* ? */ id = $1"},
+		{"f($$ select ? $$, ?) AND g($fn$ ? $fn$) AND id = $1", "f($$ select ? $$, $1) AND g($fn$ ? $fn$) AND id = $1"},
+	}
+	for _, tt := range tests {
+		if got, _ := execution.NumberPlaceholders(tt.sql, 0); got != tt.want {
+			t.Errorf("NumberPlaceholders(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+
+	sql, bindings, err := Table(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL, "users").
+		WhereRaw("settings ?? ?", "theme").Where("id", 7).ToSQL()
+	if err != nil || sql != "SELECT * FROM users WHERE settings ? $1 AND id = $2" || len(bindings) != 2 {
+		t.Errorf("Unexpected SQL %q, %v, %v", sql, bindings, err)
+	}
+}
+
+func TestDeterministicColumnOrder(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "users"
+
+	row := map[string]interface{}{"name": "a", "email": "a@example.com", "age": 30, "city": "Cairo"}
+	for i := 0; i < 10; i++ {
+		if err := qb.Insert(context.Background(), row); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	expectedSQL := "INSERT INTO users (age, city, email, name) VALUES ($1, $2, $3, $4)"
+	for _, sql := range executor.execs {
+		if sql != expectedSQL {
+			t.Fatalf("Expected SQL: %s, got: %s", expectedSQL, sql)
+		}
+	}
+
+	update := NewBuilder(executor, types.MySQL)
+	update.table = "users"
+	update.Where("id", 1)
+	sql, _, err := update.ToUpdateSQL(row)
+	if err != nil {
+		t.Fatalf("ToUpdateSQL failed: %v", err)
+	}
+
+	expectedSQL = "UPDATE users SET age = ?, city = ?, email = ?, name = ? WHERE id = ?"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL: %s, got: %s", expectedSQL, sql)
+	}
+}
+
+func TestInsertBatchSplitsIntoSubBatches(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "users"
+
+	rows := make([]map[string]interface{}, 5)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "user"}
+	}
+
+	if err := qb.InsertBatchWithOptions(context.Background(), rows, types.BulkInsertOptions{BatchSize: 2}); err != nil {
+		t.Fatalf("InsertBatchWithOptions failed: %v", err)
+	}
+
+	expected := []string{
+		"INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)",
+		"INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)",
+		"INSERT INTO users (id, name) VALUES ($1, $2)",
+		"COMMIT",
+	}
+	if strings.Join(executor.execs, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("Expected statements: %v, got: %v", expected, executor.execs)
+	}
+
+	executor.execs = nil
+	if err := qb.InsertBatch(context.Background(), rows); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if len(executor.execs) != 1 {
+		t.Errorf("Expected a single statement without a transaction, got: %v", executor.execs)
+	}
+}
+
+type mockRows struct {
+	columns []string
+	data    [][]interface{}
+	pos     int
+}
+
+func (r *mockRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.data)
+}
+
+func (r *mockRows) Scan(dest ...interface{}) error {
+	for i, value := range r.data[r.pos-1] {
+		*(dest[i].(*interface{})) = value
+	}
+	return nil
+}
+
+func (r *mockRows) Close() error               { return nil }
+func (r *mockRows) Columns() ([]string, error) { return r.columns, nil }
+func (r *mockRows) Err() error                 { return nil }
+
+// shardExecutor returns one row holding its shard name, or fails with err.
+type shardExecutor struct {
+	MockExecutor
+	shard string
+	err   error
+}
+
+func (s *shardExecutor) QueryContext(_ context.Context, _ string, _ ...interface{}) (types.Rows, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &mockRows{columns: []string{"shard"}, data: [][]interface{}{{s.shard}}}, nil
+}
+
+func TestParallelMergesResultsAndReportsFailures(t *testing.T) {
+	var builders []types.QueryBuilder
+	for _, shard := range []string{"eu", "us", "asia"} {
+		executor := &shardExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, shard: shard}
+		if shard == "us" {
+			executor.err = errors.New("connection refused")
+		}
+		qb := NewBuilder(executor, types.MySQL)
+		qb.table = "orders"
+		builders = append(builders, qb)
+	}
+
+	rows, err := execution.Parallel(context.Background(), builders, optimization.NewConcurrencyManager(2))
+
+	var batchErr *types.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed()) != 1 || batchErr.Failed()[0] != 1 {
+		t.Fatalf("Expected a BatchError for builder 1, got: %v", err)
+	}
+
+	shards := rows.Pluck("shard")
+	if len(shards) != 2 || shards[0] != "eu" || shards[1] != "asia" {
+		t.Errorf("Expected rows from eu and asia in order, got: %v", shards)
+	}
+}
+
+type limitedExecutor struct {
+	recordingExecutor
+	limiter *optimization.ConcurrencyManager
+}
+
+func (l *limitedExecutor) ConcurrencyLimiter() *optimization.ConcurrencyManager {
+	return l.limiter
+}
+
+func TestConcurrencyLimitQueueTimeout(t *testing.T) {
+	executor := &limitedExecutor{
+		recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}},
+		limiter:           optimization.NewConcurrencyManagerWithTimeout(1, 20*time.Millisecond),
+	}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	qb.Where("id", 1)
+
+	// Hold the only slot so the delete has to queue.
+	if err := executor.limiter.Acquire(context.Background()); err != nil {
+		t.Fatalf("Expected to acquire the slot, got: %v", err)
+	}
+	if _, err := qb.Clone().Delete(context.Background()); !errors.Is(err, types.ErrQueueTimeout) {
+		t.Fatalf("Expected ErrQueueTimeout while the slot is held, got: %v", err)
+	}
+	executor.limiter.Release()
+
+	if _, err := qb.Clone().Delete(context.Background()); err != nil {
+		t.Fatalf("Expected delete to run once the slot is free, got: %v", err)
+	}
+	stats := executor.limiter.Stats()
+	if stats.TimedOut != 1 || stats.Active != 0 {
+		t.Errorf("Expected one timeout and no queries in flight, got: %+v", stats)
+	}
+
+	execution.SetGlobalConcurrencyLimit(1, 20*time.Millisecond)
+	defer execution.SetGlobalConcurrencyLimit(0, 0)
+	if _, err := qb.Clone().Delete(context.Background()); err != nil {
+		t.Errorf("Expected delete to run under the global limit, got: %v", err)
+	}
+	if global := execution.GlobalConcurrencyStats(); global.Acquired != 1 {
+		t.Errorf("Expected one query through the global limiter, got: %+v", global)
+	}
+}
+
+func TestRateLimitHookRejectsQueries(t *testing.T) {
+	remove := execution.AddHook(ratelimit.New(ratelimit.Limit{Rate: 0, Burst: 1}))
+	defer remove()
+
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	qb.Where("id", 1)
+
+	ctx := ratelimit.WithCaller(context.Background(), "tenant-1")
+	if _, err := qb.Clone().Delete(ctx); err != nil {
+		t.Fatalf("Expected the first delete to be allowed, got: %v", err)
+	}
+	if _, err := qb.Clone().Delete(ctx); !errors.Is(err, types.ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited for the second delete, got: %v", err)
+	}
+	if len(executor.execs) != 1 {
+		t.Errorf("Expected the rejected delete not to reach the database, got: %v", executor.execs)
+	}
+}
+
+type capturingLogger struct {
+	entries []types.QueryLog
+}
+
+func (c *capturingLogger) LogQuery(_ context.Context, entry types.QueryLog) {
+	c.entries = append(c.entries, entry)
+}
+
+func TestLoggerRedactsSensitiveBindings(t *testing.T) {
+	logger := &capturingLogger{}
+	execution.SetLogger(logger)
+	defer execution.SetLogger(nil)
+
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "users"
+
+	if err := qb.Clone().Insert(context.Background(), map[string]interface{}{"email": "a@b.c", "password": "hunter2"}); err != nil {
+		t.Fatalf("Expected insert to succeed, got: %v", err)
+	}
+	update := qb.Clone().Where("email", "a@b.c")
+	if _, err := update.Update(context.Background(), map[string]interface{}{"token": "abc"}); err != nil {
+		t.Fatalf("Expected update to succeed, got: %v", err)
+	}
+
+	if len(logger.entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got: %d", len(logger.entries))
+	}
+	insert := logger.entries[0]
+	if insert.Table != "users" || insert.Rows != 3 || insert.Bindings[0] != "a@b.c" || insert.Bindings[1] != execution.Redacted {
+		t.Errorf("Expected the password binding to be redacted, got: %+v", insert)
+	}
+	if bindings := logger.entries[1].Bindings; bindings[0] != execution.Redacted || bindings[1] != "a@b.c" {
+		t.Errorf("Expected only the token binding to be redacted, got: %v", bindings)
+	}
+}
+
+func TestCommentTagsStatements(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "orders"
+	qb.Where("id", 1).Comment("service=checkout,endpoint=POST /orders")
+
+	ctx := execution.WithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := qb.Clone().Delete(ctx); err != nil {
+		t.Fatalf("Expected delete to succeed, got: %v", err)
+	}
+
+	expected := "/*endpoint='POST%20%2Forders',service='checkout',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/ DELETE FROM orders WHERE id = ?"
+	if len(executor.execs) != 1 || executor.execs[0] != expected {
+		t.Errorf("Expected %q, got: %v", expected, executor.execs)
+	}
+
+	if comment := execution.FormatComment(map[string]string{"note": "*/ DROP TABLE users; --"}); strings.Contains(comment[2:len(comment)-2], "*/") {
+		t.Errorf("Expected the tag to be escaped, got: %s", comment)
+	}
+}
+
+// deadlineExecutor records the time left on the context of each statement.
+type deadlineExecutor struct {
+	recordingExecutor
+	remaining []time.Duration
+}
+
+func (d *deadlineExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (types.Result, error) {
+	deadline, ok := ctx.Deadline()
+	if ok {
+		d.remaining = append(d.remaining, time.Until(deadline))
+	}
+	return d.recordingExecutor.ExecContext(ctx, query, args...)
+}
+
+func (d *deadlineExecutor) CopyFrom(ctx context.Context, table string, _ []string, rows [][]interface{}) (int64, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		d.remaining = append(d.remaining, time.Until(deadline))
+	}
+	d.execs = append(d.execs, "COPY "+table)
+	return int64(len(rows)), nil
+}
+
+func (d *deadlineExecutor) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx, error) {
+	return &deadlineTx{recordingTx: recordingTx{recordingExecutor: &d.recordingExecutor}, executor: d}, nil
+}
+
+// deadlineTx is a transaction whose statements are recorded by its deadlineExecutor.
+type deadlineTx struct {
+	recordingTx
+	executor *deadlineExecutor
+}
+
+func (t *deadlineTx) ExecContext(ctx context.Context, query string, args ...interface{}) (types.Result, error) {
+	return t.executor.ExecContext(ctx, query, args...)
+}
+
+func TestInsertBatchChunksUseLimitsAndTimeout(t *testing.T) {
+	executor := &deadlineExecutor{recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}}
+	qb := NewBuilder(executor, types.PostgreSQL)
+	qb.table = "users"
+
+	rows := make([]map[string]interface{}, 5)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "user"}
+	}
+
+	execution.SetGlobalConcurrencyLimit(1, 20*time.Millisecond)
+	defer execution.SetGlobalConcurrencyLimit(0, 0)
+	ctx := execution.WithTimeout(context.Background(), time.Minute)
+
+	if err := qb.InsertBatchWithOptions(ctx, rows, types.BulkInsertOptions{BatchSize: 2}); err != nil {
+		t.Fatalf("InsertBatchWithOptions failed: %v", err)
+	}
+	if len(executor.remaining) != 3 {
+		t.Errorf("Expected every sub-batch to get the statement timeout, got: %v", executor.remaining)
+	}
+	if global := execution.GlobalConcurrencyStats(); global.Acquired != 3 {
+		t.Errorf("Expected every sub-batch through the global limiter, got: %+v", global)
+	}
+
+	executor.remaining = nil
+	if err := qb.InsertBatchWithOptions(ctx, rows, types.BulkInsertOptions{UseCopy: true}); err != nil {
+		t.Fatalf("InsertBatchWithOptions with COPY failed: %v", err)
+	}
+	if len(executor.remaining) != 1 || executor.execs[len(executor.execs)-1] != "COPY users" {
+		t.Errorf("Expected COPY to get the statement timeout, got: %v, %v", executor.remaining, executor.execs)
+	}
+	if global := execution.GlobalConcurrencyStats(); global.Acquired != 4 {
+		t.Errorf("Expected COPY through the global limiter, got: %+v", global)
+	}
+}
+
+func TestContextOptions(t *testing.T) {
+	primary := &deadlineExecutor{recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}}
+	replica := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	analytics := &recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}
+	resolver := func(name string) (types.QueryExecutor, types.Driver, error) {
+		switch name {
+		case "replica":
+			return replica, types.MySQL, nil
+		case "analytics":
+			return analytics, types.PostgreSQL, nil
+		}
+		return nil, "", fmt.Errorf("connection '%s' not found", name)
+	}
+
+	qb := NewBuilder(primary, types.MySQL).SetConnectionResolver(resolver)
+	qb.table = "orders"
+	qb.Where("id", 1).Comment("service=checkout")
+
+	ctx := execution.WithTag(context.Background(), "route", "POST /orders")
+	ctx = execution.WithTag(ctx, "service", "api")
+	ctx = execution.WithTimeout(ctx, time.Minute)
+	if _, err := qb.Clone().Delete(ctx); err != nil {
+		t.Fatalf("Expected delete to succeed, got: %v", err)
+	}
+	expected := "/*route='POST%20%2Forders',service='checkout'*/ DELETE FROM orders WHERE id = ?"
+	if len(primary.execs) != 1 || primary.execs[0] != expected {
+		t.Errorf("Expected %q, got: %v", expected, primary.execs)
+	}
+	if len(primary.remaining) != 1 || primary.remaining[0] > time.Minute || primary.remaining[0] < 50*time.Second {
+		t.Errorf("Expected the statement to get a one minute deadline, got: %v", primary.remaining)
+	}
+
+	if _, err := qb.Clone().Delete(execution.WithConnection(context.Background(), "replica")); err != nil {
+		t.Fatalf("Expected delete on the replica to succeed, got: %v", err)
+	}
+	if len(replica.execs) != 1 || len(primary.execs) != 1 {
+		t.Errorf("Expected the statement on the replica, got primary %v, replica %v", primary.execs, replica.execs)
+	}
+
+	if _, err := qb.Clone().Delete(execution.WithConnection(context.Background(), "analytics")); err == nil || !strings.Contains(err.Error(), "built for mysql") {
+		t.Errorf("Expected a driver mismatch error, got: %v", err)
+	}
+	if _, err := qb.Clone().Delete(execution.WithConnection(context.Background(), "missing")); err == nil {
+		t.Error("Expected an unknown connection to fail")
+	}
+	if len(analytics.execs) != 0 || len(primary.execs) != 1 {
+		t.Errorf("Expected failed lookups to run nothing, got primary %v, analytics %v", primary.execs, analytics.execs)
+	}
+}
+
+func TestDebugInfoCapturesExecution(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	qb.Debug().Where("id", 7)
+
+	if _, _, err := qb.ToSQL(); err != nil {
+		t.Fatalf("Expected SQL to compile, got: %v", err)
+	}
+	if info := qb.GetDebugInfo(); info == nil || info.Rows != -1 || info.SQL != "SELECT * FROM users WHERE id = ?" {
+		t.Fatalf("Expected compile debug info before execution, got: %+v", info)
+	}
+
+	if _, err := qb.Delete(context.Background()); err != nil {
+		t.Fatalf("Expected delete to succeed, got: %v", err)
+	}
+	info := qb.GetDebugInfo()
+	if info == nil || info.SQL != "DELETE FROM users WHERE id = ?" || info.Rows != 3 || info.Driver != types.MySQL || len(info.Bindings) != 1 {
+		t.Errorf("Expected debug info for the executed delete, got: %+v", info)
+	}
+}
+
+func TestToRawSQL(t *testing.T) {
+	mysqlQB := NewBuilder(&MockExecutor{driver: types.MySQL}, types.MySQL)
+	mysqlQB.table = "users"
+	mysqlQB.Where("name", "O'Brien").Where("active", true).WhereNull("deleted_at").Where("age", ">", 30)
+
+	raw, err := mysqlQB.ToRawSQL()
+	expected := "SELECT * FROM users WHERE name = 'O''Brien' AND active = TRUE AND deleted_at IS NULL AND age > 30"
+	if err != nil || raw != expected {
+		t.Errorf("Expected %q, got: %q (%v)", expected, raw, err)
+	}
+
+	pgQB := NewBuilder(&MockExecutor{driver: types.PostgreSQL}, types.PostgreSQL)
+	pgQB.table = "users"
+	pgQB.WhereIn("id", []interface{}{1, 2}).Where("note", "costs $1")
+
+	raw, err = pgQB.ToRawSQL()
+	expected = "SELECT * FROM users WHERE id IN (1, 2) AND note = 'costs $1'"
+	if err != nil || raw != expected {
+		t.Errorf("Expected %q, got: %q (%v)", expected, raw, err)
+	}
+}
+
+func TestSQLFormatterAppliesToRawSQL(t *testing.T) {
+	execution.SetSQLFormatter(sqlformat.Format)
+	defer execution.SetSQLFormatter(nil)
+
+	qb := NewBuilder(&MockExecutor{driver: types.MySQL}, types.MySQL)
+	qb.table = "users"
+	qb.Where("status", "active").Where("age", ">", 18)
+
+	raw, err := qb.ToRawSQL()
+	expected := "SELECT *\nFROM users\nWHERE status = 'active'\n  AND age > 18"
+	if err != nil || raw != expected {
+		t.Errorf("Expected %q, got: %q (%v)", expected, raw, err)
+	}
+}
+
+// rowsExecutor answers every query with a copy of rows.
+type rowsExecutor struct {
+	MockExecutor
+	rows mockRows
+}
+
+func (r *rowsExecutor) QueryContext(_ context.Context, _ string, _ ...interface{}) (types.Rows, error) {
+	rows := r.rows
+	return &rows, nil
+}
+
+func TestExportCSVAndNDJSON(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	executor := &rowsExecutor{
+		MockExecutor: MockExecutor{driver: types.MySQL},
+		rows: mockRows{
+			columns: []string{"id", "name", "email", "created_at"},
+			data: [][]interface{}{
+				{int64(1), []byte("Ada, Countess"), nil, created},
+				{int64(2), "Grace", "grace@example.com", created},
+			},
+		},
+	}
+
+	var csvOut strings.Builder
+	err := Table(executor, types.MySQL, "users").ExportCSV(context.Background(), &csvOut, types.ExportOptions{Null: "NULL"})
+	if err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	wantCSV := "id,name,email,created_at\n" +
+		"1,\"Ada, Countess\",NULL,2024-05-01T12:00:00Z\n" +
+		"2,Grace,grace@example.com,2024-05-01T12:00:00Z\n"
+	if csvOut.String() != wantCSV {
+		t.Errorf("Unexpected CSV:\n%s", csvOut.String())
+	}
+
+	var tsvOut strings.Builder
+	err = Table(executor, types.MySQL, "users").ExportCSV(context.Background(), &tsvOut, types.ExportOptions{NoHeader: true, Delimiter: '\t'})
+	if err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	if !strings.HasPrefix(tsvOut.String(), "1\tAda, Countess\t\t") {
+		t.Errorf("Unexpected TSV:\n%s", tsvOut.String())
+	}
+
+	var ndjson strings.Builder
+	if err := Table(executor, types.MySQL, "users").ExportNDJSON(context.Background(), &ndjson); err != nil {
+		t.Fatalf("ExportNDJSON failed: %v", err)
+	}
+	wantNDJSON := `{"id":1,"name":"Ada, Countess","email":null,"created_at":"2024-05-01T12:00:00Z"}` + "\n" +
+		`{"id":2,"name":"Grace","email":"grace@example.com","created_at":"2024-05-01T12:00:00Z"}` + "\n"
+	if ndjson.String() != wantNDJSON {
+		t.Errorf("Unexpected NDJSON:\n%s", ndjson.String())
+	}
+}
+
+func TestImportCSVAndNDJSON(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	input := "Name,E-mail,Ignored\n" +
+		"Ada,ada@example.com,x\n" +
+		"Grace,,x\n" +
+		",nobody@example.com,x\n" +
+		"Linus,linus@example.com\n" +
+		"Ken,ken@example.com,x\n"
+
+	report, err := Table(executor, types.MySQL, "users").ImportCSV(context.Background(), strings.NewReader(input), types.ImportOptions{
+		Columns:    map[string]string{"Name": "name", "E-mail": "email"},
+		NullValues: []string{""},
+		BatchSize:  2,
+		Validate: func(row map[string]interface{}) error {
+			if row["name"] == nil {
+				return errors.New("name is required")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	if report.Inserted != 3 {
+		t.Errorf("Expected 3 inserted rows, got %d", report.Inserted)
+	}
+	if len(report.Rejected) != 2 || report.Rejected[0].Line != 4 || report.Rejected[1].Line != 5 {
+		t.Errorf("Unexpected rejected rows: %+v", report.Rejected)
+	}
+	if len(executor.execs) != 2 || !strings.HasPrefix(executor.execs[0], "INSERT INTO users (email, name) VALUES (?, ?), (?, ?)") {
+		t.Errorf("Unexpected statements: %v", executor.execs)
+	}
+
+	_, err = Table(executor, types.MySQL, "users").ImportCSV(context.Background(), strings.NewReader("name; DROP\nx\n"))
+	if err == nil {
+		t.Error("Expected an error for an unmapped header that is not a valid column name")
+	}
+
+	executor.execs = nil
+	ndjson := `{"name":"Ada","age":36,"tags":["math"]}` + "\n\n" +
+		`{"name":"Grace"}` + "\n" +
+		`{not json}` + "\n"
+	report, err = Table(executor, types.MySQL, "users").ImportNDJSON(context.Background(), strings.NewReader(ndjson), types.ImportOptions{MaxRejected: 1})
+	if err != nil {
+		t.Fatalf("ImportNDJSON failed: %v", err)
+	}
+	if report.Inserted != 2 || len(report.Rejected) != 1 || report.Rejected[0].Line != 4 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(executor.execs) != 1 || !strings.HasPrefix(executor.execs[0], "INSERT INTO users (age, name, tags) VALUES (?, ?, ?), (?, ?, ?)") {
+		t.Errorf("Unexpected statements: %v", executor.execs)
+	}
+
+	_, err = Table(executor, types.MySQL, "users").ImportNDJSON(context.Background(), strings.NewReader("x\ny\n"), types.ImportOptions{MaxRejected: 1})
+	if err == nil {
+		t.Error("Expected the import to abort after too many rejected rows")
+	}
+}
+
+type versionedExecutor struct {
+	recordingExecutor
+	version types.ServerVersion
+}
+
+func (v *versionedExecutor) ServerVersion() types.ServerVersion {
+	return v.version
+}
+
+func TestUpsertUsesRowAliasOnNewerMySQL(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"8.0.36", " AS new ON DUPLICATE KEY UPDATE name = new.name"},
+		{"8.0.19", " ON DUPLICATE KEY UPDATE name = VALUES(name)"},
+		{"10.11.6-MariaDB", " ON DUPLICATE KEY UPDATE name = VALUES(name)"},
+		{"", " ON DUPLICATE KEY UPDATE name = VALUES(name)"},
+	}
+
+	for _, tt := range tests {
+		executor := &versionedExecutor{
+			recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}},
+			version:           types.ParseServerVersion(types.MySQL, tt.version),
+		}
+		qb := NewBuilder(executor, types.MySQL)
+		qb.table = "users"
+		_, err := execution.NewQueryExecutor(executor, types.MySQL).Upsert(context.Background(), qb,
+			[]map[string]interface{}{{"id": 1, "name": "Ada"}}, types.UpsertOptions{UpdateColumns: []string{"name"}})
+		if err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+		if len(executor.execs) != 1 || !strings.HasSuffix(executor.execs[0], tt.want) {
+			t.Errorf("version %q: unexpected upsert %v", tt.version, executor.execs)
+		}
+	}
+}
+
+func TestUpsertSplitsIntoBatches(t *testing.T) {
+	executor := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	rows := make([]map[string]interface{}, 4)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "user"}
+	}
+
+	result, err := execution.NewQueryExecutor(executor, types.MySQL).Upsert(context.Background(), Table(executor, types.MySQL, "users").(*Builder),
+		rows, types.UpsertOptions{UpdateColumns: []string{"name"}, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	pair := "INSERT INTO users (id, name) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)"
+	if expected := []string{pair, pair, "COMMIT"}; !reflect.DeepEqual(executor.execs, expected) {
+		t.Errorf("Expected statements %v, got %v", expected, executor.execs)
+	}
+	if result.Affected != 6 || result.Inserted != -1 || result.Updated != -1 {
+		t.Errorf("Expected the batch counts added up, got %+v", result)
+	}
+
+	executor.execs = nil
+	debugged := execution.NewQueryExecutor(executor, types.MySQL).SetDebug(true)
+	if _, err := debugged.Upsert(context.Background(), Table(executor, types.MySQL, "users").(*Builder),
+		rows, types.UpsertOptions{UpdateColumns: []string{"name"}, BatchSize: 2}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if info := debugged.DebugInfo(); info == nil || !strings.Contains(info.SQL, "ON DUPLICATE KEY UPDATE") {
+		t.Errorf("Expected the batches in the upsert transaction to be debugged, got %+v", info)
+	}
+}
+
+func TestStream(t *testing.T) {
+	executor := &rowsExecutor{
+		MockExecutor: MockExecutor{driver: types.MySQL},
+		rows: mockRows{
+			columns: []string{"id"},
+			data:    [][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}},
+		},
+	}
+
+	rows, errs := Table(executor, types.MySQL, "users").Stream(context.Background(), 1)
+	var ids []int64
+	for row := range rows {
+		ids = append(ids, row["id"].(int64))
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("Unexpected rows: %v", ids)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, errs = Table(executor, types.MySQL, "users").Stream(ctx, 1)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a blocked stream to stop on cancellation, got %v", err)
+	}
+}
+
+// pagedExecutor answers each query with the next page of ids, repeated in every one of its columns
+// (default "id"), and records the queries.
+type pagedExecutor struct {
+	MockExecutor
+	columns  []string
+	pages    [][]int64
+	calls    int
+	queries  []string
+	bindings [][]interface{}
+}
+
+func (p *pagedExecutor) QueryContext(_ context.Context, query string, args ...interface{}) (types.Rows, error) {
+	rows := &mockRows{columns: p.columns}
+	if len(rows.columns) == 0 {
+		rows.columns = []string{"id"}
+	}
+	if p.calls < len(p.pages) {
+		for _, id := range p.pages[p.calls] {
+			row := make([]interface{}, len(rows.columns))
+			for i := range row {
+				row[i] = id
+			}
+			rows.data = append(rows.data, row)
+		}
+	}
+	p.calls++
+	p.queries = append(p.queries, query)
+	p.bindings = append(p.bindings, args)
+	return rows, nil
+}
+
+func TestChunkConcurrent(t *testing.T) {
+	newExecutor := func() *pagedExecutor {
+		return &pagedExecutor{
+			MockExecutor: MockExecutor{driver: types.MySQL},
+			pages:        [][]int64{{1, 2}, {3, 4}, {5, 6}, {7}},
+		}
+	}
+
+	executor := newExecutor()
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	var mu sync.Mutex
+	var sum int64
+	err := execution.NewQueryExecutor(executor, types.MySQL).ChunkConcurrent(context.Background(), qb, 2, 3,
+		func(chunk types.Collection) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, row := range chunk.ToSlice() {
+				sum += row["id"].(int64)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("ChunkConcurrent failed: %v", err)
+	}
+	if sum != 28 {
+		t.Errorf("Expected every row to be processed once, got sum %d", sum)
+	}
+
+	executor = newExecutor()
+	qb = NewBuilder(executor, types.MySQL)
+	qb.table = "users"
+	failure := errors.New("boom")
+	err = execution.NewQueryExecutor(executor, types.MySQL).ChunkConcurrent(context.Background(), qb, 2, 1,
+		func(chunk types.Collection) error {
+			if chunk.First()["id"] == int64(3) {
+				return failure
+			}
+			return nil
+		})
+	var batchErr *types.BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, failure) {
+		t.Fatalf("Expected a BatchError wrapping the callback error, got %v", err)
+	}
+	if executor.calls == 4 {
+		t.Errorf("Expected fetching to stop after the failure")
+	}
+}
+
+func TestStreamingChunk(t *testing.T) {
+	ctx := context.Background()
+	executor := &pagedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, pages: [][]int64{{1, 2, 3, 4, 5}}}
+	qb := Table(executor, types.MySQL, "events").(*Builder)
+	exec := execution.NewQueryExecutor(executor, types.MySQL).SetStreaming(true)
+
+	var sizes []int
+	err := exec.Chunk(ctx, qb, 2, func(chunk types.Collection) error {
+		sizes = append(sizes, chunk.Count())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Chunk failed: %v", err)
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[2] != 1 {
+		t.Errorf("Expected chunks of 2, 2 and 1 rows, got %v", sizes)
+	}
+	if len(executor.queries) != 1 || strings.Contains(executor.queries[0], "LIMIT") {
+		t.Errorf("Expected a single unpaged query, got %v", executor.queries)
+	}
+
+	executor.calls, executor.queries = 0, nil
+	var seen []int64
+	stop := errors.New("stop")
+	err = exec.Each(ctx, qb, func(row map[string]interface{}) error {
+		seen = append(seen, row["id"].(int64))
+		if len(seen) == 3 {
+			return stop
+		}
+		return nil
+	}, 2)
+	if !errors.Is(err, stop) || len(seen) != 3 || len(executor.queries) != 1 {
+		t.Errorf("Expected Each to stream until the callback stops it, got %v after %v, %v", seen, executor.queries, err)
+	}
+}
+
+func TestChunkOrderColumns(t *testing.T) {
+	executor := &pagedExecutor{
+		MockExecutor: MockExecutor{driver: types.MySQL},
+		columns:      []string{"tenant_id", "user_id"},
+		pages:        [][]int64{{1, 2}, {3}},
+	}
+	qb := NewBuilder(executor, types.MySQL)
+	qb.table = "memberships"
+	exec := execution.NewQueryExecutor(executor, types.MySQL).SetChunkOrder("tenant_id", "user_id")
+
+	chunks := 0
+	err := exec.ChunkByID(context.Background(), qb, 2, func(types.Collection) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChunkByID failed: %v", err)
+	}
+	if chunks != 2 || len(executor.queries) != 2 {
+		t.Fatalf("Expected 2 chunks from 2 queries, got %d from %v", chunks, executor.queries)
+	}
+	want := "SELECT * FROM memberships WHERE ((tenant_id > ?) OR (tenant_id = ? AND user_id > ?)) ORDER BY tenant_id ASC, user_id ASC LIMIT 2"
+	if executor.queries[1] != want {
+		t.Errorf("Unexpected keyset query:\n got %s\nwant %s", executor.queries[1], want)
+	}
+	if b := executor.bindings[1]; len(b) != 3 || b[0] != int64(2) || b[2] != int64(2) {
+		t.Errorf("Unexpected keyset bindings: %v", b)
+	}
+
+	executor.calls, executor.queries = 0, nil
+	lazy, err := exec.Lazy(context.Background(), qb, 2)
+	if err != nil {
+		t.Fatalf("Lazy failed: %v", err)
+	}
+	if rows, _ := lazy.ToSlice(); len(rows) != 3 {
+		t.Errorf("Expected 3 rows, got %d", len(rows))
+	}
+	if !strings.Contains(executor.queries[0], "ORDER BY tenant_id ASC, user_id ASC LIMIT 2 OFFSET 0") {
+		t.Errorf("Expected Lazy to use the chunk order, got %s", executor.queries[0])
+	}
+
+	if err := exec.Chunk(context.Background(), qb, 2, func(types.Collection) error { return nil }, "id; DROP TABLE x"); err == nil {
+		t.Error("Expected an invalid chunk column to be rejected")
+	}
+}
+
+// snapshotExecutor is a pagedExecutor that records the options of the transactions it begins and
+// the statements executed in them.
+type snapshotExecutor struct {
+	pagedExecutor
+	options []*types.TxOptions
+	execs   []string
+}
+
+func (s *snapshotExecutor) ExecContext(_ context.Context, query string, _ ...interface{}) (types.Result, error) {
+	s.execs = append(s.execs, query)
+	return mockResult{}, nil
+}
+
+func (s *snapshotExecutor) BeginTx(_ context.Context, options *types.TxOptions) (types.Tx, error) {
+	s.options = append(s.options, options)
+	return &snapshotTx{s}, nil
+}
+
+type snapshotTx struct {
+	*snapshotExecutor
+}
+
+func (t *snapshotTx) Commit() error {
+	t.execs = append(t.execs, "COMMIT")
+	return nil
+}
+
+func (t *snapshotTx) Rollback() error {
+	t.execs = append(t.execs, "ROLLBACK")
+	return nil
+}
+
+func TestSnapshotChunk(t *testing.T) {
+	ctx := context.Background()
+	readOnlySnapshot := func(options []*types.TxOptions) bool {
+		return len(options) == 1 && options[0] != nil && options[0].Isolation == types.RepeatableRead && options[0].ReadOnly
+	}
+	collect := func(ids *[]int64) types.ChunkFunc {
+		return func(chunk types.Collection) error {
+			for _, row := range chunk.ToSlice() {
+				*ids = append(*ids, row["id"].(int64))
+			}
+			return nil
+		}
+	}
+
+	pg := &snapshotExecutor{pagedExecutor: pagedExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}, pages: [][]int64{{1, 2}, {3}}}}
+	var ids []int64
+	if err := Table(pg, types.PostgreSQL, "events").Where("type", "click").SnapshotChunk(ctx, 2, collect(&ids)); err != nil {
+		t.Fatalf("SnapshotChunk failed: %v", err)
+	}
+	if len(ids) != 3 || ids[2] != 3 {
+		t.Errorf("Expected every row once, got %v", ids)
+	}
+	if !readOnlySnapshot(pg.options) {
+		t.Errorf("Expected one read-only REPEATABLE READ transaction, got %+v", pg.options)
+	}
+	want := []string{"DECLARE qb_cursor NO SCROLL CURSOR FOR SELECT * FROM events WHERE type = $1", "CLOSE qb_cursor", "COMMIT"}
+	if len(pg.execs) < len(want) || strings.Join(pg.execs[:len(want)], "; ") != strings.Join(want, "; ") {
+		t.Errorf("Unexpected cursor statements: %v", pg.execs)
+	}
+	if len(pg.queries) != 2 || pg.queries[0] != "FETCH FORWARD 2 FROM qb_cursor" || pg.queries[1] != pg.queries[0] {
+		t.Errorf("Expected two fetches, got %v", pg.queries)
+	}
+
+	mysql := &snapshotExecutor{pagedExecutor: pagedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, pages: [][]int64{{1, 2}, {3}}}}
+	ids = nil
+	if err := Table(mysql, types.MySQL, "events").SnapshotChunk(ctx, 2, collect(&ids)); err != nil {
+		t.Fatalf("SnapshotChunk failed: %v", err)
+	}
+	if len(ids) != 3 || !readOnlySnapshot(mysql.options) || len(mysql.execs) != 1 || mysql.execs[0] != "COMMIT" {
+		t.Errorf("Expected chunks read in one committed snapshot, got %v, %+v, %v", ids, mysql.options, mysql.execs)
+	}
+	if len(mysql.queries) != 2 || !strings.HasSuffix(mysql.queries[1], "LIMIT 2 OFFSET 2") {
+		t.Errorf("Expected offset chunk queries, got %v", mysql.queries)
+	}
+
+	ordered := &snapshotExecutor{pagedExecutor: pagedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, pages: [][]int64{{1}}}}
+	err := execution.NewQueryExecutor(ordered, types.MySQL).
+		SetChunkOrder("created_at").
+		SetComment(map[string]string{"route": "report"}).
+		SnapshotChunk(ctx, NewBuilder(ordered, types.MySQL).From("events").(*Builder), 2, collect(&ids))
+	if err != nil {
+		t.Fatalf("SnapshotChunk failed: %v", err)
+	}
+	if len(ordered.queries) != 1 || !strings.HasPrefix(ordered.queries[0], "/*route='report'*/ ") || !strings.Contains(ordered.queries[0], "ORDER BY created_at") {
+		t.Errorf("Expected the snapshot to keep the chunk order and comment, got %v", ordered.queries)
+	}
+
+	if err := Table(mysql, types.MySQL, "events").ServerCursorChunk(ctx, 2, collect(&ids)); err == nil {
+		t.Error("Expected server-side cursors to be rejected on MySQL")
+	}
+}
+
+func TestServerCursorChunk(t *testing.T) {
+	ctx := context.Background()
+	newExecutor := func(pages ...[]int64) *snapshotExecutor {
+		return &snapshotExecutor{pagedExecutor: pagedExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}, pages: pages}}
+	}
+
+	executor := newExecutor([]int64{1, 2}, []int64{3, 4})
+	chunks := 0
+	err := Table(executor, types.PostgreSQL, "events").ServerCursorChunk(ctx, 2, func(types.Collection) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ServerCursorChunk failed: %v", err)
+	}
+	if chunks != 2 || len(executor.queries) != 3 {
+		t.Errorf("Expected full pages to be followed by a final empty fetch, got %d chunks from %v", chunks, executor.queries)
+	}
+
+	executor = newExecutor([]int64{1, 2}, []int64{3, 4})
+	failure := errors.New("boom")
+	err = Table(executor, types.PostgreSQL, "events").ServerCursorChunk(ctx, 2, func(types.Collection) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the callback error, got %v", err)
+	}
+	if len(executor.queries) != 1 || len(executor.execs) != 2 || executor.execs[1] != "ROLLBACK" {
+		t.Errorf("Expected the cursor to stop and roll back, got %v, %v", executor.queries, executor.execs)
+	}
+
+	logger := &capturingLogger{}
+	execution.SetLogger(logger)
+	defer execution.SetLogger(nil)
+	executor = newExecutor([]int64{1})
+	err = execution.NewQueryExecutor(executor, types.PostgreSQL).
+		SetReadOnly(true).
+		SetComment(map[string]string{"route": "report"}).
+		ServerCursorChunk(ctx, NewBuilder(executor, types.PostgreSQL).From("events").(*Builder), 2, func(types.Collection) error { return nil })
+	if err != nil {
+		t.Fatalf("ServerCursorChunk failed on a read-only executor: %v", err)
+	}
+	if len(executor.queries) != 1 || len(executor.execs) < 2 {
+		t.Fatalf("Expected one fetch between DECLARE and CLOSE, got %v, %v", executor.queries, executor.execs)
+	}
+	for _, statement := range []string{executor.execs[0], executor.queries[0], executor.execs[1]} {
+		if !strings.HasPrefix(statement, "/*route='report'*/ ") {
+			t.Errorf("Expected every cursor statement to be tagged, got %q", statement)
+		}
+	}
+	if len(logger.entries) != 3 || !strings.HasPrefix(logger.entries[0].SQL, "DECLARE") ||
+		!strings.HasPrefix(logger.entries[1].SQL, "FETCH") || logger.entries[1].Rows != 1 || logger.entries[2].SQL != "CLOSE qb_cursor" {
+		t.Errorf("Expected DECLARE, FETCH and CLOSE to be logged, got %+v", logger.entries)
+	}
+
+	if err := Table(executor, types.PostgreSQL, "events").ServerCursorChunk(ctx, 0, func(types.Collection) error { return nil }); err == nil {
+		t.Error("Expected a non-positive chunk size to be rejected")
+	}
+}
+
+// countingExecutor records the count queries of a recordingExecutor and returns no rows.
+type countingExecutor struct {
+	recordingExecutor
+}
+
+func (c *countingExecutor) QueryContext(_ context.Context, _ string, _ ...interface{}) (types.Rows, error) {
+	return &mockRows{columns: []string{"id"}}, nil
+}
+
+func TestPaginateCountsGroupedQueriesAsSubquery(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(types.QueryBuilder) types.QueryBuilder
+		want  string
+	}{
+		{"plain", func(qb types.QueryBuilder) types.QueryBuilder {
+			return qb.Where("status", "active").OrderBy("id")
+		}, "SELECT COUNT(*) as aggregate FROM orders WHERE status = ?"},
+		{"grouped", func(qb types.QueryBuilder) types.QueryBuilder {
+			return qb.Select("customer_id").GroupBy("customer_id").OrderBy("customer_id")
+		}, "SELECT COUNT(*) FROM (SELECT customer_id FROM orders GROUP BY customer_id) AS count_query"},
+		{"distinct", func(qb types.QueryBuilder) types.QueryBuilder {
+			return qb.Select("customer_id").Distinct()
+		}, "SELECT COUNT(*) FROM (SELECT DISTINCT customer_id FROM orders) AS count_query"},
+	}
+
+	for _, tt := range tests {
+		executor := &countingExecutor{recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, count: 42}}
+		result, err := tt.build(Table(executor, types.MySQL, "orders")).Paginate(context.Background(), 2, 10)
+		if err != nil {
+			t.Fatalf("%s: Paginate failed: %v", tt.name, err)
+		}
+		if result.Meta.Total != 42 || result.Meta.LastPage != 5 {
+			t.Errorf("%s: unexpected meta %+v", tt.name, result.Meta)
+		}
+		if len(executor.queries) != 1 || executor.queries[0] != tt.want {
+			t.Errorf("%s: unexpected count query %v", tt.name, executor.queries)
+		}
+	}
+}
+
+func TestUnionOrderingAppliesToCombinedResult(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	archived := Table(executor, types.PostgreSQL, "archived_posts").Select("id", "title").Where("author_id", 7)
+	qb := Table(executor, types.PostgreSQL, "posts").Select("id", "title").Where("author_id", 7).
+		Union(archived).UnionOrderBy("id", types.Desc).Limit(10).Offset(20)
+
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	want := "(SELECT id, title FROM posts WHERE author_id = $1) UNION (SELECT id, title FROM archived_posts WHERE author_id = $2) ORDER BY id DESC LIMIT 10 OFFSET 20"
+	if sql != want {
+		t.Errorf("Unexpected union SQL:\n got %s\nwant %s", sql, want)
+	}
+	if len(bindings) != 2 {
+		t.Errorf("Expected 2 bindings, got %v", bindings)
+	}
+
+	counter := &countingExecutor{recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, count: 3}}
+	posts := Table(counter, types.MySQL, "posts").UnionAll(Table(counter, types.MySQL, "drafts")).OrderBy("id")
+	if _, err := posts.Paginate(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	wantCount := "SELECT COUNT(*) FROM ((SELECT * FROM posts) UNION ALL (SELECT * FROM drafts)) AS count_query"
+	if len(counter.queries) != 1 || counter.queries[0] != wantCount {
+		t.Errorf("Unexpected union count query %v", counter.queries)
+	}
+}
+
+func TestIntersectAndExcept(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	buyers := Table(executor, types.PostgreSQL, "orders").Select("customer_id")
+	sql, _, err := Table(executor, types.PostgreSQL, "customers").Select("id").Where("active", true).
+		Intersect(buyers).Except(Table(executor, types.PostgreSQL, "bans").Select("customer_id")).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	want := "(SELECT id FROM customers WHERE active = $1) INTERSECT (SELECT customer_id FROM orders) EXCEPT (SELECT customer_id FROM bans)"
+	if sql != want {
+		t.Errorf("Unexpected set operation SQL:\n got %s\nwant %s", sql, want)
+	}
+
+	newMySQL := func(version string) *versionedExecutor {
+		return &versionedExecutor{
+			recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}},
+			version:           types.ParseServerVersion(types.MySQL, version),
+		}
+	}
+
+	native := newMySQL("8.0.31")
+	sql, _, _ = Table(native, types.MySQL, "customers").Select("id").Except(Table(native, types.MySQL, "bans").Select("customer_id")).ToSQL()
+	if sql != "(SELECT id FROM customers) EXCEPT (SELECT customer_id FROM bans)" {
+		t.Errorf("Expected native EXCEPT on MySQL 8.0.31, got %s", sql)
+	}
+
+	old := newMySQL("5.7.44")
+	sql, bindings, err := Table(old, types.MySQL, "customers").Select("customers.id").Where("active", true).
+		Intersect(Table(old, types.MySQL, "orders").Select("customer_id").Where("total", ">", 100)).
+		OrderBy("id").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	want = "SELECT DISTINCT * FROM (SELECT customers.id FROM customers WHERE active = ?) AS set_left WHERE EXISTS " +
+		"(SELECT 1 FROM (SELECT customer_id FROM orders WHERE total > ?) AS set_right WHERE set_right.customer_id <=> set_left.id) ORDER BY id ASC"
+	if sql != want {
+		t.Errorf("Unexpected emulated INTERSECT:\n got %s\nwant %s", sql, want)
+	}
+	if len(bindings) != 2 || bindings[0] != true || bindings[1] != 100 {
+		t.Errorf("Unexpected bindings: %v", bindings)
+	}
+
+	_, _, err = Table(old, types.MySQL, "customers").Select("id").Except(Table(old, types.MySQL, "bans").Select("*")).ToSQL()
+	if !errors.Is(err, types.ErrUnsupportedFeature) {
+		t.Errorf("Expected EXCEPT on * to be rejected on MySQL 5.7, got %v", err)
+	}
+}
+
+func TestHavingAggregatesAndRawBindings(t *testing.T) {
+	executor := &MockExecutor{driver: types.PostgreSQL}
+	sql, bindings, err := Table(executor, types.PostgreSQL, "orders").Select("customer_id").Where("status", "paid").
+		GroupBy("customer_id").HavingCount("> ", 5).HavingSum("total", ">=", 1000).
+		OrHavingRaw("MAX(total) > ? AND MIN(total) < ?", 500, 10).Limit(10).ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	want := "SELECT customer_id FROM orders WHERE status = $1 GROUP BY customer_id " +
+		"HAVING COUNT(*) > $2 AND SUM(total) >= $3 OR MAX(total) > $4 AND MIN(total) < $5 LIMIT 10"
+	if sql != want {
+		t.Errorf("Unexpected HAVING SQL:\n got %s\nwant %s", sql, want)
+	}
+	if len(bindings) != 5 || bindings[1] != 5 || bindings[2] != 1000 || bindings[3] != 500 || bindings[4] != 10 {
+		t.Errorf("Unexpected bindings: %v", bindings)
+	}
+}
+
+func TestRawBindingsFollowClausePositions(t *testing.T) {
+	tests := []struct {
+		driver types.Driver
+		want   string
+	}{
+		{types.MySQL, "SELECT id, LENGTH(name) > ? AS long_name FROM users WHERE status = ? AND age > ? OR JSON_EXTRACT(meta, '$.vip') = ? " +
+			"AND score > ? GROUP BY id HAVING COUNT(*) > ? ORDER BY id ASC"},
+		{types.PostgreSQL, "SELECT id, LENGTH(name) > $1 AS long_name FROM users WHERE status = $2 AND age > $3 OR meta ->> '$.vip' = $4 " +
+			"AND score > $5 GROUP BY id HAVING COUNT(*) > $6 ORDER BY id ASC"},
+	}
+
+	for _, tt := range tests {
+		executor := &MockExecutor{driver: tt.driver}
+		qb := Table(executor, tt.driver, "users").Select("id").SelectRaw("LENGTH(name) > ? AS long_name", 10).
+			Where("status", "active").WhereRaw("age > ?", 18).OrWhereJSONPath("meta", "$.vip", true).
+			Where("score", ">", 50).GroupBy("id").HavingRaw("COUNT(*) > ?", 2).OrderBy("id")
+
+		sql, bindings, err := qb.ToSQL()
+		if err != nil {
+			t.Fatalf("%s: ToSQL failed: %v", tt.driver, err)
+		}
+		if sql != tt.want {
+			t.Errorf("%s: unexpected SQL:\n got %s\nwant %s", tt.driver, sql, tt.want)
+		}
+		want := []interface{}{10, "active", 18, true, 50, 2}
+		if len(bindings) != len(want) {
+			t.Fatalf("%s: expected bindings %v, got %v", tt.driver, want, bindings)
+		}
+		for i := range want {
+			if bindings[i] != want[i] {
+				t.Errorf("%s: expected bindings %v, got %v", tt.driver, want, bindings)
+				break
+			}
+		}
+	}
+}
+
+func TestImmutableBuilder(t *testing.T) {
+	executor := &MockExecutor{driver: types.MySQL}
+	base := Table(executor, types.MySQL, "users").Where("active", true).
+		Scope(func(q types.QueryBuilder) types.QueryBuilder { return q.WhereNull("deleted_at") }).
+		Immutable()
+	if !base.IsImmutable() {
+		t.Fatal("Expected an immutable builder")
+	}
+
+	admins := base.Where("role", "admin").OrderBy("name")
+	tapped := base.Tap(func(q types.QueryBuilder) types.QueryBuilder { return q.Limit(5) })
+	scoped := base.Scope(func(q types.QueryBuilder) types.QueryBuilder { return q.Where("verified", true) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, _ = base.Where("id", i).ToSQL()
+		}(i)
+	}
+	wg.Wait()
+
+	tests := []struct {
+		qb   types.QueryBuilder
+		want string
+	}{
+		{base, "SELECT * FROM users WHERE active = ? AND deleted_at IS NULL"},
+		{admins, "SELECT * FROM users WHERE active = ? AND deleted_at IS NULL AND role = ? ORDER BY name ASC"},
+		{tapped, "SELECT * FROM users WHERE active = ? AND deleted_at IS NULL LIMIT 5"},
+		{scoped, "SELECT * FROM users WHERE active = ? AND deleted_at IS NULL AND verified = ?"},
+	}
+	for _, tt := range tests {
+		if sql, _, _ := tt.qb.ToSQL(); sql != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, sql)
+		}
+	}
+	if !admins.IsImmutable() {
+		t.Error("Expected builders derived from an immutable builder to be immutable")
+	}
+
+	mutable := Table(executor, types.MySQL, "users")
+	if mutable.Where("id", 1) != mutable {
+		t.Error("Expected the default builder to keep modifying itself")
+	}
+}
+
+func TestTableMetadata(t *testing.T) {
+	schema.Register("accounts", types.TableMeta{
+		PrimaryKey: "account_id",
+		KeyType:    types.IntKey,
+		SoftDelete: "deleted_at",
+		CreatedAt:  "inserted_at",
+		UpdatedAt:  "modified_at",
+		Guarded:    []string{"is_admin"},
+	})
+	t.Cleanup(schema.Flush)
+	ctx := context.Background()
+
+	finder := &pagedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, columns: []string{"account_id"}, pages: [][]int64{{7}}}
+	row, err := Table(finder, types.MySQL, "accounts").Find(ctx, "7")
+	if err != nil || row["account_id"] != int64(7) {
+		t.Fatalf("Expected account 7, got %v, %v", row, err)
+	}
+	if expected := "SELECT * FROM accounts WHERE account_id = ? AND deleted_at IS NULL LIMIT 1"; finder.queries[0] != expected || finder.bindings[0][0] != int64(7) {
+		t.Errorf("Expected %q with an integer key, got %q %v", expected, finder.queries[0], finder.bindings[0])
+	}
+	if _, err := Table(finder, types.MySQL, "accounts").Find(ctx, "abc"); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for a non-integer key, got %v", err)
+	}
+
+	var notFound *types.NotFoundError
+	if _, err := Table(finder, types.MySQL, "accounts").FindOrFail(ctx, "8"); !errors.As(err, &notFound) || !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("Expected a NotFoundError, got %v", err)
+	} else if notFound.Table != "accounts" || notFound.Key != "account_id" || notFound.ID != int64(8) {
+		t.Errorf("Expected the error to name the table, key and ID, got %+v", notFound)
+	}
+
+	many := &pagedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, columns: []string{"account_id"}, pages: [][]int64{{7, 9}}}
+	rows, err := Table(many, types.MySQL, "accounts").FindMany(ctx, []string{"7", "8", "9"})
+	if err != nil || rows.Count() != 2 {
+		t.Fatalf("Expected the two existing accounts, got %v, %v", rows, err)
+	}
+	if expected := "SELECT * FROM accounts WHERE account_id IN (?, ?, ?) AND deleted_at IS NULL"; many.queries[0] != expected || many.bindings[0][2] != int64(9) {
+		t.Errorf("Expected %q with integer keys, got %q %v", expected, many.queries[0], many.bindings[0])
+	}
+	if rows, err := Table(many, types.MySQL, "accounts").FindMany(ctx, []int{}); err != nil || rows.Count() != 0 || many.calls != 1 {
+		t.Errorf("Expected no query for an empty ID list, got %v, %v after %d calls", rows, err, many.calls)
+	}
+
+	chunker := &pagedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, columns: []string{"account_id"}}
+	chunkQB := NewBuilder(chunker, types.MySQL)
+	chunkQB.table = "accounts"
+	if err := execution.NewQueryExecutor(chunker, types.MySQL).ChunkByID(ctx, chunkQB, 10, func(types.Collection) error { return nil }); err != nil {
+		t.Fatalf("ChunkByID failed: %v", err)
+	}
+	if !strings.Contains(chunker.queries[0], "ORDER BY account_id ASC") {
+		t.Errorf("Expected chunks ordered by the primary key, got %q", chunker.queries[0])
+	}
+
+	selects := []struct {
+		qb       types.QueryBuilder
+		expected string
+	}{
+		{
+			Table(&MockExecutor{}, types.MySQL, "accounts").Where("status", "active").OrWhere("vip", true).Latest(),
+			"SELECT * FROM accounts WHERE (status = ? OR vip = ?) AND deleted_at IS NULL ORDER BY inserted_at DESC",
+		},
+		{Table(&MockExecutor{}, types.MySQL, "accounts").OnlyTrashed(), "SELECT * FROM accounts WHERE deleted_at IS NOT NULL"},
+		{Table(&MockExecutor{}, types.MySQL, "accounts").WithTrashed(), "SELECT * FROM accounts"},
+		{
+			Table(&MockExecutor{}, types.MySQL, "accounts a").Join("users", "users.id", "=", "a.user_id"),
+			"SELECT * FROM accounts a INNER JOIN users ON users.id = a.user_id WHERE a.deleted_at IS NULL",
+		},
+	}
+	for _, tc := range selects {
+		if sql, _, err := tc.qb.ToSQL(); err != nil || sql != tc.expected {
+			t.Errorf("Expected %q, got %q (%v)", tc.expected, sql, err)
+		}
+	}
+
+	writer := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	accounts := func() types.QueryBuilder { return Table(writer, types.MySQL, "accounts") }
+	if _, err := accounts().Where("account_id", 1).Delete(ctx); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := accounts().Where("account_id", 1).ForceDelete(ctx); err != nil {
+		t.Fatalf("ForceDelete failed: %v", err)
+	}
+	if _, err := accounts().Where("account_id", 1).Restore(ctx); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if err := accounts().Insert(ctx, map[string]interface{}{"name": "Ada"}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	expected := []string{
+		"UPDATE accounts SET deleted_at = ?, modified_at = ? WHERE account_id = ? AND deleted_at IS NULL",
+		"DELETE FROM accounts WHERE account_id = ? AND deleted_at IS NULL",
+		"UPDATE accounts SET deleted_at = ?, modified_at = ? WHERE account_id = ? AND deleted_at IS NOT NULL",
+		"INSERT INTO accounts (inserted_at, modified_at, name) VALUES (?, ?, ?)",
+	}
+	if strings.Join(writer.execs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(writer.execs, "\n"))
+	}
+
+	if err := accounts().Insert(ctx, map[string]interface{}{"name": "Eve", "is_admin": true}); !errors.Is(err, types.ErrGuardedColumn) {
+		t.Errorf("Expected ErrGuardedColumn from Insert, got %v", err)
+	}
+	if _, err := accounts().Where("account_id", 2).Update(ctx, map[string]interface{}{"is_admin": true}); !errors.Is(err, types.ErrGuardedColumn) {
+		t.Errorf("Expected ErrGuardedColumn from Update, got %v", err)
+	}
+	if _, err := Table(writer, types.MySQL, "users").Where("id", 1).Restore(ctx); err == nil {
+		t.Error("Expected Restore to fail on a table without a soft delete column")
+	}
+}
+
+func TestHostileColumnNamesAreRejected(t *testing.T) {
+	ctx := context.Background()
+	writer := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	executor := execution.NewQueryExecutor(writer, types.MySQL)
+	users := func() types.QueryBuilder { return Table(writer, types.MySQL, "users") }
+
+	hostile := []string{
+		"name) VALUES ('x'); DROP TABLE users; --",
+		"name = 1, is_admin",
+		"name`",
+		"",
+	}
+	for _, column := range hostile {
+		values := map[string]interface{}{column: "x"}
+		if err := users().Insert(ctx, values); !errors.Is(err, types.ErrInvalidIdentifier) {
+			t.Errorf("Insert %q: expected ErrInvalidIdentifier, got %v", column, err)
+		}
+		if err := users().InsertBatch(ctx, []map[string]interface{}{values}); !errors.Is(err, types.ErrInvalidIdentifier) {
+			t.Errorf("InsertBatch %q: expected ErrInvalidIdentifier, got %v", column, err)
+		}
+		if _, err := users().Where("id", 1).Update(ctx, values); !errors.Is(err, types.ErrInvalidIdentifier) {
+			t.Errorf("Update %q: expected ErrInvalidIdentifier, got %v", column, err)
+		}
+		if _, err := executor.Increment(ctx, users().Where("id", 1).(*Builder), column); !errors.Is(err, types.ErrInvalidIdentifier) {
+			t.Errorf("Increment %q: expected ErrInvalidIdentifier, got %v", column, err)
+		}
+	}
+
+	_, err := executor.Upsert(ctx, users().(*Builder),
+		[]map[string]interface{}{{"id": 1, "name": "Ada"}}, types.UpsertOptions{UpdateColumns: []string{"name = 'x', is_admin"}})
+	if !errors.Is(err, types.ErrInvalidIdentifier) {
+		t.Errorf("Upsert: expected ErrInvalidIdentifier, got %v", err)
+	}
+	if len(writer.execs) != 0 {
+		t.Fatalf("Expected nothing to run, got %v", writer.execs)
+	}
+
+	if err := users().Insert(ctx, map[string]interface{}{"system": 1, "users.created_at": "now"}); err != nil {
+		t.Errorf("Expected keyword-like and qualified columns to be accepted, got %v", err)
+	}
+}
+
+func TestAttributeCasts(t *testing.T) {
+	t.Cleanup(schema.Flush)
+	schema.Register("products", types.TableMeta{Casts: map[string]types.Cast{
+		"active":     types.CastBool,
+		"price":      types.CastDecimal,
+		"attributes": types.CastJSON,
+		"created_at": types.CastTime,
+	}})
+
+	executor := &rowsExecutor{
+		MockExecutor: MockExecutor{driver: types.MySQL},
+		rows: mockRows{
+			columns: []string{"active", "price", "attributes", "created_at"},
+			data: [][]interface{}{
+				{int64(1), []byte("19.99"), []byte(`{"color":"red"}`), []byte("2024-05-01 12:30:00")},
+				{int64(0), nil, nil, nil},
+			},
+		},
+	}
+
+	rows, err := Table(executor, types.MySQL, "products").Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	first := rows.First()
+	if first["active"] != true {
+		t.Errorf("Expected a bool, got %#v", first["active"])
+	}
+	if price, ok := first["price"].(*big.Rat); !ok || price.FloatString(2) != "19.99" {
+		t.Errorf("Expected an exact decimal, got %#v", first["price"])
+	}
+	if attributes, ok := first["attributes"].(map[string]interface{}); !ok || attributes["color"] != "red" {
+		t.Errorf("Expected a decoded JSON object, got %#v", first["attributes"])
+	}
+	if created, ok := first["created_at"].(time.Time); !ok || !created.Equal(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected a time, got %#v", first["created_at"])
+	}
+	if last := rows.ToSlice()[1]; last["active"] != false || last["price"] != nil {
+		t.Errorf("Expected NULLs to stay nil, got %#v", last)
+	}
+
+	executor.rows.data = [][]interface{}{{int64(1), []byte("abc"), nil, nil}}
+	if _, err := Table(executor, types.MySQL, "products").Get(context.Background()); err == nil || !strings.Contains(err.Error(), "products.price") {
+		t.Errorf("Expected a cast error naming the column, got %v", err)
+	}
+}
+
+func TestMaskedColumns(t *testing.T) {
+	t.Cleanup(schema.Flush)
+	schema.Register("customers", types.TableMeta{Masks: map[string]types.MaskFunc{"ssn": schema.KeepLast(4), "notes": nil}})
+
+	executor := &rowsExecutor{
+		MockExecutor: MockExecutor{driver: types.MySQL},
+		rows: mockRows{
+			columns: []string{"id", "ssn", "notes"},
+			data:    [][]interface{}{{int64(1), []byte("123-45-6789"), "vip"}},
+		},
+	}
+	ctx := context.Background()
+
+	row, err := Table(executor, types.MySQL, "customers").First(ctx)
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if _, dropped := row["notes"]; row["ssn"] != "*******6789" || dropped {
+		t.Errorf("Expected masked and dropped columns, got %v", row)
+	}
+
+	var csv strings.Builder
+	if err := Table(executor, types.MySQL, "customers").ExportCSV(ctx, &csv, types.ExportOptions{}); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	if csv.String() != "id,ssn\n1,*******6789\n" {
+		t.Errorf("Expected a masked export, got %q", csv.String())
+	}
+
+	row, err = Table(executor, types.MySQL, "customers").First(schema.WithUnmask(ctx))
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if row["ssn"] != "123-45-6789" || row["notes"] != "vip" {
+		t.Errorf("Expected raw values with the unmask capability, got %v", row)
+	}
+
+	cursor, err := execution.NewQueryExecutor(executor, types.MySQL).Cursor(ctx, Table(executor, types.MySQL, "customers").(*Builder))
+	if err != nil {
+		t.Fatalf("Cursor failed: %v", err)
+	}
+	defer func() { _ = cursor.Close() }()
+	var id, ssn, notes interface{}
+	if cursor.Next(); !errors.Is(cursor.Scan(&id, &ssn, &notes), types.ErrMaskedColumns) {
+		t.Error("Expected raw cursor scans to be refused on masked tables")
+	}
+}
+
+type userKey struct{}
+
+func TestRowLevelPolicies(t *testing.T) {
+	t.Cleanup(FlushPolicies)
+	RegisterPolicy("documents", "owner", func(ctx context.Context, q types.QueryBuilder) (types.QueryBuilder, error) {
+		user, ok := ctx.Value(userKey{}).(int)
+		if !ok {
+			return nil, errors.New("no user")
+		}
+		return q.Where("owner_id", user).OrWhere("public", true), nil
+	})
+	ctx := context.WithValue(context.Background(), userKey{}, 7)
+
+	reader := &pagedExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}, columns: []string{"id"}}
+	if _, err := Table(reader, types.PostgreSQL, "documents").Where("status", "draft").OrWhere("status", "review").Get(ctx); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if expected := "SELECT * FROM documents WHERE (status = $1 OR status = $2) AND (owner_id = $3 OR public = $4)"; reader.queries[0] != expected || reader.bindings[0][2] != 7 {
+		t.Errorf("Expected %q, got %q %v", expected, reader.queries[0], reader.bindings[0])
+	}
+
+	writer := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	if _, err := Table(writer, types.MySQL, "documents").Where("id", 1).Update(ctx, map[string]interface{}{"title": "x"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := Table(writer, types.MySQL, "documents").Where("id", 1).Delete(ctx); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Table(writer, types.MySQL, "documents").Count(ctx); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	expected := []string{
+		"UPDATE documents SET title = ? WHERE id = ? AND (owner_id = ? OR public = ?)",
+		"DELETE FROM documents WHERE id = ? AND (owner_id = ? OR public = ?)",
+	}
+	for i, statement := range expected {
+		if writer.execs[i] != statement {
+			t.Errorf("Expected %q, got %q", statement, writer.execs[i])
+		}
+	}
+	if !strings.HasSuffix(writer.queries[0], "FROM documents WHERE (owner_id = ? OR public = ?)") {
+		t.Errorf("Expected the count to be restricted, got %q", writer.queries[0])
+	}
+
+	if _, err := Table(writer, types.MySQL, "documents").Get(context.Background()); err == nil || !strings.Contains(err.Error(), "no user") {
+		t.Errorf("Expected the policy to refuse the query, got %v", err)
+	}
+	if _, err := Table(writer, types.MySQL, "documents").WithoutPolicies().Count(context.Background()); err != nil {
+		t.Errorf("Expected WithoutPolicies to skip the policy, got %v", err)
+	}
+	if _, err := Table(writer, types.MySQL, "documents").Delete(ctx); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Errorf("Expected policies not to count as a where clause, got %v", err)
+	}
+}
+
+func TestFirewall(t *testing.T) {
+	fw := security.NewFirewall(security.RequireLimit("events"), security.DenyUnboundedLike(), security.MaxInSize(2))
+	SetFirewall(fw)
+	t.Cleanup(func() { SetFirewall(nil) })
+
+	if _, _, err := Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "events").ToSQL(); !errors.Is(err, types.ErrFirewallDenied) {
+		t.Errorf("Expected a select without a limit to be denied, got %v", err)
+	}
+	if _, _, err := Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "events").Limit(10).ToSQL(); err != nil {
+		t.Errorf("Expected a limited select to pass, got %v", err)
+	}
+	if _, _, err := Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "users").Where("name", "LIKE", "%ada%").ToSQL(); !errors.Is(err, types.ErrFirewallDenied) {
+		t.Errorf("Expected a leading wildcard to be denied, got %v", err)
+	}
+	if _, err := Table(&MockExecutor{driver: types.MySQL}, types.MySQL, "users").WhereIn("id", []interface{}{1, 2, 3}).Delete(context.Background()); !errors.Is(err, types.ErrFirewallDenied) {
+		t.Errorf("Expected a large IN list to be denied on delete, got %v", err)
+	}
+
+	writer := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	if _, err := Table(writer, types.MySQL, "events").Count(context.Background()); err != nil {
+		t.Errorf("Expected aggregates to be exempt from RequireLimit, got %v", err)
+	}
+	if stats := fw.Stats(); stats.Checked != 5 || stats.Denied != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	schema.Register("stock", types.TableMeta{PrimaryKeys: []string{"warehouse_id", "sku"}})
+	t.Cleanup(schema.Flush)
+	ctx := context.Background()
+
+	finder := &pagedExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}, pages: [][]int64{{1}, {1}}}
+	if _, err := Table(finder, types.PostgreSQL, "stock").Find(ctx, map[string]interface{}{"warehouse_id": 1, "sku": "A-1"}); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if _, err := Table(finder, types.PostgreSQL, "stock").FindMany(ctx, [][]interface{}{{1, "A-1"}, {2, "B-2"}}); err != nil {
+		t.Fatalf("FindMany failed: %v", err)
+	}
+	expected := []string{
+		"SELECT * FROM stock WHERE warehouse_id = $1 AND sku = $2 LIMIT 1",
+		"SELECT * FROM stock WHERE (warehouse_id, sku) IN (($1, $2), ($3, $4))",
+	}
+	if !reflect.DeepEqual(finder.queries, expected) {
+		t.Errorf("Expected %q, got %q", expected, finder.queries)
+	}
+	if want := []interface{}{1, "A-1", 2, "B-2"}; !reflect.DeepEqual(finder.bindings[1], want) {
+		t.Errorf("Expected tuple bindings %v, got %v", want, finder.bindings[1])
+	}
+	if _, err := Table(finder, types.PostgreSQL, "stock").Find(ctx, 1); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for a scalar ID, got %v", err)
+	}
+
+	recorder := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	_, err := Table(recorder, types.MySQL, "stock").UpdateBatch(ctx, []map[string]interface{}{
+		{"warehouse_id": 1, "sku": "A-1", "quantity": 5},
+		{"warehouse_id": 2, "sku": "B-2", "quantity": 7, "reserved": 1},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBatch failed: %v", err)
+	}
+	expectedUpdate := "UPDATE stock SET quantity = CASE WHEN warehouse_id = ? AND sku = ? THEN ? WHEN warehouse_id = ? AND sku = ? THEN ? ELSE quantity END, " +
+		"reserved = CASE WHEN warehouse_id = ? AND sku = ? THEN ? ELSE reserved END WHERE (warehouse_id, sku) IN ((?, ?), (?, ?))"
+	if len(recorder.execs) != 1 || recorder.execs[0] != expectedUpdate {
+		t.Errorf("Expected %q, got %q", expectedUpdate, recorder.execs)
+	} else if want := []interface{}{1, "A-1", 5, 2, "B-2", 7, 2, "B-2", 1, 1, "A-1", 2, "B-2"}; !reflect.DeepEqual(recorder.args[0], want) {
+		t.Errorf("Expected bindings %v, got %v", want, recorder.args[0])
+	}
+	if _, err := Table(recorder, types.MySQL, "stock").UpdateBatch(ctx, []map[string]interface{}{{"sku": "A-1", "quantity": 1}}); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for a row without its key, got %v", err)
+	}
+
+	upserts := &returningExecutor{recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}}
+	_, err = execution.NewQueryExecutor(upserts, types.PostgreSQL).Upsert(ctx, Table(upserts, types.PostgreSQL, "stock").(*Builder),
+		[]map[string]interface{}{{"warehouse_id": 1, "sku": "A-1", "quantity": 5}}, types.UpsertOptions{ConflictAction: types.DoUpdate})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if want := " ON CONFLICT (warehouse_id, sku) DO UPDATE SET quantity = EXCLUDED.quantity"; len(upserts.queries) != 1 || !strings.Contains(upserts.queries[0], want) {
+		t.Errorf("Expected the composite key as conflict target, got %q", upserts.queries)
+	}
+}
+
+// returningExecutor answers queries with rows, recording them like recordingExecutor records
+// statements.
+type returningExecutor struct {
+	recordingExecutor
+	rows mockRows
+}
+
+func (r *returningExecutor) QueryContext(_ context.Context, query string, _ ...interface{}) (types.Rows, error) {
+	r.queries = append(r.queries, query)
+	rows := r.rows
+	return &rows, nil
+}
+
+func TestUpsertResult(t *testing.T) {
+	ctx := context.Background()
+	rows := []map[string]interface{}{{"id": 1, "name": "Ada"}, {"id": 2, "name": "Bob"}}
+
+	pg := &returningExecutor{
+		recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}},
+		rows:              mockRows{columns: []string{"id", "name", "qb_inserted"}, data: [][]interface{}{{int64(1), "Ada", true}, {int64(2), "Bob", false}}},
+	}
+	result, err := execution.NewQueryExecutor(pg, types.PostgreSQL).Upsert(ctx, Table(pg, types.PostgreSQL, "users").(*Builder), rows,
+		types.UpsertOptions{ConflictAction: types.DoUpdate, Returning: []string{"*"}})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if !strings.HasSuffix(pg.queries[0], " RETURNING *, (xmax = 0) AS qb_inserted") {
+		t.Errorf("Expected a RETURNING clause, got %q", pg.queries[0])
+	}
+	if result.Affected != 2 || result.Inserted != 1 || result.Updated != 1 {
+		t.Errorf("Expected one inserted and one updated row, got %+v", result)
+	}
+	if result.Rows.Count() != 2 || result.Rows.First()["name"] != "Ada" {
+		t.Errorf("Expected the returned rows, got %v", result.Rows)
+	} else if _, ok := result.Rows.First()["qb_inserted"]; ok {
+		t.Error("Expected the inserted flag to be removed from the returned rows")
+	}
+
+	mysql := &affectedExecutor{recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}}
+	upsert := func(values []map[string]interface{}, options types.UpsertOptions) (*types.UpsertResult, error) {
+		return execution.NewQueryExecutor(mysql, types.MySQL).Upsert(ctx, Table(mysql, types.MySQL, "users").(*Builder), values, options)
+	}
+	for affected, want := range map[int64]types.UpsertResult{
+		0: {},
+		1: {Affected: 1, Inserted: 1},
+		2: {Affected: 1, Updated: 1},
+	} {
+		mysql.affected = affected
+		if result, err := upsert(rows[:1], types.UpsertOptions{}); err != nil || *result != want {
+			t.Errorf("MySQL reporting %d affected rows: expected %+v, got %+v, %v", affected, want, result, err)
+		}
+	}
+	mysql.affected = 3
+	if result, err := upsert(rows, types.UpsertOptions{}); err != nil || result.Affected != 3 || result.Inserted != -1 || result.Updated != -1 {
+		t.Errorf("Expected unknown counts for a multi-row MySQL upsert, got %+v, %v", result, err)
+	}
+	if _, err := upsert(rows, types.UpsertOptions{Returning: []string{"*"}}); !errors.Is(err, types.ErrUnsupportedFeature) {
+		t.Errorf("Expected ErrUnsupportedFeature for RETURNING on MySQL, got %v", err)
+	}
+}
+
+func TestUpsertUpdateExpressionsAndWhere(t *testing.T) {
+	ctx := context.Background()
+	rows := []map[string]interface{}{{"id": 1, "name": "signup", "hits": 1, "updated_at": "2024-05-01"}}
+	options := types.UpsertOptions{
+		ConflictAction:    types.DoUpdate,
+		UpdateColumns:     []string{"name", "hits"},
+		UpdateExpressions: map[string]types.Expression{"hits": types.Raw("events.hits + EXCLUDED.hits + ?", 0)},
+		UpdateWhere:       types.Raw("EXCLUDED.updated_at > events.updated_at AND events.name <> ?", "locked"),
+	}
+
+	pg := &returningExecutor{recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.PostgreSQL}}}
+	if _, err := execution.NewQueryExecutor(pg, types.PostgreSQL).Upsert(ctx, Table(pg, types.PostgreSQL, "events").(*Builder), rows, options); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	expected := "INSERT INTO events (hits, id, name, updated_at) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO UPDATE SET " +
+		"name = EXCLUDED.name, hits = events.hits + EXCLUDED.hits + $5 WHERE EXCLUDED.updated_at > events.updated_at AND events.name <> $6 " +
+		"RETURNING (xmax = 0) AS qb_inserted"
+	if pg.queries[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, pg.queries[0])
+	}
+
+	pgUpsert := func(rows []map[string]interface{}, options types.UpsertOptions) error {
+		_, err := execution.NewQueryExecutor(pg, types.PostgreSQL).Upsert(ctx, Table(pg, types.PostgreSQL, "events").(*Builder), rows, options)
+		return err
+	}
+	implied := types.UpsertOptions{ConflictTarget: []string{"id"}, UpdateExpressions: map[string]types.Expression{"tags": types.Raw("CASE WHEN events.tags ?? ? THEN events.tags ELSE EXCLUDED.tags END", "vip")}}
+	if err := pgUpsert(rows, implied); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if got := pg.queries[len(pg.queries)-1]; !strings.Contains(got, " DO UPDATE SET ") || !strings.Contains(got, "events.tags ? $5 THEN") {
+		t.Errorf("Expected update expressions to imply DO UPDATE, got %q", got)
+	}
+	implied.ConflictAction = types.DoNothing
+	if err := pgUpsert(rows, implied); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for update expressions with DO NOTHING, got %v", err)
+	}
+	keysOnly := types.UpsertOptions{ConflictTarget: []string{"id"}, UpdateWhere: types.Raw("events.name <> ?", "locked")}
+	if err := pgUpsert([]map[string]interface{}{{"id": 1}}, keysOnly); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for UpdateWhere without columns to update, got %v", err)
+	}
+
+	mysql := &recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}
+	upsert := func(options types.UpsertOptions) error {
+		_, err := execution.NewQueryExecutor(mysql, types.MySQL).Upsert(ctx, Table(mysql, types.MySQL, "events").(*Builder), rows, options)
+		return err
+	}
+	if err := upsert(types.UpsertOptions{UpdateExpressions: map[string]types.Expression{"hits": types.Raw("hits + ?", 1)}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if !strings.HasSuffix(mysql.execs[0], ", hits = hits + ?") || mysql.args[0][len(mysql.args[0])-1] != 1 {
+		t.Errorf("Expected the update expression, got %q", mysql.execs[0])
+	}
+	if err := upsert(options); !errors.Is(err, types.ErrUnsupportedFeature) {
+		t.Errorf("Expected ErrUnsupportedFeature for UpdateWhere on MySQL, got %v", err)
+	}
+}
+
+type affectedExecutor struct {
+	recordingExecutor
+	affected int64
+}
+
+func (a *affectedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (types.Result, error) {
+	_, _ = a.recordingExecutor.ExecContext(ctx, query, args...)
+	return mockResult{rows: a.affected}, nil
+}
+
+func TestWithVersioning(t *testing.T) {
+	executor := &affectedExecutor{recordingExecutor: recordingExecutor{MockExecutor: MockExecutor{driver: types.MySQL}}, affected: 1}
+	ctx := context.Background()
+	posts := func() types.QueryBuilder {
+		return Table(executor, types.MySQL, "posts").Where("id", 7).WithVersioning("version")
+	}
+
+	if _, err := posts().Update(ctx, map[string]interface{}{"title": "Draft", "version": 3}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if expected := "UPDATE posts SET title = ?, version = version + 1 WHERE id = ? AND version = ?"; executor.execs[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, executor.execs[0])
+	}
+	if want := []interface{}{"Draft", 7, 3}; !reflect.DeepEqual(executor.args[0], want) {
+		t.Errorf("Expected bindings %v, got %v", want, executor.args[0])
+	}
+
+	executor.affected = 0
+	if _, err := posts().Update(ctx, map[string]interface{}{"title": "Final", "version": 3}); !errors.Is(err, types.ErrStaleRecord) {
+		t.Errorf("Expected ErrStaleRecord when no row has the version, got %v", err)
+	}
+	if _, err := posts().Update(ctx, map[string]interface{}{"title": "Final"}); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter without the current version, got %v", err)
+	}
+	if len(executor.execs) != 2 {
+		t.Errorf("Expected no statement without the current version, got %q", executor.execs)
+	}
+}
+
+type locatedExecutor struct {
+	MockExecutor
+	loc *time.Location
+}
+
+func (l *locatedExecutor) Location() *time.Location {
+	return l.loc
+}
+
+func TestDateHelpersUseSessionTimeZone(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	conn := &locatedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, loc: tokyo}
+
+	// 20:00 UTC on the 1st is already the 2nd in Tokyo.
+	evening := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	_, bindings, err := Table(conn, types.MySQL, "events").
+		WhereDate("starts_at", evening).
+		WhereDateBetween("ends_at", evening, evening.Add(24*time.Hour)).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if want := []interface{}{"2024-01-02", "2024-01-02", "2024-01-03"}; !reflect.DeepEqual(bindings, want) {
+		t.Errorf("Expected dates in the session time zone %v, got %v", want, bindings)
+	}
+
+	_, bindings, err = Table(conn, types.MySQL, "events").WhereToday("starts_at").ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+	if today := time.Now().In(tokyo).Format("2006-01-02"); len(bindings) != 1 || bindings[0] != today {
+		t.Errorf("Expected today in the session time zone %s, got %v", today, bindings)
+	}
+}
+
+type budgetedExecutor struct {
+	MockExecutor
+	budget types.ComplexityBudget
+}
+
+func (b *budgetedExecutor) ComplexityBudget() types.ComplexityBudget {
+	return b.budget
+}
+
+func TestComplexityBudget(t *testing.T) {
+	conn := &budgetedExecutor{MockExecutor: MockExecutor{driver: types.MySQL}, budget: types.ComplexityBudget{Max: 10}}
+
+	if _, _, err := Table(conn, types.MySQL, "users").Where("id", 1).Limit(1).ToSQL(); err != nil {
+		t.Errorf("Expected a lookup to fit the budget, got %v", err)
+	}
+	scan := Table(conn, types.MySQL, "users").
+		Join("posts", "posts.user_id", "=", "users.id").
+		Where("users.name", "LIKE", "%ada%")
+	if _, _, err := scan.ToSQL(); !errors.Is(err, types.ErrComplexityBudget) {
+		t.Errorf("Expected an unbounded wildcard scan to be rejected, got %v", err)
+	}
+	if _, err := Table(conn, types.MySQL, "users").Delete(context.Background()); !errors.Is(err, types.ErrUnconditionedMutation) {
+		t.Errorf("Expected the full-table check to run first, got %v", err)
+	}
+
+	conn.budget.Mode = types.BudgetLog
+	if _, _, err := scan.ToSQL(); err != nil {
+		t.Errorf("Expected the statement to be logged only, got %v", err)
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	executor := &rowsExecutor{
+		MockExecutor: MockExecutor{driver: types.MySQL},
+		rows:         mockRows{columns: []string{"id"}, data: [][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}}},
+	}
+	ctx := context.Background()
+
+	if rows, err := Table(executor, types.MySQL, "events").MaxRows(3).Get(ctx); err != nil || rows.Count() != 3 {
+		t.Errorf("Expected all rows within the limit, got %v, %v", rows, err)
+	}
+	if _, err := Table(executor, types.MySQL, "events").MaxRows(2).Get(ctx); !errors.Is(err, types.ErrTooManyRows) {
+		t.Errorf("Expected ErrTooManyRows, got %v", err)
+	}
+
+	logger := &capturingLogger{}
+	execution.SetLogger(logger)
+	defer execution.SetLogger(nil)
+	rows, err := Table(executor, types.MySQL, "events").MaxRowsTruncate(2).Get(ctx)
+	if err != nil || rows.Count() != 2 || rows.ToSlice()[1]["id"] != int64(2) {
+		t.Errorf("Expected the first two rows, got %v, %v", rows, err)
+	}
+	var truncated []types.QueryLog
+	for _, entry := range logger.entries {
+		if entry.Truncated {
+			truncated = append(truncated, entry)
+		}
+	}
+	if len(truncated) != 1 || truncated[0].Rows != 2 || truncated[0].SQL != "" || truncated[0].Table != "events" {
+		t.Errorf("Expected one truncation entry without SQL, got %+v", truncated)
+	}
+
+	chunked := 0
+	capped := Table(executor, types.MySQL, "events").MaxRows(2).(*Builder)
+	err = execution.NewQueryExecutor(executor, types.MySQL).Chunk(ctx, capped, 5, func(chunk types.Collection) error {
+		chunked += chunk.Count()
+		return nil
+	})
+	if err != nil || chunked != 3 {
+		t.Errorf("Expected Chunk to ignore MaxRows, got %d rows, %v", chunked, err)
+	}
+}
+
+func TestSQLCache(t *testing.T) {
+	shapes := map[string]func(types.Driver, int) types.QueryBuilder{
+		"wheres": func(driver types.Driver, n int) types.QueryBuilder {
+			return Table(&MockExecutor{driver: driver}, driver, "users as u").
+				SelectRaw("COUNT(*) > ? AS many", n).
+				Join("posts", "posts.user_id", "=", "u.id").
+				Where("u.age", ">", n).
+				OrWhere("u.name", "ada").
+				WhereIn("u.id", []interface{}{n, n + 1}).
+				WhereBetween("u.score", []interface{}{n, n * 2}).
+				WhereNull("u.deleted_at").
+				WhereRaw("u.flags & ? = 0", n).
+				WhereLike("u.email", "%@example.com").
+				GroupBy("u.id").
+				Having("COUNT(*)", ">", n).
+				OrderBy("u.name", "asc").
+				Limit(10).Offset(20)
+		},
+		"json": func(driver types.Driver, n int) types.QueryBuilder {
+			return Table(&MockExecutor{driver: driver}, driver, "users").WhereJSONContains("tags", n)
+		},
+	}
+
+	for name, build := range shapes {
+		for _, driver := range []types.Driver{types.MySQL, types.PostgreSQL} {
+			expected := make([]string, 2)
+			for i, n := range []int{1, 2} {
+				sql, bindings, err := build(driver, n).ToSQL()
+				if err != nil {
+					t.Fatalf("%s on %s: %v", name, driver, err)
+				}
+				expected[i] = fmt.Sprint(sql, bindings)
+			}
+
+			cache := optimization.NewSQLCache(10)
+			optimization.SetSQLCache(cache)
+			for i, n := range []int{1, 2} {
+				sql, bindings, err := build(driver, n).ToSQL()
+				if got := fmt.Sprint(sql, bindings); err != nil || got != expected[i] {
+					t.Errorf("%s on %s: expected %s, got %s (%v)", name, driver, expected[i], got, err)
+				}
+			}
+			optimization.SetSQLCache(nil)
+
+			hits := int64(1)
+			if name == "json" {
+				hits = 0
+			}
+			if stats := cache.Stats(); stats.Hits != hits {
+				t.Errorf("%s on %s: expected %d hits, got %+v", name, driver, hits, stats)
+			}
+		}
+	}
+}
+
+func TestSQLCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := optimization.NewSQLCache(2)
+	cache.Set("a", "SELECT a")
+	cache.Set("b", "SELECT b")
+	cache.Get("a")
+	cache.Set("c", "SELECT c")
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if sql, ok := cache.Get("a"); !ok || sql != "SELECT a" {
+		t.Errorf("Expected a to be kept, got %q", sql)
+	}
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
@@ -1,11 +1,20 @@
 package query
 
 import (
+	"bytes"
+	"database/sql/driver"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/omarhamdy49/go-query-builder/pkg/clauses"
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
 )
 
@@ -35,177 +44,717 @@ func (c *SQLCompiler) GetDebugInfo() *types.DebugInfo {
 	return c.debugInfo
 }
 
-// CompileSelect compiles a query builder into a SELECT SQL statement.
+// CompileSelect compiles a query builder into a SELECT SQL statement. With an SQL cache set, a
+// statement compiled before for a builder of the same shape is reused.
 func (c *SQLCompiler) CompileSelect(qb *Builder) (string, []interface{}, error) {
 	start := time.Now()
-	var parts []string
-	var bindings []interface{}
+	cache, key := c.cacheKey(qb)
+	if cache != nil {
+		if sql, ok := cache.Get(key); ok {
+			return sql, shapeBindings(qb), nil
+		}
+	}
 
-	selects := c.compileSelects(qb.GetSelects(), qb.IsDistinct())
-	parts = append(parts, "SELECT "+selects)
+	if err := c.checkSetOperations(qb); err != nil {
+		return "", nil, err
+	}
+	if err := c.checkConditions(qb); err != nil {
+		return "", nil, err
+	}
+	sql, bindings := c.compileSelect(qb)
+	sql, bindings, err := c.finish(sql, bindings, start)
+	if cache != nil && err == nil {
+		cache.Set(key, sql)
+	}
+	return sql, bindings, err
+}
 
-	if table := qb.GetTable(); table != "" {
-		parts = append(parts, "FROM "+table)
+// CompileUpdate compiles a query builder into an UPDATE statement that sets the given values.
+// Values of type types.Expression are inlined as raw SQL with their own bindings.
+func (c *SQLCompiler) CompileUpdate(qb *Builder, values map[string]interface{}) (string, []interface{}, error) {
+	start := time.Now()
+	table := qb.GetTable()
+	if table == "" {
+		return "", nil, fmt.Errorf("no table specified for update")
+	}
+	if len(values) == 0 {
+		return "", nil, fmt.Errorf("no values provided for update")
 	}
 
-	if joins := qb.GetJoins(); len(joins) > 0 {
+	sets, bindings := c.compileSets(values)
+	where, whereBindings, err := c.compileMutationWheres(qb)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if c.driver == types.ClickHouse {
+		conditions, err := clickHouseMutationConditions(qb, where)
+		if err != nil {
+			return "", nil, err
+		}
+		sql := fmt.Sprintf("ALTER TABLE %s UPDATE %s%s", table, sets, conditions)
+		return c.finish(sql, append(bindings, whereBindings...), start)
+	}
+
+	joins := qb.GetJoins()
+	if len(joins) == 0 {
+		conditions, conditionBindings := c.compileMutationConditions(qb, where, whereBindings)
+		sql := fmt.Sprintf("UPDATE %s SET %s%s", table, sets, conditions)
+		return c.finish(sql, append(bindings, conditionBindings...), start)
+	}
+
+	if c.driver == types.MySQL {
+		if qb.GetLimit() != nil {
+			return "", nil, fmt.Errorf("MySQL does not support LIMIT on a multi-table UPDATE")
+		}
 		joinSQL, joinBindings := c.compileJoins(joins)
-		parts = append(parts, joinSQL)
-		bindings = append(bindings, joinBindings...)
+		sql := fmt.Sprintf("UPDATE %s %s SET %s%s", table, joinSQL, sets, prefixWhere(where))
+		bindings = append(append(joinBindings, bindings...), whereBindings...)
+		return c.finish(sql, bindings, start)
+	}
+
+	if qb.GetLimit() == nil && innerJoinsOnly(joins) {
+		from, conditions, conditionBindings := c.compileJoinsAsFrom(joins, where, whereBindings)
+		sql := fmt.Sprintf("UPDATE %s SET %s FROM %s%s", table, sets, from, conditions)
+		return c.finish(sql, append(bindings, conditionBindings...), start)
 	}
 
+	conditions, conditionBindings := c.compileMutationConditions(qb, where, whereBindings)
+	sql := fmt.Sprintf("UPDATE %s SET %s%s", table, sets, conditions)
+	return c.finish(sql, append(bindings, conditionBindings...), start)
+}
+
+// CompileInsertUsing compiles an INSERT INTO ... SELECT statement that inserts the rows selected by sub.
+func (c *SQLCompiler) CompileInsertUsing(qb *Builder, columns []string, sub types.QueryBuilder) (string, []interface{}, error) {
+	start := time.Now()
+	if qb.GetTable() == "" {
+		return "", nil, fmt.Errorf("no table specified for insert")
+	}
+	if sub == nil {
+		return "", nil, fmt.Errorf("no query provided for insert")
+	}
+
+	subSQL, bindings, err := c.compileSubquery(sub)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sql := "INSERT INTO " + qb.GetTable()
+	if len(columns) > 0 {
+		sql += " (" + strings.Join(columns, ", ") + ")"
+	}
+	return c.finish(sql+" "+subSQL, bindings, start)
+}
+
+// CompileDelete compiles a query builder into a DELETE statement.
+func (c *SQLCompiler) CompileDelete(qb *Builder) (string, []interface{}, error) {
+	start := time.Now()
+	table := qb.GetTable()
+	if table == "" {
+		return "", nil, fmt.Errorf("no table specified for delete")
+	}
+
+	where, whereBindings, err := c.compileMutationWheres(qb)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if c.driver == types.ClickHouse {
+		conditions, err := clickHouseMutationConditions(qb, where)
+		if err != nil {
+			return "", nil, err
+		}
+		return c.finish("DELETE FROM "+table+conditions, whereBindings, start)
+	}
+
+	joins := qb.GetJoins()
+	if len(joins) == 0 {
+		conditions, bindings := c.compileMutationConditions(qb, where, whereBindings)
+		return c.finish("DELETE FROM "+table+conditions, bindings, start)
+	}
+
+	if c.driver == types.MySQL {
+		if qb.GetLimit() != nil {
+			return "", nil, fmt.Errorf("MySQL does not support LIMIT on a multi-table DELETE")
+		}
+		joinSQL, joinBindings := c.compileJoins(joins)
+		sql := fmt.Sprintf("DELETE %s FROM %s %s%s", tableQualifier(table), table, joinSQL, prefixWhere(where))
+		return c.finish(sql, append(joinBindings, whereBindings...), start)
+	}
+
+	if qb.GetLimit() == nil && innerJoinsOnly(joins) {
+		using, conditions, bindings := c.compileJoinsAsFrom(joins, where, whereBindings)
+		return c.finish(fmt.Sprintf("DELETE FROM %s USING %s%s", table, using, conditions), bindings, start)
+	}
+
+	conditions, bindings := c.compileMutationConditions(qb, where, whereBindings)
+	return c.finish("DELETE FROM "+table+conditions, bindings, start)
+}
+
+// compileSets compiles the SET list of an UPDATE.
+func (c *SQLCompiler) compileSets(values map[string]interface{}) (string, []interface{}) {
+	setParts := make([]string, 0, len(values))
+	bindings := make([]interface{}, 0, len(values))
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		value := values[column]
+		if expr, ok := value.(types.Expression); ok {
+			setParts = append(setParts, fmt.Sprintf("%s = %s", column, expr.SQL))
+			bindings = append(bindings, expr.Bindings...)
+			continue
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = %s", column, c.getParameterPlaceholder()))
+		bindings = append(bindings, value)
+	}
+	return strings.Join(setParts, ", "), bindings
+}
+
+// compileMutationWheres compiles the WHERE conditions of an UPDATE or DELETE, without the WHERE
+// keyword. It refuses to touch every row of the table unless the mutation is restricted by a WHERE
+// clause or an inner join, or is explicitly allowed. A LIMIT only caps how many rows are touched,
+// so it does not count as a restriction.
+func (c *SQLCompiler) compileMutationWheres(qb *Builder) (string, []interface{}, error) {
+	qb.applyScopes()
+	if err := c.checkConditions(qb); err != nil {
+		return "", nil, err
+	}
+
+	var where string
+	var bindings []interface{}
 	if wheres := qb.GetWheres(); len(wheres) > 0 {
-		whereSQL, whereBindings := c.compileWheres(wheres)
-		parts = append(parts, "WHERE "+whereSQL)
+		where, bindings = c.compileWheres(wheres)
+	}
+
+	restricted := where != "" || hasInnerJoin(qb.GetJoins())
+	if !restricted && !qb.AllowsFullTableMutation() {
+		return "", nil, types.ErrUnconditionedMutation
+	}
+
+	where, bindings = c.withPolicies(qb, where, bindings)
+	return withSoftDeletes(qb, where), bindings, nil
+}
+
+// compileMutationConditions compiles the row selection of an UPDATE or DELETE from the compiled WHERE
+// conditions, the builder's joins, ORDER BY and LIMIT. MySQL supports ORDER BY and LIMIT natively on
+// single-table mutations. PostgreSQL supports neither, nor outer joins in UPDATE/DELETE, so there the
+// rows are selected by ctid in a subquery instead.
+func (c *SQLCompiler) compileMutationConditions(qb *Builder, where string, whereBindings []interface{}) (string, []interface{}) {
+	joins := qb.GetJoins()
+	limit := qb.GetLimit()
+	if limit == nil && len(joins) == 0 {
+		return prefixWhere(where), whereBindings
+	}
+
+	var tail string
+	if limit != nil {
+		if orders := qb.GetOrders(); len(orders) > 0 {
+			tail = " ORDER BY " + c.compileOrders(orders)
+		}
+		tail += " " + c.compileLimit(*limit)
+	}
+
+	if c.driver != types.PostgreSQL {
+		return prefixWhere(where) + tail, whereBindings
+	}
+
+	table := qb.GetTable()
+	var joinSQL string
+	var bindings []interface{}
+	if len(joins) > 0 {
+		var joinBindings []interface{}
+		joinSQL, joinBindings = c.compileJoins(joins)
+		joinSQL = " " + joinSQL
+		bindings = append(bindings, joinBindings...)
+	}
+	bindings = append(bindings, whereBindings...)
+
+	qualifier := tableQualifier(table)
+	return fmt.Sprintf(" WHERE %s.ctid IN (SELECT %s.ctid FROM %s%s%s%s)",
+		qualifier, qualifier, table, joinSQL, prefixWhere(where), tail), bindings
+}
+
+// compileJoinsAsFrom compiles inner joins into PostgreSQL's UPDATE ... FROM / DELETE ... USING form:
+// the joined tables as a list and their ON conditions folded into the WHERE clause.
+func (c *SQLCompiler) compileJoinsAsFrom(joins []*clauses.JoinClause, where string, whereBindings []interface{}) (string, string, []interface{}) {
+	tables := make([]string, 0, len(joins))
+	var conditions []string
+	var bindings []interface{}
+
+	for _, join := range joins {
+		tables = append(tables, join.GetTable())
+		if !join.IsCrossJoin() {
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", join.First, join.Operator, join.Second))
+		}
+		for _, clause := range join.Clauses {
+			clauseSQL, clauseBindings := c.compileWhereClause(clause)
+			conditions = append(conditions, clauseSQL)
+			bindings = append(bindings, clauseBindings...)
+		}
+	}
+
+	if where != "" {
+		conditions = append(conditions, "("+where+")")
 		bindings = append(bindings, whereBindings...)
 	}
 
-	if groups := qb.GetGroups(); len(groups) > 0 {
-		parts = append(parts, "GROUP BY "+c.compileGroups(groups))
+	return strings.Join(tables, ", "), prefixWhere(strings.Join(conditions, " AND ")), bindings
+}
+
+// clickHouseMutationConditions returns the WHERE clause of a ClickHouse ALTER TABLE ... UPDATE or
+// lightweight DELETE. Both require a WHERE clause and support neither joins nor LIMIT.
+func clickHouseMutationConditions(qb *Builder, where string) (string, error) {
+	if len(qb.GetJoins()) > 0 || qb.GetLimit() != nil {
+		return "", fmt.Errorf("ClickHouse does not support joins or LIMIT on UPDATE and DELETE")
+	}
+	if where == "" {
+		where = "1"
 	}
+	return prefixWhere(where), nil
+}
 
-	if havings := qb.GetHavings(); len(havings) > 0 {
-		havingSQL, havingBindings := c.compileHavings(havings)
-		parts = append(parts, "HAVING "+havingSQL)
-		bindings = append(bindings, havingBindings...)
+func prefixWhere(where string) string {
+	if where == "" {
+		return ""
 	}
+	return " WHERE " + where
+}
 
-	if unions := qb.GetUnions(); len(unions) > 0 {
-		unionSQL, unionBindings := c.compileUnions(unions)
-		parts = append(parts, unionSQL)
-		bindings = append(bindings, unionBindings...)
+// withSoftDeletes appends the soft delete condition of qb to its compiled WHERE conditions,
+// parenthesizing them when they contain OR.
+func withSoftDeletes(qb *Builder, where string) string {
+	condition := qb.softDeleteCondition()
+	switch {
+	case condition == "":
+		return where
+	case where == "":
+		return condition
 	}
 
-	if orders := qb.GetOrders(); len(orders) > 0 {
-		parts = append(parts, "ORDER BY "+c.compileOrders(orders))
+	for _, clause := range qb.GetWheres() {
+		if clause.Boolean == types.Or {
+			return "(" + where + ") AND " + condition
+		}
 	}
+	return where + " AND " + condition
+}
 
-	if limit := qb.GetLimit(); limit != nil {
-		parts = append(parts, c.compileLimit(*limit))
+// withPolicies appends the row-level security conditions of qb to its compiled WHERE conditions and
+// bindings. Either side is parenthesized when it contains OR, so neither can widen the other.
+func (c *SQLCompiler) withPolicies(qb *Builder, where string, bindings []interface{}) (string, []interface{}) {
+	if len(qb.policies) == 0 {
+		return where, bindings
 	}
 
-	if offset := qb.GetOffset(); offset != nil {
-		parts = append(parts, c.compileOffset(*offset))
+	policy, policyBindings := c.compileWheres(qb.policies)
+	bindings = append(bindings, policyBindings...)
+	if hasOr(qb.policies) {
+		policy = "(" + policy + ")"
+	}
+	if where == "" {
+		return policy, bindings
 	}
+	if hasOr(qb.GetWheres()) {
+		where = "(" + where + ")"
+	}
+	return where + " AND " + policy, bindings
+}
 
-	if lock := qb.GetLock(); lock != nil {
-		parts = append(parts, string(*lock))
+// tableQualifier returns the name that qualifies columns of a table reference, i.e. its alias if it has one.
+func tableQualifier(table string) string {
+	fields := strings.Fields(table)
+	if len(fields) == 0 {
+		return table
 	}
+	return fields[len(fields)-1]
+}
 
-	sql := strings.Join(parts, " ")
-	bindings = append(bindings, qb.GetBindings()...)
+func innerJoinsOnly(joins []*clauses.JoinClause) bool {
+	for _, join := range joins {
+		if join.GetType() != types.InnerJoin && !join.IsCrossJoin() {
+			return false
+		}
+	}
+	return true
+}
+
+func hasInnerJoin(joins []*clauses.JoinClause) bool {
+	for _, join := range joins {
+		if join.GetType() == types.InnerJoin {
+			return true
+		}
+	}
+	return false
+}
+
+// finish converts placeholders for the target driver and records debug information.
+func (c *SQLCompiler) finish(sql string, bindings []interface{}, start time.Time) (string, []interface{}, error) {
+	if c.driver == types.PostgreSQL {
+		sql, _ = execution.NumberPlaceholders(sql, 0)
+	}
 
 	if c.debug {
 		c.debugInfo = &types.DebugInfo{
-			SQL:      sql,
+			SQL:      execution.FormatSQL(sql),
 			Bindings: bindings,
 			Driver:   c.driver,
 			Duration: time.Since(start),
+			Rows:     -1,
 		}
 	}
 
 	return sql, bindings, nil
 }
 
-func (c *SQLCompiler) compileSelects(selects []*clauses.SelectClause, distinct bool) string {
-	if len(selects) == 0 {
-		if distinct {
-			return "DISTINCT *"
+// compileSelect compiles a SELECT with ? placeholders so it can be embedded in another statement
+// before placeholders are numbered.
+func (c *SQLCompiler) compileSelect(qb *Builder) (string, []interface{}) {
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
+
+	bindings := c.writeSelect(buf, qb)
+	return buf.String(), bindings
+}
+
+// writeSelect writes the SELECT of qb to buf and returns its bindings.
+func (c *SQLCompiler) writeSelect(buf *bytes.Buffer, qb *Builder) []interface{} {
+	bindings := make([]interface{}, 0, bindingCapacity(qb))
+
+	unions := qb.GetUnions()
+
+	buf.WriteString("SELECT ")
+	bindings = c.writeSelects(buf, qb.GetSelects(), qb.IsDistinct(), bindings)
+
+	if table := qb.GetTable(); table != "" {
+		buf.WriteString(" FROM ")
+		buf.WriteString(table)
+		if c.driver == types.ClickHouse && qb.IsFinal() {
+			buf.WriteString(" FINAL")
+		}
+		if sample := qb.GetTableSample(); sample != nil {
+			buf.WriteByte(' ')
+			buf.WriteString(c.compileTableSample(*sample))
 		}
-		return "*"
 	}
 
-	var selectParts []string
-	for _, sel := range selects {
-		if sel.IsRaw() {
-			selectParts = append(selectParts, sel.GetRaw())
-		} else if sel.HasAlias() {
-			selectParts = append(selectParts, fmt.Sprintf("%s AS %s", sel.GetColumn(), sel.GetAlias()))
+	if joins := qb.GetJoins(); len(joins) > 0 {
+		buf.WriteByte(' ')
+		bindings = c.writeJoins(buf, joins, bindings)
+	}
+
+	// Policies and soft deletes may parenthesize the conditions, so they are combined as strings.
+	if len(qb.policies) > 0 || qb.softDeleteCondition() != "" {
+		var whereSQL string
+		if wheres := qb.GetWheres(); len(wheres) > 0 {
+			var whereBindings []interface{}
+			whereSQL, whereBindings = c.compileWheres(wheres)
+			bindings = append(bindings, whereBindings...)
+		}
+		whereSQL, bindings = c.withPolicies(qb, whereSQL, bindings)
+		if whereSQL = withSoftDeletes(qb, whereSQL); whereSQL != "" {
+			buf.WriteString(" WHERE ")
+			buf.WriteString(whereSQL)
+		}
+	} else if wheres := qb.GetWheres(); len(wheres) > 0 {
+		buf.WriteString(" WHERE ")
+		bindings = c.writeWheres(buf, wheres, bindings)
+	}
+
+	if groups := qb.GetGroups(); len(groups) > 0 {
+		buf.WriteString(" GROUP BY ")
+		c.writeGroups(buf, groups)
+	}
+
+	if havings := qb.GetHavings(); len(havings) > 0 {
+		buf.WriteString(" HAVING ")
+		bindings = c.writeHavings(buf, havings, bindings)
+	}
+
+	// The first side of a union is parenthesized like the others, so the ORDER BY and LIMIT that
+	// follow unambiguously apply to the combined result.
+	if len(unions) > 0 {
+		unionSQL, unionBindings := c.compileUnions(qb, buf.String(), unions)
+		buf.Reset()
+		buf.WriteString(unionSQL)
+		bindings = append(bindings, unionBindings...)
+	}
+
+	if orders := qb.GetOrders(); len(orders) > 0 {
+		buf.WriteString(" ORDER BY ")
+		c.writeOrders(buf, orders)
+	}
+
+	limit, offset := qb.GetLimit(), qb.GetOffset()
+	if limit != nil {
+		buf.WriteByte(' ')
+		c.writeLimit(buf, *limit)
+	} else if offset != nil && c.driver == types.ClickHouse {
+		buf.WriteByte(' ')
+		buf.WriteString(clickHouseNoLimit)
+	}
+
+	if offset != nil {
+		buf.WriteByte(' ')
+		c.writeOffset(buf, *offset)
+	}
+
+	// ClickHouse has no row locks.
+	if lock := qb.GetLock(); lock != nil && c.driver != types.ClickHouse {
+		buf.WriteByte(' ')
+		buf.WriteString(string(*lock))
+	}
+
+	return bindings
+}
+
+// sqlBuffers pools the buffers statements are compiled into, so compiling a statement allocates
+// little more than the final string. A strings.Builder cannot be reused once its String is taken.
+var sqlBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// maxPooledSQLBuffer keeps the buffer of an unusually large statement out of the pool.
+const maxPooledSQLBuffer = 64 << 10
+
+func getSQLBuffer() *bytes.Buffer {
+	return sqlBuffers.Get().(*bytes.Buffer)
+}
+
+func putSQLBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledSQLBuffer {
+		return
+	}
+	buf.Reset()
+	sqlBuffers.Put(buf)
+}
+
+// bindingCapacity estimates the number of bindings of qb's conditions, to size its binding slice once.
+func bindingCapacity(qb *Builder) int {
+	n := 0
+	for _, where := range qb.GetWheres() {
+		if len(where.Values) > 0 {
+			n += len(where.Values)
 		} else {
-			selectParts = append(selectParts, sel.GetColumn())
+			n++
 		}
 	}
+	return n + len(qb.GetHavings())
+}
 
-	result := strings.Join(selectParts, ", ")
+// clickHouseNoLimit is the LIMIT emitted before an OFFSET without a limit, which ClickHouse rejects.
+const clickHouseNoLimit = "LIMIT 18446744073709551615"
+
+// compileTableSample compiles a sample of percent% of the table's rows.
+func (c *SQLCompiler) compileTableSample(percent float64) string {
+	if c.driver == types.ClickHouse {
+		return "SAMPLE " + formatFloat(percent/100)
+	}
+	return fmt.Sprintf("TABLESAMPLE BERNOULLI (%s)", formatFloat(percent))
+}
+
+// compileSubquery compiles a nested query without numbering its placeholders, so the enclosing
+// statement can number them in order.
+func (c *SQLCompiler) compileSubquery(query types.QueryBuilder) (string, []interface{}, error) {
+	if sub, ok := query.(*Builder); ok {
+		sub.applyScopes()
+		if err := sub.compiler.checkConditions(sub); err != nil {
+			return "", nil, err
+		}
+		sql, bindings := sub.compiler.compileSelect(sub)
+		return sql, bindings, nil
+	}
+
+	return query.ToSQL()
+}
+
+func (c *SQLCompiler) writeSelects(buf *bytes.Buffer, selects []*clauses.SelectClause, distinct bool, bindings []interface{}) []interface{} {
 	if distinct {
-		return "DISTINCT " + result
+		buf.WriteString("DISTINCT ")
 	}
-	return result
+	if len(selects) == 0 {
+		buf.WriteByte('*')
+		return bindings
+	}
+
+	for i, sel := range selects {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if sel.IsRaw() {
+			buf.WriteString(sel.GetRaw())
+			bindings = append(bindings, sel.GetBindings()...)
+		} else if sel.HasAlias() {
+			buf.WriteString(sel.GetColumn())
+			buf.WriteString(" AS ")
+			buf.WriteString(sel.GetAlias())
+		} else {
+			buf.WriteString(sel.GetColumn())
+		}
+	}
+	return bindings
 }
 
 func (c *SQLCompiler) compileJoins(joins []*clauses.JoinClause) (string, []interface{}) {
-	var parts []string
-	var bindings []interface{}
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
 
-	for _, join := range joins {
-		if join.IsCrossJoin() {
-			parts = append(parts, fmt.Sprintf("%s %s", join.GetType(), join.GetTable()))
-		} else {
-			parts = append(parts, fmt.Sprintf("%s %s ON %s %s %s",
-				join.GetType(), join.GetTable(), join.First, join.Operator, join.Second))
+	bindings := c.writeJoins(buf, joins, nil)
+	return buf.String(), bindings
+}
+
+func (c *SQLCompiler) writeJoins(buf *bytes.Buffer, joins []*clauses.JoinClause, bindings []interface{}) []interface{} {
+	for i, join := range joins {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(string(join.GetType()))
+		buf.WriteByte(' ')
+		buf.WriteString(join.GetTable())
+		if !join.IsCrossJoin() {
+			buf.WriteString(" ON ")
+			buf.WriteString(join.First)
+			buf.WriteByte(' ')
+			buf.WriteString(string(join.Operator))
+			buf.WriteByte(' ')
+			buf.WriteString(join.Second)
 		}
 
 		for _, clause := range join.Clauses {
-			clauseSQL, clauseBindings := c.compileWhereClause(clause)
-			parts = append(parts, "AND "+clauseSQL)
-			bindings = append(bindings, clauseBindings...)
+			buf.WriteString(" AND ")
+			bindings = c.writeWhereClause(buf, clause, bindings)
 		}
 	}
-
-	return strings.Join(parts, " "), bindings
+	return bindings
 }
 
 func (c *SQLCompiler) compileWheres(wheres []*clauses.WhereClause) (string, []interface{}) {
-	var parts []string
-	var bindings []interface{}
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
 
+	bindings := c.writeWheres(buf, wheres, nil)
+	return buf.String(), bindings
+}
+
+func (c *SQLCompiler) writeWheres(buf *bytes.Buffer, wheres []*clauses.WhereClause, bindings []interface{}) []interface{} {
 	for i, where := range wheres {
-		clauseSQL, clauseBindings := c.compileWhereClause(where)
-		
-		if i == 0 {
-			parts = append(parts, clauseSQL)
-		} else {
-			parts = append(parts, strings.ToUpper(string(where.Boolean))+" "+clauseSQL)
+		if i > 0 {
+			buf.WriteByte(' ')
+			buf.WriteString(strings.ToUpper(string(where.Boolean)))
+			buf.WriteByte(' ')
 		}
-		
-		bindings = append(bindings, clauseBindings...)
+		bindings = c.writeWhereClause(buf, where, bindings)
 	}
-
-	return strings.Join(parts, " "), bindings
+	return bindings
 }
 
 func (c *SQLCompiler) compileWhereClause(where *clauses.WhereClause) (string, []interface{}) {
-	var bindings []interface{}
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
+
+	bindings := c.writeWhereClause(buf, where, nil)
+	return buf.String(), bindings
+}
+
+// writeWhereClause writes a single condition to buf and appends its bindings to bindings.
+func (c *SQLCompiler) writeWhereClause(buf *bytes.Buffer, where *clauses.WhereClause, bindings []interface{}) []interface{} {
+	var sql string
 
 	switch where.Type {
 	case "basic":
-		bindings = append(bindings, where.Value)
-		return fmt.Sprintf("%s %s %s", where.Column, where.Operator, c.getParameterPlaceholder()), bindings
-	case "raw":
-		return where.Raw, bindings
+		writeCondition(buf, where.Column, string(where.Operator))
+		buf.WriteByte(' ')
+		buf.WriteString(c.getParameterPlaceholder())
+		return append(bindings, where.Value)
+	case "raw", "integer_in", "distance":
+		buf.WriteString(where.Raw)
+		return append(bindings, where.GetBindings()...)
 	case "between":
-		bindings = append(bindings, where.Values...)
-		return fmt.Sprintf("%s %s %s AND %s", where.Column, where.Operator, 
-			c.getParameterPlaceholder(), c.getParameterPlaceholder()), bindings
+		writeCondition(buf, where.Column, string(where.Operator))
+		buf.WriteByte(' ')
+		buf.WriteString(c.getParameterPlaceholder())
+		buf.WriteString(" AND ")
+		buf.WriteString(c.getParameterPlaceholder())
+		return append(bindings, where.Values...)
+	case "between_columns":
+		writeCondition(buf, c.getParameterPlaceholder(), string(where.Operator))
+		fmt.Fprintf(buf, " %v AND %v", where.Values[0], where.Values[1])
+		return append(bindings, where.Value)
+	case "relative_date":
+		writeCondition(buf, where.Column, string(where.Operator))
+		buf.WriteByte(' ')
+		buf.WriteString(c.compileTimeAgo())
+		return append(bindings, where.Value)
 	case "in":
-		placeholders := c.getInPlaceholders(len(where.Values))
-		bindings = append(bindings, where.Values...)
-		return fmt.Sprintf("%s %s (%s)", where.Column, where.Operator, placeholders), bindings
+		// IN () is a syntax error: an empty list matches no rows, or every row for NOT IN.
+		if len(where.Values) == 0 {
+			buf.WriteString(emptyInCondition(where.Operator))
+			return bindings
+		}
+		writeCondition(buf, where.Column, string(where.Operator))
+		buf.WriteString(" (")
+		c.writeInPlaceholders(buf, len(where.Values))
+		buf.WriteByte(')')
+		return append(bindings, where.Values...)
+	case "tuple_in":
+		if len(where.Values) == 0 || len(where.Columns) == 0 {
+			buf.WriteString(emptyInCondition(where.Operator))
+			return bindings
+		}
+		writeCondition(buf, where.Column, string(where.Operator))
+		buf.WriteString(" (")
+		for i := 0; i < len(where.Values); i += len(where.Columns) {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteByte('(')
+			c.writeInPlaceholders(buf, min(len(where.Columns), len(where.Values)-i))
+			buf.WriteByte(')')
+		}
+		buf.WriteByte(')')
+		return append(bindings, where.Values...)
 	case "null":
-		return fmt.Sprintf("%s %s", where.Column, where.Operator), bindings
+		writeCondition(buf, where.Column, string(where.Operator))
+		return bindings
 	case "exists":
-		subSQL, subBindings, _ := where.Query.ToSQL()
-		bindings = append(bindings, subBindings...)
-		return fmt.Sprintf("%s (%s)", where.Operator, subSQL), bindings
+		// checkConditions has already reported the errors of subqueries.
+		subSQL, subBindings, _ := c.compileSubquery(where.Query)
+		buf.WriteString(string(where.Operator))
+		buf.WriteString(" (")
+		buf.WriteString(subSQL)
+		buf.WriteByte(')')
+		return append(bindings, subBindings...)
 	case "json":
-		return c.compileJSONWhereClause(where, &bindings)
+		sql, bindings = c.compileJSONWhereClause(where, &bindings)
 	case "json_length":
-		return c.compileJSONLengthWhereClause(where, &bindings)
+		sql, bindings = c.compileJSONLengthWhereClause(where, &bindings)
 	case "fulltext":
-		return c.compileFullTextWhereClause(where, &bindings)
-	default:
-		return "", bindings
+		sql, bindings = c.compileFullTextWhereClause(where, &bindings)
+	case "like":
+		buf.WriteString(c.compileLikeWhereClause(where))
+		return append(bindings, where.Value)
+	case "array", "array_any":
+		sql, bindings = c.compileArrayWhereClause(where, &bindings)
 	}
+
+	buf.WriteString(sql)
+	return bindings
+}
+
+// emptyInCondition returns the condition an IN or NOT IN with an empty list compiles to.
+func emptyInCondition(operator types.Operator) string {
+	if operator == types.OpNotIn {
+		return "1 = 1"
+	}
+	return "1 = 0"
+}
+
+// writeCondition writes "column operator" to buf.
+func writeCondition(buf *bytes.Buffer, column, operator string) {
+	buf.WriteString(column)
+	buf.WriteByte(' ')
+	buf.WriteString(operator)
 }
 
 func (c *SQLCompiler) compileJSONWhereClause(where *clauses.WhereClause, bindings *[]interface{}) (string, []interface{}) {
@@ -237,108 +786,470 @@ func (c *SQLCompiler) compileJSONLengthWhereClause(where *clauses.WhereClause, b
 func (c *SQLCompiler) compileFullTextWhereClause(where *clauses.WhereClause, bindings *[]interface{}) (string, []interface{}) {
 	columns := make([]string, len(where.Values))
 	for i, col := range where.Values {
-		columns[i] = col.(string)
+		columns[i] = fmt.Sprintf("%v", col)
+	}
+
+	var options types.FullTextOptions
+	if where.FullText != nil {
+		options = *where.FullText
 	}
-	
+
 	switch c.driver {
 	case types.MySQL:
 		*bindings = append(*bindings, where.Value)
-		return fmt.Sprintf("MATCH(%s) AGAINST(%s)", strings.Join(columns, ", "), c.getParameterPlaceholder()), *bindings
+		return c.fullTextScore(columns, options), *bindings
 	case types.PostgreSQL:
 		*bindings = append(*bindings, where.Value)
-		return fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(%s)", strings.Join(columns, " || ' ' || "), c.getParameterPlaceholder()), *bindings
+		return fmt.Sprintf("%s @@ %s", c.tsVector(columns, options), c.tsQuery(options)), *bindings
 	default:
 		return "", *bindings
 	}
 }
 
-func (c *SQLCompiler) compileGroups(groups []*clauses.GroupClause) string {
-	var parts []string
-	for _, group := range groups {
+// fullTextScore returns the expression computing the relevance of the search term for the columns.
+// The expression contains a single placeholder for the search term.
+func (c *SQLCompiler) fullTextScore(columns []string, options types.FullTextOptions) string {
+	switch c.driver {
+	case types.MySQL:
+		return fmt.Sprintf("MATCH(%s) AGAINST(%s%s)", strings.Join(columns, ", "), c.getParameterPlaceholder(), mysqlFullTextModifier(options))
+	case types.PostgreSQL:
+		return fmt.Sprintf("ts_rank(%s, %s)", c.tsVector(columns, options), c.tsQuery(options))
+	default:
+		return ""
+	}
+}
+
+func (c *SQLCompiler) tsVector(columns []string, options types.FullTextOptions) string {
+	document := strings.Join(columns, " || ' ' || ")
+	if config := tsConfig(options.Language); config != "" {
+		return fmt.Sprintf("to_tsvector(%s, %s)", config, document)
+	}
+	return fmt.Sprintf("to_tsvector(%s)", document)
+}
+
+func (c *SQLCompiler) tsQuery(options types.FullTextOptions) string {
+	function := "plainto_tsquery"
+	switch options.Mode {
+	case types.FullTextBoolean:
+		function = "to_tsquery"
+	case types.FullTextPhrase:
+		function = "phraseto_tsquery"
+	case types.FullTextWebsearch:
+		function = "websearch_to_tsquery"
+	}
+
+	if config := tsConfig(options.Language); config != "" {
+		return fmt.Sprintf("%s(%s, %s)", function, config, c.getParameterPlaceholder())
+	}
+	return fmt.Sprintf("%s(%s)", function, c.getParameterPlaceholder())
+}
+
+var tsConfigPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// checkFullTextOptions rejects a Language that is not a text search configuration name.
+func checkFullTextOptions(options types.FullTextOptions) error {
+	if options.Language != "" && !tsConfigPattern.MatchString(options.Language) {
+		return fmt.Errorf("%w: full-text language %q", types.ErrInvalidParameter, options.Language)
+	}
+	return nil
+}
+
+// tsConfig returns the quoted text search configuration, or "" when none is set. Invalid ones are
+// rejected by checkFullTextOptions before the statement is compiled.
+func tsConfig(language string) string {
+	if !tsConfigPattern.MatchString(language) {
+		return ""
+	}
+	return "'" + strings.ToLower(language) + "'"
+}
+
+func mysqlFullTextModifier(options types.FullTextOptions) string {
+	switch options.Mode {
+	case types.FullTextBoolean, types.FullTextWebsearch:
+		return " IN BOOLEAN MODE"
+	case types.FullTextNatural, types.FullTextPhrase:
+		if options.QueryExpansion {
+			return " IN NATURAL LANGUAGE MODE WITH QUERY EXPANSION"
+		}
+		return " IN NATURAL LANGUAGE MODE"
+	default:
+		if options.QueryExpansion {
+			return " WITH QUERY EXPANSION"
+		}
+		return ""
+	}
+}
+
+// compileTimeAgo returns the expression for the current database time minus a bound number of microseconds.
+func (c *SQLCompiler) compileTimeAgo() string {
+	switch c.driver {
+	case types.PostgreSQL:
+		return fmt.Sprintf("NOW() - %s * INTERVAL '1 microsecond'", c.getParameterPlaceholder())
+	case types.ClickHouse:
+		return fmt.Sprintf("now64(6) - toIntervalMicrosecond(%s)", c.getParameterPlaceholder())
+	default:
+		return fmt.Sprintf("NOW() - INTERVAL %s MICROSECOND", c.getParameterPlaceholder())
+	}
+}
+
+func (c *SQLCompiler) compileLikeWhereClause(where *clauses.WhereClause) string {
+	if where.Operator != types.OpILike {
+		return fmt.Sprintf("%s LIKE %s", where.Column, c.getParameterPlaceholder())
+	}
+
+	switch c.driver {
+	case types.PostgreSQL, types.ClickHouse:
+		return fmt.Sprintf("%s ILIKE %s", where.Column, c.getParameterPlaceholder())
+	default:
+		return fmt.Sprintf("LOWER(%s) LIKE LOWER(%s)", where.Column, c.getParameterPlaceholder())
+	}
+}
+
+func (c *SQLCompiler) compileArrayWhereClause(where *clauses.WhereClause, bindings *[]interface{}) (string, []interface{}) {
+	if c.driver == types.ClickHouse {
+		return c.compileClickHouseArrayWhereClause(where, bindings)
+	}
+
+	if where.Type == "array_any" {
+		*bindings = append(*bindings, where.Value)
+		return fmt.Sprintf("%s %s ANY(%s)", c.getParameterPlaceholder(), where.Operator, where.Column), *bindings
+	}
+
+	*bindings = append(*bindings, arrayBinding(where.Value))
+	return fmt.Sprintf("%s %s %s", where.Column, where.Operator, c.getParameterPlaceholder()), *bindings
+}
+
+// compileClickHouseArrayWhereClause compiles array conditions with ClickHouse's array functions,
+// which take Go slices as bindings directly.
+func (c *SQLCompiler) compileClickHouseArrayWhereClause(where *clauses.WhereClause, bindings *[]interface{}) (string, []interface{}) {
+	*bindings = append(*bindings, where.Value)
+	switch {
+	case where.Type == "array_any" && where.Operator == types.OpEqual:
+		return fmt.Sprintf("has(%s, %s)", where.Column, c.getParameterPlaceholder()), *bindings
+	case where.Type == "array_any":
+		return fmt.Sprintf("arrayExists(x -> %s %s x, %s)", c.getParameterPlaceholder(), where.Operator, where.Column), *bindings
+	case where.Operator == types.OpArrayContains:
+		return fmt.Sprintf("hasAll(%s, %s)", where.Column, c.getParameterPlaceholder()), *bindings
+	default:
+		return fmt.Sprintf("hasAny(%s, %s)", where.Column, c.getParameterPlaceholder()), *bindings
+	}
+}
+
+// arrayBinding wraps Go slices so they are encoded as PostgreSQL array literals.
+func arrayBinding(value interface{}) interface{} {
+	if _, ok := value.(driver.Valuer); ok {
+		return value
+	}
+
+	switch value.(type) {
+	case []byte, string:
+		return value
+	}
+
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		return pq.Array(value)
+	}
+
+	return value
+}
+
+func (c *SQLCompiler) writeGroups(buf *bytes.Buffer, groups []*clauses.GroupClause) {
+	for i, group := range groups {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
 		if group.IsRaw() {
-			parts = append(parts, group.GetRaw())
+			buf.WriteString(group.GetRaw())
 		} else {
-			parts = append(parts, group.GetColumn())
+			buf.WriteString(group.GetColumn())
 		}
 	}
-	return strings.Join(parts, ", ")
 }
 
-func (c *SQLCompiler) compileHavings(havings []*clauses.HavingClause) (string, []interface{}) {
-	var parts []string
-	var bindings []interface{}
-
+func (c *SQLCompiler) writeHavings(buf *bytes.Buffer, havings []*clauses.HavingClause, bindings []interface{}) []interface{} {
 	for i, having := range havings {
-		var havingSQL string
-		if having.IsRaw() {
-			havingSQL = having.GetRaw()
-		} else {
-			havingSQL = fmt.Sprintf("%s %s %s", having.GetColumn(), having.GetOperator(), c.getParameterPlaceholder())
-			bindings = append(bindings, having.GetValue())
+		if i > 0 {
+			buf.WriteByte(' ')
+			buf.WriteString(strings.ToUpper(string(having.GetBoolean())))
+			buf.WriteByte(' ')
 		}
 
-		if i == 0 {
-			parts = append(parts, havingSQL)
+		if having.IsRaw() {
+			buf.WriteString(having.GetRaw())
+			bindings = append(bindings, having.GetBindings()...)
 		} else {
-			parts = append(parts, strings.ToUpper(string(having.GetBoolean()))+" "+havingSQL)
+			writeCondition(buf, having.GetColumn(), string(having.GetOperator()))
+			buf.WriteByte(' ')
+			buf.WriteString(c.getParameterPlaceholder())
+			bindings = append(bindings, having.GetValue())
 		}
 	}
-
-	return strings.Join(parts, " "), bindings
+	return bindings
 }
 
 func (c *SQLCompiler) compileOrders(orders []*clauses.OrderClause) string {
-	var parts []string
-	for _, order := range orders {
+	buf := getSQLBuffer()
+	defer putSQLBuffer(buf)
+
+	c.writeOrders(buf, orders)
+	return buf.String()
+}
+
+func (c *SQLCompiler) writeOrders(buf *bytes.Buffer, orders []*clauses.OrderClause) {
+	for i, order := range orders {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
 		if order.IsRaw() {
-			parts = append(parts, order.GetRaw())
+			buf.WriteString(order.GetRaw())
+		} else if order.GetNulls() != "" {
+			buf.WriteString(c.compileOrderNulls(order))
 		} else {
-			parts = append(parts, fmt.Sprintf("%s %s", order.GetColumn(), order.GetDirection()))
+			buf.WriteString(order.GetColumn())
+			buf.WriteByte(' ')
+			buf.WriteString(string(order.GetDirection()))
 		}
 	}
-	return strings.Join(parts, ", ")
 }
 
-func (c *SQLCompiler) compileUnions(unions []*clauses.UnionClause) (string, []interface{}) {
-	var parts []string
+// compileOrderNulls compiles an ORDER BY with explicit NULL placement. MySQL has no NULLS FIRST/LAST
+// syntax, so it is emulated by sorting on ISNULL(column) first.
+func (c *SQLCompiler) compileOrderNulls(order *clauses.OrderClause) string {
+	if c.driver == types.PostgreSQL || c.driver == types.ClickHouse {
+		return fmt.Sprintf("%s %s %s", order.GetColumn(), order.GetDirection(), order.GetNulls())
+	}
+
+	nullsDirection := types.Asc
+	if order.GetNulls() == types.NullsFirst {
+		nullsDirection = types.Desc
+	}
+	return fmt.Sprintf("ISNULL(%s) %s, %s %s", order.GetColumn(), nullsDirection, order.GetColumn(), order.GetDirection())
+}
+
+// compileUnions combines the query compiled as base with its set operations. INTERSECT and EXCEPT
+// are rewritten to EXISTS and NOT EXISTS when emulateIntersect says the server lacks them.
+func (c *SQLCompiler) compileUnions(qb *Builder, base string, unions []*clauses.UnionClause) (string, []interface{}) {
 	var bindings []interface{}
+	sql := base
+	compound := false
+	emulate := c.emulateIntersect(qb)
 
 	for _, union := range unions {
-		unionSQL, unionBindings, _ := union.GetQuery().ToSQL()
-		
-		if union.IsUnionAll() {
-			parts = append(parts, "UNION ALL ("+unionSQL+")")
-		} else {
-			parts = append(parts, "UNION ("+unionSQL+")")
-		}
-		
+		// checkConditions has already reported the errors of subqueries.
+		unionSQL, unionBindings, _ := c.compileSubquery(union.GetQuery())
 		bindings = append(bindings, unionBindings...)
+
+		if emulate && union.GetOperator() != clauses.SetUnion {
+			sql = c.compileEmulatedSetOperation(qb, sql, union, unionSQL)
+			compound = false
+			continue
+		}
+
+		if !compound {
+			sql = "(" + sql + ")"
+			compound = true
+		}
+		sql += " " + union.Keyword() + " (" + unionSQL + ")"
+	}
+
+	return sql, bindings
+}
+
+// emulateIntersect reports whether INTERSECT and EXCEPT must be emulated: on MySQL servers known
+// not to support them. Unknown versions get the native operators.
+func (c *SQLCompiler) emulateIntersect(qb *Builder) bool {
+	if c.driver != types.MySQL {
+		return false
+	}
+	version := qb.execEngine.ServerVersion()
+	return version.Known() && !version.Supports(types.FeatureIntersectExcept)
+}
+
+// compileEmulatedSetOperation keeps the distinct rows of left that have (INTERSECT) or lack
+// (EXCEPT) a matching row in right. Columns are matched by position with the NULL-safe <=>, as
+// the set operators treat NULLs as equal.
+func (c *SQLCompiler) compileEmulatedSetOperation(qb *Builder, left string, union *clauses.UnionClause, right string) string {
+	leftColumns, _ := setOperationColumns(qb)
+	rightColumns, _ := setOperationColumns(union.GetQuery().(*Builder))
+
+	conditions := make([]string, len(leftColumns))
+	for i := range leftColumns {
+		conditions[i] = fmt.Sprintf("set_right.%s <=> set_left.%s", rightColumns[i], leftColumns[i])
 	}
 
-	return strings.Join(parts, " "), bindings
+	exists := "EXISTS"
+	if union.GetOperator() == clauses.SetExcept {
+		exists = "NOT EXISTS"
+	}
+	return fmt.Sprintf("SELECT DISTINCT * FROM (%s) AS set_left WHERE %s (SELECT 1 FROM (%s) AS set_right WHERE %s)",
+		left, exists, right, strings.Join(conditions, " AND "))
+}
+
+// checkSetOperations rejects INTERSECT and EXCEPT that cannot be emulated, because a side selects
+// * or raw expressions whose result columns are unknown, or the sides select different numbers of
+// columns.
+func (c *SQLCompiler) checkSetOperations(qb *Builder) error {
+	if !c.emulateIntersect(qb) {
+		return nil
+	}
+
+	for _, union := range qb.GetUnions() {
+		if union.GetOperator() == clauses.SetUnion {
+			continue
+		}
+		left, err := setOperationColumns(qb)
+		if err != nil {
+			return err
+		}
+		sub, ok := union.GetQuery().(*Builder)
+		if !ok {
+			return fmt.Errorf("%w: %s on %s needs a query builder on both sides",
+				types.ErrUnsupportedFeature, union.GetOperator(), qb.execEngine.ServerVersion())
+		}
+		right, err := setOperationColumns(sub)
+		if err != nil {
+			return err
+		}
+		if len(left) != len(right) {
+			return fmt.Errorf("%s sides select %d and %d columns", union.GetOperator(), len(left), len(right))
+		}
+	}
+	return nil
+}
+
+// checkConditions rejects the conditions of qb and its subqueries that the driver cannot compile:
+// array conditions outside PostgreSQL and ClickHouse, array comparisons whose operator is not
+// allowed, distance conditions outside MySQL and PostgreSQL, full-text searches with an invalid
+// language and the errors recorded while building qb.
+func (c *SQLCompiler) checkConditions(qb *Builder) error {
+	if err := c.checkWheres(qb.GetWheres()); err != nil {
+		return err
+	}
+	if err := c.checkWheres(qb.policies); err != nil {
+		return err
+	}
+	for _, join := range qb.GetJoins() {
+		if err := c.checkWheres(join.Clauses); err != nil {
+			return err
+		}
+	}
+	for _, union := range qb.GetUnions() {
+		if err := checkSubquery(union.GetQuery()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anyValueOperators are the scalar comparisons WhereAnyValue accepts. Other operators do not fit
+// "value op ANY(column)" and would compile to invalid SQL.
+var anyValueOperators = map[types.Operator]bool{
+	types.OpEqual:              true,
+	types.OpNotEqual:           true,
+	"<>":                       true,
+	types.OpLessThan:           true,
+	types.OpLessThanOrEqual:    true,
+	types.OpGreaterThan:        true,
+	types.OpGreaterThanOrEqual: true,
+}
+
+func (c *SQLCompiler) checkWheres(wheres []*clauses.WhereClause) error {
+	for _, where := range wheres {
+		switch where.Type {
+		case "array", "array_any":
+			if c.driver != types.PostgreSQL && c.driver != types.ClickHouse {
+				return fmt.Errorf("%w: array conditions on %s", types.ErrUnsupportedFeature, c.driver)
+			}
+			if where.Type == "array_any" && !anyValueOperators[where.Operator] {
+				return fmt.Errorf("%w: operator %q is not allowed", types.ErrInvalidParameter, where.Operator)
+			}
+		case "error":
+			return where.Value.(error)
+		case "fulltext":
+			if where.FullText != nil {
+				if err := checkFullTextOptions(*where.FullText); err != nil {
+					return err
+				}
+			}
+		case "distance":
+			if where.Raw == "" {
+				return fmt.Errorf("%w: distance conditions on %s", types.ErrUnsupportedFeature, c.driver)
+			}
+		case "exists":
+			if err := checkSubquery(where.Query); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkSubquery runs checkConditions on a subquery built by this package. Other builders are
+// compiled to surface their errors.
+func checkSubquery(query types.QueryBuilder) error {
+	if sub, ok := query.(*Builder); ok {
+		sub.applyScopes()
+		return sub.compiler.checkConditions(sub)
+	}
+	_, _, err := query.ToSQL()
+	return err
+}
+
+// setOperationColumns returns the result column names of qb's selects.
+func setOperationColumns(qb *Builder) ([]string, error) {
+	selects := qb.GetSelects()
+	if len(selects) == 0 {
+		return nil, fmt.Errorf("%w: emulating INTERSECT and EXCEPT on %s needs explicitly selected columns",
+			types.ErrUnsupportedFeature, qb.execEngine.ServerVersion())
+	}
+
+	columns := make([]string, len(selects))
+	for i, sel := range selects {
+		switch {
+		case sel.IsRaw() || strings.HasSuffix(sel.GetColumn(), "*"):
+			return nil, fmt.Errorf("%w: emulating INTERSECT and EXCEPT on %s needs plain columns, not %q",
+				types.ErrUnsupportedFeature, qb.execEngine.ServerVersion(), sel.GetRaw()+sel.GetColumn())
+		case sel.HasAlias():
+			columns[i] = sel.GetAlias()
+		default:
+			columns[i] = sel.GetColumn()
+			if dot := strings.LastIndexByte(columns[i], '.'); dot >= 0 {
+				columns[i] = columns[i][dot+1:]
+			}
+		}
+	}
+	return columns, nil
 }
 
 func (c *SQLCompiler) compileLimit(limit int) string {
-	return fmt.Sprintf("LIMIT %d", limit)
+	return "LIMIT " + strconv.Itoa(limit)
 }
 
-func (c *SQLCompiler) compileOffset(offset int) string {
-	return fmt.Sprintf("OFFSET %d", offset)
+func (c *SQLCompiler) writeLimit(buf *bytes.Buffer, limit int) {
+	var digits [20]byte
+	buf.WriteString("LIMIT ")
+	buf.Write(strconv.AppendInt(digits[:0], int64(limit), 10))
 }
 
+func (c *SQLCompiler) writeOffset(buf *bytes.Buffer, offset int) {
+	var digits [20]byte
+	buf.WriteString("OFFSET ")
+	buf.Write(strconv.AppendInt(digits[:0], int64(offset), 10))
+}
+
+// getParameterPlaceholder returns the placeholder used while compiling. PostgreSQL statements are
+// renumbered to $n by finish once the whole statement is compiled.
 func (c *SQLCompiler) getParameterPlaceholder() string {
-	switch c.driver {
-	case types.PostgreSQL:
-		return "$%d"
-	default:
-		return "?"
-	}
+	return "?"
 }
 
-func (c *SQLCompiler) getInPlaceholders(count int) string {
-	placeholders := make([]string, count)
+// writeInPlaceholders writes count comma-separated placeholders to buf without allocating.
+func (c *SQLCompiler) writeInPlaceholders(buf *bytes.Buffer, count int) {
+	buf.Grow(3 * count)
 	for i := 0; i < count; i++ {
-		placeholders[i] = c.getParameterPlaceholder()
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(c.getParameterPlaceholder())
 	}
-	return strings.Join(placeholders, ", ")
-}
\ No newline at end of file
+}
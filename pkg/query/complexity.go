@@ -0,0 +1,37 @@
+package query
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/security"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// budgeted is implemented by connections with a complexity budget, such as database.Connection.
+type budgeted interface {
+	ComplexityBudget() types.ComplexityBudget
+}
+
+// checkComplexity scores a compiled statement and compares it with the complexity budget of the
+// builder's connection.
+func (qb *Builder) checkComplexity(kind, sql string) error {
+	conn, ok := qb.executor.(budgeted)
+	if !ok {
+		return nil
+	}
+	budget := conn.ComplexityBudget()
+	if budget.Max <= 0 {
+		return nil
+	}
+
+	score := security.Complexity(qb.describe(kind, sql))
+	if score <= budget.Max {
+		return nil
+	}
+	if budget.Mode == types.BudgetLog {
+		slog.Warn("query over complexity budget", "table", tableName(qb.table), "score", score, "budget", budget.Max, "sql", sql)
+		return nil
+	}
+	return fmt.Errorf("%w: %s on %s scores %d (budget %d)", types.ErrComplexityBudget, kind, tableName(qb.table), score, budget.Max)
+}
@@ -0,0 +1,112 @@
+package query
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/security"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var firewall atomic.Pointer[security.Firewall]
+
+// SetFirewall makes every builder check the statements it compiles against fw, failing ToSQL and
+// the statements run with it when a deny rule matches. A nil firewall removes the checks.
+func SetFirewall(fw *security.Firewall) {
+	firewall.Store(fw)
+}
+
+// checkFirewall checks a compiled statement against the firewall set with SetFirewall.
+func (qb *Builder) checkFirewall(kind, sql string) error {
+	fw := firewall.Load()
+	if fw == nil {
+		return nil
+	}
+	return fw.Check(qb.describe(kind, sql))
+}
+
+// describe summarizes the builder for the firewall rules.
+func (qb *Builder) describe(kind, sql string) *security.Statement {
+	statement := &security.Statement{
+		Kind:      kind,
+		Table:     tableName(qb.table),
+		SQL:       sql,
+		Joins:     len(qb.joins),
+		Wheres:    len(qb.wheres) + len(qb.policies),
+		SelectAll: len(qb.selects) == 0,
+		Aggregate: len(qb.selects) > 0,
+		Limited:   qb.limitValue != nil,
+	}
+
+	if strings.HasPrefix(qb.table, "(") {
+		statement.Subqueries++
+	}
+	for _, join := range qb.joins {
+		if strings.HasPrefix(join.Table, "(") {
+			statement.Subqueries++
+		}
+	}
+	statement.Subqueries += len(qb.unions)
+
+	for _, sel := range qb.selects {
+		if sel.Column == "*" || strings.HasSuffix(sel.Column, ".*") {
+			statement.SelectAll = true
+		}
+		if sel.Raw == "" || !isAggregate(sel.Raw) {
+			statement.Aggregate = false
+		}
+	}
+
+	for _, where := range qb.wheres {
+		if where.Query != nil {
+			statement.Subqueries++
+		}
+		switch {
+		case where.Type == "in":
+			statement.InSizes = append(statement.InSizes, len(where.Values))
+		case where.Type == "tuple_in" && len(where.Columns) > 0:
+			statement.InSizes = append(statement.InSizes, len(where.Values)/len(where.Columns))
+		case where.Type == "like", where.Type == "basic" && isLike(where.Operator):
+			if pattern, ok := where.Value.(string); ok {
+				statement.Likes = append(statement.Likes, pattern)
+			}
+		}
+	}
+	return statement
+}
+
+func isLike(operator types.Operator) bool {
+	switch types.Operator(strings.ToUpper(string(operator))) {
+	case types.OpLike, types.OpNotLike, types.OpILike:
+		return true
+	}
+	return false
+}
+
+// isAggregate reports whether a raw select expression is a call of an aggregate function.
+func isAggregate(raw string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(raw))
+	for _, fn := range []types.AggregateFunction{types.Count, types.Sum, types.Avg, types.Min, types.Max} {
+		if strings.HasPrefix(upper, string(fn)+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// guard returns a function passing a compiled statement of the given kind through the firewall
+// and the complexity budget of the builder's connection.
+func (qb *Builder) guard(kind string) func(string, []interface{}, error) (string, []interface{}, error) {
+	return func(sql string, bindings []interface{}, err error) (string, []interface{}, error) {
+		if err != nil {
+			return "", nil, err
+		}
+		if err := qb.checkFirewall(kind, sql); err != nil {
+			return "", nil, err
+		}
+		if err := qb.checkComplexity(kind, sql); err != nil {
+			return "", nil, err
+		}
+		return sql, bindings, nil
+	}
+}
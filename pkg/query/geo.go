@@ -0,0 +1,75 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/clauses"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// WhereDistanceWithin adds a WHERE clause matching rows whose point column lies within
+// the given number of meters from the latitude/longitude pair. Drivers other than MySQL and
+// PostgreSQL fail to compile it with ErrUnsupportedFeature.
+func (qb *Builder) WhereDistanceWithin(column string, lat, lng, meters float64) types.QueryBuilder {
+	qb = qb.mutable()
+	var raw string
+	switch qb.driver {
+	case types.MySQL:
+		raw = fmt.Sprintf("%s <= %s", qb.distanceExpression(column, lat, lng), formatFloat(meters))
+	case types.PostgreSQL:
+		raw = fmt.Sprintf("ST_DWithin(%s::geography, %s, %s)", column, geoPoint(qb.driver, lat, lng), formatFloat(meters))
+	}
+
+	qb.wheres = append(qb.wheres, clauses.NewWhereDistanceClause(column, raw))
+	return qb
+}
+
+// OrderByDistance orders the results by distance from the latitude/longitude pair, nearest first by default.
+func (qb *Builder) OrderByDistance(column string, lat, lng float64, direction ...types.OrderDirection) types.QueryBuilder {
+	qb = qb.mutable()
+	dir := types.Asc
+	if len(direction) > 0 {
+		dir = direction[0]
+	}
+
+	qb.orders = append(qb.orders, clauses.NewOrderRawClause(fmt.Sprintf("%s %s", qb.distanceExpression(column, lat, lng), dir)))
+	return qb
+}
+
+// SelectDistance selects the distance in meters between the point column and the latitude/longitude pair.
+func (qb *Builder) SelectDistance(column string, lat, lng float64, alias string) types.QueryBuilder {
+	qb = qb.mutable()
+	if alias == "" {
+		alias = "distance"
+	}
+
+	qb.selects = append(qb.selects, clauses.NewSelectRawClause(fmt.Sprintf("%s AS %s", qb.distanceExpression(column, lat, lng), alias)))
+	return qb
+}
+
+// distanceExpression returns the driver-specific expression computing the distance in meters.
+func (qb *Builder) distanceExpression(column string, lat, lng float64) string {
+	switch qb.driver {
+	case types.MySQL:
+		return fmt.Sprintf("ST_Distance_Sphere(%s, %s)", column, geoPoint(qb.driver, lat, lng))
+	case types.PostgreSQL:
+		return fmt.Sprintf("ST_Distance(%s::geography, %s)", column, geoPoint(qb.driver, lat, lng))
+	default:
+		return column
+	}
+}
+
+// geoPoint builds a WGS 84 point literal. Coordinates are typed floats, so inlining them is safe.
+// MySQL compares only geometries of the same SRID, so the column must be stored with SRID 4326.
+func geoPoint(driver types.Driver, lat, lng float64) string {
+	if driver == types.PostgreSQL {
+		return fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), 4326)::geography", formatFloat(lng), formatFloat(lat))
+	}
+
+	return fmt.Sprintf("ST_SRID(POINT(%s, %s), 4326)", formatFloat(lng), formatFloat(lat))
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
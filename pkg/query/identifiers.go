@@ -0,0 +1,25 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/security"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// columnValidator checks the column names INSERT and UPDATE interpolate into SQL. The allowed
+// patterns already exclude anything but identifiers, so keywords are not forbidden: a column may be
+// named "system".
+var columnValidator = security.NewValidator().SetForbiddenKeywords()
+
+// ValidateColumns fails with types.ErrInvalidIdentifier unless every column is a plain or
+// table-qualified identifier. The executor calls it for the columns of inserted and updated values,
+// which are written into the statement rather than bound.
+func (qb *Builder) ValidateColumns(columns ...string) error {
+	for _, column := range columns {
+		if err := columnValidator.ValidateColumnName(column); err != nil {
+			return fmt.Errorf("%w %q on %s: %v", types.ErrInvalidIdentifier, column, qb.table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package query
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var (
+	macros     = make(map[string]types.MacroFunc)
+	macrosLock sync.RWMutex
+)
+
+// Macro registers a named query fragment that can be applied to any builder with Call.
+// Registering the same name again replaces the previous macro.
+func Macro(name string, fn types.MacroFunc) {
+	macrosLock.Lock()
+	defer macrosLock.Unlock()
+	macros[name] = fn
+}
+
+// HasMacro reports whether a macro with the given name is registered.
+func HasMacro(name string) bool {
+	macrosLock.RLock()
+	defer macrosLock.RUnlock()
+	_, ok := macros[name]
+	return ok
+}
+
+// FlushMacros removes all registered macros.
+func FlushMacros() {
+	macrosLock.Lock()
+	defer macrosLock.Unlock()
+	macros = make(map[string]types.MacroFunc)
+}
+
+// Call applies the named macro to the query. It panics if the macro is not registered,
+// since that is a programming error rather than a runtime condition.
+func (qb *Builder) Call(name string, args ...interface{}) types.QueryBuilder {
+	macrosLock.RLock()
+	fn, ok := macros[name]
+	macrosLock.RUnlock()
+
+	if !ok {
+		panic(fmt.Sprintf("querybuilder: macro %q is not registered", name))
+	}
+	return fn(qb, args...)
+}
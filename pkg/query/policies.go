@@ -0,0 +1,114 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/clauses"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var (
+	policies     = make(map[string]map[string]types.PolicyFunc)
+	policiesLock sync.RWMutex
+)
+
+// RegisterPolicy registers a named row-level security policy for a table. Every SELECT, UPDATE and
+// DELETE on the table is restricted by the Where clauses the policies add for the statement's
+// context. Registering the same table and name again replaces the previous policy.
+func RegisterPolicy(table, name string, fn types.PolicyFunc) {
+	policiesLock.Lock()
+	defer policiesLock.Unlock()
+
+	if policies[table] == nil {
+		policies[table] = make(map[string]types.PolicyFunc)
+	}
+	policies[table][name] = fn
+}
+
+// Policies returns the sorted names of the policies registered for a table.
+func Policies(table string) []string {
+	policiesLock.RLock()
+	defer policiesLock.RUnlock()
+
+	names := make([]string, 0, len(policies[table]))
+	for name := range policies[table] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FlushPolicies removes all registered policies.
+func FlushPolicies() {
+	policiesLock.Lock()
+	defer policiesLock.Unlock()
+	policies = make(map[string]map[string]types.PolicyFunc)
+}
+
+// WithoutPolicies skips the row-level security policies of the table, for trusted jobs such as
+// migrations and reports.
+func (qb *Builder) WithoutPolicies() types.QueryBuilder {
+	qb = qb.mutable()
+	qb.withoutPolicies = true
+	return qb
+}
+
+// ApplyPolicies returns a copy of the builder restricted by the policies registered for its table,
+// evaluated for ctx. It returns the builder itself when there is nothing to apply.
+func (qb *Builder) ApplyPolicies(ctx context.Context) (types.QueryBuilder, error) {
+	if qb.withoutPolicies || qb.policed {
+		return qb, nil
+	}
+
+	table := tableName(qb.table)
+	policiesLock.RLock()
+	names := make([]string, 0, len(policies[table]))
+	for name := range policies[table] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fns := make([]types.PolicyFunc, len(names))
+	for i, name := range names {
+		fns[i] = policies[table][name]
+	}
+	policiesLock.RUnlock()
+	if len(fns) == 0 {
+		return qb, nil
+	}
+
+	clone := qb.Clone().(*Builder)
+	clone.policed = true
+	for i, fn := range fns {
+		restricted, err := fn(ctx, NewBuilder(qb.executor, qb.driver).From(qb.table))
+		if err != nil {
+			return nil, fmt.Errorf("policy %s on %s denied the query: %w", names[i], table, err)
+		}
+		if b, ok := restricted.(*Builder); ok {
+			b.applyScopes()
+			clone.policies = append(clone.policies, b.wheres...)
+		}
+	}
+	return clone, nil
+}
+
+// tableName strips the alias from a table reference such as "users as u".
+func tableName(table string) string {
+	if fields := strings.Fields(table); len(fields) > 0 {
+		return fields[0]
+	}
+	return table
+}
+
+// hasOr reports whether any of wheres is joined with OR.
+func hasOr(wheres []*clauses.WhereClause) bool {
+	for _, where := range wheres {
+		if where.Boolean == types.Or {
+			return true
+		}
+	}
+	return false
+}
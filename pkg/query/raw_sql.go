@@ -0,0 +1,105 @@
+package query
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// ToRawSQL returns the SELECT statement with its bindings interpolated as literals, for pasting into
+// a database console while debugging. It is rendered with the formatter set by SetSQLFormatter.
+//
+// UNSAFE FOR EXECUTION: values are quoted on a best-effort basis and are not escaped the way the
+// driver escapes bound parameters. Never run the result or build queries from it; use ToSQL.
+func (qb *Builder) ToRawSQL() (string, error) {
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		return "", err
+	}
+	return execution.FormatSQL(interpolate(sql, bindings, qb.driver)), nil
+}
+
+// interpolate replaces the ? or $n placeholders of sql outside quotes with literal bindings.
+func interpolate(sql string, bindings []interface{}, driver types.Driver) string {
+	var b strings.Builder
+	b.Grow(len(sql) + 16*len(bindings))
+
+	position := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '?' && driver != types.PostgreSQL && position < len(bindings):
+			b.WriteString(sqlLiteral(bindings[position], driver))
+			position++
+			continue
+		case ch == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(sql[i+1 : j]); err == nil && n >= 1 && n <= len(bindings) {
+				b.WriteString(sqlLiteral(bindings[n-1], driver))
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(ch)
+	}
+
+	return b.String()
+}
+
+// sqlLiteral formats a binding as a SQL literal for display.
+func sqlLiteral(value interface{}, dbDriver types.Driver) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "NULL"
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case []byte:
+		if dbDriver == types.PostgreSQL {
+			return `'\x` + hex.EncodeToString(v) + `'`
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case string:
+		return quoteString(v, dbDriver)
+	default:
+		return quoteString(fmt.Sprint(v), dbDriver)
+	}
+}
+
+func quoteString(value string, dbDriver types.Driver) string {
+	value = strings.ReplaceAll(value, "'", "''")
+	if dbDriver == types.MySQL {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + value + "'"
+}
@@ -0,0 +1,62 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var (
+	namedScopes     = make(map[string]map[string]types.NamedScopeFunc)
+	namedScopesLock sync.RWMutex
+)
+
+// RegisterScope registers a named, parameterized scope for a table. Registering the same
+// table and name again replaces the previous scope.
+func RegisterScope(table, name string, fn types.NamedScopeFunc) {
+	namedScopesLock.Lock()
+	defer namedScopesLock.Unlock()
+
+	if namedScopes[table] == nil {
+		namedScopes[table] = make(map[string]types.NamedScopeFunc)
+	}
+	namedScopes[table][name] = fn
+}
+
+// Scopes returns the sorted names of the scopes registered for a table.
+func Scopes(table string) []string {
+	namedScopesLock.RLock()
+	defer namedScopesLock.RUnlock()
+
+	names := make([]string, 0, len(namedScopes[table]))
+	for name := range namedScopes[table] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FlushScopes removes all registered named scopes.
+func FlushScopes() {
+	namedScopesLock.Lock()
+	defer namedScopesLock.Unlock()
+	namedScopes = make(map[string]map[string]types.NamedScopeFunc)
+}
+
+// UseScope applies a named scope registered for the query's table with the given arguments.
+// Like Scope, it runs when the query is compiled. It panics if the scope is not registered.
+func (qb *Builder) UseScope(name string, args ...interface{}) types.QueryBuilder {
+	namedScopesLock.RLock()
+	fn, ok := namedScopes[qb.table][name]
+	namedScopesLock.RUnlock()
+
+	if !ok {
+		panic(fmt.Sprintf("querybuilder: scope %q is not registered for table %q", name, qb.table))
+	}
+
+	return qb.Scope(func(q types.QueryBuilder) types.QueryBuilder {
+		return fn(q, args...)
+	})
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/clauses"
+	"github.com/omarhamdy49/go-query-builder/pkg/optimization"
+)
+
+// cacheableWheres are the WHERE clause types whose SQL depends only on their shape and whose
+// bindings shapeBindings knows how to collect.
+var cacheableWheres = map[string]bool{
+	"basic": true, "raw": true, "between": true, "relative_date": true, "in": true, "null": true, "like": true,
+}
+
+// cacheKey returns the SQL cache set with optimization.SetSQLCache and the key of qb's compiled
+// SELECT in it, or nil if there is no cache or qb's SQL may depend on more than its shape:
+// subqueries, set operations and driver-specific conditions such as JSON and full-text search.
+func (c *SQLCompiler) cacheKey(qb *Builder) (*optimization.SQLCache, string) {
+	cache := optimization.ActiveSQLCache()
+	if cache == nil || c.debug || len(qb.unions) > 0 {
+		return nil, ""
+	}
+
+	var key strings.Builder
+	field := func(values ...string) {
+		for _, value := range values {
+			key.WriteString(value)
+			key.WriteByte(0)
+		}
+	}
+	wheres := func(section string, wheres []*clauses.WhereClause) bool {
+		field(section)
+		for _, where := range wheres {
+			if !cacheableWheres[where.Type] {
+				return false
+			}
+			field(where.Type, where.Column, string(where.Operator), string(where.Boolean), where.Raw, strconv.Itoa(len(where.Values)))
+		}
+		return true
+	}
+
+	field(string(c.driver), qb.table, strconv.FormatBool(qb.distinct), strconv.FormatBool(qb.final), qb.softDeleteCondition())
+	if qb.tableSample != nil {
+		field("sample", strconv.FormatFloat(*qb.tableSample, 'g', -1, 64))
+	}
+	field("select")
+	for _, sel := range qb.selects {
+		field(sel.Column, sel.Alias, sel.Raw)
+	}
+	field("join")
+	for _, join := range qb.joins {
+		field(string(join.Type), join.Table, join.First, string(join.Operator), join.Second)
+		if !wheres("on", join.Clauses) {
+			return nil, ""
+		}
+	}
+	if !wheres("where", qb.wheres) || !wheres("policy", qb.policies) {
+		return nil, ""
+	}
+	field("group")
+	for _, group := range qb.groups {
+		field(group.Column, group.Raw)
+	}
+	field("having")
+	for _, having := range qb.havings {
+		field(having.Column, string(having.Operator), string(having.Boolean), having.Raw)
+	}
+	field("order")
+	for _, order := range qb.orders {
+		field(order.Column, string(order.Direction), string(order.Nulls), order.Raw)
+	}
+	if qb.limitValue != nil {
+		field("limit", strconv.Itoa(*qb.limitValue))
+	}
+	if qb.offsetValue != nil {
+		field("offset", strconv.Itoa(*qb.offsetValue))
+	}
+	if qb.lock != nil {
+		field("lock", string(*qb.lock))
+	}
+	return cache, key.String()
+}
+
+// shapeBindings collects the bindings of a builder accepted by cacheKey in the order compileSelect
+// produces them.
+func shapeBindings(qb *Builder) []interface{} {
+	var bindings []interface{}
+	for _, sel := range qb.selects {
+		if sel.IsRaw() {
+			bindings = append(bindings, sel.Bindings...)
+		}
+	}
+	for _, join := range qb.joins {
+		bindings = appendWhereBindings(bindings, join.Clauses)
+	}
+	bindings = appendWhereBindings(bindings, qb.wheres)
+	bindings = appendWhereBindings(bindings, qb.policies)
+	for _, having := range qb.havings {
+		if having.IsRaw() {
+			bindings = append(bindings, having.Bindings...)
+		} else {
+			bindings = append(bindings, having.Value)
+		}
+	}
+	return bindings
+}
+
+func appendWhereBindings(bindings []interface{}, wheres []*clauses.WhereClause) []interface{} {
+	for _, where := range wheres {
+		switch where.Type {
+		case "basic", "relative_date", "like":
+			bindings = append(bindings, where.Value)
+		case "raw":
+			bindings = append(bindings, where.Bindings...)
+		case "between", "in":
+			bindings = append(bindings, where.Values...)
+		}
+	}
+	return bindings
+}
@@ -2,6 +2,7 @@ package query
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -59,6 +60,47 @@ func (qb *Builder) OrWhereYear(column string, args ...interface{}) types.QueryBu
 	return qb.addDateWhere(column, "year", types.Or, args...)
 }
 
+// WhereDateBetween adds a WHERE clause matching the date portion of a column within an inclusive date window.
+// time.Time bounds are reduced to their date.
+func (qb *Builder) WhereDateBetween(column string, from, to interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereBetweenClause(fmt.Sprintf("DATE(%s)", column), []interface{}{qb.dateValue(from), qb.dateValue(to)}, false)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// located is implemented by connections with a session time zone, such as database.Connection.
+type located interface {
+	Location() *time.Location
+}
+
+// location returns the session time zone of the builder's connection, or nil when it has none.
+func (qb *Builder) location() *time.Location {
+	if conn, ok := qb.executor.(located); ok {
+		return conn.Location()
+	}
+	return nil
+}
+
+// today returns the current date in the session time zone, or in the local one without it.
+func (qb *Builder) today() string {
+	return qb.dateValue(time.Now()).(string)
+}
+
+// dateValue reduces a time.Time to its date in the session time zone. Without one the date is
+// taken in the value's own zone.
+func (qb *Builder) dateValue(value interface{}) interface{} {
+	t, ok := value.(time.Time)
+	if !ok {
+		return value
+	}
+	if loc := qb.location(); loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format("2006-01-02")
+}
+
 // WherePast adds a WHERE clause that matches dates in the past.
 func (qb *Builder) WherePast(column string) types.QueryBuilder {
 	return qb.Where(column, "<", time.Now())
@@ -79,32 +121,56 @@ func (qb *Builder) WhereNowOrFuture(column string) types.QueryBuilder {
 	return qb.Where(column, ">=", time.Now())
 }
 
-// WhereToday adds a WHERE clause that matches today's date.
+// WhereToday adds a WHERE clause that matches today's date in the connection's time zone.
 func (qb *Builder) WhereToday(column string) types.QueryBuilder {
-	return qb.WhereDate(column, time.Now().Format("2006-01-02"))
+	return qb.WhereDate(column, qb.today())
+}
+
+// WhereWithinLast adds a WHERE clause matching timestamps within the given duration before the database's current time.
+func (qb *Builder) WhereWithinLast(column string, d time.Duration) types.QueryBuilder {
+	return qb.addRelativeDateWhere(column, types.OpGreaterThanOrEqual, d)
+}
+
+// WhereOlderThan adds a WHERE clause matching timestamps older than the given duration before the database's current time.
+func (qb *Builder) WhereOlderThan(column string, d time.Duration) types.QueryBuilder {
+	return qb.addRelativeDateWhere(column, types.OpLessThan, d)
+}
+
+// WhereBetweenDates adds a WHERE clause matching timestamps between start and end (inclusive).
+func (qb *Builder) WhereBetweenDates(column string, start, end time.Time) types.QueryBuilder {
+	return qb.WhereBetween(column, []interface{}{start, end})
+}
+
+func (qb *Builder) addRelativeDateWhere(column string, operator types.Operator, d time.Duration) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereRelativeDateClause(column, operator, d)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
 }
 
 // WhereBeforeToday adds a WHERE clause that matches dates before today.
 func (qb *Builder) WhereBeforeToday(column string) types.QueryBuilder {
-	return qb.WhereDate(column, "<", time.Now().Format("2006-01-02"))
+	return qb.WhereDate(column, "<", qb.today())
 }
 
 // WhereAfterToday adds a WHERE clause that matches dates after today.
 func (qb *Builder) WhereAfterToday(column string) types.QueryBuilder {
-	return qb.WhereDate(column, ">", time.Now().Format("2006-01-02"))
+	return qb.WhereDate(column, ">", qb.today())
 }
 
 // WhereTodayOrBefore adds a WHERE clause that matches today's date or earlier.
 func (qb *Builder) WhereTodayOrBefore(column string) types.QueryBuilder {
-	return qb.WhereDate(column, "<=", time.Now().Format("2006-01-02"))
+	return qb.WhereDate(column, "<=", qb.today())
 }
 
 // WhereTodayOrAfter adds a WHERE clause that matches today's date or later.
 func (qb *Builder) WhereTodayOrAfter(column string) types.QueryBuilder {
-	return qb.WhereDate(column, ">=", time.Now().Format("2006-01-02"))
+	return qb.WhereDate(column, ">=", qb.today())
 }
 
 func (qb *Builder) addDateWhere(column, dateType string, boolean types.BooleanOperator, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(args) == 0 {
 		return qb
 	}
@@ -123,6 +189,9 @@ func (qb *Builder) addDateWhere(column, dateType string, boolean types.BooleanOp
 		operator = types.Operator(fmt.Sprintf("%v", args[0]))
 		value = args[1]
 	}
+	if dateType == "date" {
+		value = qb.dateValue(value)
+	}
 
 	var raw string
 	switch qb.driver {
@@ -154,15 +223,15 @@ func (qb *Builder) addDateWhere(column, dateType string, boolean types.BooleanOp
 		}
 	}
 
-	clause := clauses.NewWhereRawClause(raw)
+	clause := clauses.NewWhereRawClause(raw, value)
 	clause.SetBoolean(boolean)
 	qb.wheres = append(qb.wheres, clause)
-	qb.bindings = append(qb.bindings, value)
 	return qb
 }
 
 // WhereJSONContains adds a WHERE clause for JSON containment checks.
 func (qb *Builder) WhereJSONContains(column string, value interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := clauses.NewWhereJSONContainsClause(column, value)
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
@@ -171,6 +240,7 @@ func (qb *Builder) WhereJSONContains(column string, value interface{}) types.Que
 
 // OrWhereJSONContains adds an OR WHERE clause for JSON containment checks.
 func (qb *Builder) OrWhereJSONContains(column string, value interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	clause := clauses.NewWhereJSONContainsClause(column, value)
 	clause.SetBoolean(types.Or)
 	qb.wheres = append(qb.wheres, clause)
@@ -188,6 +258,7 @@ func (qb *Builder) OrWhereJSONLength(column string, args ...interface{}) types.Q
 }
 
 func (qb *Builder) addJSONLengthWhere(column string, boolean types.BooleanOperator, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(args) == 0 {
 		return qb
 	}
@@ -224,6 +295,7 @@ func (qb *Builder) OrWhereJSONPath(column, path string, args ...interface{}) typ
 }
 
 func (qb *Builder) addJSONPathWhere(column, path string, boolean types.BooleanOperator, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(args) == 0 {
 		return qb
 	}
@@ -255,29 +327,155 @@ func (qb *Builder) addJSONPathWhere(column, path string, boolean types.BooleanOp
 		}
 	}
 
-	clause := clauses.NewWhereRawClause(raw)
+	clause := clauses.NewWhereRawClause(raw, value)
 	clause.SetBoolean(boolean)
 	qb.wheres = append(qb.wheres, clause)
-	qb.bindings = append(qb.bindings, value)
 	return qb
 }
 
 // WhereFullText adds a WHERE clause for full-text search across multiple columns.
-func (qb *Builder) WhereFullText(columns []string, value string) types.QueryBuilder {
-	clause := clauses.NewWhereFullTextClause(columns, value)
+func (qb *Builder) WhereFullText(columns []string, value string, options ...types.FullTextOptions) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereFullTextClause(columns, value, firstFullTextOptions(options))
 	clause.SetBoolean(types.And)
 	qb.wheres = append(qb.wheres, clause)
 	return qb
 }
 
 // OrWhereFullText adds an OR WHERE clause for full-text search across multiple columns.
-func (qb *Builder) OrWhereFullText(columns []string, value string) types.QueryBuilder {
-	clause := clauses.NewWhereFullTextClause(columns, value)
+func (qb *Builder) OrWhereFullText(columns []string, value string, options ...types.FullTextOptions) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereFullTextClause(columns, value, firstFullTextOptions(options))
 	clause.SetBoolean(types.Or)
 	qb.wheres = append(qb.wheres, clause)
 	return qb
 }
 
+// OrderByRelevance selects the full-text match score as "relevance" and orders the results by it,
+// best match first. Further calls select their scores as "relevance_2", "relevance_3" and so on,
+// ordering by each in turn. An invalid Language makes the statement fail with
+// types.ErrInvalidParameter.
+func (qb *Builder) OrderByRelevance(columns []string, term string, options ...types.FullTextOptions) types.QueryBuilder {
+	qb = qb.mutable()
+	if len(columns) == 0 {
+		return qb
+	}
+
+	opts := firstFullTextOptions(options)
+	if err := checkFullTextOptions(opts); err != nil {
+		qb.wheres = append(qb.wheres, clauses.NewWhereErrorClause(err))
+		return qb
+	}
+
+	score := qb.compiler.fullTextScore(columns, opts)
+	if len(qb.selects) == 0 {
+		qb.selects = append(qb.selects, clauses.NewSelectRawClause("*"))
+	}
+	alias := qb.relevanceAlias()
+	qb.selects = append(qb.selects, clauses.NewSelectRawClauseWithBindings(score+" AS "+alias, term))
+	qb.orders = append(qb.orders, clauses.NewOrderClause(alias, types.Desc))
+	return qb
+}
+
+// relevanceAlias returns "relevance", or "relevance_n" with the first n from 2 that no select of qb
+// is aliased as.
+func (qb *Builder) relevanceAlias() string {
+	alias := "relevance"
+	for n := 2; qb.selectsAlias(alias); n++ {
+		alias = "relevance_" + strconv.Itoa(n)
+	}
+	return alias
+}
+
+// selectsAlias reports whether a select of qb, raw or not, is aliased as alias.
+func (qb *Builder) selectsAlias(alias string) bool {
+	for _, sel := range qb.selects {
+		if sel.GetAlias() == alias || strings.HasSuffix(sel.GetRaw(), " AS "+alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstFullTextOptions(options []types.FullTextOptions) types.FullTextOptions {
+	if len(options) > 0 {
+		return options[0]
+	}
+	return types.FullTextOptions{}
+}
+
+// WhereLike adds a case-sensitive LIKE clause. The pattern is used as given, so % and _ act as wildcards.
+func (qb *Builder) WhereLike(column string, pattern string) types.QueryBuilder {
+	return qb.addLikeWhere(column, types.OpLike, pattern)
+}
+
+// WhereILike adds a case-insensitive LIKE clause. The pattern is used as given, so % and _ act as wildcards.
+func (qb *Builder) WhereILike(column string, pattern string) types.QueryBuilder {
+	return qb.addLikeWhere(column, types.OpILike, pattern)
+}
+
+// WhereStartsWith adds a case-insensitive clause matching values that start with the given text.
+// Wildcard characters in the text are escaped and matched literally.
+func (qb *Builder) WhereStartsWith(column string, value string) types.QueryBuilder {
+	return qb.addLikeWhere(column, types.OpILike, escapeLike(value)+"%")
+}
+
+// WhereEndsWith adds a case-insensitive clause matching values that end with the given text.
+// Wildcard characters in the text are escaped and matched literally.
+func (qb *Builder) WhereEndsWith(column string, value string) types.QueryBuilder {
+	return qb.addLikeWhere(column, types.OpILike, "%"+escapeLike(value))
+}
+
+// WhereContains adds a case-insensitive clause matching values that contain the given text.
+// Wildcard characters in the text are escaped and matched literally.
+func (qb *Builder) WhereContains(column string, value string) types.QueryBuilder {
+	return qb.addLikeWhere(column, types.OpILike, "%"+escapeLike(value)+"%")
+}
+
+func (qb *Builder) addLikeWhere(column string, operator types.Operator, pattern string) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereLikeClause(column, operator, pattern)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// escapeLike escapes the LIKE wildcards and the default backslash escape character.
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// WhereArrayContains adds a WHERE clause matching rows whose array column contains all of the given values.
+func (qb *Builder) WhereArrayContains(column string, values interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereArrayClause(column, types.OpArrayContains, values)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// WhereArrayOverlaps adds a WHERE clause matching rows whose array column shares any element with the given values.
+func (qb *Builder) WhereArrayOverlaps(column string, values interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereArrayClause(column, types.OpArrayOverlaps, values)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
+// WhereAnyValue adds a WHERE clause comparing a value against any element of an array column.
+// Only the comparisons =, <>, !=, <, <=, > and >= are accepted; any other operator makes the statement
+// fail with types.ErrInvalidParameter.
+func (qb *Builder) WhereAnyValue(column string, operator string, value interface{}) types.QueryBuilder {
+	qb = qb.mutable()
+	clause := clauses.NewWhereArrayAnyClause(column, types.Operator(operator), value)
+	clause.SetBoolean(types.And)
+	qb.wheres = append(qb.wheres, clause)
+	return qb
+}
+
 // WhereAny adds a WHERE clause that matches if any of the specified columns meet the criteria.
 func (qb *Builder) WhereAny(columns []string, args ...interface{}) types.QueryBuilder {
 	return qb.addWhereAny(columns, types.And, false, args...)
@@ -309,6 +507,7 @@ func (qb *Builder) OrWhereNone(columns []string, args ...interface{}) types.Quer
 }
 
 func (qb *Builder) addWhereAny(columns []string, boolean types.BooleanOperator, not bool, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(columns) == 0 || len(args) == 0 {
 		return qb
 	}
@@ -340,18 +539,20 @@ func (qb *Builder) addWhereAny(columns []string, boolean types.BooleanOperator,
 		raw = fmt.Sprintf("(%s)", strings.Join(conditions, " OR "))
 	}
 
-	clause := clauses.NewWhereRawClause(raw)
+	bindings := make([]interface{}, len(columns))
+	for i := range bindings {
+		bindings[i] = value
+	}
+
+	clause := clauses.NewWhereRawClause(raw, bindings...)
 	clause.SetBoolean(boolean)
 	qb.wheres = append(qb.wheres, clause)
 
-	for i := 0; i < len(columns); i++ {
-		qb.bindings = append(qb.bindings, value)
-	}
-
 	return qb
 }
 
 func (qb *Builder) addWhereAll(columns []string, boolean types.BooleanOperator, not bool, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	if len(columns) == 0 || len(args) == 0 {
 		return qb
 	}
@@ -383,14 +584,15 @@ func (qb *Builder) addWhereAll(columns []string, boolean types.BooleanOperator,
 		raw = fmt.Sprintf("(%s)", strings.Join(conditions, " AND "))
 	}
 
-	clause := clauses.NewWhereRawClause(raw)
+	bindings := make([]interface{}, len(columns))
+	for i := range bindings {
+		bindings[i] = value
+	}
+
+	clause := clauses.NewWhereRawClause(raw, bindings...)
 	clause.SetBoolean(boolean)
 	qb.wheres = append(qb.wheres, clause)
 
-	for i := 0; i < len(columns); i++ {
-		qb.bindings = append(qb.bindings, value)
-	}
-
 	return qb
 }
 
@@ -405,6 +607,7 @@ func (qb *Builder) OrWhereColumn(first, second string, args ...interface{}) type
 }
 
 func (qb *Builder) addWhereColumn(first, second string, boolean types.BooleanOperator, args ...interface{}) types.QueryBuilder {
+	qb = qb.mutable()
 	var operator types.Operator
 
 	switch len(args) {
@@ -0,0 +1,318 @@
+package querybuildertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	querybuilder "github.com/omarhamdy49/go-query-builder"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestMissingConnectionReturnsError(t *testing.T) {
+	builder := querybuilder.New()
+	builder.AddDB("primary", New(types.MySQL))
+
+	ctx := context.Background()
+	_, err := builder.Connection("analytics").Table("events").Get(ctx)
+	if !errors.Is(err, types.ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "'analytics'") || !strings.Contains(err.Error(), "[primary]") {
+		t.Errorf("Expected the error to name the connection and the available ones, got %v", err)
+	}
+
+	if _, err := builder.Connection("analytics").Table("events").Count(ctx); !errors.Is(err, types.ErrConnectionNotFound) {
+		t.Errorf("Expected Count to fail with ErrConnectionNotFound, got %v", err)
+	}
+	if err := builder.Connection("analytics").Table("events").Insert(ctx, map[string]interface{}{"name": "x"}); !errors.Is(err, types.ErrConnectionNotFound) {
+		t.Errorf("Expected Insert to fail with ErrConnectionNotFound, got %v", err)
+	}
+	if _, err := builder.Connection("analytics").DB(); !errors.Is(err, types.ErrConnectionNotFound) {
+		t.Errorf("Expected DB to fail with ErrConnectionNotFound, got %v", err)
+	}
+}
+
+func TestTableEReportsMissingConnection(t *testing.T) {
+	builder := querybuilder.New()
+	if qb, err := builder.TableE("users"); !errors.Is(err, types.ErrConnectionNotFound) || qb != nil {
+		t.Fatalf("Expected ErrConnectionNotFound and no builder, got %v, %v", qb, err)
+	}
+
+	db := New(types.PostgreSQL)
+	builder.AddDB("primary", db)
+	qb, err := builder.TableE("users")
+	if err != nil {
+		t.Fatalf("TableE failed: %v", err)
+	}
+	if _, err := qb.Where("id", 1).Get(context.Background()); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	db.AssertQueried(t, "users", WithBinding(1))
+}
+
+func TestConnectionSwitchingIsRaceFree(t *testing.T) {
+	builder := querybuilder.New()
+	primary := New(types.MySQL)
+	builder.AddDB("primary", primary)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			builder.AddDB(fmt.Sprintf("replica_%d", i), New(types.PostgreSQL))
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := builder.Table("users").Get(ctx); err != nil {
+				t.Errorf("Default connection query failed: %v", err)
+			}
+			if _, err := builder.On("primary").Table("users").Get(ctx); err != nil {
+				t.Errorf("Named connection query failed: %v", err)
+			}
+			_ = builder.CircuitStates()
+		}()
+	}
+	wg.Wait()
+
+	if n := primary.Count("users"); n != 16 {
+		t.Errorf("Expected every query on the primary, got %d", n)
+	}
+}
+
+func TestOnRunsOnNamedConnection(t *testing.T) {
+	builder := querybuilder.New()
+	primary, analytics := New(types.MySQL), New(types.PostgreSQL)
+	builder.AddDB("primary", primary)
+
+	view := builder.On("analytics")
+	builder.AddDB("analytics", analytics)
+
+	ctx := context.Background()
+	if _, err := view.Table("events").Get(ctx); err != nil {
+		t.Fatalf("Expected the view to see connections added after it, got %v", err)
+	}
+	if _, err := builder.Table("users").Get(ctx); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	analytics.AssertQueried(t, "events")
+	analytics.AssertNotQueried(t, "users")
+	primary.AssertQueried(t, "users")
+	primary.AssertNotQueried(t, "events")
+
+	if db, err := view.DB(); err != nil || db != analytics {
+		t.Errorf("Expected the view's DB to be the analytics connection, got %v (%v)", db, err)
+	}
+}
+
+func TestTransactionIsSticky(t *testing.T) {
+	builder := querybuilder.New()
+	primary, analytics := New(types.MySQL), New(types.MySQL)
+	builder.AddDB("primary", primary)
+	builder.AddDB("analytics", analytics)
+
+	ctx := context.Background()
+	err := builder.On("analytics").Transaction(ctx, func(tx *querybuilder.Builder) error {
+		if err := tx.Table("events").Insert(ctx, map[string]interface{}{"name": "signup"}); err != nil {
+			return err
+		}
+		_, err := tx.Table("events").Get(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if n := analytics.Count("events", InTransaction()); n != 2 {
+		t.Errorf("Expected both statements in the transaction, got %d", n)
+	}
+	analytics.AssertNotQueried(t, "events", func(q Query) bool { return !q.InTx })
+	primary.AssertQueryCount(t, 0)
+	if last := analytics.Queries()[2]; last.SQL != "COMMIT" {
+		t.Errorf("Expected the transaction to commit, got %s", last.SQL)
+	}
+
+	analytics.Reset()
+	failure := errors.New("boom")
+	err = builder.On("analytics").Transaction(ctx, func(tx *querybuilder.Builder) error {
+		if _, err := tx.Table("events").Get(ctx); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the callback error, got %v", err)
+	}
+	if queries := analytics.Queries(); len(queries) != 2 || queries[1].SQL != "ROLLBACK" {
+		t.Errorf("Expected a rollback after the callback error, got %v", queries)
+	}
+
+	analytics.Reset()
+	func() {
+		defer func() {
+			if p := recover(); p != "panic in transaction" {
+				t.Errorf("Expected the panic to propagate, got %v", p)
+			}
+		}()
+		_ = builder.On("analytics").Transaction(ctx, func(tx *querybuilder.Builder) error {
+			panic("panic in transaction")
+		})
+	}()
+	if queries := analytics.Queries(); len(queries) != 1 || queries[0].SQL != "ROLLBACK" {
+		t.Errorf("Expected a rollback after the panic, got %v", queries)
+	}
+}
+
+func TestNestedTransactionUsesSavepoints(t *testing.T) {
+	builder := querybuilder.New()
+	db := New(types.PostgreSQL)
+	builder.AddDB("primary", db)
+
+	ctx := context.Background()
+	failure := errors.New("boom")
+	err := builder.Transaction(ctx, func(tx *querybuilder.Builder) error {
+		if err := tx.Transaction(ctx, func(inner *querybuilder.Builder) error {
+			_, err := inner.Table("users").Get(ctx)
+			return err
+		}); err != nil {
+			return err
+		}
+		if err := tx.Transaction(ctx, func(inner *querybuilder.Builder) error {
+			return failure
+		}); !errors.Is(err, failure) {
+			t.Errorf("Expected the inner callback error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	var statements []string
+	for _, q := range db.Queries() {
+		if !q.InTx {
+			t.Errorf("Expected every statement in the transaction, got %s outside it", q.SQL)
+		}
+		statements = append(statements, q.SQL)
+	}
+	want := []string{
+		"SAVEPOINT qb_savepoint_1", "SELECT * FROM users", "RELEASE SAVEPOINT qb_savepoint_1",
+		"SAVEPOINT qb_savepoint_1", "ROLLBACK TO SAVEPOINT qb_savepoint_1", "COMMIT",
+	}
+	if strings.Join(statements, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, statements)
+	}
+}
+
+func TestCloseUnregistersConnections(t *testing.T) {
+	builder := querybuilder.New()
+	builder.AddDB("primary", New(types.MySQL))
+	if err := builder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if _, err := builder.DB(); !errors.Is(err, types.ErrConnectionNotFound) {
+		t.Fatalf("Expected closed connections to be unregistered, got %v", err)
+	}
+
+	replacement := New(types.PostgreSQL)
+	builder.AddDB("replacement", replacement)
+	if _, err := builder.Table("users").Get(context.Background()); err != nil {
+		t.Fatalf("Expected the new connection to become the default, got %v", err)
+	}
+	replacement.AssertQueried(t, "users")
+}
+
+func TestLazyConnectionDialsOnFirstQuery(t *testing.T) {
+	builder := querybuilder.New()
+	unreachable := &types.Config{Driver: types.MySQL, Host: "127.0.0.1", Port: 1, Database: "app", Username: "app"}
+	if err := builder.AddLazyConnection("primary", unreachable); err != nil {
+		t.Fatalf("AddLazyConnection should not dial, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := builder.Table("users").Get(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "failed to create connection primary") {
+			t.Fatalf("Expected the first query to report the dial error, got %v", err)
+		}
+	}
+
+	fake := New(types.MySQL)
+	builder.AddDB("primary", fake)
+	if _, err := builder.Table("users").Get(context.Background()); err != nil {
+		t.Fatalf("Expected AddDB to replace the pending connection, got %v", err)
+	}
+	fake.AssertQueried(t, "users")
+
+	if err := builder.AddLazyConnection("other", &types.Config{Driver: "oracle"}); err == nil {
+		t.Error("Expected an unsupported driver to be rejected")
+	}
+}
+
+func TestInitAndReset(t *testing.T) {
+	if err := querybuilder.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	t.Cleanup(func() { _ = querybuilder.Reset() })
+
+	config := &types.Config{Driver: types.PostgreSQL, Host: "127.0.0.1", Port: 1, Database: "app", Username: "app"}
+	if err := querybuilder.Init(config); err != nil {
+		t.Fatalf("Init should not dial, got %v", err)
+	}
+	if err := querybuilder.Init(config); !errors.Is(err, querybuilder.ErrAlreadyInitialized) {
+		t.Fatalf("Expected ErrAlreadyInitialized, got %v", err)
+	}
+
+	fake := New(types.PostgreSQL)
+	querybuilder.QB().AddDB("default", fake)
+	if _, err := querybuilder.TableBuilder("users").Get(context.Background()); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	fake.AssertQueried(t, "users")
+
+	if err := querybuilder.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	t.Setenv("DB_DRIVER", "")
+	if err := querybuilder.InitFromEnv(); err == nil || !strings.Contains(err.Error(), "DB_DRIVER") {
+		t.Fatalf("Expected InitFromEnv to return the env error, got %v", err)
+	}
+	if err := querybuilder.Init(config); err != nil {
+		t.Fatalf("Expected Init to succeed after a failed InitFromEnv, got %v", err)
+	}
+}
+
+func TestWithConnectionRoutesStatements(t *testing.T) {
+	builder := querybuilder.New()
+	primary, replica := New(types.MySQL), New(types.MySQL)
+	builder.AddDB("primary", primary)
+	builder.AddDB("replica", replica)
+
+	users := builder.Table("users").Where("active", true)
+	ctx := querybuilder.WithConnection(context.Background(), "replica")
+	if _, err := users.Clone().Get(ctx); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if _, err := users.Clone().Get(context.Background()); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	replica.AssertQueryCount(t, 1)
+	primary.AssertQueryCount(t, 1)
+
+	if _, err := users.Clone().Get(querybuilder.WithConnection(context.Background(), "archive")); !errors.Is(err, types.ErrConnectionNotFound) {
+		t.Errorf("Expected ErrConnectionNotFound, got %v", err)
+	}
+
+	err := builder.Transaction(context.Background(), func(tx *querybuilder.Builder) error {
+		_, err := tx.Table("users").Get(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	replica.AssertQueryCount(t, 1)
+}
@@ -0,0 +1,467 @@
+// Package querybuildertest provides an in-memory FakeDB for unit testing code that uses the query
+// builder, without a live database or hand-written mocks.
+//
+// A FakeDB records every statement it receives and answers queries with canned rows registered
+// with On:
+//
+//	db := querybuildertest.New(types.MySQL)
+//	db.On(querybuildertest.Table("users"), querybuildertest.WhereContains("status")).
+//		Return(map[string]interface{}{"id": 1, "status": "active"})
+//
+//	users, _ := db.Table("users").Where("status", "active").Get(ctx)
+//	db.AssertQueried(t, "users", querybuildertest.WhereContains("status"))
+package querybuildertest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/query"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Query is a statement recorded by a FakeDB.
+type Query struct {
+	SQL       string
+	Bindings  []interface{}
+	Table     string // first table the statement reads from or writes to
+	Operation string // lower-case leading keyword: select, insert, update, delete, commit, ...
+	InTx      bool   // run inside a transaction
+}
+
+// Matcher selects recorded or incoming statements.
+type Matcher func(q Query) bool
+
+// FakeDB is an in-memory types.DB that records statements and returns canned results.
+type FakeDB struct {
+	driver  types.Driver
+	mu      sync.Mutex
+	queries []Query
+	stubs   []*Stub
+}
+
+// New creates an empty FakeDB that compiles SQL for driver.
+func New(driver types.Driver) *FakeDB {
+	return &FakeDB{driver: driver}
+}
+
+// Table returns a query builder for table that runs against the FakeDB.
+func (db *FakeDB) Table(table string) types.QueryBuilder {
+	return query.Table(db, db.driver, table)
+}
+
+// Stub is a canned response for statements matching all of its matchers.
+type Stub struct {
+	matchers []Matcher
+	rows     []map[string]interface{}
+	affected int64
+	insertID int64
+	err      error
+}
+
+// On registers a response for statements matching every matcher. When several stubs match, the
+// most recently registered one wins, so specific stubs can be layered over general ones.
+func (db *FakeDB) On(matchers ...Matcher) *Stub {
+	stub := &Stub{matchers: matchers}
+	db.mu.Lock()
+	db.stubs = append(db.stubs, stub)
+	db.mu.Unlock()
+	return stub
+}
+
+// Return makes matching queries return rows.
+func (s *Stub) Return(rows ...map[string]interface{}) *Stub {
+	s.rows = rows
+	return s
+}
+
+// ReturnValue makes matching queries return a single value, as for Count or Sum.
+func (s *Stub) ReturnValue(value interface{}) *Stub {
+	s.rows = []map[string]interface{}{{"aggregate": value}}
+	return s
+}
+
+// ReturnAffected makes matching writes report n affected rows.
+func (s *Stub) ReturnAffected(n int64) *Stub {
+	s.affected = n
+	return s
+}
+
+// ReturnInsertID makes matching writes report id as the last insert ID.
+func (s *Stub) ReturnInsertID(id int64) *Stub {
+	s.insertID = id
+	return s
+}
+
+// ReturnError makes matching statements fail with err.
+func (s *Stub) ReturnError(err error) *Stub {
+	s.err = err
+	return s
+}
+
+func (s *Stub) matches(q Query) bool {
+	for _, matcher := range s.matchers {
+		if !matcher(q) {
+			return false
+		}
+	}
+	return true
+}
+
+// Queries returns every statement recorded so far.
+func (db *FakeDB) Queries() []Query {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]Query(nil), db.queries...)
+}
+
+// Reset forgets recorded statements and stubs.
+func (db *FakeDB) Reset() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = nil
+	db.stubs = nil
+}
+
+// record stores a statement and returns the stub answering it, if any.
+func (db *FakeDB) record(sqlText string, args []interface{}, inTx bool) (Query, *Stub) {
+	q := Query{SQL: sqlText, Bindings: args, Table: tableOf(sqlText), Operation: operationOf(sqlText), InTx: inTx}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, q)
+	for i := len(db.stubs) - 1; i >= 0; i-- {
+		if db.stubs[i].matches(q) {
+			return q, db.stubs[i]
+		}
+	}
+	return q, nil
+}
+
+func (db *FakeDB) query(sqlText string, args []interface{}, inTx bool) (types.Rows, error) {
+	_, stub := db.record(sqlText, args, inTx)
+	if stub == nil {
+		return newFakeRows(nil), nil
+	}
+	if stub.err != nil {
+		return nil, stub.err
+	}
+	return newFakeRows(stub.rows), nil
+}
+
+func (db *FakeDB) queryRow(sqlText string, args []interface{}, inTx bool) types.Row {
+	rows, err := db.query(sqlText, args, inTx)
+	if err != nil {
+		return fakeRow{err: err}
+	}
+	if !rows.Next() {
+		return fakeRow{err: sql.ErrNoRows}
+	}
+	return fakeRow{rows: rows}
+}
+
+func (db *FakeDB) exec(sqlText string, args []interface{}, inTx bool) (types.Result, error) {
+	_, stub := db.record(sqlText, args, inTx)
+	if stub == nil {
+		return fakeResult{}, nil
+	}
+	if stub.err != nil {
+		return nil, stub.err
+	}
+	return fakeResult{affected: stub.affected, insertID: stub.insertID}, nil
+}
+
+// QueryContext implements types.QueryExecutor.
+func (db *FakeDB) QueryContext(_ context.Context, sqlText string, args ...interface{}) (types.Rows, error) {
+	return db.query(sqlText, args, false)
+}
+
+// QueryRowContext implements types.QueryExecutor.
+func (db *FakeDB) QueryRowContext(_ context.Context, sqlText string, args ...interface{}) types.Row {
+	return db.queryRow(sqlText, args, false)
+}
+
+// ExecContext implements types.QueryExecutor.
+func (db *FakeDB) ExecContext(_ context.Context, sqlText string, args ...interface{}) (types.Result, error) {
+	return db.exec(sqlText, args, false)
+}
+
+// Begin implements types.QueryExecutor.
+func (db *FakeDB) Begin() (types.Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx implements types.QueryExecutor. Statements in the transaction are recorded with InTx set,
+// and Commit and Rollback are recorded as "COMMIT" and "ROLLBACK".
+func (db *FakeDB) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx, error) {
+	return &fakeTx{db: db}, nil
+}
+
+// Driver implements types.DB.
+func (db *FakeDB) Driver() types.Driver { return db.driver }
+
+// Close implements types.DB.
+func (db *FakeDB) Close() error { return nil }
+
+// Ping implements types.DB.
+func (db *FakeDB) Ping() error { return nil }
+
+// Stats implements types.DB.
+func (db *FakeDB) Stats() types.DBStats { return types.DBStats{} }
+
+type fakeTx struct {
+	db *FakeDB
+}
+
+func (tx *fakeTx) QueryContext(_ context.Context, sqlText string, args ...interface{}) (types.Rows, error) {
+	return tx.db.query(sqlText, args, true)
+}
+
+func (tx *fakeTx) QueryRowContext(_ context.Context, sqlText string, args ...interface{}) types.Row {
+	return tx.db.queryRow(sqlText, args, true)
+}
+
+func (tx *fakeTx) ExecContext(_ context.Context, sqlText string, args ...interface{}) (types.Result, error) {
+	return tx.db.exec(sqlText, args, true)
+}
+
+func (tx *fakeTx) Begin() (types.Tx, error) {
+	return nil, fmt.Errorf("cannot start a transaction within a transaction")
+}
+
+func (tx *fakeTx) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx, error) {
+	return nil, fmt.Errorf("cannot start a transaction within a transaction")
+}
+
+func (tx *fakeTx) Commit() error {
+	_, err := tx.db.exec("COMMIT", nil, true)
+	return err
+}
+
+func (tx *fakeTx) Rollback() error {
+	_, err := tx.db.exec("ROLLBACK", nil, true)
+	return err
+}
+
+func (tx *fakeTx) Driver() types.Driver { return tx.db.driver }
+
+type fakeResult struct {
+	affected int64
+	insertID int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.insertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+// fakeRows serves canned rows. Columns are the sorted union of the rows' keys.
+type fakeRows struct {
+	columns []string
+	data    []map[string]interface{}
+	pos     int
+}
+
+func newFakeRows(data []map[string]interface{}) *fakeRows {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range data {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return &fakeRows{columns: columns, data: data}
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.data)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	if r.pos < 1 || r.pos > len(r.data) {
+		return fmt.Errorf("scan called without a current row")
+	}
+	if len(dest) > len(r.columns) {
+		return fmt.Errorf("expected at most %d destinations, got %d", len(r.columns), len(dest))
+	}
+
+	row := r.data[r.pos-1]
+	for i, target := range dest {
+		if err := assign(target, row[r.columns[i]]); err != nil {
+			return fmt.Errorf("column %s: %w", r.columns[i], err)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRows) Close() error               { return nil }
+func (r *fakeRows) Columns() ([]string, error) { return r.columns, nil }
+func (r *fakeRows) Err() error                 { return nil }
+
+type fakeRow struct {
+	rows types.Rows
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Scan(dest...)
+}
+
+// assign stores value in the pointer target, converting between compatible types.
+func assign(target interface{}, value interface{}) error {
+	if ptr, ok := target.(*interface{}); ok {
+		*ptr = value
+		return nil
+	}
+
+	dest := reflect.ValueOf(target)
+	if dest.Kind() != reflect.Pointer || dest.IsNil() {
+		return fmt.Errorf("destination must be a non-nil pointer, got %T", target)
+	}
+	elem := dest.Elem()
+	if value == nil {
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+	switch {
+	case src.Type().AssignableTo(elem.Type()):
+		elem.Set(src)
+	case src.Type().ConvertibleTo(elem.Type()):
+		elem.Set(src.Convert(elem.Type()))
+	default:
+		return fmt.Errorf("cannot assign %T to %s", value, elem.Type())
+	}
+	return nil
+}
+
+var tablePattern = regexp.MustCompile("(?i)\\b(?:from|into|update|join)\\s+([^\\s(,;]+)")
+
+func tableOf(sqlText string) string {
+	match := tablePattern.FindStringSubmatch(sqlText)
+	if match == nil {
+		return ""
+	}
+	return strings.Trim(match[1], "`\"")
+}
+
+func operationOf(sqlText string) string {
+	text := strings.TrimSpace(sqlText)
+	for strings.HasPrefix(text, "/*") {
+		end := strings.Index(text, "*/")
+		if end < 0 {
+			return ""
+		}
+		text = strings.TrimSpace(text[end+2:])
+	}
+	if fields := strings.Fields(text); len(fields) > 0 {
+		return strings.ToLower(fields[0])
+	}
+	return ""
+}
+
+// Table matches statements on table.
+func Table(name string) Matcher {
+	return func(q Query) bool { return q.Table == name }
+}
+
+// Operation matches statements starting with the keyword op (select, insert, update, delete, ...).
+func Operation(op string) Matcher {
+	op = strings.ToLower(op)
+	return func(q Query) bool { return q.Operation == op }
+}
+
+// SQLContains matches statements whose SQL contains fragment, ignoring case.
+func SQLContains(fragment string) Matcher {
+	fragment = strings.ToLower(fragment)
+	return func(q Query) bool { return strings.Contains(strings.ToLower(q.SQL), fragment) }
+}
+
+// WhereContains matches statements whose WHERE clause contains fragment, ignoring case.
+func WhereContains(fragment string) Matcher {
+	fragment = strings.ToLower(fragment)
+	return func(q Query) bool {
+		lower := strings.ToLower(q.SQL)
+		where := strings.Index(lower, " where ")
+		return where >= 0 && strings.Contains(lower[where:], fragment)
+	}
+}
+
+// WithBinding matches statements bound to value.
+func WithBinding(value interface{}) Matcher {
+	return func(q Query) bool {
+		for _, binding := range q.Bindings {
+			if reflect.DeepEqual(binding, value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// InTransaction matches statements run inside a transaction.
+func InTransaction() Matcher {
+	return func(q Query) bool { return q.InTx }
+}
+
+// Count returns the number of recorded statements on table matching every matcher.
+func (db *FakeDB) Count(table string, matchers ...Matcher) int {
+	matchers = append([]Matcher{Table(table)}, matchers...)
+	count := 0
+	for _, q := range db.Queries() {
+		if (&Stub{matchers: matchers}).matches(q) {
+			count++
+		}
+	}
+	return count
+}
+
+// AssertQueried fails the test unless a statement on table matching every matcher was recorded.
+func (db *FakeDB) AssertQueried(t testing.TB, table string, matchers ...Matcher) {
+	t.Helper()
+	if db.Count(table, matchers...) == 0 {
+		t.Errorf("Expected a matching statement on %s, got:\n%s", table, db.describe())
+	}
+}
+
+// AssertNotQueried fails the test if a statement on table matching every matcher was recorded.
+func (db *FakeDB) AssertNotQueried(t testing.TB, table string, matchers ...Matcher) {
+	t.Helper()
+	if n := db.Count(table, matchers...); n > 0 {
+		t.Errorf("Expected no matching statement on %s, got %d:\n%s", table, n, db.describe())
+	}
+}
+
+// AssertQueryCount fails the test unless exactly n statements were recorded.
+func (db *FakeDB) AssertQueryCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(db.Queries()); got != n {
+		t.Errorf("Expected %d statements, got %d:\n%s", n, got, db.describe())
+	}
+}
+
+func (db *FakeDB) describe() string {
+	queries := db.Queries()
+	if len(queries) == 0 {
+		return "  (no statements)"
+	}
+	lines := make([]string, len(queries))
+	for i, q := range queries {
+		lines[i] = fmt.Sprintf("  %s %v", q.SQL, q.Bindings)
+	}
+	return strings.Join(lines, "\n")
+}
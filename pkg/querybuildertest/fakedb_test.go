@@ -0,0 +1,108 @@
+package querybuildertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestFakeDBReturnsStubbedRows(t *testing.T) {
+	db := New(types.MySQL)
+	db.On(Table("users"), WhereContains("status")).Return(
+		map[string]interface{}{"id": int64(1), "status": "active"},
+		map[string]interface{}{"id": int64(2), "status": "active"},
+	)
+
+	users, err := db.Table("users").Where("status", "active").Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if users.Count() != 2 {
+		t.Fatalf("Expected 2 users, got %d", users.Count())
+	}
+
+	db.AssertQueried(t, "users", Operation("select"), WhereContains("status"), WithBinding("active"))
+	db.AssertNotQueried(t, "orders")
+	db.AssertQueryCount(t, 1)
+}
+
+func TestFakeDBUnmatchedQueriesReturnNothing(t *testing.T) {
+	db := New(types.PostgreSQL)
+
+	users, err := db.Table("users").Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if users.Count() != 0 {
+		t.Errorf("Expected no users, got %d", users.Count())
+	}
+	if _, err := db.Table("users").First(context.Background()); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFakeDBAggregatesAndErrors(t *testing.T) {
+	db := New(types.MySQL)
+	db.On(Table("users"), SQLContains("count(")).ReturnValue(int64(42))
+	failure := errors.New("boom")
+	db.On(Table("orders")).ReturnError(failure)
+
+	count, err := db.Table("users").Count(context.Background())
+	if err != nil || count != 42 {
+		t.Errorf("Expected count 42, got %d (%v)", count, err)
+	}
+	if _, err := db.Table("orders").Get(context.Background()); !errors.Is(err, failure) {
+		t.Errorf("Expected stubbed error, got %v", err)
+	}
+}
+
+func TestFakeDBLaterStubsWin(t *testing.T) {
+	db := New(types.MySQL)
+	db.On(Table("users")).Return(map[string]interface{}{"id": int64(1)})
+	db.On(Table("users"), WhereContains("id")).Return(map[string]interface{}{"id": int64(7)})
+
+	user, err := db.Table("users").Where("id", 7).First(context.Background())
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if user["id"] != int64(7) {
+		t.Errorf("Expected the more specific stub, got %v", user["id"])
+	}
+}
+
+func TestFakeDBRecordsWritesAndTransactions(t *testing.T) {
+	db := New(types.MySQL)
+	db.On(Operation("update"), Table("users")).ReturnAffected(3)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	result, err := tx.ExecContext(context.Background(), "UPDATE users SET active = ? WHERE id > ?", true, 10)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n != 3 {
+		t.Errorf("Expected 3 affected rows, got %d", n)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	db.AssertQueried(t, "users", Operation("update"), InTransaction(), WithBinding(10))
+	queries := db.Queries()
+	if last := queries[len(queries)-1]; last.Operation != "commit" {
+		t.Errorf("Expected COMMIT to be recorded last, got %q", last.SQL)
+	}
+}
+
+func TestAssertQueriedReportsFailures(t *testing.T) {
+	db := New(types.MySQL)
+	spy := &testing.T{}
+	db.AssertQueried(spy, "users")
+	if !spy.Failed() {
+		t.Error("Expected AssertQueried to fail when nothing was queried")
+	}
+}
@@ -0,0 +1,124 @@
+package querybuildertest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/query"
+	"github.com/omarhamdy49/go-query-builder/pkg/sqlformat"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// UpdateGoldenEnv is the environment variable that makes the golden assertions rewrite their files
+// instead of comparing against them: QB_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "QB_UPDATE_GOLDEN"
+
+// GoldenDir is the directory golden files are read from and written to, relative to the package
+// under test.
+var GoldenDir = filepath.Join("testdata", "golden")
+
+// Snapshot renders qb as canonical, formatted SQL followed by its bindings in order, one per line.
+func Snapshot(qb types.QueryBuilder) (string, error) {
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(sqlformat.Format(sql))
+	b.WriteString("\n-- bindings\n")
+	for i, binding := range bindings {
+		switch v := binding.(type) {
+		case string:
+			fmt.Fprintf(&b, "%d: %q\n", i+1, v)
+		case nil:
+			fmt.Fprintf(&b, "%d: NULL\n", i+1)
+		default:
+			fmt.Fprintf(&b, "%d: %v (%T)\n", i+1, v, v)
+		}
+	}
+	return b.String(), nil
+}
+
+// AssertGolden compares the snapshot of qb with the golden file name. The file is created or
+// rewritten when UpdateGoldenEnv is set.
+func AssertGolden(t testing.TB, name string, qb types.QueryBuilder) {
+	t.Helper()
+	got, err := Snapshot(qb)
+	if err != nil {
+		t.Fatalf("Failed to compile %s: %v", name, err)
+	}
+	compareGolden(t, name, got)
+}
+
+// AssertGoldenDrivers calls build with an empty builder for every driver (MySQL and PostgreSQL by
+// default) and compares the snapshots, one section per driver, with the golden file name.
+func AssertGoldenDrivers(t testing.TB, name string, build func(qb types.QueryBuilder) types.QueryBuilder, drivers ...types.Driver) {
+	t.Helper()
+	if len(drivers) == 0 {
+		drivers = []types.Driver{types.MySQL, types.PostgreSQL}
+	}
+
+	var b strings.Builder
+	for i, driver := range drivers {
+		got, err := Snapshot(build(query.NewBuilder(nil, driver)))
+		if err != nil {
+			t.Fatalf("Failed to compile %s for %s: %v", name, driver, err)
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "-- %s\n%s", driver, got)
+	}
+	compareGolden(t, name, b.String())
+}
+
+func compareGolden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join(GoldenDir, name+".golden")
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if diff := lineDiff(string(want), got); diff != "" {
+		t.Errorf("Query does not match %s (run with %s=1 to update):\n%s", path, UpdateGoldenEnv, diff)
+	}
+}
+
+// lineDiff reports the lines that differ between want and got, or "" when they are equal.
+func lineDiff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&b, "line %d:\n  - %s\n  + %s\n", i+1, w, g)
+		}
+	}
+	return b.String()
+}
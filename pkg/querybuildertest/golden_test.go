@@ -0,0 +1,51 @@
+package querybuildertest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func activeUsers(qb types.QueryBuilder) types.QueryBuilder {
+	return qb.From("users").Select("id", "name").
+		Where("status", "active").
+		Where("age", ">", 18).
+		OrderBy("name", "asc").
+		Limit(10)
+}
+
+func TestAssertGoldenDrivers(t *testing.T) {
+	AssertGoldenDrivers(t, "active_users", activeUsers)
+}
+
+func TestSnapshotListsBindingsInOrder(t *testing.T) {
+	got, err := Snapshot(New(types.PostgreSQL).Table("users").Where("status", "active").Where("age", ">", 18))
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if !strings.HasSuffix(got, "-- bindings\n1: \"active\"\n2: 18 (int)\n") {
+		t.Errorf("Unexpected snapshot:\n%s", got)
+	}
+}
+
+func TestAssertGoldenUpdatesAndDetectsChanges(t *testing.T) {
+	dir := GoldenDir
+	GoldenDir = t.TempDir()
+	defer func() { GoldenDir = dir }()
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, "users", New(types.MySQL).Table("users").Where("id", 1))
+	if _, err := os.Stat(filepath.Join(GoldenDir, "users.golden")); err != nil {
+		t.Fatalf("Expected golden file to be written: %v", err)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	spy := &testing.T{}
+	AssertGolden(spy, "users", New(types.MySQL).Table("users").Where("id", 2))
+	if !spy.Failed() {
+		t.Error("Expected a changed binding to fail the golden comparison")
+	}
+}
@@ -0,0 +1,144 @@
+package querybuildertest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/database"
+	"github.com/omarhamdy49/go-query-builder/pkg/query"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// SQLDB adapts a *sql.DB to types.DB, so the builder can run against any database/sql handle, in
+// particular the one returned by go-sqlmock:
+//
+//	mockDB, mock, _ := sqlmock.New()
+//	db := querybuildertest.WrapDB(mockDB, types.MySQL)
+//	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+//	users, err := db.Table("users").Get(ctx)
+//
+// Errors are classified like those of a real connection, so errors.Is(err, types.ErrNotFound) and
+// friends behave the same in tests.
+type SQLDB struct {
+	db     *sql.DB
+	driver types.Driver
+}
+
+// WrapDB wraps db, compiling SQL for driver.
+func WrapDB(db *sql.DB, driver types.Driver) *SQLDB {
+	return &SQLDB{db: db, driver: driver}
+}
+
+// Table returns a query builder for table that runs against the wrapped handle.
+func (s *SQLDB) Table(table string) types.QueryBuilder {
+	return query.Table(s, s.driver, table)
+}
+
+// QueryContext implements types.QueryExecutor.
+func (s *SQLDB) QueryContext(ctx context.Context, sqlText string, args ...interface{}) (types.Rows, error) {
+	rows, err := s.db.QueryContext(ctx, sqlText, args...)
+	if err != nil {
+		return nil, database.ClassifyError(err)
+	}
+	return rows, nil
+}
+
+// QueryRowContext implements types.QueryExecutor.
+func (s *SQLDB) QueryRowContext(ctx context.Context, sqlText string, args ...interface{}) types.Row {
+	return sqlRow{row: s.db.QueryRowContext(ctx, sqlText, args...)}
+}
+
+// ExecContext implements types.QueryExecutor.
+func (s *SQLDB) ExecContext(ctx context.Context, sqlText string, args ...interface{}) (types.Result, error) {
+	result, err := s.db.ExecContext(ctx, sqlText, args...)
+	if err != nil {
+		return nil, database.ClassifyError(err)
+	}
+	return result, nil
+}
+
+// Begin implements types.QueryExecutor.
+func (s *SQLDB) Begin() (types.Tx, error) {
+	return s.BeginTx(context.Background(), nil)
+}
+
+// BeginTx implements types.QueryExecutor.
+func (s *SQLDB) BeginTx(ctx context.Context, opts *types.TxOptions) (types.Tx, error) {
+	sqlOpts := &sql.TxOptions{}
+	if opts != nil {
+		sqlOpts.Isolation = sql.IsolationLevel(opts.Isolation)
+		sqlOpts.ReadOnly = opts.ReadOnly
+	}
+
+	tx, err := s.db.BeginTx(ctx, sqlOpts)
+	if err != nil {
+		return nil, database.ClassifyError(err)
+	}
+	return &sqlTx{tx: tx, driver: s.driver}, nil
+}
+
+// Driver implements types.DB.
+func (s *SQLDB) Driver() types.Driver { return s.driver }
+
+// Close implements types.DB.
+func (s *SQLDB) Close() error { return s.db.Close() }
+
+// Ping implements types.DB.
+func (s *SQLDB) Ping() error { return s.db.Ping() }
+
+// Stats implements types.DB.
+func (s *SQLDB) Stats() types.DBStats {
+	stats := s.db.Stats()
+	return types.DBStats{
+		OpenConnections: stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+	}
+}
+
+type sqlTx struct {
+	tx     *sql.Tx
+	driver types.Driver
+}
+
+func (t *sqlTx) QueryContext(ctx context.Context, sqlText string, args ...interface{}) (types.Rows, error) {
+	rows, err := t.tx.QueryContext(ctx, sqlText, args...)
+	if err != nil {
+		return nil, database.ClassifyError(err)
+	}
+	return rows, nil
+}
+
+func (t *sqlTx) QueryRowContext(ctx context.Context, sqlText string, args ...interface{}) types.Row {
+	return sqlRow{row: t.tx.QueryRowContext(ctx, sqlText, args...)}
+}
+
+func (t *sqlTx) ExecContext(ctx context.Context, sqlText string, args ...interface{}) (types.Result, error) {
+	result, err := t.tx.ExecContext(ctx, sqlText, args...)
+	if err != nil {
+		return nil, database.ClassifyError(err)
+	}
+	return result, nil
+}
+
+func (t *sqlTx) Begin() (types.Tx, error) {
+	return nil, fmt.Errorf("cannot start a transaction within a transaction")
+}
+
+func (t *sqlTx) BeginTx(_ context.Context, _ *types.TxOptions) (types.Tx, error) {
+	return nil, fmt.Errorf("cannot start a transaction within a transaction")
+}
+
+func (t *sqlTx) Commit() error   { return database.ClassifyError(t.tx.Commit()) }
+func (t *sqlTx) Rollback() error { return t.tx.Rollback() }
+
+func (t *sqlTx) Driver() types.Driver { return t.driver }
+
+type sqlRow struct {
+	row *sql.Row
+}
+
+func (r sqlRow) Scan(dest ...interface{}) error {
+	return database.ClassifyError(r.row.Scan(dest...))
+}
@@ -0,0 +1,163 @@
+package querybuildertest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// stubDriver is a minimal database/sql driver standing in for go-sqlmock: every query returns
+// the same rows and every statement is recorded.
+type stubDriver struct {
+	columns    []string
+	types      []string
+	rows       [][]driver.Value
+	statements []string
+}
+
+func (d *stubDriver) Open(string) (driver.Conn, error) { return stubConn{d}, nil }
+
+type stubConn struct{ d *stubDriver }
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt{c.d, query}, nil }
+func (c stubConn) Close() error                              { return nil }
+func (c stubConn) Begin() (driver.Tx, error)                 { return stubTx{c.d}, nil }
+
+type stubTx struct{ d *stubDriver }
+
+func (t stubTx) Commit() error   { t.d.statements = append(t.d.statements, "COMMIT"); return nil }
+func (t stubTx) Rollback() error { t.d.statements = append(t.d.statements, "ROLLBACK"); return nil }
+
+type stubStmt struct {
+	d     *stubDriver
+	query string
+}
+
+func (s stubStmt) Close() error  { return nil }
+func (s stubStmt) NumInput() int { return -1 }
+
+func (s stubStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.statements = append(s.d.statements, s.query)
+	return driver.RowsAffected(2), nil
+}
+
+func (s stubStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.statements = append(s.d.statements, s.query)
+	return &stubRows{columns: s.d.columns, types: s.d.types, rows: s.d.rows}, nil
+}
+
+type stubRows struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *stubRows) Columns() []string { return r.columns }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(r.types) {
+		return r.types[index]
+	}
+	return ""
+}
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+func openStub(t *testing.T, d *stubDriver) *SQLDB {
+	t.Helper()
+	sql.Register(t.Name(), d)
+	db, err := sql.Open(t.Name(), "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return WrapDB(db, types.MySQL)
+}
+
+func TestSQLDBRunsBuilderQueries(t *testing.T) {
+	stub := &stubDriver{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), []byte("Ada")}}}
+	db := openStub(t, stub)
+
+	users, err := db.Table("users").Where("id", 1).Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if users.Count() != 1 || users.First()["name"] != "Ada" {
+		t.Errorf("Unexpected result: %v", users.ToSlice())
+	}
+
+	affected, err := db.Table("users").Where("id", 1).Update(context.Background(), map[string]interface{}{"name": "Grace"})
+	if err != nil || affected != 2 {
+		t.Errorf("Expected 2 affected rows, got %d (%v)", affected, err)
+	}
+	if len(stub.statements) != 2 {
+		t.Errorf("Expected 2 statements, got %v", stub.statements)
+	}
+}
+
+func TestSQLDBKeepsBinaryColumns(t *testing.T) {
+	stub := &stubDriver{
+		columns: []string{"name", "avatar"},
+		types:   []string{"VARCHAR", "BLOB"},
+		rows:    [][]driver.Value{{[]byte("Ada"), []byte{0xff, 0xd8}}, {[]byte("Grace"), nil}},
+	}
+	db := openStub(t, stub)
+
+	users, err := db.Table("users").Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	first := users.First()
+	if first["name"] != "Ada" {
+		t.Errorf("Expected text as a string, got %#v", first["name"])
+	}
+	if avatar, ok := first["avatar"].([]byte); !ok || len(avatar) != 2 {
+		t.Errorf("Expected the binary column as []byte, got %#v", first["avatar"])
+	}
+	if last := users.ToSlice()[1]; last["name"] != "Grace" || last["avatar"] != nil {
+		t.Errorf("Expected the buffers to be reset between rows, got %v", last)
+	}
+}
+
+func TestSQLDBClassifiesNoRows(t *testing.T) {
+	db := openStub(t, &stubDriver{columns: []string{"id"}})
+
+	var id int64
+	err := db.QueryRowContext(context.Background(), "SELECT id FROM users").Scan(&id)
+	if !errors.Is(err, types.ErrNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected a not-found error wrapping sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestSQLDBTransactions(t *testing.T) {
+	stub := &stubDriver{}
+	db := openStub(t, stub)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(context.Background(), "DELETE FROM users"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if len(stub.statements) != 2 || stub.statements[1] != "COMMIT" {
+		t.Errorf("Unexpected statements: %v", stub.statements)
+	}
+}
@@ -0,0 +1,37 @@
+package querybuildertest
+
+import (
+	"context"
+	"testing"
+
+	querybuilder "github.com/omarhamdy49/go-query-builder"
+)
+
+// WithTestTransaction runs fn with a view of the singleton builder whose queries all run in one
+// transaction, rolled back when the test ends. Transactions fn starts on that view become savepoints,
+// so code under test can commit and roll back as usual against a shared database.
+func WithTestTransaction(t testing.TB, fn func(qb *querybuilder.Builder)) {
+	t.Helper()
+	WithTestTransactionOn(t, querybuilder.QB(), fn)
+}
+
+// WithTestTransactionOn is WithTestTransaction for the connection of builder.
+func WithTestTransactionOn(t testing.TB, builder *querybuilder.Builder, fn func(qb *querybuilder.Builder)) {
+	t.Helper()
+
+	conn, err := builder.DB()
+	if err != nil {
+		t.Fatalf("Failed to start test transaction: %v", err)
+	}
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to start test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := tx.Rollback(); err != nil {
+			t.Errorf("Failed to roll back test transaction: %v", err)
+		}
+	})
+
+	fn(builder.WithTx(tx))
+}
@@ -0,0 +1,80 @@
+package querybuildertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	querybuilder "github.com/omarhamdy49/go-query-builder"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestWithTestTransactionRollsBackAndUsesSavepoints(t *testing.T) {
+	db := New(types.MySQL)
+	builder := querybuilder.New()
+	builder.AddDB("default", db)
+
+	t.Run("inner", func(t *testing.T) {
+		WithTestTransactionOn(t, builder, func(qb *querybuilder.Builder) {
+			ctx := context.Background()
+			if err := qb.Table("users").Insert(ctx, map[string]interface{}{"name": "Ada"}); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+
+			failure := errors.New("boom")
+			err := qb.Transaction(ctx, func(tx *querybuilder.Builder) error {
+				_, err := tx.Table("users").Where("id", 1).Delete(ctx)
+				if err != nil {
+					return err
+				}
+				return failure
+			})
+			if !errors.Is(err, failure) {
+				t.Fatalf("Expected the inner error, got %v", err)
+			}
+		})
+	})
+
+	var statements []string
+	for _, q := range db.Queries() {
+		if !q.InTx {
+			t.Errorf("Expected %q to run in the test transaction", q.SQL)
+		}
+		statements = append(statements, q.Operation)
+	}
+	want := []string{"insert", "savepoint", "delete", "rollback", "rollback"}
+	if len(statements) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, statements)
+	}
+	for i := range want {
+		if statements[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, statements)
+		}
+	}
+	db.AssertQueried(t, "", SQLContains("ROLLBACK TO SAVEPOINT qb_savepoint_1"))
+	db.AssertNotQueried(t, "", Operation("commit"))
+}
+
+func TestTransactionWithOptionsRetriesDeadlocks(t *testing.T) {
+	db := New(types.PostgreSQL)
+	db.On(Operation("update")).ReturnError(&types.DBError{Kind: types.ErrDeadlock, Err: errors.New("deadlock detected")})
+	builder := querybuilder.New()
+	builder.AddDB("default", db)
+
+	attempts := 0
+	err := builder.TransactionWithOptions(context.Background(), &types.TxOptions{Isolation: types.Serializable, MaxRetries: 2},
+		func(tx *querybuilder.Builder) error {
+			attempts++
+			_, err := tx.Table("accounts").Where("id", 1).Update(context.Background(), map[string]interface{}{"balance": 0})
+			return err
+		})
+	if !errors.Is(err, types.ErrDeadlock) {
+		t.Fatalf("Expected ErrDeadlock after retries, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if n := db.Count("", Operation("rollback")); n != 3 {
+		t.Errorf("Expected every attempt to roll back, got %d rollbacks", n)
+	}
+}
@@ -0,0 +1,176 @@
+// Package ratelimit provides token-bucket rate limiting for query execution.
+//
+// A Limiter is registered as a query hook (see querybuilder.AddHook). Before each statement it
+// takes a token from the caller's bucket for the statement's table, if that table has its own
+// limit, and from the caller's global bucket, if a global limit is set. When either bucket is empty
+// the statement fails with types.ErrRateLimited and never reaches the database. The caller is read
+// from the context with WithCaller; queries without a caller share one anonymous bucket.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// Limit is a token-bucket rate: Rate tokens are added per second, up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerSecond returns a limit of n queries per second with a burst of n.
+func PerSecond(n int) Limit {
+	return Limit{Rate: float64(n), Burst: n}
+}
+
+// PerMinute returns a limit of n queries per minute with a burst of n.
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context whose queries are counted against caller's buckets.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller set with WithCaller, or "" when none is set.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+type bucketKey struct {
+	caller string
+	table  string // "" for the global bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter enforces per-caller token-bucket limits, globally and per table.
+type Limiter struct {
+	mu        sync.Mutex
+	global    *Limit
+	tables    map[string]Limit
+	buckets   map[bucketKey]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// New creates a Limiter applying global to every query of each caller. Use NoGlobalLimit with
+// SetTableLimit to only limit specific tables.
+func New(global Limit) *Limiter {
+	l := newLimiter()
+	l.global = &global
+	return l
+}
+
+// NoGlobalLimit creates a Limiter that only enforces the limits set with SetTableLimit.
+func NoGlobalLimit() *Limiter {
+	return newLimiter()
+}
+
+func newLimiter() *Limiter {
+	return &Limiter{
+		tables:  make(map[string]Limit),
+		buckets: make(map[bucketKey]*bucket),
+		now:     time.Now,
+	}
+}
+
+// SetTableLimit sets the per-caller limit for queries on table, in addition to the global limit.
+func (l *Limiter) SetTableLimit(table string, limit Limit) *Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tables[table] = limit
+	for key := range l.buckets {
+		if key.table == table {
+			delete(l.buckets, key)
+		}
+	}
+	return l
+}
+
+// Allow reports whether caller may run a query on table now, taking a token from each bucket
+// that applies. No token is taken unless every bucket has one.
+func (l *Limiter) Allow(caller string, table string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	var buckets []*bucket
+	if limit, ok := l.tables[table]; ok && table != "" {
+		buckets = append(buckets, l.bucket(bucketKey{caller: caller, table: table}, limit, now))
+	}
+	if l.global != nil {
+		buckets = append(buckets, l.bucket(bucketKey{caller: caller}, *l.global, now))
+	}
+
+	for _, b := range buckets {
+		if b.tokens < 1 {
+			return false
+		}
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true
+}
+
+// BeforeQuery implements types.QueryHook, rejecting queries over the limit with ErrRateLimited.
+func (l *Limiter) BeforeQuery(ctx context.Context, event types.QueryEvent) error {
+	caller := CallerFromContext(ctx)
+	if l.Allow(caller, event.Table) {
+		return nil
+	}
+	return fmt.Errorf("%w: caller %q on table %q", types.ErrRateLimited, caller, event.Table)
+}
+
+// bucket returns the refilled bucket for key, creating a full one on first use.
+func (l *Limiter) bucket(key bucketKey, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * limit.Rate
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.last = now
+	return b
+}
+
+// prune drops buckets that have been idle long enough to refill, so callers that stop sending
+// queries do not accumulate. It runs at most once a minute.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		limit, ok := l.tables[key.table]
+		if key.table == "" && l.global != nil {
+			limit, ok = *l.global, true
+		}
+		if !ok || limit.Rate <= 0 {
+			continue
+		}
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestLimiterRefillsPerCaller(t *testing.T) {
+	now := time.Now()
+	limiter := New(Limit{Rate: 1, Burst: 2})
+	limiter.now = func() time.Time { return now }
+
+	if !limiter.Allow("alice", "users") || !limiter.Allow("alice", "users") {
+		t.Fatal("Expected the burst of 2 to be allowed")
+	}
+	if limiter.Allow("alice", "orders") {
+		t.Error("Expected alice to be limited once the burst is used")
+	}
+	if !limiter.Allow("bob", "users") {
+		t.Error("Expected bob to have a separate bucket")
+	}
+
+	now = now.Add(time.Second)
+	if !limiter.Allow("alice", "users") {
+		t.Error("Expected one token to be refilled after a second")
+	}
+}
+
+func TestLimiterTableLimits(t *testing.T) {
+	limiter := NoGlobalLimit().SetTableLimit("reports", Limit{Rate: 0, Burst: 1})
+
+	ctx := WithCaller(context.Background(), "alice")
+	if err := limiter.BeforeQuery(ctx, types.QueryEvent{Table: "reports"}); err != nil {
+		t.Fatalf("Expected the first report query to be allowed, got: %v", err)
+	}
+	err := limiter.BeforeQuery(ctx, types.QueryEvent{Table: "reports"})
+	if !errors.Is(err, types.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited for the second report query, got: %v", err)
+	}
+	if err := limiter.BeforeQuery(ctx, types.QueryEvent{Table: "users"}); err != nil {
+		t.Errorf("Expected tables without a limit to be allowed, got: %v", err)
+	}
+}
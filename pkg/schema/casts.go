@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// timeLayouts are the formats CastTime accepts, tried in order.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02",
+}
+
+var (
+	casters = map[types.Cast]types.CastFunc{
+		types.CastBool:    castBool,
+		types.CastInt:     castInt,
+		types.CastFloat:   castFloat,
+		types.CastDecimal: castDecimal,
+		types.CastTime:    castTime,
+		types.CastJSON:    castJSON,
+	}
+	castersLock sync.RWMutex
+)
+
+// RegisterCast adds a cast, or replaces a built-in one. Registering types.CastDecimal with a
+// function returning, say, decimal.Decimal makes every decimal column use it.
+func RegisterCast(name types.Cast, fn types.CastFunc) {
+	castersLock.Lock()
+	defer castersLock.Unlock()
+	casters[name] = fn
+}
+
+// RowCaster returns a function converting a scanned row of table in place according to its
+// registered casts, or nil if the table has none.
+func RowCaster(table string) func(row map[string]interface{}) error {
+	casts := Lookup(table).Casts
+	if len(casts) == 0 {
+		return nil
+	}
+
+	castersLock.RLock()
+	fns := make(map[string]types.CastFunc, len(casts))
+	for column, cast := range casts {
+		fns[column] = casters[cast]
+	}
+	castersLock.RUnlock()
+
+	return func(row map[string]interface{}) error {
+		for column, fn := range fns {
+			value, ok := row[column]
+			if !ok || value == nil {
+				continue
+			}
+			if fn == nil {
+				return fmt.Errorf("%w: unknown cast %q for %s.%s", types.ErrInvalidParameter, casts[column], table, column)
+			}
+			cast, err := fn(value)
+			if err != nil {
+				return fmt.Errorf("failed to cast %s.%s to %s: %w", table, column, casts[column], err)
+			}
+			row[column] = cast
+		}
+		return nil
+	}
+}
+
+func castBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	}
+	return nil, fmt.Errorf("unsupported value %T", value)
+}
+
+func castInt(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	}
+	return nil, fmt.Errorf("unsupported value %T", value)
+}
+
+func castFloat(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return nil, fmt.Errorf("unsupported value %T", value)
+}
+
+func castDecimal(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case *big.Rat:
+		return v, nil
+	case int64:
+		return new(big.Rat).SetInt64(v), nil
+	case float64:
+		return new(big.Rat).SetFloat64(v), nil
+	case string:
+		if r, ok := new(big.Rat).SetString(strings.TrimSpace(v)); ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("invalid decimal %q", v)
+	}
+	return nil, fmt.Errorf("unsupported value %T", value)
+}
+
+func castTime(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", v)
+	}
+	return nil, fmt.Errorf("unsupported value %T", value)
+}
+
+func castJSON(value interface{}) (interface{}, error) {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return nil, fmt.Errorf("unsupported value %T", value)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
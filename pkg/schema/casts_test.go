@@ -0,0 +1,40 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestRowCaster(t *testing.T) {
+	t.Cleanup(Flush)
+	if RowCaster("users") != nil {
+		t.Fatal("Expected no caster for a table without casts")
+	}
+
+	Register("users", types.TableMeta{Casts: map[string]types.Cast{"admin": types.CastBool, "age": types.CastInt, "tier": "tier"}})
+	row := map[string]interface{}{"admin": "true", "age": "42"}
+	if err := RowCaster("users")(row); err != nil || row["admin"] != true || row["age"] != int64(42) {
+		t.Fatalf("Expected cast values, got %v, %v", row, err)
+	}
+
+	err := RowCaster("users")(map[string]interface{}{"tier": "gold"})
+	if !errors.Is(err, types.ErrInvalidParameter) {
+		t.Fatalf("Expected an unknown cast to fail, got %v", err)
+	}
+
+	RegisterCast("tier", func(value interface{}) (interface{}, error) {
+		return strings.ToUpper(value.(string)), nil
+	})
+	t.Cleanup(func() {
+		castersLock.Lock()
+		delete(casters, "tier")
+		castersLock.Unlock()
+	})
+	row = map[string]interface{}{"tier": "gold"}
+	if err := RowCaster("users")(row); err != nil || row["tier"] != "GOLD" {
+		t.Errorf("Expected the registered cast to apply, got %v, %v", row, err)
+	}
+}
@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+type unmaskKey struct{}
+
+// WithUnmask returns a context whose reads see masked columns unredacted. Grant it only to
+// callers entitled to the raw values.
+func WithUnmask(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unmaskKey{}, true)
+}
+
+// CanUnmask reports whether ctx carries the unmask capability.
+func CanUnmask(ctx context.Context) bool {
+	unmask, _ := ctx.Value(unmaskKey{}).(bool)
+	return unmask
+}
+
+// Masks returns the masks that apply to rows of table read with ctx: none when ctx can unmask.
+// The map must not be modified.
+func Masks(ctx context.Context, table string) map[string]types.MaskFunc {
+	if CanUnmask(ctx) {
+		return nil
+	}
+	return Lookup(table).Masks
+}
+
+// MaskRow applies masks to row in place, deleting the columns whose mask is nil.
+func MaskRow(masks map[string]types.MaskFunc, row map[string]interface{}) {
+	for column, mask := range masks {
+		value, ok := row[column]
+		switch {
+		case !ok:
+		case mask == nil:
+			delete(row, column)
+		case value != nil:
+			row[column] = mask(value)
+		}
+	}
+}
+
+// Redact returns a mask replacing every value with replacement.
+func Redact(replacement string) types.MaskFunc {
+	return func(interface{}) interface{} {
+		return replacement
+	}
+}
+
+// KeepLast returns a mask replacing all but the last n characters of a value with '*', as in
+// "************4242".
+func KeepLast(n int) types.MaskFunc {
+	return func(value interface{}) interface{} {
+		s := []rune(fmt.Sprint(value))
+		if len(s) <= n {
+			return strings.Repeat("*", len(s))
+		}
+		return strings.Repeat("*", len(s)-n) + string(s[len(s)-n:])
+	}
+}
@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestMasks(t *testing.T) {
+	t.Cleanup(Flush)
+	Register("payments", types.TableMeta{Masks: map[string]types.MaskFunc{"card": KeepLast(4), "cvv": nil, "ssn": Redact("[redacted]")}})
+
+	row := map[string]interface{}{"card": "4242424242424242", "cvv": "123", "ssn": nil, "amount": 10}
+	MaskRow(Masks(context.Background(), "payments"), row)
+	if row["card"] != "************4242" || row["ssn"] != nil || row["amount"] != 10 {
+		t.Errorf("Unexpected masked row %v", row)
+	}
+	if _, ok := row["cvv"]; ok {
+		t.Errorf("Expected cvv to be dropped, got %v", row)
+	}
+
+	if masks := Masks(WithUnmask(context.Background()), "payments"); masks != nil {
+		t.Errorf("Expected no masks with the unmask capability, got %v", masks)
+	}
+	if got := KeepLast(4)("12"); got != "**" {
+		t.Errorf("Expected short values to be fully masked, got %v", got)
+	}
+}
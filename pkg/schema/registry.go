@@ -0,0 +1,232 @@
+// Package schema holds per-table metadata registered at startup: the primary key, soft-delete and
+// timestamp columns, guarded columns, column casts and masks. Find, Chunk, cursor pagination, Delete and the insert and
+// update paths read it instead of assuming "id" and "created_at".
+package schema
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// DefaultPrimaryKey is the key column of tables without registered metadata.
+const DefaultPrimaryKey = "id"
+
+// DefaultCreatedAt is the column Latest and Oldest order by when a table registers none.
+const DefaultCreatedAt = "created_at"
+
+var (
+	tables     = make(map[string]types.TableMeta)
+	tablesLock sync.RWMutex
+)
+
+// Register sets the metadata of a table. Registering the same table again replaces it.
+func Register(table string, meta types.TableMeta) {
+	meta.Guarded = append([]string(nil), meta.Guarded...)
+	meta.PrimaryKeys = append([]string(nil), meta.PrimaryKeys...)
+	if meta.Casts != nil {
+		casts := make(map[string]types.Cast, len(meta.Casts))
+		for column, cast := range meta.Casts {
+			casts[column] = cast
+		}
+		meta.Casts = casts
+	}
+	if meta.Masks != nil {
+		masks := make(map[string]types.MaskFunc, len(meta.Masks))
+		for column, mask := range meta.Masks {
+			masks[column] = mask
+		}
+		meta.Masks = masks
+	}
+
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+	tables[table] = meta
+}
+
+// Lookup returns the metadata registered for table, with PrimaryKey defaulted to "id". table may
+// carry an alias, as in "users as u".
+func Lookup(table string) types.TableMeta {
+	if fields := strings.Fields(table); len(fields) > 0 {
+		table = fields[0]
+	}
+
+	tablesLock.RLock()
+	meta := tables[table]
+	tablesLock.RUnlock()
+
+	if meta.PrimaryKey == "" {
+		meta.PrimaryKey = DefaultPrimaryKey
+	}
+	return meta
+}
+
+// Tables returns the sorted names of the tables with registered metadata.
+func Tables() []string {
+	tablesLock.RLock()
+	defer tablesLock.RUnlock()
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flush removes all registered metadata.
+func Flush() {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+	tables = make(map[string]types.TableMeta)
+}
+
+// CreatedAtColumn returns the creation timestamp column of table, defaulting to "created_at".
+func CreatedAtColumn(table string) string {
+	if column := Lookup(table).CreatedAt; column != "" {
+		return column
+	}
+	return DefaultCreatedAt
+}
+
+// KeyColumns returns the primary key columns of table: the registered composite key, or the
+// single PrimaryKey column.
+func KeyColumns(table string) []string {
+	meta := Lookup(table)
+	if len(meta.PrimaryKeys) > 0 {
+		return meta.PrimaryKeys
+	}
+	return []string{meta.PrimaryKey}
+}
+
+// KeyValues converts id to the values of the KeyColumns of table, in order. A single-column key
+// is converted by KeyValue; a composite key takes a map from column to value or a []interface{}
+// in key order.
+func KeyValues(table string, id interface{}) ([]interface{}, error) {
+	columns := KeyColumns(table)
+	if len(columns) == 1 {
+		key, err := KeyValue(table, id)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{key}, nil
+	}
+
+	values := make([]interface{}, len(columns))
+	switch v := id.(type) {
+	case map[string]interface{}:
+		for i, column := range columns {
+			value, ok := v[column]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s key is missing %s", types.ErrInvalidParameter, table, column)
+			}
+			values[i] = value
+		}
+	case []interface{}:
+		if len(v) != len(columns) {
+			return nil, fmt.Errorf("%w: %s key has %d values, want %d", types.ErrInvalidParameter, table, len(v), len(columns))
+		}
+		copy(values, v)
+	default:
+		return nil, fmt.Errorf("%w: %s has a composite key (%s); pass a map or []interface{}", types.ErrInvalidParameter, table, strings.Join(columns, ", "))
+	}
+	return values, nil
+}
+
+// KeyValue converts id to the key type registered for table.
+func KeyValue(table string, id interface{}) (interface{}, error) {
+	switch Lookup(table).KeyType {
+	case types.IntKey:
+		if s, ok := id.(string); ok {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s key %q is not an integer", types.ErrInvalidParameter, table, s)
+			}
+			return n, nil
+		}
+	case types.StringKey:
+		if _, ok := id.(string); !ok {
+			return fmt.Sprint(id), nil
+		}
+	case types.UUIDKey:
+		return uuidValue(table, id)
+	}
+	return id, nil
+}
+
+// uuidValue formats id, a UUID string or 16-byte array, as a canonical lowercase UUID.
+func uuidValue(table string, id interface{}) (string, error) {
+	var b [16]byte
+	switch v := id.(type) {
+	case [16]byte:
+		b = v
+	case []byte:
+		if len(v) != len(b) {
+			return "", fmt.Errorf("%w: %s key of %d bytes is not a UUID", types.ErrInvalidParameter, table, len(v))
+		}
+		copy(b[:], v)
+	default:
+		s := strings.ReplaceAll(fmt.Sprint(id), "-", "")
+		if len(s) != 2*len(b) {
+			return "", fmt.Errorf("%w: %s key %q is not a UUID", types.ErrInvalidParameter, table, fmt.Sprint(id))
+		}
+		if _, err := hex.Decode(b[:], []byte(s)); err != nil {
+			return "", fmt.Errorf("%w: %s key %q is not a UUID", types.ErrInvalidParameter, table, fmt.Sprint(id))
+		}
+	}
+
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32], nil
+}
+
+// CheckGuarded fails with types.ErrGuardedColumn if values write a column table guards.
+func CheckGuarded(table string, values map[string]interface{}) error {
+	for _, column := range Lookup(table).Guarded {
+		if _, ok := values[column]; ok {
+			return fmt.Errorf("%w: %s.%s", types.ErrGuardedColumn, table, column)
+		}
+	}
+	return nil
+}
+
+// StampInsert returns values with the creation and update timestamps of table set to now, unless
+// values set them already. values is not modified.
+func StampInsert(table string, values map[string]interface{}, now time.Time) map[string]interface{} {
+	meta := Lookup(table)
+	return stamp(values, now, meta.CreatedAt, meta.UpdatedAt)
+}
+
+// StampUpdate returns values with the update timestamp of table set to now, unless values set it
+// already. values is not modified.
+func StampUpdate(table string, values map[string]interface{}, now time.Time) map[string]interface{} {
+	return stamp(values, now, Lookup(table).UpdatedAt)
+}
+
+func stamp(values map[string]interface{}, now time.Time, columns ...string) map[string]interface{} {
+	var stamped map[string]interface{}
+	for _, column := range columns {
+		if column == "" {
+			continue
+		}
+		if _, ok := values[column]; ok {
+			continue
+		}
+		if stamped == nil {
+			stamped = make(map[string]interface{}, len(values)+len(columns))
+			for key, value := range values {
+				stamped[key] = value
+			}
+		}
+		stamped[column] = now
+	}
+	if stamped == nil {
+		return values
+	}
+	return stamped
+}
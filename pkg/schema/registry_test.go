@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Cleanup(Flush)
+	Register("orders", types.TableMeta{PrimaryKey: "order_id", KeyType: types.StringKey, UpdatedAt: "updated_at"})
+
+	if key := Lookup("orders as o").PrimaryKey; key != "order_id" {
+		t.Errorf("Expected the aliased table to resolve, got %q", key)
+	}
+	if key := Lookup("users").PrimaryKey; key != DefaultPrimaryKey {
+		t.Errorf("Expected the default key, got %q", key)
+	}
+	if column := CreatedAtColumn("orders"); column != DefaultCreatedAt {
+		t.Errorf("Expected the default creation column, got %q", column)
+	}
+	if id, err := KeyValue("orders", 42); err != nil || id != "42" {
+		t.Errorf("Expected a string key, got %v, %v", id, err)
+	}
+	if id, err := KeyValue("users", "42"); err != nil || id != "42" {
+		t.Errorf("Expected the ID unchanged, got %v, %v", id, err)
+	}
+
+	Register("sessions", types.TableMeta{KeyType: types.UUIDKey})
+	const canonical = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	for _, id := range []interface{}{canonical, "6BA7B8109DAD11D180B400C04FD430C8", [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}} {
+		if key, err := KeyValue("sessions", id); err != nil || key != canonical {
+			t.Errorf("Expected %v to become %s, got %v, %v", id, canonical, key, err)
+		}
+	}
+	if _, err := KeyValue("sessions", "not-a-uuid"); !errors.Is(err, types.ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for a malformed UUID, got %v", err)
+	}
+
+	now := time.Now()
+	values := map[string]interface{}{"status": "paid"}
+	stamped := StampUpdate("orders", values, now)
+	if stamped["updated_at"] != now || len(values) != 1 {
+		t.Errorf("Expected a stamped copy, got %v (original %v)", stamped, values)
+	}
+	explicit := map[string]interface{}{"updated_at": "2024-01-01"}
+	if got := StampUpdate("orders", explicit, now); got["updated_at"] != "2024-01-01" {
+		t.Errorf("Expected an explicit timestamp to be kept, got %v", got)
+	}
+
+	Register("users", types.TableMeta{Guarded: []string{"role"}})
+	if err := CheckGuarded("users", map[string]interface{}{"role": "admin"}); !errors.Is(err, types.ErrGuardedColumn) {
+		t.Errorf("Expected ErrGuardedColumn, got %v", err)
+	}
+	if names := Tables(); len(names) != 3 || names[0] != "orders" || names[2] != "users" {
+		t.Errorf("Expected both tables, got %v", names)
+	}
+}
@@ -0,0 +1,37 @@
+package security
+
+import "strings"
+
+// Weights of the parts of a statement in its complexity score.
+const (
+	ComplexityJoin      = 2 // per join
+	ComplexitySubquery  = 3 // per subquery or unioned query
+	ComplexityUnbounded = 5 // a select returning rows without a LIMIT
+	ComplexityFullScan  = 5 // a statement without any WHERE condition
+	ComplexityWildcard  = 1 // per LIKE pattern with a trailing wildcard, such as 'ada%'
+	ComplexityLeading   = 4 // per LIKE pattern with a leading wildcard, such as '%ada', which defeats indexes
+)
+
+// Complexity scores how expensive a statement is likely to be: 1, plus the weights above for its
+// joins, subqueries, unbounded scans and LIKE wildcards. First by key scores 1; a LIMIT-less
+// select joining two tables and filtering on '%term%' scores 1+2+5+4 = 12.
+func Complexity(s *Statement) int {
+	score := 1 + s.Joins*ComplexityJoin + s.Subqueries*ComplexitySubquery
+
+	if s.Kind == "select" && !s.Limited && !s.Aggregate {
+		score += ComplexityUnbounded
+	}
+	if s.Wheres == 0 {
+		score += ComplexityFullScan
+	}
+
+	for _, pattern := range s.Likes {
+		switch {
+		case strings.HasPrefix(pattern, "%"), strings.HasPrefix(pattern, "_"):
+			score += ComplexityLeading
+		case strings.ContainsAny(pattern, "%_"):
+			score += ComplexityWildcard
+		}
+	}
+	return score
+}
@@ -0,0 +1,27 @@
+package security
+
+import "testing"
+
+func TestComplexity(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement Statement
+		score     int
+	}{
+		{"lookup", Statement{Kind: "select", Wheres: 1, Limited: true}, 1},
+		{"aggregate", Statement{Kind: "select", Wheres: 1, Aggregate: true}, 1},
+		{"unbounded select", Statement{Kind: "select", Wheres: 1}, 6},
+		{"full scan", Statement{Kind: "select"}, 11},
+		{"unconditioned update", Statement{Kind: "update"}, 6},
+		{"joins and subqueries", Statement{Kind: "select", Wheres: 2, Limited: true, Joins: 2, Subqueries: 1}, 8},
+		{"wildcards", Statement{Kind: "select", Wheres: 3, Limited: true, Likes: []string{"ada%", "%ada", "_da", "ada"}}, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Complexity(&tt.statement); got != tt.score {
+				t.Errorf("Expected %d, got %d", tt.score, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package security
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// FirewallAction is what a Firewall does with a statement that breaks a rule.
+type FirewallAction int
+
+const (
+	// FirewallDeny refuses the statement with types.ErrFirewallDenied.
+	FirewallDeny FirewallAction = iota
+	// FirewallWarn lets the statement run and reports the violation.
+	FirewallWarn
+	// FirewallAllow lets the statement run and only counts the violation, to try a rule out
+	// before enforcing it.
+	FirewallAllow
+)
+
+// String returns the name of the action.
+func (a FirewallAction) String() string {
+	switch a {
+	case FirewallDeny:
+		return "deny"
+	case FirewallWarn:
+		return "warn"
+	case FirewallAllow:
+		return "allow"
+	default:
+		return fmt.Sprintf("FirewallAction(%d)", int(a))
+	}
+}
+
+// Statement describes a statement compiled by a builder to the firewall rules and Complexity.
+type Statement struct {
+	Kind       string // "select", "update" or "delete"
+	Table      string // without its alias
+	SQL        string
+	Joins      int
+	Subqueries int      // subqueries in conditions, joins and FROM, and unioned queries
+	Wheres     int      // top-level WHERE conditions
+	SelectAll  bool     // no columns, or *, are selected
+	Aggregate  bool     // every selected column is an aggregate such as COUNT(*)
+	Limited    bool     // the statement has a LIMIT
+	InSizes    []int    // the number of values of each IN list
+	Likes      []string // the bound patterns of LIKE conditions
+}
+
+// FirewallRule is a named check with the action taken when it fails. Check returns a description
+// of the violation, or "" if the statement complies.
+type FirewallRule struct {
+	Name   string
+	Action FirewallAction
+	Check  func(s *Statement) string
+}
+
+// As returns the rule with another action.
+func (r FirewallRule) As(action FirewallAction) FirewallRule {
+	r.Action = action
+	return r
+}
+
+// MaxJoins denies statements joining more than n tables.
+func MaxJoins(n int) FirewallRule {
+	return FirewallRule{Name: "max_joins", Check: func(s *Statement) string {
+		if s.Joins > n {
+			return fmt.Sprintf("%d joins, at most %d allowed", s.Joins, n)
+		}
+		return ""
+	}}
+}
+
+// MaxInSize denies IN lists of more than n values.
+func MaxInSize(n int) FirewallRule {
+	return FirewallRule{Name: "max_in_size", Check: func(s *Statement) string {
+		for _, size := range s.InSizes {
+			if size > n {
+				return fmt.Sprintf("IN list of %d values, at most %d allowed", size, n)
+			}
+		}
+		return ""
+	}}
+}
+
+// DenySelectAll denies SELECT * on the given tables, or on every table if none are given.
+func DenySelectAll(tables ...string) FirewallRule {
+	return FirewallRule{Name: "deny_select_all", Check: func(s *Statement) string {
+		if s.Kind == "select" && s.SelectAll && matchesTable(tables, s.Table) {
+			return "SELECT * on " + s.Table
+		}
+		return ""
+	}}
+}
+
+// DenyUnboundedLike denies LIKE patterns starting with a wildcard, such as '%x%', which scan every
+// row instead of using an index.
+func DenyUnboundedLike() FirewallRule {
+	return FirewallRule{Name: "deny_unbounded_like", Check: func(s *Statement) string {
+		for _, pattern := range s.Likes {
+			if strings.HasPrefix(pattern, "%") || strings.HasPrefix(pattern, "_") {
+				return fmt.Sprintf("LIKE pattern %q starts with a wildcard", pattern)
+			}
+		}
+		return ""
+	}}
+}
+
+// RequireLimit denies selects without a LIMIT on the given tables, or on every table if none are
+// given. Aggregates return a single row and are exempt.
+func RequireLimit(tables ...string) FirewallRule {
+	return FirewallRule{Name: "require_limit", Check: func(s *Statement) string {
+		if s.Kind == "select" && !s.Limited && !s.Aggregate && matchesTable(tables, s.Table) {
+			return "select on " + s.Table + " without a LIMIT"
+		}
+		return ""
+	}}
+}
+
+func matchesTable(tables []string, table string) bool {
+	if len(tables) == 0 {
+		return true
+	}
+	for _, t := range tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// FirewallViolation is a rule broken by a statement.
+type FirewallViolation struct {
+	Rule    string
+	Action  FirewallAction
+	Message string
+	Table   string
+	SQL     string
+}
+
+// FirewallStats contains the metrics of a Firewall.
+type FirewallStats struct {
+	Checked    int64            `json:"checked"`
+	Denied     int64            `json:"denied"`
+	Warned     int64            `json:"warned"`
+	Allowed    int64            `json:"allowed"`
+	Violations map[string]int64 `json:"violations"` // by rule name
+}
+
+// Firewall checks the statements builders compile against a set of rules.
+type Firewall struct {
+	rules       []FirewallRule
+	onViolation func(FirewallViolation)
+
+	mu    sync.Mutex
+	stats FirewallStats
+}
+
+// NewFirewall creates a firewall with the given rules. Violations of warn rules are logged with
+// slog until OnViolation is set.
+func NewFirewall(rules ...FirewallRule) *Firewall {
+	return &Firewall{
+		rules: append([]FirewallRule(nil), rules...),
+		stats: FirewallStats{Violations: make(map[string]int64)},
+	}
+}
+
+// OnViolation sets the function called with every violation, whatever its action.
+func (f *Firewall) OnViolation(fn func(FirewallViolation)) *Firewall {
+	f.onViolation = fn
+	return f
+}
+
+// Check evaluates every rule against s and fails with types.ErrFirewallDenied if a deny rule
+// matched.
+func (f *Firewall) Check(s *Statement) error {
+	var violations []FirewallViolation
+	for _, rule := range f.rules {
+		if message := rule.Check(s); message != "" {
+			violations = append(violations, FirewallViolation{Rule: rule.Name, Action: rule.Action, Message: message, Table: s.Table, SQL: s.SQL})
+		}
+	}
+
+	var denied *FirewallViolation
+	f.mu.Lock()
+	f.stats.Checked++
+	for i, violation := range violations {
+		f.stats.Violations[violation.Rule]++
+		switch violation.Action {
+		case FirewallDeny:
+			f.stats.Denied++
+			if denied == nil {
+				denied = &violations[i]
+			}
+		case FirewallWarn:
+			f.stats.Warned++
+		default:
+			f.stats.Allowed++
+		}
+	}
+	f.mu.Unlock()
+
+	for _, violation := range violations {
+		switch {
+		case f.onViolation != nil:
+			f.onViolation(violation)
+		case violation.Action == FirewallWarn:
+			slog.Warn("query firewall violation", "rule", violation.Rule, "table", violation.Table, "message", violation.Message, "sql", violation.SQL)
+		}
+	}
+
+	if denied != nil {
+		return fmt.Errorf("%w: %s: %s", types.ErrFirewallDenied, denied.Rule, denied.Message)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the firewall's metrics.
+func (f *Firewall) Stats() FirewallStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := f.stats
+	stats.Violations = make(map[string]int64, len(f.stats.Violations))
+	for rule, count := range f.stats.Violations {
+		stats.Violations[rule] = count
+	}
+	return stats
+}
@@ -0,0 +1,58 @@
+package security
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestFirewallRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      FirewallRule
+		statement Statement
+		violates  bool
+	}{
+		{"too many joins", MaxJoins(2), Statement{Joins: 3}, true},
+		{"few joins", MaxJoins(2), Statement{Joins: 2}, false},
+		{"large IN list", MaxInSize(100), Statement{InSizes: []int{5, 101}}, true},
+		{"select all on a listed table", DenySelectAll("users"), Statement{Kind: "select", Table: "users", SelectAll: true}, true},
+		{"select all on another table", DenySelectAll("users"), Statement{Kind: "select", Table: "posts", SelectAll: true}, false},
+		{"leading wildcard", DenyUnboundedLike(), Statement{Likes: []string{"ada%", "%lovelace%"}}, true},
+		{"prefix match", DenyUnboundedLike(), Statement{Likes: []string{"ada%"}}, false},
+		{"missing limit", RequireLimit("events"), Statement{Kind: "select", Table: "events"}, true},
+		{"aggregate without limit", RequireLimit("events"), Statement{Kind: "select", Table: "events", Aggregate: true}, false},
+		{"update without limit", RequireLimit(), Statement{Kind: "update", Table: "events"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Check(&tt.statement) != ""; got != tt.violates {
+				t.Errorf("Expected violation %v, got %v", tt.violates, got)
+			}
+		})
+	}
+}
+
+func TestFirewallActionsAndStats(t *testing.T) {
+	var reported []FirewallViolation
+	fw := NewFirewall(MaxJoins(1), RequireLimit().As(FirewallWarn), DenySelectAll().As(FirewallAllow)).
+		OnViolation(func(v FirewallViolation) { reported = append(reported, v) })
+
+	if err := fw.Check(&Statement{Kind: "select", Table: "users", SelectAll: true}); err != nil {
+		t.Fatalf("Expected warn and allow rules to let the statement run, got %v", err)
+	}
+	err := fw.Check(&Statement{Kind: "select", Table: "users", Joins: 2, Limited: true})
+	if !errors.Is(err, types.ErrFirewallDenied) {
+		t.Fatalf("Expected ErrFirewallDenied, got %v", err)
+	}
+
+	stats := fw.Stats()
+	if stats.Checked != 2 || stats.Denied != 1 || stats.Warned != 1 || stats.Allowed != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.Violations["max_joins"] != 1 || len(reported) != 3 {
+		t.Errorf("Expected every violation to be reported, got %+v and %v", stats.Violations, reported)
+	}
+}
@@ -0,0 +1,129 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/ratelimit"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// HoneypotAlert describes a statement that referenced a honeypot, with the caller metadata of its
+// context.
+type HoneypotAlert struct {
+	Identifier string // the honeypot, as registered
+	Table      string
+	Operation  string
+	SQL        string
+	Caller     string            // set with ratelimit.WithCaller
+	Connection string            // set with querybuilder.WithConnection
+	Tags       map[string]string // set with querybuilder.WithTag
+	Blocked    bool
+}
+
+// Honeypots is a query hook that watches for tables and columns no legitimate code uses. Register
+// it with querybuilder.AddHook; statements referencing a honeypot fire the alert callback and,
+// unless the honeypot was added with Flag, fail with types.ErrHoneypot.
+type Honeypots struct {
+	mu          sync.RWMutex
+	identifiers map[string]bool // lower-cased identifier -> block
+	onAlert     func(ctx context.Context, alert HoneypotAlert)
+}
+
+// NewHoneypots creates an empty set of honeypots.
+func NewHoneypots() *Honeypots {
+	return &Honeypots{identifiers: make(map[string]bool)}
+}
+
+// Block registers tables or columns, such as "admin_users" or "users.password_plain", whose
+// statements are refused. An unqualified column matches it on every table.
+func (h *Honeypots) Block(identifiers ...string) *Honeypots {
+	return h.add(true, identifiers)
+}
+
+// Flag registers tables or columns whose statements run but fire the alert callback.
+func (h *Honeypots) Flag(identifiers ...string) *Honeypots {
+	return h.add(false, identifiers)
+}
+
+func (h *Honeypots) add(block bool, identifiers []string) *Honeypots {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, identifier := range identifiers {
+		h.identifiers[strings.ToLower(identifier)] = block
+	}
+	return h
+}
+
+// OnAlert sets the function called when a statement references a honeypot.
+func (h *Honeypots) OnAlert(fn func(ctx context.Context, alert HoneypotAlert)) *Honeypots {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onAlert = fn
+	return h
+}
+
+// BeforeQuery implements types.QueryHook.
+func (h *Honeypots) BeforeQuery(ctx context.Context, event types.QueryEvent) error {
+	h.mu.RLock()
+	identifier, block, found := h.match(event.SQL)
+	onAlert := h.onAlert
+	h.mu.RUnlock()
+	if !found {
+		return nil
+	}
+
+	if onAlert != nil {
+		onAlert(ctx, HoneypotAlert{
+			Identifier: identifier,
+			Table:      event.Table,
+			Operation:  event.Operation,
+			SQL:        event.SQL,
+			Caller:     ratelimit.CallerFromContext(ctx),
+			Connection: execution.ConnectionFromContext(ctx),
+			Tags:       execution.TagsFromContext(ctx),
+			Blocked:    block,
+		})
+	}
+	if block {
+		return fmt.Errorf("%w: %s", types.ErrHoneypot, identifier)
+	}
+	return nil
+}
+
+var (
+	literalOrComment = regexp.MustCompile(`(?s)'(?:[^']|'')*'|/\*.*?\*/`)
+	quotedIdentifier = regexp.MustCompile("[`\"\\[\\]]")
+	sqlIdentifier    = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$]*(?:\.[A-Za-z_][A-Za-z0-9_$]*)*`)
+)
+
+// match returns the first honeypot referenced by sql, preferring blocking ones. String literals
+// and comments are skipped so that values and tags do not trigger honeypots.
+func (h *Honeypots) match(sql string) (identifier string, block bool, found bool) {
+	if len(h.identifiers) == 0 {
+		return "", false, false
+	}
+
+	sql = literalOrComment.ReplaceAllString(sql, " ")
+	sql = quotedIdentifier.ReplaceAllString(sql, "")
+	for _, token := range sqlIdentifier.FindAllString(sql, -1) {
+		token = strings.ToLower(token)
+		candidates := []string{token}
+		if i := strings.LastIndexByte(token, '.'); i >= 0 {
+			candidates = append(candidates, token[i+1:])
+			if j := strings.LastIndexByte(token[:i], '.'); j >= 0 {
+				candidates = append(candidates, token[j+1:])
+			}
+		}
+		for _, candidate := range candidates {
+			if b, ok := h.identifiers[candidate]; ok && (!found || b && !block) {
+				identifier, block, found = candidate, b, true
+			}
+		}
+	}
+	return identifier, block, found
+}
@@ -0,0 +1,54 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/execution"
+	"github.com/omarhamdy49/go-query-builder/pkg/ratelimit"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+func TestHoneypots(t *testing.T) {
+	var alerts []HoneypotAlert
+	honeypots := NewHoneypots().
+		Block("admin_users", "users.password_plain").
+		Flag("ssn").
+		OnAlert(func(ctx context.Context, alert HoneypotAlert) { alerts = append(alerts, alert) })
+
+	ctx := ratelimit.WithCaller(execution.WithTag(context.Background(), "route", "/export"), "user-7")
+	tests := []struct {
+		sql        string
+		identifier string
+		blocked    bool
+	}{
+		{"SELECT * FROM `admin_users` WHERE `id` = ?", "admin_users", true},
+		{`SELECT "u"."name", "users"."password_plain" FROM "users" AS "u"`, "users.password_plain", true},
+		{"SELECT `ssn` FROM `people` WHERE `name` = ?", "ssn", false},
+		{"SELECT `password_plain` FROM `accounts`", "", false},
+		{"SELECT * FROM `users` WHERE `name` = 'admin_users' /* admin_users */", "", false},
+		{"SELECT * FROM `admin_users_archive`", "", false},
+	}
+
+	for _, tt := range tests {
+		alerts = nil
+		err := honeypots.BeforeQuery(ctx, types.QueryEvent{SQL: tt.sql, Operation: "select"})
+		if got := errors.Is(err, types.ErrHoneypot); got != tt.blocked {
+			t.Errorf("%s: expected blocked %v, got %v", tt.sql, tt.blocked, err)
+		}
+		if tt.identifier == "" {
+			if len(alerts) != 0 {
+				t.Errorf("%s: expected no alert, got %+v", tt.sql, alerts)
+			}
+			continue
+		}
+		if len(alerts) != 1 {
+			t.Fatalf("%s: expected one alert, got %+v", tt.sql, alerts)
+		}
+		alert := alerts[0]
+		if alert.Identifier != tt.identifier || alert.Blocked != tt.blocked || alert.Caller != "user-7" || alert.Tags["route"] != "/export" {
+			t.Errorf("%s: unexpected alert %+v", tt.sql, alert)
+		}
+	}
+}
@@ -17,6 +17,14 @@ type Validator struct {
 	allowedColumnPatterns []*regexp.Regexp
 	forbiddenKeywords     []string
 	maxQueryLength        int
+	valueRules            []ValueRule
+}
+
+// ValueRule checks the string parameter values of a query. Check returns a description of the
+// problem, or "" if the value is acceptable.
+type ValueRule struct {
+	Name  string
+	Check func(value string) string
 }
 
 // NewValidator creates a new security validator with default configuration.
@@ -69,12 +77,30 @@ func (v *Validator) AddForbiddenKeyword(keyword string) *Validator {
 	return v
 }
 
+// SetForbiddenKeywords replaces the list of forbidden terms. With none, identifiers are checked
+// against the allowed patterns only.
+func (v *Validator) SetForbiddenKeywords(keywords ...string) *Validator {
+	v.forbiddenKeywords = make([]string, len(keywords))
+	for i, keyword := range keywords {
+		v.forbiddenKeywords[i] = strings.ToUpper(keyword)
+	}
+	return v
+}
+
 // SetMaxQueryLength sets the maximum allowed length for SQL queries.
 func (v *Validator) SetMaxQueryLength(length int) *Validator {
 	v.maxQueryLength = length
 	return v
 }
 
+// ValidateValues makes ValidateValue check string values against rules, such as
+// DefaultValueRules(). Without rules values are not checked: they are bound as parameters, so
+// text like "I select wines" is data, not SQL.
+func (v *Validator) ValidateValues(rules ...ValueRule) *Validator {
+	v.valueRules = append(v.valueRules, rules...)
+	return v
+}
+
 // ValidateTableName validates a table name against security rules.
 func (v *Validator) ValidateTableName(table string) error {
 	if table == "" {
@@ -86,7 +112,7 @@ func (v *Validator) ValidateTableName(table string) error {
 	}
 
 	for _, keyword := range v.forbiddenKeywords {
-		if strings.Contains(strings.ToUpper(table), keyword) {
+		if containsKeyword(table, keyword) {
 			return fmt.Errorf("table name contains forbidden keyword: %s", keyword)
 		}
 	}
@@ -118,7 +144,7 @@ func (v *Validator) ValidateColumnName(column string) error {
 	}
 
 	for _, keyword := range v.forbiddenKeywords {
-		if strings.Contains(strings.ToUpper(column), keyword) {
+		if containsKeyword(column, keyword) {
 			return fmt.Errorf("column name contains forbidden keyword: %s", keyword)
 		}
 	}
@@ -141,40 +167,16 @@ func (v *Validator) ValidateColumnName(column string) error {
 
 // ValidateOperator validates that an SQL operator is allowed.
 func (v *Validator) ValidateOperator(operator types.Operator) error {
-	allowedOperators := map[types.Operator]bool{
-		types.OpEqual:              true,
-		types.OpNotEqual:           true,
-		types.OpGreaterThan:        true,
-		types.OpGreaterThanOrEqual: true,
-		types.OpLessThan:           true,
-		types.OpLessThanOrEqual:    true,
-		types.OpLike:               true,
-		types.OpNotLike:            true,
-		types.OpILike:              true,
-		types.OpNotILike:           true,
-		types.OpIn:                 true,
-		types.OpNotIn:              true,
-		types.OpBetween:            true,
-		types.OpNotBetween:         true,
-		types.OpIsNull:             true,
-		types.OpIsNotNull:          true,
-		types.OpExists:             true,
-		types.OpNotExists:          true,
-		types.OpJSONContains:       true,
-		types.OpJSONExtract:        true,
-		types.OpFullText:           true,
-	}
-
-	if !allowedOperators[operator] {
+	if !operator.Allowed() {
 		return fmt.Errorf("operator not allowed: %s", operator)
 	}
 
 	return nil
 }
 
-// ValidateValue validates a query parameter value for security risks.
+// ValidateValue validates a query parameter value against the rules enabled with ValidateValues.
 func (v *Validator) ValidateValue(value interface{}) error {
-	if value == nil {
+	if value == nil || len(v.valueRules) == 0 {
 		return nil
 	}
 
@@ -193,24 +195,53 @@ func (v *Validator) ValidateValue(value interface{}) error {
 }
 
 func (v *Validator) validateStringValue(value string) error {
-	if len(value) > 1000 {
-		return fmt.Errorf("string value too long: %d characters (max 1000)", len(value))
+	for _, rule := range v.valueRules {
+		if problem := rule.Check(value); problem != "" {
+			return fmt.Errorf("string value rejected by %s: %s", rule.Name, problem)
+		}
 	}
+	return nil
+}
 
-	suspicious := []string{
-		"<script", "javascript:", "vbscript:", "onload=", "onerror=",
-		"UNION", "SELECT", "INSERT", "UPDATE", "DELETE", "DROP",
-		"--", "/*", "*/", "xp_", "sp_",
-	}
+// DefaultValueRules returns MaxValueLength(1000), DenyMarkup and DenyInjection.
+func DefaultValueRules() []ValueRule {
+	return []ValueRule{MaxValueLength(1000), DenyMarkup(), DenyInjection()}
+}
 
-	upperValue := strings.ToUpper(value)
-	for _, pattern := range suspicious {
-		if strings.Contains(upperValue, strings.ToUpper(pattern)) {
-			return fmt.Errorf("string value contains suspicious content: %s", pattern)
+// MaxValueLength rejects values longer than n bytes.
+func MaxValueLength(n int) ValueRule {
+	return ValueRule{Name: "max_value_length", Check: func(value string) string {
+		if len(value) > n {
+			return fmt.Sprintf("%d characters (max %d)", len(value), n)
 		}
-	}
+		return ""
+	}}
+}
 
-	return nil
+// DenyMarkup rejects values carrying script tags, script URLs or inline event handlers.
+func DenyMarkup() ValueRule {
+	return DenyValuePattern("deny_markup", regexp.MustCompile(`(?i)<script|(?:java|vb)script:|\bon(?:load|error)\s*=`))
+}
+
+// DenyInjection rejects values that look like an attempt to break out of a string literal, such as
+// "'; DROP TABLE users; --" or "' OR 1=1". SQL words in ordinary text are accepted.
+func DenyInjection() ValueRule {
+	return DenyValuePattern("deny_injection", injectionPattern)
+}
+
+var injectionPattern = regexp.MustCompile(`(?i)` +
+	`'\s*(?:;|--|#|/\*|\b(?:or|and)\b\s+\S+\s*(?:=|like\b))` + // closing quote followed by SQL
+	`|;\s*(?:drop|delete|insert|update|alter|truncate|create|exec)\b` + // stacked statements
+	`|\bunion\s+(?:all\s+)?select\b`)
+
+// DenyValuePattern rejects values matching pattern.
+func DenyValuePattern(name string, pattern *regexp.Regexp) ValueRule {
+	return ValueRule{Name: name, Check: func(value string) string {
+		if match := pattern.FindString(value); match != "" {
+			return fmt.Sprintf("suspicious content %q", match)
+		}
+		return ""
+	}}
 }
 
 // ValidateRawSQL validates raw SQL strings for injection attacks and forbidden patterns.
@@ -224,7 +255,7 @@ func (v *Validator) ValidateRawSQL(sql string) error {
 	}
 
 	for _, keyword := range v.forbiddenKeywords {
-		if strings.Contains(strings.ToUpper(sql), keyword) {
+		if containsKeyword(sql, keyword) {
 			return fmt.Errorf("raw SQL contains forbidden keyword: %s", keyword)
 		}
 	}
@@ -259,6 +290,29 @@ func (v *Validator) checkForSQLInjectionPatterns(sql string) error {
 	return nil
 }
 
+// containsKeyword reports whether s contains keyword as a word of its own, so that "DROP" matches
+// "users_drop" but "CREATE" does not match "created_at". Keywords ending in a separator, such as
+// "xp_", match as prefixes.
+func containsKeyword(s, keyword string) bool {
+	s, keyword = strings.ToUpper(s), strings.ToUpper(keyword)
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], keyword)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(keyword)
+		if (start == 0 || !isWordByte(s[start-1]) || !isWordByte(keyword[0])) &&
+			(end == len(s) || !isWordByte(s[end]) || !isWordByte(keyword[len(keyword)-1])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
+
 // SanitizeInput removes potentially dangerous characters from user input.
 func (v *Validator) SanitizeInput(input string) string {
 	if input == "" {
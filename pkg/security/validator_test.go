@@ -1,6 +1,8 @@
 package security
 
 import (
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/omarhamdy49/go-query-builder/pkg/types"
@@ -20,6 +22,7 @@ func TestValidateTableName(t *testing.T) {
 		{"Empty table name", "", true},
 		{"Too long table name", "this_is_a_very_long_table_name_that_exceeds_the_maximum_allowed_length_for_table_names_in_most_databases", true},
 		{"Table with forbidden keyword", "users_DROP", true},
+		{"Table containing a keyword inside a word", "dropouts", false},
 		{"Table with spaces", "user profiles", true},
 		{"Table with special chars", "users@domain", true},
 	}
@@ -50,6 +53,7 @@ func TestValidateColumnName(t *testing.T) {
 		{"Valid column with table prefix", "users.email", false},
 		{"Empty column name", "", true},
 		{"Column with forbidden keyword", "email_DROP", true},
+		{"Column containing a keyword inside a word", "created_at", false},
 		{"Column with spaces", "first name", true},
 		{"Column with special chars", "email@domain", true},
 	}
@@ -96,7 +100,7 @@ func TestValidateOperator(t *testing.T) {
 }
 
 func TestValidateStringValue(t *testing.T) {
-	validator := NewValidator()
+	validator := NewValidator().ValidateValues(DefaultValueRules()...)
 
 	tests := []struct {
 		name      string
@@ -107,6 +111,10 @@ func TestValidateStringValue(t *testing.T) {
 		{"Empty string", "", false},
 		{"String with SQL injection attempt", "'; DROP TABLE users; --", true},
 		{"String with UNION attack", "' UNION SELECT * FROM users --", true},
+		{"String with tautology", "x' OR 1=1", true},
+		{"Stacked statement", "1; DELETE FROM users", true},
+		{"SQL words in prose", "I select wines -- mostly reds -- and update my cellar/*notes*/ weekly", false},
+		{"Apostrophe", "Don't drop the ball; it's fine", false},
 		{"String with script tag", "<script>alert('xss')</script>", true},
 		{"String with javascript", "javascript:alert('xss')", true},
 		{"Very long string", string(make([]byte, 1500)), true},
@@ -126,6 +134,20 @@ func TestValidateStringValue(t *testing.T) {
 	}
 }
 
+func TestValueValidationIsOptIn(t *testing.T) {
+	if err := NewValidator().ValidateValue("'; DROP TABLE users; --"); err != nil {
+		t.Errorf("Expected values to be accepted without rules, got %v", err)
+	}
+
+	validator := NewValidator().ValidateValues(DenyValuePattern("no_emails", regexp.MustCompile(`@`)))
+	if err := validator.ValidateValue([]interface{}{"ada", "ada@example.com"}); err == nil || !strings.Contains(err.Error(), "no_emails") {
+		t.Errorf("Expected the custom rule to reject the value, got %v", err)
+	}
+	if err := validator.ValidateValue("I select wines"); err != nil {
+		t.Errorf("Expected the value to pass, got %v", err)
+	}
+}
+
 func TestValidateRawSQL(t *testing.T) {
 	validator := NewValidator()
 
@@ -135,6 +157,7 @@ func TestValidateRawSQL(t *testing.T) {
 		shouldErr bool
 	}{
 		{"Valid SELECT", "SELECT * FROM users WHERE age > ?", false},
+		{"Valid SELECT on created_at", "SELECT * FROM users WHERE created_at > ?", false},
 		{"Valid JOIN", "SELECT u.name, p.bio FROM users u JOIN profiles p ON u.id = p.user_id", false},
 		{"Empty SQL", "", true},
 		{"SQL with DROP", "SELECT * FROM users; DROP TABLE users;", true},
@@ -338,6 +361,15 @@ func TestCustomValidationPatterns(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for custom forbidden keyword")
 	}
+
+	// Replacing the forbidden keywords
+	validator.SetForbiddenKeywords()
+	if err := validator.ValidateColumnName("system"); err != nil {
+		t.Errorf("Expected no error without forbidden keywords: %v", err)
+	}
+	if err := validator.ValidateColumnName("name; DROP TABLE users"); err == nil {
+		t.Error("Expected the allowed patterns to still apply")
+	}
 }
 
 func TestStrictModeToggle(t *testing.T) {
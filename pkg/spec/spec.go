@@ -0,0 +1,240 @@
+// Package spec describes queries as plain data, so services can send a query over gRPC or REST and
+// the service owning the data can validate it against a Policy and materialize it into builder
+// calls.
+//
+//	var s spec.QuerySpec
+//	_ = json.Unmarshal(body, &s)
+//	qb, err := s.ApplyTo(querybuilder.QB().Table(s.Table), policy)
+package spec
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/filter"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+// QuerySpec is a serializable description of a SELECT query.
+type QuerySpec struct {
+	Table   string   `json:"table"`
+	Select  []string `json:"select,omitempty"`
+	Filters []Filter `json:"filters,omitempty"`
+	Sorts   []Sort   `json:"sorts,omitempty"`
+	Page    int      `json:"page,omitempty"`     // 1-based; 0 disables pagination
+	PerPage int      `json:"per_page,omitempty"` // defaults to 15 when Page is set
+}
+
+// Filter is one condition. Filters are combined with AND.
+type Filter struct {
+	Field    string          `json:"field"`
+	Operator filter.Operator `json:"op"`
+	Value    interface{}     `json:"value"` // a list for in and nin, a bool for null; like patterns use % and _
+}
+
+// Sort orders by one field.
+type Sort struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction,omitempty"` // asc (default) or desc
+}
+
+// Policy lists what specs may access.
+type Policy struct {
+	Tables     map[string]TablePolicy
+	MaxPerPage int // default 100
+}
+
+// TablePolicy lists the columns of a table specs may select, filter and sort by, and the filter
+// operators they may use (all operators when empty).
+type TablePolicy struct {
+	Columns   []string
+	Operators []filter.Operator
+}
+
+// Validate checks s against policy, reporting violations as types.ErrInvalidParameter.
+func (s QuerySpec) Validate(policy Policy) error {
+	table, ok := policy.Tables[s.Table]
+	if !ok {
+		return fmt.Errorf("%w: table %q is not queryable", types.ErrInvalidParameter, s.Table)
+	}
+
+	for _, column := range s.Select {
+		if !contains(table.Columns, column) {
+			return fmt.Errorf("%w: cannot select %q", types.ErrInvalidParameter, column)
+		}
+	}
+	for _, f := range s.Filters {
+		if err := f.validate(table); err != nil {
+			return err
+		}
+	}
+	for _, sort := range s.Sorts {
+		if !contains(table.Columns, sort.Field) {
+			return fmt.Errorf("%w: cannot sort by %q", types.ErrInvalidParameter, sort.Field)
+		}
+		if _, err := sort.direction(); err != nil {
+			return err
+		}
+	}
+
+	if s.Page < 0 || s.PerPage < 0 {
+		return fmt.Errorf("%w: page and per_page must not be negative", types.ErrInvalidParameter)
+	}
+	maxPerPage := policy.MaxPerPage
+	if maxPerPage < 1 {
+		maxPerPage = 100
+	}
+	if s.PerPage > maxPerPage {
+		return fmt.Errorf("%w: per_page must be at most %d", types.ErrInvalidParameter, maxPerPage)
+	}
+	return nil
+}
+
+func (f Filter) validate(table TablePolicy) error {
+	if !contains(table.Columns, f.Field) {
+		return fmt.Errorf("%w: cannot filter by %q", types.ErrInvalidParameter, f.Field)
+	}
+	if len(table.Operators) > 0 && !containsOperator(table.Operators, f.operator()) {
+		return fmt.Errorf("%w: operator %q is not allowed", types.ErrInvalidParameter, f.operator())
+	}
+	_, err := f.condition()
+	return err
+}
+
+func (f Filter) operator() filter.Operator {
+	if f.Operator == "" {
+		return filter.Eq
+	}
+	return f.Operator
+}
+
+// condition converts f into a filter condition with a bindable value.
+func (f Filter) condition() (filter.Condition, error) {
+	condition := filter.Condition{Field: f.Field, Column: f.Field, Operator: f.operator()}
+
+	switch condition.Operator {
+	case filter.Null:
+		isNull, ok := f.Value.(bool)
+		if !ok {
+			return condition, fmt.Errorf("%w: %s null filter needs a boolean", types.ErrInvalidParameter, f.Field)
+		}
+		condition.Value = isNull
+	case filter.In, filter.NotIn:
+		list, ok := f.Value.([]interface{})
+		if !ok || len(list) == 0 {
+			return condition, fmt.Errorf("%w: %s %s filter needs a non-empty list", types.ErrInvalidParameter, f.Field, condition.Operator)
+		}
+		values := make([]interface{}, len(list))
+		for i, item := range list {
+			value, err := scalar(f.Field, item)
+			if err != nil {
+				return condition, err
+			}
+			values[i] = value
+		}
+		condition.Value = values
+	case filter.Eq, filter.Ne, filter.Gt, filter.Gte, filter.Lt, filter.Lte, filter.Like:
+		value, err := scalar(f.Field, f.Value)
+		if err != nil {
+			return condition, err
+		}
+		condition.Value = value
+	default:
+		return condition, fmt.Errorf("%w: unknown operator %q", types.ErrInvalidParameter, condition.Operator)
+	}
+	return condition, nil
+}
+
+// scalar accepts the scalar values JSON and protobuf Struct decode to, turning integral floats into
+// int64 so ids bind as integers.
+func scalar(field string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	case string, bool, int, int64, int32, float32:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported value %T for %s", types.ErrInvalidParameter, value, field)
+	}
+}
+
+func (s Sort) direction() (types.OrderDirection, error) {
+	switch strings.ToUpper(s.Direction) {
+	case "", string(types.Asc):
+		return types.Asc, nil
+	case string(types.Desc):
+		return types.Desc, nil
+	default:
+		return "", fmt.Errorf("%w: sort direction must be asc or desc", types.ErrInvalidParameter)
+	}
+}
+
+// ApplyTo validates s against policy and adds its selects, filters, sorts and page to qb, which
+// should be a builder for s.Table.
+func (s QuerySpec) ApplyTo(qb types.QueryBuilder, policy Policy) (types.QueryBuilder, error) {
+	if err := s.Validate(policy); err != nil {
+		return nil, err
+	}
+
+	if len(s.Select) > 0 {
+		qb = qb.Select(s.Select...)
+	}
+	for _, f := range s.Filters {
+		condition, err := f.condition()
+		if err != nil {
+			return nil, err
+		}
+		qb = condition.Apply(qb)
+	}
+	for _, sort := range s.Sorts {
+		direction, _ := sort.direction()
+		qb = qb.OrderBy(sort.Field, direction)
+	}
+	if s.Page > 0 {
+		perPage := s.perPage()
+		qb = qb.Limit(perPage).Offset((s.Page - 1) * perPage)
+	}
+	return qb, nil
+}
+
+// Paginate validates s against policy and returns the requested page of qb with pagination
+// metadata. Without a page, the first page is returned.
+func (s QuerySpec) Paginate(ctx context.Context, qb types.QueryBuilder, policy Policy) (types.PaginationResult, error) {
+	page := s.Page
+	s.Page = 0
+	qb, err := s.ApplyTo(qb, policy)
+	if err != nil {
+		return types.PaginationResult{}, err
+	}
+	return qb.Paginate(ctx, max(page, 1), s.perPage())
+}
+
+func (s QuerySpec) perPage() int {
+	if s.PerPage < 1 {
+		return 15
+	}
+	return s.PerPage
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOperator(operators []filter.Operator, op filter.Operator) bool {
+	for _, allowed := range operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
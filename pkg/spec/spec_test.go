@@ -0,0 +1,92 @@
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/omarhamdy49/go-query-builder/pkg/filter"
+	"github.com/omarhamdy49/go-query-builder/pkg/query"
+	"github.com/omarhamdy49/go-query-builder/pkg/querybuildertest"
+	"github.com/omarhamdy49/go-query-builder/pkg/types"
+)
+
+var policy = Policy{
+	Tables: map[string]TablePolicy{
+		"users": {Columns: []string{"id", "name", "status", "age"}},
+	},
+	MaxPerPage: 50,
+}
+
+func TestApplyToMaterializesSpec(t *testing.T) {
+	var s QuerySpec
+	body := `{
+		"table": "users",
+		"select": ["id", "name"],
+		"filters": [
+			{"field": "status", "op": "in", "value": ["active", "pending"]},
+			{"field": "age", "op": "gte", "value": 18}
+		],
+		"sorts": [{"field": "name", "direction": "desc"}],
+		"page": 2,
+		"per_page": 10
+	}`
+	if err := json.Unmarshal([]byte(body), &s); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	qb, err := s.ApplyTo(query.NewBuilder(nil, types.PostgreSQL).From(s.Table), policy)
+	if err != nil {
+		t.Fatalf("ApplyTo failed: %v", err)
+	}
+	sql, bindings, err := qb.ToSQL()
+	if err != nil {
+		t.Fatalf("ToSQL failed: %v", err)
+	}
+
+	want := "SELECT id, name FROM users WHERE status IN ($1, $2) AND age >= $3 ORDER BY name DESC LIMIT 10 OFFSET 10"
+	if sql != want {
+		t.Errorf("Expected %s, got %s", want, sql)
+	}
+	if len(bindings) != 3 || bindings[2] != int64(18) {
+		t.Errorf("Unexpected bindings: %v", bindings)
+	}
+}
+
+func TestValidateRejectsSpecsOutsidePolicy(t *testing.T) {
+	for _, s := range []QuerySpec{
+		{Table: "secrets"},
+		{Table: "users", Select: []string{"password"}},
+		{Table: "users", Filters: []Filter{{Field: "password", Value: "x"}}},
+		{Table: "users", Filters: []Filter{{Field: "id", Operator: "regexp", Value: "x"}}},
+		{Table: "users", Filters: []Filter{{Field: "id", Operator: filter.In, Value: "1"}}},
+		{Table: "users", Filters: []Filter{{Field: "id", Value: map[string]interface{}{"$gt": 1}}}},
+		{Table: "users", Sorts: []Sort{{Field: "id", Direction: "sideways"}}},
+		{Table: "users", PerPage: 500},
+	} {
+		if err := s.Validate(policy); !errors.Is(err, types.ErrInvalidParameter) {
+			t.Errorf("Expected ErrInvalidParameter for %+v, got %v", s, err)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	db := querybuildertest.New(types.MySQL)
+	db.On(querybuildertest.SQLContains("count(")).ReturnValue(int64(30))
+
+	s := QuerySpec{Table: "users", Filters: []Filter{{Field: "status", Value: "active"}}, Page: 3, PerPage: 10}
+	result, err := s.Paginate(context.Background(), db.Table("users"), policy)
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if result.Meta.CurrentPage != 3 || result.Meta.LastPage != 3 {
+		t.Errorf("Unexpected meta: %+v", result.Meta)
+	}
+	for _, q := range db.Queries() {
+		if strings.Count(q.SQL, "LIMIT") > 1 {
+			t.Errorf("Expected a single LIMIT, got %s", q.SQL)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Package sqlformat pretty-prints generated SQL for logs and debug output.
+//
+// Format upper-cases keywords, starts each clause (SELECT, FROM, WHERE, JOIN, ORDER BY, ...) on its
+// own line, puts top-level AND/OR conditions on indented lines and indents subqueries. String
+// literals, quoted identifiers and comments are left untouched. The output is meant for people to
+// read; it is equivalent SQL, but there is no need to execute it.
+package sqlformat
+
+import (
+	"strings"
+)
+
+// Options controls the formatting.
+type Options struct {
+	Indent            string // indentation per level (default two spaces)
+	LowercaseKeywords bool   // keep keywords in lower case instead of upper-casing them
+}
+
+// Format formats sql with the default options.
+func Format(sql string) string {
+	return FormatWithOptions(sql, Options{})
+}
+
+// FormatWithOptions formats sql with the given options.
+func FormatWithOptions(sql string, options Options) string {
+	if options.Indent == "" {
+		options.Indent = "  "
+	}
+
+	f := formatter{options: options}
+	tokens := tokenize(sql)
+	for i, tok := range tokens {
+		f.write(tok, tokens[i+1:])
+	}
+	return strings.TrimSpace(f.out.String())
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	literalToken
+	openToken
+	closeToken
+	commaToken
+	otherToken
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	spaced bool // preceded by whitespace in the input
+}
+
+// tokenize splits sql into words, literals (strings, quoted identifiers, comments, numbers and
+// placeholders), parentheses, commas and other punctuation. Whitespace is only remembered as
+// the spaced flag of the following token.
+func tokenize(sql string) []token {
+	var tokens []token
+	spaced := false
+	add := func(kind tokenKind, text string) {
+		tokens = append(tokens, token{kind: kind, text: text, spaced: spaced})
+		spaced = false
+	}
+
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			spaced = true
+			i++
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := i + 1
+			for end < len(sql) {
+				if sql[end] == ch {
+					if end+1 < len(sql) && sql[end+1] == ch {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(sql))
+			add(literalToken, sql[i:end])
+			i = end
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 4
+			}
+			add(literalToken, sql[i:end])
+			i = end
+		case ch == '(':
+			add(openToken, "(")
+			i++
+		case ch == ')':
+			add(closeToken, ")")
+			i++
+		case ch == ',':
+			add(commaToken, ",")
+			i++
+		case isWordByte(ch):
+			end := i
+			for end < len(sql) && (isWordByte(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			add(wordToken, sql[i:end])
+			i = end
+		default:
+			end := i + 1
+			for end < len(sql) && strings.IndexByte("<>=!|&:+-*/%^~", sql[end]) >= 0 && strings.IndexByte("<>=!|&:", ch) >= 0 {
+				end++
+			}
+			add(otherToken, sql[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch == '?' || ch == '@' ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch >= 0x80
+}
+
+// clauseKeywords start a new line at the current level.
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "JOIN": true,
+	"LEFT": true, "RIGHT": true, "INNER": true, "CROSS": true, "FULL": true, "SET": true,
+	"VALUES": true, "RETURNING": true, "FOR": true, "UPDATE": true, "DELETE": true, "INSERT": true,
+	"WITH": true,
+}
+
+// joinPrefixes may precede JOIN on the same line.
+var joinPrefixes = map[string]bool{"LEFT": true, "RIGHT": true, "INNER": true, "CROSS": true, "FULL": true, "OUTER": true, "NATURAL": true}
+
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true, "CASE": true,
+	"CONFLICT": true, "DESC": true, "DISTINCT": true, "DO": true, "DUPLICATE": true, "ELSE": true,
+	"END": true, "EXISTS": true, "FIRST": true, "IGNORE": true, "ILIKE": true, "IN": true, "INTO": true,
+	"IS": true, "KEY": true, "LAST": true, "LIKE": true, "LOCK": true, "MODE": true, "NOT": true,
+	"NOTHING": true, "NULL": true, "NULLS": true, "ON": true, "OR": true, "OUTER": true, "OVER": true,
+	"PARTITION": true, "RECURSIVE": true, "REPLACE": true, "SHARE": true, "THEN": true, "USING": true,
+	"WHEN": true, "TABLESAMPLE": true, "BERNOULLI": true, "TRUE": true, "FALSE": true, "NATURAL": true,
+}
+
+type formatter struct {
+	options  Options
+	out      strings.Builder
+	level    int    // number of enclosing subqueries
+	parens   []bool // for each open parenthesis, whether it encloses a subquery
+	prevWord string // previous keyword, upper-cased
+	between  bool   // inside BETWEEN ... AND
+}
+
+// inExpression reports whether the innermost open parenthesis is an ordinary expression rather
+// than a subquery, in which case clauses are not broken onto new lines.
+func (f *formatter) inExpression() bool {
+	return len(f.parens) > 0 && !f.parens[len(f.parens)-1]
+}
+
+func (f *formatter) newline(level int) {
+	if f.out.Len() == 0 {
+		return
+	}
+	f.out.WriteByte('\n')
+	f.out.WriteString(strings.Repeat(f.options.Indent, level))
+}
+
+func (f *formatter) space() {
+	s := f.out.String()
+	if len(s) == 0 {
+		return
+	}
+	if last := s[len(s)-1]; last != ' ' && last != '\n' && last != '(' {
+		f.out.WriteByte(' ')
+	}
+}
+
+func (f *formatter) write(tok token, rest []token) {
+	switch tok.kind {
+	case openToken:
+		subquery := len(rest) > 0 && rest[0].kind == wordToken &&
+			(strings.EqualFold(rest[0].text, "SELECT") || strings.EqualFold(rest[0].text, "WITH"))
+		if tok.spaced {
+			f.space()
+		}
+		f.out.WriteByte('(')
+		f.parens = append(f.parens, subquery)
+		if subquery {
+			f.level++
+		}
+		f.prevWord = ""
+		return
+	case closeToken:
+		if len(f.parens) > 0 {
+			if f.parens[len(f.parens)-1] {
+				f.level--
+				f.newline(f.level)
+			}
+			f.parens = f.parens[:len(f.parens)-1]
+		}
+		f.out.WriteByte(')')
+		f.prevWord = ""
+		return
+	case literalToken, otherToken, commaToken:
+		if tok.spaced {
+			f.space()
+		}
+		f.out.WriteString(tok.text)
+		f.prevWord = ""
+		return
+	}
+
+	upper := strings.ToUpper(tok.text)
+	keyword := clauseKeywords[upper] || keywords[upper]
+	isClause := clauseKeywords[upper] && !f.inExpression()
+	switch {
+	case !isClause:
+	case len(rest) > 0 && rest[0].kind == openToken && !rest[0].spaced:
+		isClause = false // function calls such as LEFT(...) and VALUES(...)
+	case upper == "JOIN" && joinPrefixes[f.prevWord]:
+		isClause = false
+	case upper == "UPDATE" && (f.prevWord == "FOR" || f.prevWord == "DO" || f.prevWord == "KEY"):
+		isClause = false
+	}
+	if upper == "ON" && !f.inExpression() && len(rest) > 0 &&
+		(strings.EqualFold(rest[0].text, "DUPLICATE") || strings.EqualFold(rest[0].text, "CONFLICT")) {
+		isClause = true
+	}
+
+	switch {
+	case isClause:
+		f.newline(f.level)
+	case (upper == "AND" || upper == "OR") && !f.inExpression() && !f.between:
+		f.newline(f.level + 1)
+	case tok.spaced:
+		f.space()
+	}
+
+	if upper == "BETWEEN" {
+		f.between = true
+	} else if upper == "AND" {
+		f.between = false
+	}
+
+	switch {
+	case keyword && f.options.LowercaseKeywords:
+		f.out.WriteString(strings.ToLower(tok.text))
+	case keyword:
+		f.out.WriteString(upper)
+	default:
+		f.out.WriteString(tok.text)
+	}
+
+	f.prevWord = ""
+	if keyword {
+		f.prevWord = upper
+	}
+}
@@ -0,0 +1,52 @@
+package sqlformat
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name: "select with join and conditions",
+			sql:  "select u.id, count(*) as total from users u left join orders o on o.user_id = u.id where u.status = ? and (u.age > ? or u.vip = true) and u.created_at between ? and ? group by u.id order by total desc limit 10",
+			expected: "SELECT u.id, count(*) AS total\n" +
+				"FROM users u\n" +
+				"LEFT JOIN orders o ON o.user_id = u.id\n" +
+				"WHERE u.status = ?\n" +
+				"  AND (u.age > ? OR u.vip = TRUE)\n" +
+				"  AND u.created_at BETWEEN ? AND ?\n" +
+				"GROUP BY u.id\n" +
+				"ORDER BY total DESC\n" +
+				"LIMIT 10",
+		},
+		{
+			name: "subquery and literals",
+			sql:  "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders WHERE total > $1) AND name = 'select and from'",
+			expected: "SELECT *\n" +
+				"FROM users\n" +
+				"WHERE id IN (\n" +
+				"  SELECT user_id\n" +
+				"  FROM orders\n" +
+				"  WHERE total > $1\n" +
+				")\n" +
+				"  AND name = 'select and from'",
+		},
+		{
+			name: "upsert",
+			sql:  "INSERT INTO users (a, b) VALUES (?, ?) ON DUPLICATE KEY UPDATE a = VALUES(a)",
+			expected: "INSERT INTO users (a, b)\n" +
+				"VALUES (?, ?)\n" +
+				"ON DUPLICATE KEY UPDATE a = VALUES(a)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.sql); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
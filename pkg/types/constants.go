@@ -12,6 +12,30 @@ const (
 	MySQL Driver = "mysql"
 	// PostgreSQL driver.
 	PostgreSQL Driver = "postgres"
+	// ClickHouse driver, for analytics workloads. It needs the clickhouse-go database/sql driver.
+	ClickHouse Driver = "clickhouse"
+)
+
+// IsolationLevel is a transaction isolation level. Its values match database/sql.IsolationLevel.
+type IsolationLevel int
+
+// Transaction isolation levels.
+const (
+	// IsolationDefault uses the database's default isolation level.
+	IsolationDefault IsolationLevel = iota
+	// ReadUncommitted allows dirty reads.
+	ReadUncommitted
+	// ReadCommitted only sees data committed before each statement.
+	ReadCommitted
+	// WriteCommitted is not supported by MySQL or PostgreSQL.
+	WriteCommitted
+	// RepeatableRead sees a consistent snapshot for the whole transaction.
+	RepeatableRead
+	// Snapshot is not supported by MySQL or PostgreSQL; use RepeatableRead.
+	Snapshot
+	// Serializable behaves as if transactions ran one at a time; conflicting transactions fail with
+	// ErrSerializationFailure and should be retried.
+	Serializable
 )
 
 // Operator represents SQL comparison and logical operators.
@@ -61,6 +85,56 @@ const (
 	OpJSONExtract Operator = "JSON_EXTRACT"
 	// OpFullText represents the MATCH operator.
 	OpFullText Operator = "MATCH"
+	// OpArrayContains represents the PostgreSQL @> array containment operator.
+	OpArrayContains Operator = "@>"
+	// OpArrayOverlaps represents the PostgreSQL && array overlap operator.
+	OpArrayOverlaps Operator = "&&"
+)
+
+// allowedOperators are the operators accepted from callers in conditions.
+var allowedOperators = map[Operator]bool{
+	OpEqual:              true,
+	OpNotEqual:           true,
+	OpGreaterThan:        true,
+	OpGreaterThanOrEqual: true,
+	OpLessThan:           true,
+	OpLessThanOrEqual:    true,
+	OpLike:               true,
+	OpNotLike:            true,
+	OpILike:              true,
+	OpNotILike:           true,
+	OpIn:                 true,
+	OpNotIn:              true,
+	OpBetween:            true,
+	OpNotBetween:         true,
+	OpIsNull:             true,
+	OpIsNotNull:          true,
+	OpExists:             true,
+	OpNotExists:          true,
+	OpJSONContains:       true,
+	OpJSONExtract:        true,
+	OpFullText:           true,
+}
+
+// Allowed reports whether o is an operator conditions accept from callers. Anything else must not
+// be written into SQL.
+func (o Operator) Allowed() bool {
+	return allowedOperators[o]
+}
+
+// FullTextMode represents the search mode used by full-text queries.
+type FullTextMode string
+
+// Full-text search modes.
+const (
+	// FullTextNatural uses MySQL natural language mode or PostgreSQL plainto_tsquery.
+	FullTextNatural FullTextMode = "natural"
+	// FullTextBoolean uses MySQL boolean mode or PostgreSQL to_tsquery operators.
+	FullTextBoolean FullTextMode = "boolean"
+	// FullTextPhrase uses PostgreSQL phraseto_tsquery (natural language mode on MySQL).
+	FullTextPhrase FullTextMode = "phrase"
+	// FullTextWebsearch uses PostgreSQL websearch_to_tsquery (boolean mode on MySQL).
+	FullTextWebsearch FullTextMode = "websearch"
 )
 
 // JoinType represents different types of SQL joins.
@@ -91,6 +165,17 @@ const (
 	Desc OrderDirection = "DESC"
 )
 
+// NullsPosition represents where NULL values are placed in an ordered result.
+type NullsPosition string
+
+// NULL ordering positions.
+const (
+	// NullsFirst places NULL values before non-NULL values.
+	NullsFirst NullsPosition = "NULLS FIRST"
+	// NullsLast places NULL values after non-NULL values.
+	NullsLast NullsPosition = "NULLS LAST"
+)
+
 // LockType represents different types of row locking in SQL.
 type LockType string
 
@@ -0,0 +1,228 @@
+package types
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrReadOnly is returned when a write is attempted through a read-only builder or connection.
+var ErrReadOnly = errors.New("write attempted in read-only mode")
+
+// ErrUnconditionedMutation is returned when an UPDATE or DELETE has no WHERE clause and
+// AllowFullTableMutation was not called.
+var ErrUnconditionedMutation = errors.New("update or delete without a where clause; call AllowFullTableMutation to affect every row")
+
+// ErrTooManyRows is returned when a query returns more rows than allowed by MaxRows.
+var ErrTooManyRows = errors.New("query returned more rows than allowed")
+
+// ErrMaxAffectedExceeded is returned when an UPDATE or DELETE would touch more rows than allowed by MaxAffected.
+var ErrMaxAffectedExceeded = errors.New("mutation would affect more rows than allowed")
+
+// ErrShuttingDown is returned for queries started after Shutdown began draining a connection.
+var ErrShuttingDown = errors.New("connection is shutting down")
+
+// ErrConnectionNotFound is returned when a query names a connection that is not registered.
+var ErrConnectionNotFound = errors.New("connection not found")
+
+// ErrStaleRecord is returned by a versioned Update that matched no row because the version column
+// no longer holds the version the caller read: another writer changed or deleted the record.
+var ErrStaleRecord = errors.New("record was modified since it was read")
+
+// ErrGuardedColumn is returned when an insert or update writes a column its table guards.
+var ErrGuardedColumn = errors.New("column is guarded")
+
+// ErrInvalidIdentifier is returned when an insert or update writes a column whose name is not a
+// plain or table-qualified identifier.
+var ErrInvalidIdentifier = errors.New("invalid column identifier")
+
+// ErrFirewallDenied is returned when a query firewall rule with the deny action matches a statement.
+var ErrFirewallDenied = errors.New("statement denied by the query firewall")
+
+// ErrComplexityBudget is returned for statements whose complexity score exceeds the budget of their
+// connection.
+var ErrComplexityBudget = errors.New("query complexity budget exceeded")
+
+// ErrHoneypot is returned for statements referencing a table or column registered as a honeypot.
+var ErrHoneypot = errors.New("statement references a honeypot")
+
+// ErrMaskedColumns is returned when raw cursor scanning would bypass the masks of a table.
+var ErrMaskedColumns = errors.New("result has masked columns")
+
+// ErrQueueTimeout is returned when a query waited too long for a slot under a concurrency limit.
+var ErrQueueTimeout = errors.New("timed out waiting for a query slot")
+
+// ErrCircuitOpen is returned without contacting the database while a connection's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrRateLimited is returned when a rate limiting hook rejects a query before it reaches the database.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// ErrNotFound is returned when a query that expects a row finds none. It also matches sql.ErrNoRows.
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicateKey is returned when a write violates a unique or primary key constraint.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// ErrForeignKeyViolation is returned when a write violates a foreign key constraint.
+var ErrForeignKeyViolation = errors.New("foreign key violation")
+
+// ErrDeadlock is returned when the database aborted the statement to resolve a deadlock.
+var ErrDeadlock = errors.New("deadlock detected")
+
+// ErrSerializationFailure is returned when a serializable transaction could not be committed
+// because of concurrent changes. The transaction can be retried.
+var ErrSerializationFailure = errors.New("serialization failure")
+
+// ErrTimeout is returned when a statement was cancelled by a timeout, either the context deadline
+// or a server-side statement or lock timeout.
+var ErrTimeout = errors.New("query timed out")
+
+// ErrInvalidParameter is returned when a request parameter bound to a query, such as a page size,
+// sort column or filter, is malformed or not allowed. HTTP handlers should answer 400.
+var ErrInvalidParameter = errors.New("invalid request parameter")
+
+// ErrFailover is returned for statements that failed because the database is failing over, such as
+// writes that reached a demoted primary or a Vitess shard without a serving primary. The statement
+// can be retried; in Aurora mode the connection re-resolves the writer in the background.
+var ErrFailover = errors.New("database failover in progress")
+
+// ErrResourceExhausted is returned when the database refused a statement because it hit a server
+// limit, such as Vitess's row count or transaction pool limits.
+var ErrResourceExhausted = errors.New("database resource limit exceeded")
+
+// ErrUnsupportedFeature is returned when a statement needs SQL the connected server version lacks.
+var ErrUnsupportedFeature = errors.New("feature not supported by the database server")
+
+// DBError is a driver error classified into one of the sentinel errors above. errors.Is matches
+// both the sentinel in Kind and the original driver error.
+type DBError struct {
+	Kind       error  // ErrNotFound, ErrDuplicateKey, ErrForeignKeyViolation, ...
+	Code       string // driver error code: the MySQL error number, PostgreSQL SQLSTATE or Vitess RPC code
+	Constraint string // name of the violated constraint or key, when the driver reports it
+	Table      string // table the error relates to, when the driver reports it
+	Err        error  // the original driver error
+}
+
+// Error implements the error interface.
+func (e *DBError) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%v (constraint %s): %v", e.Kind, e.Constraint, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+// Is reports whether target is the error's Kind.
+func (e *DBError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the original driver error.
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError is returned by FindOrFail when no row has the requested primary key. errors.Is
+// matches both ErrNotFound and sql.ErrNoRows.
+type NotFoundError struct {
+	Table string      // table that was searched
+	Key   string      // primary key column
+	ID    interface{} // the requested ID, after key conversion
+}
+
+// Error implements the error interface.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%v: no %s with %s %v", ErrNotFound, e.Table, e.Key, e.ID)
+}
+
+// Is reports whether target is ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Unwrap returns sql.ErrNoRows.
+func (e *NotFoundError) Unwrap() error {
+	return sql.ErrNoRows
+}
+
+// ItemError describes the failure of a single item in a batch operation.
+type ItemError struct {
+	Index int         // position of the item in the batch, -1 if not applicable
+	Key   interface{} // optional identifier of the item (primary key, connection name, ...)
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *ItemError) Error() string {
+	if e.Key != nil {
+		return fmt.Sprintf("item %d (%v): %v", e.Index, e.Key, e.Err)
+	}
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError collects the per-item failures of a batch operation. It implements Unwrap() []error,
+// so errors.Is and errors.As see every item error.
+type BatchError struct {
+	Op     string
+	Total  int
+	Errors []*ItemError
+}
+
+// NewBatchError creates an empty BatchError for an operation over total items.
+func NewBatchError(op string, total int) *BatchError {
+	return &BatchError{Op: op, Total: total}
+}
+
+// Add records the failure of an item. Nil errors are ignored.
+func (e *BatchError) Add(index int, key interface{}, err error) {
+	if err == nil {
+		return
+	}
+	e.Errors = append(e.Errors, &ItemError{Index: index, Key: key, Err: err})
+}
+
+// HasErrors returns true if at least one item failed.
+func (e *BatchError) HasErrors() bool {
+	return len(e.Errors) > 0
+}
+
+// Failed returns the indexes of the failed items.
+func (e *BatchError) Failed() []int {
+	indexes := make([]int, len(e.Errors))
+	for i, itemErr := range e.Errors {
+		indexes[i] = itemErr.Index
+	}
+	return indexes
+}
+
+// ErrorOrNil returns the BatchError if any item failed, or nil otherwise.
+func (e *BatchError) ErrorOrNil() error {
+	if e == nil || !e.HasErrors() {
+		return nil
+	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, itemErr := range e.Errors {
+		messages[i] = itemErr.Error()
+	}
+	return fmt.Sprintf("%s: %d of %d items failed: %s", e.Op, len(e.Errors), e.Total, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the item errors.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, itemErr := range e.Errors {
+		errs[i] = itemErr
+	}
+	return errs
+}
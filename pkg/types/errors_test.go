@@ -0,0 +1,43 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+var errDuplicate = errors.New("duplicate key")
+
+func TestBatchErrorUnwrap(t *testing.T) {
+	batchErr := NewBatchError("insert", 3)
+	batchErr.Add(0, nil, nil)
+	batchErr.Add(2, "user-3", errDuplicate)
+
+	err := batchErr.ErrorOrNil()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if !errors.Is(err, errDuplicate) {
+		t.Error("Expected errors.Is to find the item error")
+	}
+
+	var itemErr *ItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 2 || itemErr.Key != "user-3" {
+		t.Errorf("Expected item error for index 2, got %+v", itemErr)
+	}
+
+	if failed := batchErr.Failed(); len(failed) != 1 || failed[0] != 2 {
+		t.Errorf("Expected failed indexes [2], got %v", failed)
+	}
+
+	expected := "insert: 1 of 3 items failed: item 2 (user-3): duplicate key"
+	if err.Error() != expected {
+		t.Errorf("Expected message %q, got %q", expected, err.Error())
+	}
+}
+
+func TestBatchErrorOrNil(t *testing.T) {
+	if err := NewBatchError("insert", 2).ErrorOrNil(); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+}
@@ -5,7 +5,10 @@ package types
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"io"
+	"time"
 )
 
 // QueryExecutor defines the interface for executing database queries.
@@ -26,6 +29,21 @@ type Rows interface {
 	Err() error
 }
 
+// ColumnTyper is implemented by Rows that report the database types of their columns, such as
+// *sql.Rows.
+type ColumnTyper interface {
+	ColumnTypes() ([]*sql.ColumnType, error)
+}
+
+// RowColumnTypes returns the column types of rows, or nil if rows does not report them. Wrappers
+// of Rows use it to implement ColumnTyper.
+func RowColumnTypes(rows Rows) ([]*sql.ColumnType, error) {
+	if typer, ok := rows.(ColumnTyper); ok {
+		return typer.ColumnTypes()
+	}
+	return nil, nil
+}
+
 // Row represents a single row result from a query.
 type Row interface {
 	Scan(dest ...interface{}) error
@@ -44,10 +62,24 @@ type Tx interface {
 	Rollback() error
 }
 
+// TwoPhaseTx is a transaction that takes part in a two-phase commit. Once Prepare succeeds, the
+// transaction survives crashes and disconnects, and Commit or Rollback completes it.
+type TwoPhaseTx interface {
+	Tx
+	Prepare(ctx context.Context) error
+	ID() string
+}
+
 // TxOptions holds transaction configuration options.
 type TxOptions struct {
-	Isolation int
+	Isolation IsolationLevel
 	ReadOnly  bool
+
+	// MaxRetries is how many times Builder.TransactionWithOptions re-runs the transaction after it
+	// fails with ErrDeadlock or ErrSerializationFailure. BeginTx ignores it.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each further retry.
+	RetryBackoff time.Duration
 }
 
 // DB represents a database connection.
@@ -64,6 +96,7 @@ type DBStats struct {
 	OpenConnections int
 	InUse           int
 	Idle            int
+	PreparedStmts   int
 }
 
 // QueryBuilder defines the interface for building SQL queries fluently.
@@ -71,7 +104,9 @@ type QueryBuilder interface {
 	From(table string) QueryBuilder
 	Select(columns ...string) QueryBuilder
 	SelectRaw(raw string, bindings ...interface{}) QueryBuilder
+	ClearSelect() QueryBuilder
 	SelectAs(column, alias string) QueryBuilder
+	SelectDistance(column string, lat, lng float64, alias string) QueryBuilder
 	Distinct() QueryBuilder
 	Where(column string, args ...interface{}) QueryBuilder
 	OrWhere(column string, args ...interface{}) QueryBuilder
@@ -79,10 +114,15 @@ type QueryBuilder interface {
 	OrWhereNot(column string, args ...interface{}) QueryBuilder
 	WhereRaw(raw string, bindings ...interface{}) QueryBuilder
 	OrWhereRaw(raw string, bindings ...interface{}) QueryBuilder
+	ClearWhere() QueryBuilder
 	WhereBetween(column string, values []interface{}) QueryBuilder
 	WhereNotBetween(column string, values []interface{}) QueryBuilder
-	WhereIn(column string, values []interface{}) QueryBuilder
-	WhereNotIn(column string, values []interface{}) QueryBuilder
+	WhereBetweenColumns(value interface{}, lowColumn, highColumn string) QueryBuilder
+	WhereDateBetween(column string, from, to interface{}) QueryBuilder
+	WhereIn(column string, values interface{}) QueryBuilder
+	WhereNotIn(column string, values interface{}) QueryBuilder
+	WhereIntegerInRaw(column string, values []int64) QueryBuilder
+	WhereTupleIn(columns []string, tuples [][]interface{}) QueryBuilder
 	WhereNull(column string) QueryBuilder
 	WhereNotNull(column string) QueryBuilder
 	WhereExists(query QueryBuilder) QueryBuilder
@@ -102,6 +142,9 @@ type QueryBuilder interface {
 	WhereNowOrPast(column string) QueryBuilder
 	WhereNowOrFuture(column string) QueryBuilder
 	WhereToday(column string) QueryBuilder
+	WhereWithinLast(column string, d time.Duration) QueryBuilder
+	WhereOlderThan(column string, d time.Duration) QueryBuilder
+	WhereBetweenDates(column string, start, end time.Time) QueryBuilder
 	WhereBeforeToday(column string) QueryBuilder
 	WhereAfterToday(column string) QueryBuilder
 	WhereTodayOrBefore(column string) QueryBuilder
@@ -112,8 +155,16 @@ type QueryBuilder interface {
 	OrWhereJSONLength(column string, args ...interface{}) QueryBuilder
 	WhereJSONPath(column, path string, args ...interface{}) QueryBuilder
 	OrWhereJSONPath(column, path string, args ...interface{}) QueryBuilder
-	WhereFullText(columns []string, value string) QueryBuilder
-	OrWhereFullText(columns []string, value string) QueryBuilder
+	WhereFullText(columns []string, value string, options ...FullTextOptions) QueryBuilder
+	OrWhereFullText(columns []string, value string, options ...FullTextOptions) QueryBuilder
+	WhereLike(column string, pattern string) QueryBuilder
+	WhereILike(column string, pattern string) QueryBuilder
+	WhereStartsWith(column string, value string) QueryBuilder
+	WhereEndsWith(column string, value string) QueryBuilder
+	WhereContains(column string, value string) QueryBuilder
+	WhereArrayContains(column string, values interface{}) QueryBuilder
+	WhereArrayOverlaps(column string, values interface{}) QueryBuilder
+	WhereAnyValue(column string, operator string, value interface{}) QueryBuilder
 	WhereAny(columns []string, args ...interface{}) QueryBuilder
 	OrWhereAny(columns []string, args ...interface{}) QueryBuilder
 	WhereAll(columns []string, args ...interface{}) QueryBuilder
@@ -121,6 +172,7 @@ type QueryBuilder interface {
 	WhereNone(columns []string, args ...interface{}) QueryBuilder
 	OrWhereNone(columns []string, args ...interface{}) QueryBuilder
 	WhereColumn(first, second string, args ...interface{}) QueryBuilder
+	WhereDistanceWithin(column string, lat, lng, meters float64) QueryBuilder
 	OrWhereColumn(first, second string, args ...interface{}) QueryBuilder
 	Join(table, first string, args ...interface{}) QueryBuilder
 	LeftJoin(table, first string, args ...interface{}) QueryBuilder
@@ -129,29 +181,64 @@ type QueryBuilder interface {
 	OrderBy(column string, direction ...OrderDirection) QueryBuilder
 	OrderByDesc(column string) QueryBuilder
 	OrderByRaw(raw string) QueryBuilder
+	Latest(column ...string) QueryBuilder
+	Oldest(column ...string) QueryBuilder
+	Reorder() QueryBuilder
+	OrderByNullsFirst(column string, direction ...OrderDirection) QueryBuilder
+	OrderByNullsLast(column string, direction ...OrderDirection) QueryBuilder
+	OrderByMany(orders []OrderSpec) QueryBuilder
+	OrderByRelevance(columns []string, term string, options ...FullTextOptions) QueryBuilder
+	OrderByDistance(column string, lat, lng float64, direction ...OrderDirection) QueryBuilder
 	GroupBy(columns ...string) QueryBuilder
 	GroupByRaw(raw string) QueryBuilder
 	Having(column string, args ...interface{}) QueryBuilder
 	OrHaving(column string, args ...interface{}) QueryBuilder
-	HavingRaw(raw string) QueryBuilder
-	OrHavingRaw(raw string) QueryBuilder
+	HavingRaw(raw string, bindings ...interface{}) QueryBuilder
+	OrHavingRaw(raw string, bindings ...interface{}) QueryBuilder
+	HavingCount(args ...interface{}) QueryBuilder
+	HavingSum(column string, args ...interface{}) QueryBuilder
+	HavingAvg(column string, args ...interface{}) QueryBuilder
+	HavingMin(column string, args ...interface{}) QueryBuilder
+	HavingMax(column string, args ...interface{}) QueryBuilder
 	Limit(limit int) QueryBuilder
 	Offset(offset int) QueryBuilder
+	ClearLimit() QueryBuilder
+	InRandomOrder() QueryBuilder
+	Sample(n int) QueryBuilder
+	SamplePercent(percent float64) QueryBuilder
+	Final() QueryBuilder
 	Take(limit int) QueryBuilder
 	Skip(offset int) QueryBuilder
 	Union(query QueryBuilder) QueryBuilder
 	UnionAll(query QueryBuilder) QueryBuilder
+	UnionOrderBy(column string, direction ...OrderDirection) QueryBuilder
+	Intersect(query QueryBuilder) QueryBuilder
+	Except(query QueryBuilder) QueryBuilder
 	ForUpdate() QueryBuilder
 	ForShare() QueryBuilder
 	When(condition bool, callback ConditionalFunc) QueryBuilder
 	Unless(condition bool, callback ConditionalFunc) QueryBuilder
 	Tap(callback ConditionalFunc) QueryBuilder
 	Scope(scopes ...ScopeFunc) QueryBuilder
+	UseScope(name string, args ...interface{}) QueryBuilder
+	WithoutPolicies() QueryBuilder
+	Call(name string, args ...interface{}) QueryBuilder
+	ReadOnly() QueryBuilder
+	Comment(tags string) QueryBuilder
+	AllowFullTableMutation() QueryBuilder
+	MaxAffected(n int) QueryBuilder
+	WithVersioning(column string) QueryBuilder
+	MaxRows(n int) QueryBuilder
+	MaxRowsTruncate(n int) QueryBuilder
 	Debug() QueryBuilder
+	GetDebugInfo() *DebugInfo
 	ToSQL() (string, []interface{}, error)
+	ToRawSQL() (string, error)
 	Get(ctx context.Context) (Collection, error)
 	First(ctx context.Context) (map[string]interface{}, error)
 	Find(ctx context.Context, id interface{}) (map[string]interface{}, error)
+	FindOrFail(ctx context.Context, id interface{}) (map[string]interface{}, error)
+	FindMany(ctx context.Context, ids interface{}) (Collection, error)
 	Pluck(ctx context.Context, column string) ([]interface{}, error)
 	Count(ctx context.Context) (int64, error)
 	Sum(ctx context.Context, column string) (interface{}, error)
@@ -160,8 +247,24 @@ type QueryBuilder interface {
 	Max(ctx context.Context, column string) (interface{}, error)
 	Insert(ctx context.Context, values map[string]interface{}) error
 	InsertBatch(ctx context.Context, values []map[string]interface{}) error
+	InsertBatchWithOptions(ctx context.Context, values []map[string]interface{}, options BulkInsertOptions) error
+	InsertBatchPartial(ctx context.Context, values []map[string]interface{}) (int64, error)
+	InsertUsing(ctx context.Context, columns []string, sub QueryBuilder) (int64, error)
+	Upsert(ctx context.Context, values []map[string]interface{}, options UpsertOptions) (*UpsertResult, error)
+	ImportCSV(ctx context.Context, r io.Reader, options ...ImportOptions) (*ImportReport, error)
+	ImportNDJSON(ctx context.Context, r io.Reader, options ...ImportOptions) (*ImportReport, error)
 	Update(ctx context.Context, values map[string]interface{}) (int64, error)
+	UpdateBatch(ctx context.Context, rows []map[string]interface{}) (int64, error)
 	Delete(ctx context.Context) (int64, error)
+	WithTrashed() QueryBuilder
+	OnlyTrashed() QueryBuilder
+	ForceDelete(ctx context.Context) (int64, error)
+	Restore(ctx context.Context) (int64, error)
+	ExportCSV(ctx context.Context, w io.Writer, options ...ExportOptions) error
+	ExportNDJSON(ctx context.Context, w io.Writer, options ...ExportOptions) error
+	Stream(ctx context.Context, bufferSize ...int) (<-chan map[string]interface{}, <-chan error)
+	SnapshotChunk(ctx context.Context, size int, callback ChunkFunc) error
+	ServerCursorChunk(ctx context.Context, size int, callback ChunkFunc) error
 	Paginate(ctx context.Context, page int, perPage int) (PaginationResult, error)
 	SimplePaginate(ctx context.Context, page int, perPage int) (PaginationResult, error)
 	// Async methods
@@ -169,6 +272,8 @@ type QueryBuilder interface {
 	CountAsync(ctx context.Context) <-chan AsyncCountResult
 	PaginateAsync(ctx context.Context, page int, perPage int) <-chan AsyncPaginationResult
 	Clone() QueryBuilder
+	Immutable() QueryBuilder
+	IsImmutable() bool
 }
 
 // ConditionalFunc represents a function that can conditionally modify a query builder.
@@ -177,12 +282,47 @@ type ConditionalFunc func(QueryBuilder) QueryBuilder
 // ScopeFunc represents a function that applies a scope to a query builder.
 type ScopeFunc func(QueryBuilder) QueryBuilder
 
+// NamedScopeFunc represents a parameterized scope registered for a table with RegisterScope.
+type NamedScopeFunc func(qb QueryBuilder, args ...interface{}) QueryBuilder
+
+// PolicyFunc represents a row-level security policy registered for a table with RegisterPolicy. It
+// adds the Where clauses every query on the table must satisfy for ctx, or returns an error to
+// refuse the query, e.g. when ctx carries no user.
+type PolicyFunc func(ctx context.Context, qb QueryBuilder) (QueryBuilder, error)
+
+// MacroFunc represents a named, reusable query fragment registered with Macro.
+type MacroFunc func(qb QueryBuilder, args ...interface{}) QueryBuilder
+
 // ChunkFunc represents a function that processes data chunks.
 type ChunkFunc func(Collection) error
 
 // LazyFunc represents a function for lazy data processing.
 type LazyFunc func(map[string]interface{}) error
 
+// QueryHook is called before every statement the executor sends; returning an error cancels it.
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, event QueryEvent) error
+}
+
+// QueryHookFunc adapts an ordinary function to the QueryHook interface.
+type QueryHookFunc func(ctx context.Context, event QueryEvent) error
+
+// BeforeQuery calls f(ctx, event).
+func (f QueryHookFunc) BeforeQuery(ctx context.Context, event QueryEvent) error {
+	return f(ctx, event)
+}
+
+// QueryObserver is implemented by hooks that also want a record of every finished statement, with
+// its duration and row count. Bindings are not redacted.
+type QueryObserver interface {
+	AfterQuery(ctx context.Context, entry QueryLog)
+}
+
+// Logger receives a record of every statement the executor runs.
+type Logger interface {
+	LogQuery(ctx context.Context, entry QueryLog)
+}
+
 // AsyncResult holds the result of an asynchronous operation.
 type AsyncResult struct {
 	Data  Collection
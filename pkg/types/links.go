@@ -0,0 +1,113 @@
+package types
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// DefaultLinksOnEachSide is the number of pages Links shows on each side of the current page.
+const DefaultLinksOnEachSide = 3
+
+// PaginationLinks holds the URLs of a paginated result. Prev and Next are empty on the first and
+// last page.
+type PaginationLinks struct {
+	First string     `json:"first"`
+	Last  string     `json:"last"`
+	Prev  string     `json:"prev"`
+	Next  string     `json:"next"`
+	Pages []PageLink `json:"pages"`
+}
+
+// PageLink is one entry of the page window. Gaps between the window and the first or last pages
+// are entries with Label "..." and no URL.
+type PageLink struct {
+	Page   int    `json:"page,omitempty"`
+	Label  string `json:"label"`
+	URL    string `json:"url,omitempty"`
+	Active bool   `json:"active"`
+}
+
+// Links returns first/prev/next/last URLs and a window of page links around the current page, like
+// Laravel's links(). The page number is set as the "page" query parameter of baseURL; other
+// parameters of baseURL are kept.
+func (p PaginationResult) Links(baseURL string) PaginationLinks {
+	return p.LinksWithWindow(baseURL, DefaultLinksOnEachSide)
+}
+
+// LinksWithWindow is Links with onEachSide pages shown on each side of the current page.
+func (p PaginationResult) LinksWithWindow(baseURL string, onEachSide int) PaginationLinks {
+	current := max(p.Meta.CurrentPage, 1)
+	last := max(p.Meta.LastPage, 1)
+
+	links := PaginationLinks{
+		First: pageURL(baseURL, 1),
+		Last:  pageURL(baseURL, last),
+	}
+	if current > 1 {
+		links.Prev = pageURL(baseURL, min(current-1, last))
+	}
+	if current < last {
+		links.Next = pageURL(baseURL, current+1)
+	}
+
+	for _, page := range pageWindow(current, last, max(onEachSide, 0)) {
+		if page == 0 {
+			links.Pages = append(links.Pages, PageLink{Label: "..."})
+			continue
+		}
+		links.Pages = append(links.Pages, PageLink{
+			Page:   page,
+			Label:  strconv.Itoa(page),
+			URL:    pageURL(baseURL, page),
+			Active: page == current,
+		})
+	}
+	return links
+}
+
+// pageWindow returns the pages to link, with 0 marking a gap. The first and last two pages are
+// always shown; everything is shown when the window would not save any links.
+func pageWindow(current, last, onEachSide int) []int {
+	window := onEachSide * 2
+	if last < window+8 {
+		return pageRange(1, last)
+	}
+
+	var start, end int
+	switch {
+	case current <= window:
+		start, end = 1, window+2
+	case current > last-window:
+		start, end = last-(window+1), last
+	default:
+		start, end = current-onEachSide, current+onEachSide
+	}
+
+	pages := pageRange(start, end)
+	if start > 1 {
+		pages = append([]int{1, 2, 0}, pages...)
+	}
+	if end < last {
+		pages = append(pages, 0, last-1, last)
+	}
+	return pages
+}
+
+func pageRange(from, to int) []int {
+	pages := make([]int, 0, to-from+1)
+	for page := from; page <= to; page++ {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func pageURL(baseURL string, page int) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL + "?page=" + strconv.Itoa(page)
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
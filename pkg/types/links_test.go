@@ -0,0 +1,63 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func paginationAt(current, last int) PaginationResult {
+	return PaginationResult{Meta: PaginationMeta{CurrentPage: current, LastPage: last}}
+}
+
+func pageLabels(links PaginationLinks) []string {
+	labels := make([]string, len(links.Pages))
+	for i, link := range links.Pages {
+		labels[i] = link.Label
+	}
+	return labels
+}
+
+func TestLinksFirstPrevNextLast(t *testing.T) {
+	links := paginationAt(2, 5).Links("https://api.example.com/users?status=active")
+
+	if links.First != "https://api.example.com/users?page=1&status=active" {
+		t.Errorf("Unexpected first URL: %s", links.First)
+	}
+	if links.Prev != "https://api.example.com/users?page=1&status=active" {
+		t.Errorf("Unexpected prev URL: %s", links.Prev)
+	}
+	if links.Next != "https://api.example.com/users?page=3&status=active" {
+		t.Errorf("Unexpected next URL: %s", links.Next)
+	}
+	if links.Last != "https://api.example.com/users?page=5&status=active" {
+		t.Errorf("Unexpected last URL: %s", links.Last)
+	}
+	if len(links.Pages) != 5 || !links.Pages[1].Active {
+		t.Errorf("Expected 5 pages with page 2 active, got %+v", links.Pages)
+	}
+}
+
+func TestLinksOmitPrevAndNextAtEnds(t *testing.T) {
+	if links := paginationAt(1, 1).Links("/users"); links.Prev != "" || links.Next != "" {
+		t.Errorf("Expected no prev/next on a single page, got %q and %q", links.Prev, links.Next)
+	}
+	if links := paginationAt(0, 0).Links("/users"); links.Last != "/users?page=1" {
+		t.Errorf("Expected an empty result to link to page 1, got %q", links.Last)
+	}
+}
+
+func TestLinksWindow(t *testing.T) {
+	tests := []struct {
+		current int
+		want    []string
+	}{
+		{1, []string{"1", "2", "3", "4", "5", "6", "7", "8", "...", "49", "50"}},
+		{25, []string{"1", "2", "...", "22", "23", "24", "25", "26", "27", "28", "...", "49", "50"}},
+		{50, []string{"1", "2", "...", "43", "44", "45", "46", "47", "48", "49", "50"}},
+	}
+	for _, tt := range tests {
+		if got := pageLabels(paginationAt(tt.current, 50).Links("/users")); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Page %d: expected %v, got %v", tt.current, tt.want, got)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package types
 
 import (
+	"context"
 	"time"
 )
 
@@ -18,13 +19,52 @@ type Config struct {
 	Password        string        `json:"password"`
 	SSLMode         string        `json:"ssl_mode"`
 	Charset         string        `json:"charset"`
-	Timezone        string        `json:"timezone"`
+	Timezone        string        `json:"timezone"`        // session time zone, UTC by default
+	NormalizeTimes  bool          `json:"normalize_times"` // convert time.Time bindings to Timezone
 	MaxOpenConns    int           `json:"max_open_conns"`
 	MaxIdleConns    int           `json:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `json:"conn_max_lifetime"`
 	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time"`
+	ReadOnly        bool          `json:"read_only"`
+	StmtCacheSize   int           `json:"stmt_cache_size"` // prepared statements kept per connection; 0 disables the cache
+	TLSCACert       string        `json:"tls_ca_cert"`     // path to a PEM CA bundle used to verify the server
+	TLSCert         string        `json:"tls_cert"`        // path to a PEM client certificate for mutual TLS
+	TLSKey          string        `json:"tls_key"`         // path to the PEM key of TLSCert
+	TLSServerName   string        `json:"tls_server_name"` // expected server name, defaults to Host
+	TLSSkipVerify   bool          `json:"tls_skip_verify"`
+
+	MaxConcurrentQueries int           `json:"max_concurrent_queries"` // 0 means unlimited
+	QueueTimeout         time.Duration `json:"queue_timeout"`          // how long a query waits for a slot
+
+	CircuitBreaker CircuitBreakerOptions `json:"circuit_breaker"`
+
+	ComplexityBudget ComplexityBudget `json:"complexity_budget"`
+
+	Aurora AuroraOptions `json:"aurora"`
+	Vitess VitessOptions `json:"vitess"`
+
+	// PasswordProvider, when set, is called for every new physical connection instead of using
+	// Password, so short-lived tokens (RDS IAM, Cloud SQL IAM) are refreshed as connections are made.
+	PasswordProvider PasswordProvider `json:"-"`
+
+	// CredentialsProvider is like PasswordProvider but also supplies the username, for sources
+	// that issue dynamic users (e.g. Vault's database secrets engine). It takes precedence.
+	CredentialsProvider CredentialsProvider `json:"-"`
 }
 
+// PasswordProvider returns the password to use for a new database connection.
+type PasswordProvider func(ctx context.Context) (string, error)
+
+// Credentials is a username and password pair, optionally with the time it stops being valid.
+type Credentials struct {
+	Username  string
+	Password  string
+	ExpiresAt time.Time
+}
+
+// CredentialsProvider returns the credentials to use for a new database connection.
+type CredentialsProvider func(ctx context.Context) (Credentials, error)
+
 // PaginationResult represents the result of a paginated query.
 type PaginationResult struct {
 	Data Collection     `json:"data"`
@@ -123,12 +163,96 @@ func NewDateHelper(column string, value time.Time) DateHelper {
 	return DateHelper{Column: column, Value: value}
 }
 
+// KeyType is the type of a table's primary key, used to convert IDs passed to Find.
+type KeyType int
+
+const (
+	// AnyKey passes IDs to the database unchanged.
+	AnyKey KeyType = iota
+	// IntKey parses string IDs, such as URL parameters, as integers.
+	IntKey
+	// StringKey formats IDs as strings.
+	StringKey
+	// UUIDKey accepts UUID strings, in any case and with or without hyphens, and 16-byte arrays,
+	// and binds them in the canonical lowercase hyphenated form.
+	UUIDKey
+)
+
+// Cast names the Go type a column is converted to when rows are read.
+type Cast string
+
+const (
+	// CastBool reads 0/1 and "true"/"false" values, such as MySQL TINYINT(1), as bool.
+	CastBool Cast = "bool"
+	// CastInt reads integers, including numeric strings, as int64.
+	CastInt Cast = "int"
+	// CastFloat reads numbers, including numeric strings, as float64.
+	CastFloat Cast = "float"
+	// CastDecimal reads numeric and DECIMAL columns exactly, as *big.Rat.
+	CastDecimal Cast = "decimal"
+	// CastTime reads DATETIME, TIMESTAMP and DATE strings as time.Time in UTC.
+	CastTime Cast = "time"
+	// CastJSON decodes JSON documents, objects becoming map[string]interface{}.
+	CastJSON Cast = "json"
+)
+
+// MaskFunc redacts the value of a sensitive column, such as a card number, for callers whose
+// context does not carry the unmask capability. NULL values are not passed to it.
+type MaskFunc func(value interface{}) interface{}
+
+// CastFunc converts a scanned, non-NULL column value. Byte slices have already become strings.
+type CastFunc func(value interface{}) (interface{}, error)
+
+// TableMeta describes a table to the features that would otherwise assume conventional column
+// names. Register it once at startup; empty fields keep the conventions.
+type TableMeta struct {
+	PrimaryKey  string   // key column used by Find, Chunk and cursor pagination (default "id")
+	PrimaryKeys []string // columns of a composite key, in order; replaces PrimaryKey where keys are matched
+	KeyType     KeyType  // how Find converts IDs
+	SoftDelete  string   // nullable timestamp column; when set, Delete stamps it instead of removing rows
+	CreatedAt   string   // column stamped by inserts and ordered by Latest/Oldest (default "created_at")
+	UpdatedAt   string   // column stamped by inserts and updates
+	Guarded     []string // columns inserts and updates refuse to write, failing with ErrGuardedColumn
+
+	// Casts converts the named columns of rows read from the table, which otherwise come back as
+	// the driver returns them (often strings).
+	Casts map[string]Cast
+
+	// Masks redacts the named columns of rows read without the unmask capability; a nil MaskFunc
+	// drops the column from the row.
+	Masks map[string]MaskFunc
+}
+
 // UpsertOptions configures upsert operations.
 type UpsertOptions struct {
 	Columns        []string
 	UpdateColumns  []string
 	ConflictTarget []string
 	ConflictAction ConflictAction
+	Returning      []string // PostgreSQL only: columns of the inserted and updated rows to return, or "*"
+	BatchSize      int      // rows per statement (default 1000, lowered to fit the bind parameter limit)
+
+	// UpdateExpressions sets the named columns of conflicting rows to an expression instead of the
+	// inserted value, e.g. "hits": Raw("events.hits + EXCLUDED.hits").
+	UpdateExpressions map[string]Expression
+	// UpdateWhere limits the update to the conflicting rows that satisfy it, leaving the others
+	// untouched, e.g. Raw("EXCLUDED.updated_at > events.updated_at"). PostgreSQL only.
+	UpdateWhere Expression
+}
+
+// UpsertResult reports what an upsert did.
+type UpsertResult struct {
+	Affected int64      // rows inserted or updated; multi-row MySQL upserts count updated rows twice
+	Inserted int64      // rows inserted, or -1 when the driver cannot tell
+	Updated  int64      // rows updated, or -1 when the driver cannot tell
+	Rows     Collection // the Returning columns of the inserted and updated rows, nil without Returning
+}
+
+// FullTextOptions configures full-text search clauses.
+type FullTextOptions struct {
+	Mode           FullTextMode
+	QueryExpansion bool   // MySQL only: adds WITH QUERY EXPANSION in natural language mode
+	Language       string // PostgreSQL only: text search configuration, e.g. "english"
 }
 
 // BulkInsertOptions configures bulk insert operations.
@@ -136,6 +260,162 @@ type BulkInsertOptions struct {
 	BatchSize      int
 	IgnoreErrors   bool
 	OnDuplicateKey string
+	UseCopy        bool // PostgreSQL only: load rows with the COPY protocol when the connection supports it
+}
+
+// ExportOptions configures ExportCSV and ExportNDJSON.
+type ExportOptions struct {
+	NoHeader   bool   // CSV: omit the header row of column names
+	Delimiter  rune   // CSV: field delimiter (default ',')
+	Null       string // CSV: text written for NULL values (default empty)
+	TimeFormat string // layout for time values (default time.RFC3339Nano)
+}
+
+// ImportOptions configures ImportCSV and ImportNDJSON.
+type ImportOptions struct {
+	Columns     map[string]string                      // maps file fields to table columns; other fields are skipped. Nil uses field names as columns
+	Delimiter   rune                                   // CSV: field delimiter (default ',')
+	NullValues  []string                               // CSV: field values read as NULL, e.g. "" or "NULL"
+	Validate    func(row map[string]interface{}) error // rejects a row when it returns an error
+	BatchSize   int                                    // rows per insert statement or COPY (default 1000)
+	MaxRejected int                                    // abort after this many rejected rows (0 for no limit)
+}
+
+// ImportReport describes the outcome of an import.
+type ImportReport struct {
+	Inserted int64
+	Rejected []RejectedRow
+}
+
+// RejectedRow is an input row that was not imported.
+type RejectedRow struct {
+	Line int // line number in the input, starting at 1
+	Err  error
+}
+
+// Expression is a raw SQL fragment with its own bindings, used as a value in Update to set a column
+// to something other than a bound parameter (e.g. "count + ?").
+type Expression struct {
+	SQL      string
+	Bindings []interface{}
+}
+
+// Raw creates an Expression from SQL using ? placeholders and their bindings.
+func Raw(sql string, bindings ...interface{}) Expression {
+	return Expression{SQL: sql, Bindings: bindings}
+}
+
+// OrderSpec describes a single ORDER BY column for OrderByMany.
+type OrderSpec struct {
+	Column    string
+	Direction OrderDirection
+	Nulls     NullsPosition
+}
+
+// HealthCheckOptions configures the background connection health checker.
+type HealthCheckOptions struct {
+	Interval   time.Duration // time between pings (default 30s)
+	Timeout    time.Duration // per-ping timeout (default 5s)
+	MinBackoff time.Duration // delay before the first reconnect attempt (default 1s)
+	MaxBackoff time.Duration // cap for the exponential reconnect backoff (default 1m)
+}
+
+// CircuitBreakerOptions configures the per-connection circuit breaker. It is disabled while
+// FailureRate is zero.
+type CircuitBreakerOptions struct {
+	FailureRate   float64       `json:"failure_rate"`   // share of failed or slow calls that opens the circuit (0-1)
+	SlowThreshold time.Duration `json:"slow_threshold"` // calls slower than this count as failures (0 disables)
+	MinRequests   int           `json:"min_requests"`   // calls in the window before the rate is evaluated (default 20)
+	Window        time.Duration `json:"window"`         // length of the window the rate is measured over (default 10s)
+	OpenTimeout   time.Duration `json:"open_timeout"`   // time the circuit stays open before probing (default 30s)
+}
+
+// ComplexityBudget caps the complexity score (see security.Complexity) of the statements built on
+// a connection. It is disabled while Max is zero.
+type ComplexityBudget struct {
+	Max  int        `json:"max"`
+	Mode BudgetMode `json:"mode"` // BudgetReject (the default) or BudgetLog
+}
+
+// BudgetMode is what happens to statements over a ComplexityBudget.
+type BudgetMode string
+
+// Budget modes.
+const (
+	BudgetReject BudgetMode = "reject" // fail the statement with ErrComplexityBudget
+	BudgetLog    BudgetMode = "log"    // run the statement and log a warning
+)
+
+// AuroraOptions enables Amazon Aurora failover handling. It is disabled while Enabled is false.
+type AuroraOptions struct {
+	Enabled         bool           `json:"enabled"`
+	Endpoint        AuroraEndpoint `json:"endpoint"`         // endpoint kind, detected from Host when empty (set it for CNAMEs)
+	FailoverTimeout time.Duration  `json:"failover_timeout"` // how long to keep re-resolving the writer after a failover (default 60s)
+	PollInterval    time.Duration  `json:"poll_interval"`    // delay between attempts to reach the new writer (default 1s)
+}
+
+// VitessOptions enables the Vitess (and PlanetScale) compatibility profile for a MySQL connection.
+type VitessOptions struct {
+	Enabled bool `json:"enabled"`
+	// Target routes the connection's queries to a tablet type, e.g. "@primary", "@replica" or
+	// "@rdonly". It is appended to Database, so "commerce" with "@replica" connects to
+	// "commerce@replica".
+	Target string `json:"target"`
+}
+
+// AuroraEndpoint is the kind of Aurora endpoint a connection's host names.
+type AuroraEndpoint string
+
+// Aurora endpoint kinds.
+const (
+	AuroraWriter   AuroraEndpoint = "writer"   // cluster endpoint, <cluster>.cluster-<id>.<region>.rds.amazonaws.com
+	AuroraReader   AuroraEndpoint = "reader"   // reader endpoint, <cluster>.cluster-ro-<id>...
+	AuroraCustom   AuroraEndpoint = "custom"   // custom endpoint, <name>.cluster-custom-<id>...
+	AuroraInstance AuroraEndpoint = "instance" // a single DB instance
+)
+
+// CircuitState is the state of a connection's circuit breaker.
+type CircuitState string
+
+// Circuit breaker states.
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// HealthStatus reports the last known health of a named connection.
+type HealthStatus struct {
+	Healthy             bool          `json:"healthy"`
+	LastCheck           time.Time     `json:"last_check"`
+	LastError           string        `json:"last_error,omitempty"`
+	Latency             time.Duration `json:"latency"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	Reconnects          int           `json:"reconnects"`
+	NextReconnect       time.Time     `json:"next_reconnect,omitempty"`
+}
+
+// QueryEvent describes a statement about to be sent to the database.
+type QueryEvent struct {
+	Table     string
+	Operation string // lower-case leading keyword: select, insert, update, delete, ...
+	SQL       string
+	Bindings  []interface{}
+}
+
+// QueryLog describes an executed statement for a Logger. Bindings of sensitive columns are
+// already redacted.
+type QueryLog struct {
+	Table    string
+	SQL      string
+	Bindings []interface{}
+	Duration time.Duration
+	Rows     int64 // rows read or affected, -1 when unknown
+	Err      error
+
+	// Truncated reports that MaxRowsTruncate dropped the rows of a result past Rows. Such an entry
+	// comes in addition to the statement's own and leaves SQL and Bindings empty.
+	Truncated bool
 }
 
 // ChunkOptions configures chunk processing.
@@ -156,6 +436,8 @@ type DebugInfo struct {
 	Bindings []interface{} `json:"bindings"`
 	Duration time.Duration `json:"duration"`
 	Driver   Driver        `json:"driver"`
+	Rows     int64         `json:"rows"`            // rows read or affected, -1 when unknown or not executed
+	Error    string        `json:"error,omitempty"` // error returned by the database, if any
 }
 
 // CollectionImpl implements the Collection interface.
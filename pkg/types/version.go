@@ -0,0 +1,176 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ServerVersion is the version of the database server a connection talks to, detected on connect.
+// The zero value means the version is unknown.
+type ServerVersion struct {
+	Driver  Driver
+	MariaDB bool
+	Vitess  bool // Vitess or PlanetScale, which report a MySQL version
+	Major   int
+	Minor   int
+	Patch   int
+	Raw     string // as reported by the server, e.g. "10.5.8-MariaDB-1:10.5.8+maria~focal"
+}
+
+// Feature is SQL syntax that only some servers support.
+type Feature string
+
+// Version-gated features.
+const (
+	// FeatureCTE is WITH common table expressions (MySQL 8.0, MariaDB 10.2).
+	FeatureCTE Feature = "common table expressions"
+	// FeatureWindowFunctions is OVER (...) window functions (MySQL 8.0, MariaDB 10.2).
+	FeatureWindowFunctions Feature = "window functions"
+	// FeatureReturning is INSERT/DELETE ... RETURNING (PostgreSQL, MariaDB 10.5).
+	FeatureReturning Feature = "RETURNING"
+	// FeatureInsertAlias is INSERT ... AS alias ON DUPLICATE KEY UPDATE (MySQL 8.0.20), which
+	// replaces the deprecated VALUES() function.
+	FeatureInsertAlias Feature = "INSERT ... AS alias"
+	// FeatureTwoPhaseCommit is XA or PREPARE TRANSACTION (every server except Vitess and ClickHouse).
+	FeatureTwoPhaseCommit Feature = "two-phase commit"
+	// FeatureIntersectExcept is the INTERSECT and EXCEPT set operators (MySQL 8.0.31, MariaDB 10.3,
+	// ClickHouse 21.8).
+	FeatureIntersectExcept Feature = "INTERSECT and EXCEPT"
+)
+
+// ParseServerVersion parses the output of SELECT VERSION() (or version() on PostgreSQL and
+// ClickHouse). Unparseable input gives a version with only Driver and Raw set.
+func ParseServerVersion(driver Driver, raw string) ServerVersion {
+	v := ServerVersion{Driver: driver, Raw: raw}
+	text := strings.TrimPrefix(strings.TrimSpace(raw), "PostgreSQL ")
+	// MariaDB reports itself as 5.5.5-<version> to old MySQL clients.
+	text = strings.TrimPrefix(text, "5.5.5-")
+	lower := strings.ToLower(raw)
+	v.MariaDB = driver == MySQL && strings.Contains(lower, "mariadb")
+	v.Vitess = driver == MySQL && (strings.Contains(lower, "vitess") || strings.Contains(lower, "planetscale"))
+
+	end := strings.IndexFunc(text, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	if end >= 0 {
+		text = text[:end]
+	}
+	parts := strings.Split(text, ".")
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i := 0; i < len(parts) && i < len(numbers); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			break
+		}
+		*numbers[i] = n
+	}
+	return v
+}
+
+// Known reports whether the version was detected.
+func (v ServerVersion) Known() bool {
+	return v.Major > 0
+}
+
+// AtLeast reports whether the version is major.minor.patch or later.
+func (v ServerVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// String returns the product and version, e.g. "MariaDB 10.5.8".
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%s %d.%d.%d", v.product(), v.Major, v.Minor, v.Patch)
+}
+
+func (v ServerVersion) product() string {
+	switch {
+	case v.MariaDB:
+		return "MariaDB"
+	case v.Vitess:
+		return "Vitess"
+	case v.Driver == MySQL:
+		return "MySQL"
+	case v.Driver == PostgreSQL:
+		return "PostgreSQL"
+	case v.Driver == ClickHouse:
+		return "ClickHouse"
+	default:
+		return string(v.Driver)
+	}
+}
+
+// minimum returns the first version supporting feature, or ok=false if the server never does.
+func (v ServerVersion) minimum(feature Feature) (version [3]int, ok bool) {
+	switch {
+	case v.MariaDB:
+		switch feature {
+		case FeatureCTE, FeatureWindowFunctions:
+			return [3]int{10, 2, 0}, true
+		case FeatureReturning:
+			return [3]int{10, 5, 0}, true
+		case FeatureIntersectExcept:
+			return [3]int{10, 3, 0}, true
+		case FeatureTwoPhaseCommit:
+			return [3]int{}, true
+		}
+	case v.Vitess:
+		// Vitess parses a subset of MySQL 8.0; row aliases in upserts are not part of it.
+		switch feature {
+		case FeatureCTE, FeatureWindowFunctions:
+			return [3]int{8, 0, 0}, true
+		}
+	case v.Driver == MySQL:
+		switch feature {
+		case FeatureCTE, FeatureWindowFunctions:
+			return [3]int{8, 0, 0}, true
+		case FeatureInsertAlias:
+			return [3]int{8, 0, 20}, true
+		case FeatureIntersectExcept:
+			return [3]int{8, 0, 31}, true
+		case FeatureTwoPhaseCommit:
+			return [3]int{}, true
+		}
+	case v.Driver == PostgreSQL:
+		switch feature {
+		case FeatureCTE, FeatureWindowFunctions, FeatureReturning, FeatureTwoPhaseCommit, FeatureIntersectExcept:
+			return [3]int{}, true
+		}
+	case v.Driver == ClickHouse:
+		switch feature {
+		case FeatureCTE, FeatureWindowFunctions:
+			return [3]int{}, true
+		case FeatureIntersectExcept:
+			return [3]int{21, 8, 0}, true
+		}
+	}
+	return [3]int{}, false
+}
+
+// Supports reports whether the server supports feature. It is false when the version is unknown,
+// so callers pick their fallback; use Require to fail only on servers known to lack the feature.
+func (v ServerVersion) Supports(feature Feature) bool {
+	if !v.Known() {
+		return false
+	}
+	minimum, ok := v.minimum(feature)
+	return ok && v.AtLeast(minimum[0], minimum[1], minimum[2])
+}
+
+// Require returns an error wrapping ErrUnsupportedFeature if the server is known not to support
+// feature. Unknown versions pass, leaving the decision to the server.
+func (v ServerVersion) Require(feature Feature) error {
+	if !v.Known() || v.Supports(feature) {
+		return nil
+	}
+	minimum, ok := v.minimum(feature)
+	if !ok {
+		return fmt.Errorf("%w: %s is not supported by %s", ErrUnsupportedFeature, feature, v.product())
+	}
+	return fmt.Errorf("%w: %s requires %s %d.%d.%d or later, server is %s",
+		ErrUnsupportedFeature, feature, v.product(), minimum[0], minimum[1], minimum[2], v)
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		driver  Driver
+		raw     string
+		mariaDB bool
+		want    [3]int
+	}{
+		{MySQL, "8.0.36-0ubuntu0.22.04.1", false, [3]int{8, 0, 36}},
+		{MySQL, "10.5.8-MariaDB-1:10.5.8+maria~focal", true, [3]int{10, 5, 8}},
+		{MySQL, "5.5.5-10.4.32-MariaDB", true, [3]int{10, 4, 32}},
+		{PostgreSQL, "PostgreSQL 16.2 on x86_64-pc-linux-gnu", false, [3]int{16, 2, 0}},
+		{ClickHouse, "23.8.1.1", false, [3]int{23, 8, 1}},
+		{MySQL, "unknown", false, [3]int{}},
+	}
+
+	for _, tt := range tests {
+		v := ParseServerVersion(tt.driver, tt.raw)
+		if v.MariaDB != tt.mariaDB || [3]int{v.Major, v.Minor, v.Patch} != tt.want {
+			t.Errorf("ParseServerVersion(%q) = %+v", tt.raw, v)
+		}
+	}
+}
+
+func TestServerVersionFeatures(t *testing.T) {
+	mysql57 := ParseServerVersion(MySQL, "5.7.44")
+	mysql8 := ParseServerVersion(MySQL, "8.0.20")
+	mariaDB104 := ParseServerVersion(MySQL, "10.4.32-MariaDB")
+	mariaDB105 := ParseServerVersion(MySQL, "10.5.0-MariaDB")
+
+	if mysql57.Supports(FeatureCTE) || !mysql8.Supports(FeatureCTE) || !mysql8.Supports(FeatureWindowFunctions) {
+		t.Error("Expected CTEs and window functions from MySQL 8.0")
+	}
+	if !mysql8.Supports(FeatureInsertAlias) || ParseServerVersion(MySQL, "8.0.19").Supports(FeatureInsertAlias) {
+		t.Error("Expected INSERT ... AS alias from MySQL 8.0.20")
+	}
+	if mysql8.Supports(FeatureIntersectExcept) || !ParseServerVersion(MySQL, "8.0.31").Supports(FeatureIntersectExcept) ||
+		!mariaDB104.Supports(FeatureIntersectExcept) || !ParseServerVersion(PostgreSQL, "PostgreSQL 12.1").Supports(FeatureIntersectExcept) {
+		t.Error("Expected INTERSECT and EXCEPT from MySQL 8.0.31, MariaDB 10.3 and PostgreSQL")
+	}
+	if mariaDB104.Supports(FeatureReturning) || !mariaDB105.Supports(FeatureReturning) || mariaDB105.Supports(FeatureInsertAlias) {
+		t.Error("Expected RETURNING from MariaDB 10.5 and no insert alias")
+	}
+
+	err := mariaDB104.Require(FeatureReturning)
+	if !errors.Is(err, ErrUnsupportedFeature) || err.Error() != "feature not supported by the database server: "+
+		"RETURNING requires MariaDB 10.5.0 or later, server is MariaDB 10.4.32" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mysql8.Require(FeatureReturning); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Errorf("Expected RETURNING to be unsupported on MySQL, got %v", err)
+	}
+	vitess := ParseServerVersion(MySQL, "8.0.31-Vitess")
+	if !vitess.Vitess || vitess.Supports(FeatureInsertAlias) || !errors.Is(vitess.Require(FeatureTwoPhaseCommit), ErrUnsupportedFeature) {
+		t.Errorf("Expected Vitess to lack row aliases and XA, got %+v", vitess)
+	}
+	if err := (ServerVersion{}).Require(FeatureReturning); err != nil {
+		t.Errorf("Expected unknown versions to pass, got %v", err)
+	}
+}